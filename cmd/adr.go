@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var adrIndexPage string
+
+func init() {
+	adrCmd.Flags().StringVar(&adrIndexPage, "index-page", "Architecture Decisions", "Title of the page listing the decision records by number, their parent page")
+	rootCmd.AddCommand(adrCmd)
+}
+
+// adrCmd publishes architecture decision records
+var adrCmd = &cobra.Command{
+	Use:   "adr <directory>",
+	Short: "Publish MADR or adr-tools decision records with status labels below an ADR index page",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !m.ValidateOnly {
+			if m.Space == "" {
+				log.Fatal("--space is not defined")
+			}
+			if err := m.ValidateConnection(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		configureRenderer()
+		publish(func() []error {
+			return m.PublishADRs(args[0], adrIndexPage)
+		})
+	},
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	changelogReleasesPage string
+	changelogTitlePrefix  string
+	changelogAll          bool
+)
+
+func init() {
+	changelogCmd.Flags().StringVar(&changelogReleasesPage, "releases-page", "Releases", "Title of the parent page holding a page per release")
+	changelogCmd.Flags().StringVar(&changelogTitlePrefix, "title-prefix", "", "Prefix added to the version in every release page title, e.g. 'MyApp '")
+	changelogCmd.Flags().BoolVar(&changelogAll, "all", false, "Update every release page instead of only the newest one")
+	rootCmd.AddCommand(changelogCmd)
+}
+
+// changelogCmd publishes a changelog as a page per release
+var changelogCmd = &cobra.Command{
+	Use:   "changelog <CHANGELOG.md>",
+	Short: "Publish each version section of a changelog as a child page of a releases page",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !m.ValidateOnly {
+			if m.Space == "" {
+				log.Fatal("--space is not defined")
+			}
+			if err := m.ValidateConnection(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		configureRenderer()
+		publish(func() []error {
+			return m.PublishChangelog(args[0], changelogReleasesPage, changelogTitlePrefix, changelogAll)
+		})
+	},
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	lib "github.com/justmiles/go-markdown2confluence/lib"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkLinksLive bool
+	checkLinksFail bool
+)
+
+func init() {
+	checkLinksCmd.Flags().BoolVar(&checkLinksLive, "live", false, "Check the published pages instead of the rendered files")
+	checkLinksCmd.Flags().BoolVar(&checkLinksFail, "fail", false, "Exit with 1 when broken links are found")
+	rootCmd.AddCommand(checkLinksCmd)
+}
+
+// checkLinksCmd reports broken links of markdown files or their published pages
+var checkLinksCmd = &cobra.Command{
+	Use:   "check-links <file|directory>...",
+	Short: "Report dead relative links, missing attachments and unresolved page links",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if checkLinksLive && m.Space == "" {
+			log.Fatal("--space is not defined")
+		}
+		// page links are only resolved against Confluence when a space is given
+		if m.Space != "" {
+			if err := m.ValidateConnection(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		configureRenderer()
+		m.SourceMarkdown = args
+
+		ci := m.CI
+		if ci == "auto" {
+			ci = lib.DetectCI()
+		}
+		level := "warning"
+		if checkLinksFail {
+			level = "error"
+		}
+
+		problems, errors := m.CheckLinks(checkLinksLive)
+		for _, p := range problems {
+			lib.PrintCIAnnotation(ci, level, p.Position, p.Kind+" "+p.Target)
+		}
+		for _, err := range errors {
+			fmt.Println(err)
+		}
+		if len(problems) == 0 && len(errors) == 0 {
+			fmt.Println("no broken links found")
+		}
+		if len(errors) > 0 || (checkLinksFail && len(problems) > 0) {
+			exit(1)
+		}
+	},
+}
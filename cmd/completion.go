@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	lib "github.com/justmiles/go-markdown2confluence/lib"
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+
+	"github.com/spf13/cobra"
+)
+
+// flagValues are the values completed for flags taking one of a few values
+var flagValues = map[string][]string{
+	"code-block-theme":      renderer.CodeBlockThemes,
+	"oversized-attachments": {lib.OversizedFail, lib.OversizedSkip, lib.OversizedZip},
+	"on-conflict":           {lib.ConflictFail, lib.ConflictSkip, lib.ConflictRetry, lib.ConflictForce},
+	"manual-edits":          {lib.ManualEditsWarn, lib.ManualEditsSkip},
+	"heading-slug":          {renderer.SlugGitHub, renderer.SlugGitLab},
+	"source-encoding":       lib.SourceEncodings,
+	"transliterate":         {"slugs", "titles"},
+	"quote-style":           {renderer.QuoteBlockquote, renderer.QuotePanel},
+	"inline-code":           {renderer.InlineCodeCode, renderer.InlineCodeMonospace, renderer.InlineCodeStatus},
+	"horizontal-rules":      {renderer.HorizontalRulesHR, renderer.HorizontalRulesSection},
+	"list-spacing":          {renderer.ListSpacingKeep, renderer.ListSpacingTight},
+	"badges":                {renderer.BadgesKeep, renderer.BadgesStrip, renderer.BadgesStatus, renderer.BadgesAttach},
+	"kroki-format":          {"svg", "png"},
+}
+
+// registerCompletions completes flag values and markdown arguments in the
+// shell completions of the completion command. It runs once the flags are
+// defined.
+func registerCompletions() {
+	for flag, values := range flagValues {
+		values := values
+		if err := rootCmd.RegisterFlagCompletionFunc(flag, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return values, cobra.ShellCompDirectiveNoFileComp
+		}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles); err != nil {
+		log.Fatal(err)
+	}
+	rootCmd.ValidArgsFunction = completeMarkdown
+}
+
+// completeMarkdown completes markdown files and directories, unless the
+// argument can still be a subcommand: the shells take all completions as file
+// extensions to filter by, the names of subcommands too
+func completeMarkdown(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		for _, c := range cmd.Commands() {
+			if c.IsAvailableCommand() && strings.HasPrefix(c.Name(), toComplete) {
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+		}
+	}
+	return []string{"md", "markdown", "mdx"}, cobra.ShellCompDirectiveFilterFileExt
+}
+
+// completeProfiles completes the profiles of the repo config
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	config, err := lib.FindRepoConfig(".")
+	if err != nil || config == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	profiles := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	return profiles, cobra.ShellCompDirectiveNoFileComp
+}
+
+// isCompletionCmd reports whether cmd writes or serves shell completions
+func isCompletionCmd(cmd *cobra.Command) bool {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		switch cmd.Name() {
+		case "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var copyTitlePrefix string
+
+func init() {
+	copyTreeCmd.Flags().StringVar(&copyTitlePrefix, "title-prefix", "", "Prefix added to the title of every copied page (required when copying within a space)")
+	rootCmd.AddCommand(copyTreeCmd)
+}
+
+// copyTreeCmd clones a published page tree below another parent
+var copyTreeCmd = &cobra.Command{
+	Use:   "copy-tree <source page id> <destination parent id>",
+	Short: "Copy a page and all its descendants below another parent page",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := m.ValidateConnection(); err != nil {
+			log.Fatal(err)
+		}
+		if err := m.CopyTree(context.Background(), args[0], args[1], copyTitlePrefix); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	lib "github.com/justmiles/go-markdown2confluence/lib"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffFormat   string
+	diffExitCode bool
+)
+
+func init() {
+	diffCmd.Flags().StringVar(&diffFormat, "format", lib.DiffUnified, "Diff format: unified or html")
+	diffCmd.Flags().BoolVar(&diffExitCode, "exit-code", false, "Exit with 1 when the page would change")
+	rootCmd.AddCommand(diffCmd)
+}
+
+// diffCmd shows what publishing a file would change on its page
+var diffCmd = &cobra.Command{
+	Use:   "diff <file>",
+	Short: "Show the differences between a rendered markdown file and its published page",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if diffFormat != lib.DiffUnified && diffFormat != lib.DiffHTML {
+			log.Fatalf("unknown diff format %q, use unified or html", diffFormat)
+		}
+		if m.Space == "" {
+			log.Fatal("--space is not defined")
+		}
+		if err := m.ValidateConnection(); err != nil {
+			log.Fatal(err)
+		}
+		configureRenderer()
+
+		diff, err := m.Diff(args[0], diffFormat)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if diff == "" {
+			if diffFormat == lib.DiffUnified {
+				fmt.Printf("%s: no changes\n", args[0])
+			}
+			return
+		}
+		fmt.Print(diff)
+		if diffExitCode {
+			exit(1)
+		}
+	},
+}
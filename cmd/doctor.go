@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctorCmd checks everything a publish needs before running one
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the endpoint, credentials, Confluence version and --space, and the external programs some features need",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := false
+		for _, check := range m.Doctor() {
+			switch {
+			case check.Err == nil:
+				fmt.Printf("ok    %s: %s\n", check.Name, check.Detail)
+				continue
+			case check.Optional:
+				fmt.Printf("warn  %s: %s\n", check.Name, check.Err)
+			default:
+				fmt.Printf("FAIL  %s: %s\n", check.Name, check.Err)
+				failed = true
+			}
+			fmt.Printf("      %s\n", check.Remedy)
+		}
+		if failed {
+			exit(1)
+		}
+	},
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(openAPICmd)
+}
+
+// openAPICmd publishes an API description as a reference page
+var openAPICmd = &cobra.Command{
+	Use:   "openapi <spec.yaml|spec.json>",
+	Short: "Publish an OpenAPI or Swagger spec as a page of endpoint tables and schemas",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !m.ValidateOnly {
+			if m.Space == "" {
+				log.Fatal("--space is not defined")
+			}
+			if err := m.ValidateConnection(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		configureRenderer()
+		publish(func() []error {
+			return m.PublishOpenAPI(args[0])
+		})
+	},
+}
@@ -6,15 +6,74 @@ import (
 	"github.com/justmiles/go-markdown2confluence/lib/renderer"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	lib "github.com/justmiles/go-markdown2confluence/lib"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var m lib.Markdown2Confluence
 
+// byte sizes parsed into m once flags are parsed
+var (
+	maxUploadRate     string
+	maxAttachmentSize string
+	maxBodySize       string
+)
+
+// normalize is parsed into m.Normalization
+var normalize []string
+
+// transliterate lists what to spell in ASCII: slugs and titles
+var transliterate []string
+
+// glossaryFile is loaded into m.Glossary
+var glossaryFile string
+
+// ownerGroupsFile is loaded into m.OwnerGroups
+var ownerGroupsFile string
+
+// destructiveCommands is compiled into renderer.DestructiveCommands
+var destructiveCommands string
+
+// where to write the pages published, see lib.WriteURLMap and lib.PrintURLs,
+// and the results of the run, see lib.WriteReport
+var (
+	urlMapFile string
+	printURLs  bool
+	reportFile string
+)
+
+// HAR files API calls are recorded to or replayed from
+var (
+	recordPath    string
+	replayPath    string
+	stopRecording func() error
+)
+
+// the --audit-log of mutating API calls, published to auditPage
+var (
+	auditLogFile string
+	auditPage    string
+	auditor      *lib.Auditor
+)
+
+// the directory pprof profiles of the run are written to
+var (
+	profileDir  string
+	stopProfile func() error
+)
+
+// profileName is the --profile of the repo config to take flag values from
+var profileName string
+
 func init() {
 	log.SetFlags(0)
 
@@ -37,39 +96,448 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&m.CodeBlockTheme, "code-block-theme", "y", "RDark", "Set the code block theme,default 'RDark'")
 	rootCmd.PersistentFlags().BoolVarP(&m.CodeBlockCollapse, "code-block-collapse", "z", false, "Set the code block collapse,default 'false'")
 	rootCmd.PersistentFlags().BoolVarP(&m.CodeBlockShowLineNumbers, "code-block-show-line-numbers", "l", true, "Set the code block show line numbers,default 'true'")
+	rootCmd.PersistentFlags().BoolVar(&m.CodeBlockWrap, "code-block-wrap", false, "Wrap long lines of code blocks instead of scrolling them")
+	rootCmd.PersistentFlags().IntVar(&renderer.CodeBlockCollapseLines, "code-block-collapse-lines", 0, "Collapse code blocks longer than n lines (0 disables)")
+	rootCmd.PersistentFlags().IntVar(&renderer.CodeBlockAttachLines, "code-block-attach-lines", 0, "Attach code blocks longer than n lines as files shown with the view-file macro (0 disables)")
+	rootCmd.PersistentFlags().BoolVar(&renderer.DrawioMacro, "drawio-macro", true, "Render .drawio files with the draw.io macro. Set to false to embed a PNG export instead")
+	rootCmd.PersistentFlags().StringVar(&m.PreRenderHook, "pre-render-hook", "", "Command run before rendering each file. It receives the markdown on stdin and M2C_SOURCE_PATH, its stdout is rendered instead")
+	rootCmd.PersistentFlags().StringVar(&m.PostPublishHook, "post-publish-hook", "", "Command run after each page is published with M2C_SOURCE_PATH, M2C_PAGE_TITLE, M2C_PAGE_ID and M2C_PAGE_URL set")
+	rootCmd.PersistentFlags().StringVar(&renderer.OpenAPIMacro, "openapi-macro", "", "Name of an installed Open API viewer macro to hand specs to instead of rendering tables")
+	rootCmd.PersistentFlags().BoolVar(&renderer.Notebook, "notebook", false, "Render Jupyter and R Markdown exports: code blocks without a language as cell output and data URI images as attachments")
+	rootCmd.PersistentFlags().IntVar(&renderer.NotebookOutputLines, "notebook-output-lines", 20, "With --notebook, wrap cell outputs longer than n lines in an expand macro (0 disables)")
+	rootCmd.PersistentFlags().BoolVar(&renderer.MDX, "mdx", false, "Tolerate Docusaurus MDX: publish .mdx files, drop imports and JSX tags and render :::note containers as panels")
+	rootCmd.PersistentFlags().BoolVar(&m.ContentHash, "content-hash", false, "Store a hash of the rendered body in a page property and skip updating pages whose hash did not change")
+	rootCmd.PersistentFlags().BoolVar(&m.Prefetch, "prefetch", false, "Fetch the version and content hash of all pages below the parent with a few CQL searches instead of a request per file")
+	rootCmd.PersistentFlags().BoolVar(&m.Progress, "progress", false, "Show pages done, attachments, the current file and an ETA on stderr, as a status line on a terminal and as periodic log lines otherwise")
+	rootCmd.PersistentFlags().StringVar(&maxUploadRate, "max-upload-rate", "", "Limit attachment uploads and downloads to this many bytes per second in total, e.g. 512K or 2M")
+	rootCmd.PersistentFlags().StringVar(&maxAttachmentSize, "max-attachment-size", "100M", "Attachment size limit of the instance, files over it are handled by --oversized-attachments (0 disables)")
+	rootCmd.PersistentFlags().StringVar(&maxBodySize, "max-body-size", "5M", "Largest rendered page body, larger files fail unless --split-large-pages splits them (0 disables)")
+	rootCmd.PersistentFlags().BoolVar(&m.SplitLargePages, "split-large-pages", false, "Publish the top-level sections of files rendering over --max-body-size as child pages, leaving the text before them and a list of the sections on the page")
+	rootCmd.PersistentFlags().BoolVar(&m.SplitH1, "split-h1", false, "Publish every H1 heading of a file as a page of its own, titled after the heading, with the text up to the next H1")
+	rootCmd.PersistentFlags().BoolVar(&m.Combine, "combine", false, "Publish all files as one page, in the order they are given, each below an anchor named after its path, after a table of contents")
+	rootCmd.PersistentFlags().StringVar(&m.OversizedAttachments, "oversized-attachments", lib.OversizedFail, "What to do with attachments over --max-attachment-size: fail, skip or zip")
+	rootCmd.PersistentFlags().BoolVar(&m.PreserveInlineComments, "preserve-inline-comments", false, "Re-anchor inline comments of updated pages on their text in the new body and report those that can not be")
+	rootCmd.PersistentFlags().BoolVar(&m.Excerpt, "excerpt", false, "Wrap the front matter summary, or else the first paragraph, in the excerpt macro for page previews")
+	rootCmd.PersistentFlags().StringVar(&m.IndexPage, "index-page", "", "Create or refresh a page of this title below the parent, listing links to every published page with its front matter summary")
+	rootCmd.PersistentFlags().BoolVar(&renderer.Runbook, "runbook", false, "Follow shell code blocks with a noformat block of the bare commands to copy and warn about destructive commands")
+	rootCmd.PersistentFlags().StringVar(&destructiveCommands, "destructive-commands", renderer.DefaultDestructiveCommands, "With --runbook, regular expression matching command lines to warn about")
+	rootCmd.PersistentFlags().BoolVar(&m.CodeOwners, "codeowners", false, "Label pages owner-<team> for the teams CODEOWNERS assigns their file to, and restrict editing to the teams' groups")
+	rootCmd.PersistentFlags().StringVar(&ownerGroupsFile, "owner-groups", "", "JSON or YAML file mapping CODEOWNERS teams like '@acme/docs' to Confluence groups, by default the group is named like the team")
+	rootCmd.PersistentFlags().StringSliceVar(&m.Locales, "locales", []string{}, "Publish files named like guide.de.md below a page named after the language, or with de=SPACE to their own space, linking the language versions of each page")
+	rootCmd.PersistentFlags().StringVar(&m.DefaultLocale, "default-locale", "en", "Language of files without a locale suffix, with --locales")
+	rootCmd.PersistentFlags().StringVar(&glossaryFile, "glossary", "", "JSON or YAML file mapping terms to 'Page Title' or 'Page Title#anchor', the first occurrence of each term on a page links there")
+	rootCmd.PersistentFlags().BoolVar(&renderer.HeadingAnchors, "heading-anchors", false, "Add an anchor macro named after its slug to every heading, so deep links survive republishing")
+	rootCmd.PersistentFlags().StringVar(&renderer.HeadingSlug, "heading-slug", renderer.SlugGitHub, "Algorithm generating heading slugs: github or gitlab")
+	rootCmd.PersistentFlags().StringSliceVar(&normalize, "normalize", lib.DefaultNormalization, "Normalize sources before rendering: crlf line endings to LF, trailing-whitespace except hard breaks, tabs=N to spaces, or none")
+	rootCmd.PersistentFlags().StringVar(&lib.SourceEncoding, "source-encoding", lib.EncodingAuto, "Encoding of markdown sources: "+strings.Join(lib.SourceEncodings, ", ")+"; auto detects UTF-16, GBK and Windows-1252 and warns about converted files")
+	rootCmd.PersistentFlags().StringSliceVar(&transliterate, "transliterate", []string{}, "Spell accented letters in ASCII in slugs, titles or both, e.g. 'Über' as 'Uber'; CJK and other scripts are kept")
+	rootCmd.PersistentFlags().BoolVar(&renderer.Deterministic, "deterministic", false, "Render byte-identical storage format for identical input: sorted attributes, stable macro ids and no timestamps")
+	rootCmd.PersistentFlags().StringVar(&m.TitleTemplate, "title-template", "", "Go template for page titles, e.g. 'Meeting notes {{ .Date | date \"2006-01-02\" }}', with .Title, .Path, .FrontMatter, .Env, .Date, .GitBranch and .GitCommit")
+	rootCmd.PersistentFlags().StringVar(&m.HeaderTemplate, "header", "", "Markdown template file rendered at the top of every page, with the variables of --title-template")
+	rootCmd.PersistentFlags().StringVar(&m.FooterTemplate, "footer", "", "Markdown template file rendered at the bottom of every page, with the variables of --title-template")
+	rootCmd.PersistentFlags().BoolVar(&m.Contributors, "contributors", false, "Append the contributors, last modified date and a history link from the git log of the source file to every page")
+	rootCmd.PersistentFlags().BoolVar(&m.Drafts, "drafts", false, "Publish files with 'draft: true' front matter and the sections between <!-- m2c:begin-draft --> and <!-- m2c:end-draft --> markers")
+	rootCmd.PersistentFlags().BoolVar(&m.Banner, "banner", false, "Add an info panel with the publish date, source link, reading time and CODEOWNERS owners at the top of every page")
+	rootCmd.PersistentFlags().BoolVar(&m.ManagedNotice, "managed-notice", false, "Add an info panel saying the page is generated from its source file and edits will be overwritten at the top of every page")
+	rootCmd.PersistentFlags().BoolVar(&m.EditLock, "edit-lock", false, "Restrict editing of every published page to the publishing user, with --codeowners to the owners as well")
+	rootCmd.PersistentFlags().StringVar(&m.BannerTemplate, "banner-template", "", "Markdown template file for the --banner panel, with the variables of --title-template plus .Published, .RepoURL, .SourceURL, .SourcePath, .Owners, .Words and .ReadingTime")
+	rootCmd.PersistentFlags().StringVar(&reportFile, "report", "", "Write the result of every file as JSON to this file, which the undo command rolls back")
+	rootCmd.PersistentFlags().StringVar(&urlMapFile, "url-map", "", "Write a JSON file, or s3:// or gs:// object, mapping source paths to page id, title, short and full URL after publishing, keeping the entries of earlier runs")
+	rootCmd.PersistentFlags().StringVar(&m.Redirects, "redirects", "", "Leave a page linking to the new title at the old title of pages whose file got a new title since the --url-map was written: link, or macro to add a redirect macro")
+	rootCmd.PersistentFlags().BoolVar(&printURLs, "print-urls", false, "Print the source path and page URL of every published file, tab separated, after publishing")
+	rootCmd.PersistentFlags().StringVar(&profileDir, "pprof", "", "Write CPU and heap pprof profiles of the run to cpu.pprof and heap.pprof in this directory")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Use the flag values of this profile of the repo config for the flags not given, instead of its default profile")
+	rootCmd.PersistentFlags().StringSliceVar(&m.Labels, "labels", []string{}, "Labels added to every published page")
+	rootCmd.PersistentFlags().StringVar(&recordPath, "record", "", "Record all API calls with credentials redacted to this HAR file, to attach to bug reports")
+	rootCmd.PersistentFlags().StringVar(&auditLogFile, "audit-log", "", "Append every create, update and delete of pages, attachments, labels and properties with time, user, page id and version to this JSON lines file")
+	rootCmd.PersistentFlags().StringVar(&auditPage, "audit-page", "", "Also append the --audit-log entries of each run as a table to the page with this title in --space")
+	rootCmd.PersistentFlags().StringVar(&replayPath, "replay", "", "Answer API calls from a HAR file written by --record instead of the network")
+	rootCmd.PersistentFlags().StringVar(&m.EndpointsFile, "endpoints", "", "JSON or YAML file of named endpoints, each with its own space and parent, to publish the same content to")
+	rootCmd.PersistentFlags().BoolVar(&m.Obsidian, "obsidian", false, "Support Obsidian [[wikilinks]], ![[embeds]], callouts and front matter aliases")
+	rootCmd.PersistentFlags().BoolVar(&m.Interactive, "interactive", false, "List the pages to create and overwrite with the number of changed lines and ask before publishing, and before every delete and move")
+	rootCmd.PersistentFlags().BoolVar(&m.AssumeYes, "yes", false, "Answer yes to the questions of --interactive")
+	rootCmd.PersistentFlags().BoolVar(&m.Lock, "lock", false, "Hold a lock on the root page while publishing so concurrent syncs of the same tree run one after the other")
+	rootCmd.PersistentFlags().IntVar(&m.LockTTL, "lock-ttl", 10, "Minutes after which a lock of a run that stopped renewing it can be taken over")
+	rootCmd.PersistentFlags().IntVar(&m.LockWait, "lock-wait", 0, "Minutes to wait for a lock held by another run before failing")
+	rootCmd.PersistentFlags().StringVar(&m.OnConflict, "on-conflict", lib.ConflictFail, "What to do with a page edited by someone else during the run: fail, skip it, retry on top of the edit or force the update")
+	rootCmd.PersistentFlags().StringVar(&m.ManualEdits, "manual-edits", "", "Track the published body of pages to detect edits made in Confluence since: warn to publish over them, skip to leave edited pages alone")
+	rootCmd.PersistentFlags().StringVar(&m.MergeReport, "merge-report", "", "Write a three-way merge of the last published version, the Confluence edit and the markdown of manually edited pages to this file")
+	rootCmd.PersistentFlags().BoolVar(&m.ResolveLinks, "resolve-links", false, "Turn links to tiny links (/x/AbCd), page URLs of the Confluence published to and markdown files into page links, which follow renames")
+	rootCmd.PersistentFlags().StringVar(&m.LinkBaseURL, "link-base-url", "", "Publish links to other files of the repository, such as source files, as this URL followed by their path, e.g. https://github.com/org/repo/blob/v1.2, or auto for the origin remote at the checked out branch")
+	rootCmd.PersistentFlags().IntVar(&m.RenderParallel, "render-parallel", 0, "Number of files to render at a time before uploading, 0 for one per CPU")
+	rootCmd.PersistentFlags().StringSliceVar(&m.SafeMode.Spaces, "safe-spaces", []string{}, "Safe mode: refuse to delete, move or update pages outside of these space keys")
+	rootCmd.PersistentFlags().StringVar(&m.SafeMode.RootID, "safe-root", "", "Safe mode: refuse to delete, move or update pages that are not below this page id")
+	rootCmd.PersistentFlags().BoolVar(&m.SkipPreflight, "skip-preflight", false, "Skip checking space permissions before publishing")
+	rootCmd.PersistentFlags().StringVar(&m.AssetsPage, "assets-page", "", "Attach images used by several files once to this page and reference them from there")
+	rootCmd.PersistentFlags().StringVar(&m.CI, "ci", "auto", "CI integration for annotations, job summary and outputs: auto, github, gitlab or none")
+	rootCmd.PersistentFlags().StringVar(&m.NotifyWebhook, "notify-webhook", "", "Post a summary of published pages to this Slack, Teams or generic JSON webhook after the run")
+	rootCmd.PersistentFlags().BoolVar(&m.Verify, "verify", false, "Fetch each page back after publishing and report markup Confluence changed or stripped")
+	rootCmd.PersistentFlags().BoolVar(&m.ValidateOnly, "validate-only", false, "Render and validate the storage format of all files without uploading anything")
+	rootCmd.PersistentFlags().BoolVar(&renderer.Strict, "strict", false, "Fail the files with warnings about unsupported markdown, of the classes the warnings policy of the repo config makes errors (all by default)")
+	rootCmd.PersistentFlags().BoolVar(&renderer.StrictMacros, "strict-macros", false, "Fail the conversion when a CONFLUENCE-MACRO block does not match the known macro schema")
+	rootCmd.PersistentFlags().StringVar(&m.MacroMappingFile, "macro-mapping", "", "JSON file mapping fenced code languages to Confluence macros")
+	rootCmd.PersistentFlags().StringVar(&renderer.DrawioCommand, "drawio-command", "drawio", "draw.io desktop binary used to export .drawio files to PNG")
+	rootCmd.PersistentFlags().StringSliceVar(&renderer.HighlightLanguages, "highlight-languages", []string{}, "Code block languages to pre-render as highlighted HTML instead of the code macro ('*' for all)")
+	rootCmd.PersistentFlags().BoolVar(&renderer.HighlightUnsupported, "highlight-unsupported", false, "Pre-render code blocks as highlighted HTML when the code macro does not support their language")
+	rootCmd.PersistentFlags().StringVar(&renderer.QuoteStyle, "quote-style", renderer.QuoteBlockquote, "Style of block quotes: blockquote, or panel for a grey panel that looks the same in every Confluence theme")
+	rootCmd.PersistentFlags().StringVar(&renderer.InlineCode, "inline-code", renderer.InlineCodeCode, "Style of inline code: code, monospace for monospace text without a background, or status for subtle grey status macros")
+	rootCmd.PersistentFlags().StringVar(&renderer.HorizontalRules, "horizontal-rules", renderer.HorizontalRulesHR, "Rendering of horizontal rules: hr, or section to start a new page layout section at each rule at the top level of a page")
+	rootCmd.PersistentFlags().StringVar(&renderer.ListSpacing, "list-spacing", renderer.ListSpacingKeep, "Spacing of list items: keep wraps the items of lists with blank lines between them in paragraphs, tight never does")
+	rootCmd.PersistentFlags().StringVar(&renderer.Badges, "badges", renderer.BadgesKeep, "What to do with badge images such as shields.io SVGs: keep them, strip them, turn them into status macros or download and attach them")
+	rootCmd.PersistentFlags().StringVar(&renderer.KrokiServer, "kroki-server", "", "Render diagram code blocks (mermaid, plantuml, d2, graphviz, ...) to attachments using this Kroki server, e.g. https://kroki.io")
+	rootCmd.PersistentFlags().StringVar(&renderer.KrokiFormat, "kroki-format", "svg", "Image format requested from the Kroki server (svg or png)")
+	rootCmd.SetGlobalNormalizationFunc(aliasFlags)
+	registerCompletions()
 
 	m.SourceEnvironmentVariables()
-	renderer.CodeBlockTheme = m.CodeBlockTheme
-	renderer.CodeBlockCollapse = m.CodeBlockCollapse
-	renderer.CodeBlockShowLineNumbers = m.CodeBlockShowLineNumbers
+}
 
+// flagAliases are shorter names of flags
+var flagAliases = map[string]string{
+	"code-theme":       "code-block-theme",
+	"code-linenumbers": "code-block-show-line-numbers",
+	"code-wrap":        "code-block-wrap",
+}
+
+// aliasFlags normalizes flagAliases to the flags they stand for
+func aliasFlags(f *pflag.FlagSet, name string) pflag.NormalizedName {
+	if flag, ok := flagAliases[name]; ok {
+		name = flag
+	}
+	return pflag.NormalizedName(name)
 }
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "markdown2confluence",
 	Short: "Push markdown files to Confluence Cloud",
+	// markdown files and directories are passed as arguments next to the subcommands
+	Args: cobra.ArbitraryArgs,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// the version check must not lock out the command fixing it, nor
+		// break shell completions
+		if cmd.Name() != "self-update" && !isCompletionCmd(cmd) {
+			config, err := lib.FindRepoConfig(".")
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := config.CheckVersion(cmd.Root().Version); err != nil {
+				log.Fatal(err)
+			}
+			if renderer.WarningPolicy, err = config.WarningPolicy(); err != nil {
+				log.Fatal(err)
+			}
+			// profile values are checked like the flags they set below
+			if err := applyProfile(cmd, config); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if profileDir != "" {
+			var err error
+			if stopProfile, err = lib.StartProfile(profileDir); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if m.InsecureTLS {
+			fmt.Println("Warning: TLS verification is disabled. This allows for man-in-the-middle-attacks.")
+			http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		if recordPath != "" && replayPath != "" {
+			log.Fatal("--record and --replay can not be combined")
+		}
+		if replayPath != "" {
+			if err := lib.ReplayHTTP(replayPath); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if recordPath != "" {
+			stopRecording = lib.RecordHTTP(recordPath, cmd.Root().Version)
+		}
+		if auditPage != "" && auditLogFile == "" {
+			log.Fatal("--audit-page needs --audit-log")
+		}
+		if auditLogFile != "" {
+			var err error
+			if auditor, err = lib.AuditHTTP(auditLogFile); err != nil {
+				log.Fatal(err)
+			}
+		}
+		rate, err := lib.ParseByteSize(maxUploadRate)
+		if err != nil {
+			log.Fatalf("--max-upload-rate: %s", err)
+		}
+		m.MaxUploadRate = rate
+		size, err := lib.ParseByteSize(maxAttachmentSize)
+		if err != nil {
+			log.Fatalf("--max-attachment-size: %s", err)
+		}
+		m.MaxAttachmentSize = size
+		if m.MaxBodySize, err = lib.ParseByteSize(maxBodySize); err != nil {
+			log.Fatalf("--max-body-size: %s", err)
+		}
+		if m.LockTTL < 1 || m.LockWait < 0 {
+			log.Fatal("--lock-ttl must be at least 1 and --lock-wait must not be negative")
+		}
+		switch m.OnConflict {
+		case lib.ConflictFail, lib.ConflictSkip, lib.ConflictRetry, lib.ConflictForce:
+		default:
+			log.Fatalf("unknown --on-conflict %q, use fail, skip, retry or force", m.OnConflict)
+		}
+		switch m.ManualEdits {
+		case "", lib.ManualEditsWarn, lib.ManualEditsSkip:
+		default:
+			log.Fatalf("unknown --manual-edits %q, use warn or skip", m.ManualEdits)
+		}
+		if m.MergeReport != "" && m.ManualEdits == "" {
+			log.Fatal("--merge-report needs --manual-edits")
+		}
+		switch renderer.QuoteStyle {
+		case renderer.QuoteBlockquote, renderer.QuotePanel:
+		default:
+			log.Fatalf("unknown --quote-style %q, use blockquote or panel", renderer.QuoteStyle)
+		}
+		switch renderer.InlineCode {
+		case renderer.InlineCodeCode, renderer.InlineCodeMonospace, renderer.InlineCodeStatus:
+		default:
+			log.Fatalf("unknown --inline-code %q, use code, monospace or status", renderer.InlineCode)
+		}
+		switch renderer.HorizontalRules {
+		case renderer.HorizontalRulesHR, renderer.HorizontalRulesSection:
+		default:
+			log.Fatalf("unknown --horizontal-rules %q, use hr or section", renderer.HorizontalRules)
+		}
+		switch renderer.ListSpacing {
+		case renderer.ListSpacingKeep, renderer.ListSpacingTight:
+		default:
+			log.Fatalf("unknown --list-spacing %q, use keep or tight", renderer.ListSpacing)
+		}
+		switch renderer.Badges {
+		case renderer.BadgesKeep, renderer.BadgesStrip, renderer.BadgesStatus, renderer.BadgesAttach:
+		default:
+			log.Fatalf("unknown --badges %q, use keep, strip, status or attach", renderer.Badges)
+		}
+		if m.LinkBaseURL != "" && m.LinkBaseURL != lib.LinkBaseAuto {
+			if u, err := url.Parse(m.LinkBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				log.Fatalf("invalid --link-base-url %q, use an http(s) URL or auto", m.LinkBaseURL)
+			}
+		}
+		if m.Combine && (m.SplitH1 || m.SplitLargePages) {
+			log.Fatal("--combine can not be used with --split-h1 or --split-large-pages")
+		}
+		if m.RenderParallel < 0 {
+			log.Fatalf("--render-parallel must not be negative, got %d", m.RenderParallel)
+		}
+		switch m.OversizedAttachments {
+		case lib.OversizedFail, lib.OversizedSkip, lib.OversizedZip:
+		default:
+			log.Fatalf("unknown --oversized-attachments policy %q, use fail, skip or zip", m.OversizedAttachments)
+		}
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		finishRecording()
+	},
 	Run: func(rootCmd *cobra.Command, args []string) {
 		m.SourceMarkdown = args
+		configureRenderer()
+		if m.EndpointsFile != "" {
+			endpoints, err := lib.LoadEndpoints(m.EndpointsFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			publish(func() []error {
+				return m.RunEndpoints(endpoints)
+			})
+			return
+		}
 		// Validate the arguments
 		err := m.Validate()
 		if err != nil {
 			log.Fatal(err)
 		}
-		if m.InsecureTLS {
-			fmt.Println("Warning: TLS verification is disabled. This allows for man-in-the-middle-attacks.")
-			http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		publish(m.Run)
+	},
+}
+
+// applyProfile sets the flags of the selected profile of the repo config
+// that are not given on the command line
+func applyProfile(cmd *cobra.Command, config *lib.RepoConfig) error {
+	values, err := config.ProfileFlags(profileName)
+	if err != nil {
+		return err
+	}
+	name := profileName
+	if name == "" && config != nil {
+		name = config.Profile
+	}
+	flags := make([]string, 0, len(values))
+	for flag := range values {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+	for _, flag := range flags {
+		f := cmd.Flags().Lookup(flag)
+		if f == nil || f.Name == "profile" {
+			return fmt.Errorf("profile %s: unknown flag --%s", name, flag)
+		}
+		if f.Changed {
+			continue
 		}
+		if err := cmd.Flags().Set(flag, values[flag]); err != nil {
+			return fmt.Errorf("profile %s: invalid --%s: %s", name, flag, err)
+		}
+	}
+	return nil
+}
 
-		errors := m.Run()
-		for _, err := range errors {
-			fmt.Println()
+// configureRenderer hands rendering flags to the renderer. Flags are only
+// parsed once a command runs, so this can not happen in init.
+func configureRenderer() {
+	renderer.CodeBlockTheme = m.CodeBlockTheme
+	renderer.CodeBlockCollapse = m.CodeBlockCollapse
+	renderer.CodeBlockShowLineNumbers = m.CodeBlockShowLineNumbers
+	renderer.CodeBlockWrap = m.CodeBlockWrap
+	if renderer.HeadingSlug != renderer.SlugGitHub && renderer.HeadingSlug != renderer.SlugGitLab {
+		log.Fatalf("unknown heading slug %q, use github or gitlab", renderer.HeadingSlug)
+	}
+	normalization, err := lib.ParseNormalization(normalize)
+	if err != nil {
+		log.Fatal(err)
+	}
+	m.Normalization = normalization
+	known := false
+	for _, e := range lib.SourceEncodings {
+		known = known || e == lib.SourceEncoding
+	}
+	if !known {
+		log.Fatalf("unknown --source-encoding %q, use one of %s", lib.SourceEncoding, strings.Join(lib.SourceEncodings, ", "))
+	}
+	for _, t := range transliterate {
+		switch t {
+		case "slugs":
+			renderer.TransliterateSlugs = true
+		case "titles":
+			m.TransliterateTitles = true
+		default:
+			log.Fatalf("unknown --transliterate %q, use slugs or titles", t)
+		}
+	}
+	pattern, err := regexp.Compile(destructiveCommands)
+	if err != nil {
+		log.Fatalf("invalid --destructive-commands: %s", err)
+	}
+	renderer.DestructiveCommands = pattern
+	if ownerGroupsFile != "" {
+		if m.OwnerGroups, err = lib.LoadOwnerGroups(ownerGroupsFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if glossaryFile != "" {
+		if m.Glossary, err = lib.LoadGlossary(glossaryFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if m.MacroMappingFile != "" {
+		if err := renderer.LoadMacroMappings(m.MacroMappingFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if _, err := strconv.Atoi(m.SafeMode.RootID); m.SafeMode.RootID != "" && err != nil {
+		log.Fatalf("invalid --safe-root %q, expected a page id", m.SafeMode.RootID)
+	}
+	switch m.Redirects {
+	case "", lib.RedirectLink, lib.RedirectMacro:
+	default:
+		log.Fatalf("unknown --redirects %q, use link or macro", m.Redirects)
+	}
+	if urlMapFile != "" {
+		if _, err := lib.OpenStateStore(urlMapFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if m.Redirects != "" {
+		if urlMapFile == "" {
+			log.Fatal("--redirects needs --url-map to tell which pages were renamed")
+		}
+		if m.PublishedPages, err = lib.LoadURLMap(urlMapFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// publish runs a sync with the CI integration, prints its errors and exits
+// non-zero when there were any
+func publish(run func() []error) {
+	ci := m.CI
+	if ci == "auto" {
+		ci = lib.DetectCI()
+	}
+	lib.StartCIGroup(ci, "Publishing markdown to Confluence")
+	stop := m.HandleInterrupts()
+	errors := run()
+	stop()
+	lib.EndCIGroup(ci)
+	for _, err := range errors {
+		fmt.Println()
+		fmt.Println(err)
+	}
+	// before the report, which records the audit page when the run creates it
+	if auditor != nil && auditPage != "" && !m.ValidateOnly {
+		if err := m.PublishAuditLog(auditPage, auditor.Entries()); err != nil {
+			fmt.Println(err)
+		}
+	}
+	if err := lib.WriteCIResults(ci, m.Report); err != nil {
+		fmt.Println(err)
+	}
+	if reportFile != "" {
+		if err := lib.WriteReport(reportFile, m.Report); err != nil {
 			fmt.Println(err)
 		}
-		if len(errors) > 0 {
-			os.Exit(1)
+	}
+	if urlMapFile != "" && !m.ValidateOnly {
+		if err := lib.WriteURLMap(urlMapFile, m.Report); err != nil {
+			fmt.Println(err)
 		}
-	},
+	}
+	if printURLs {
+		lib.PrintURLs(m.Report)
+	}
+	if lib.WasInterrupted(errors) {
+		exit(130)
+	}
+	if len(errors) > 0 {
+		exit(1)
+	}
+}
+
+// finishRecording writes the --record HAR file and the --profile profiles
+// and closes the --audit-log, if any
+func finishRecording() {
+	if stopProfile != nil {
+		if err := stopProfile(); err != nil {
+			fmt.Println(err)
+		}
+		stopProfile = nil
+	}
+	if auditor != nil {
+		if err := auditor.Stop(); err != nil {
+			fmt.Println(err)
+		}
+		auditor = nil
+	}
+	if stopRecording == nil {
+		return
+	}
+	if err := stopRecording(); err != nil {
+		fmt.Println(err)
+	}
+	stopRecording = nil
+}
+
+// exit writes pending recordings and exits with code
+func exit(code int) {
+	finishRecording()
+	os.Exit(code)
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"log"
+
+	lib "github.com/justmiles/go-markdown2confluence/lib"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	selfUpdateTag   string
+	selfUpdateCheck bool
+)
+
+func init() {
+	selfUpdateCmd.Flags().StringVar(&selfUpdateTag, "release", "", "Install this release tag, e.g. v3.4.0, instead of the latest release")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateCheck, "check", false, "Only report whether a newer release is available")
+	rootCmd.AddCommand(selfUpdateCmd)
+}
+
+// selfUpdateCmd installs a release from GitHub over the running binary
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Replace markdown2confluence with the latest, or a given, GitHub release",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := lib.SelfUpdate(cmd.Root().Version, selfUpdateTag, selfUpdateCheck); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
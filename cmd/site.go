@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(publishSiteCmd)
+}
+
+// publishSiteCmd mirrors a static site content directory to Confluence
+var publishSiteCmd = &cobra.Command{
+	Use:   "publish-site <site directory>",
+	Short: "Publish a Hugo or MkDocs site as a page tree, keeping sections, titles and weights",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !m.ValidateOnly {
+			if m.Space == "" {
+				log.Fatal("--space is not defined")
+			}
+			if err := m.ValidateConnection(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		configureRenderer()
+		publish(func() []error {
+			return m.PublishSite(args[0])
+		})
+	},
+}
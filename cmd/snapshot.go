@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var restoreParentID string
+
+func init() {
+	restoreCmd.Flags().StringVar(&restoreParentID, "restore-parent-id", "", "Restore the snapshot root below this page instead of its original parent")
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(restoreCmd)
+}
+
+// snapshotCmd saves a page tree to a local archive
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot <page id> <archive.zip>",
+	Short: "Save a page and all its descendants with labels, properties and attachments to a zip archive",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := m.ValidateConnection(); err != nil {
+			log.Fatal(err)
+		}
+		if err := m.TakeSnapshot(context.Background(), args[0], args[1]); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// restoreCmd publishes the pages of a snapshot again
+var restoreCmd = &cobra.Command{
+	Use:   "restore <archive.zip>",
+	Short: "Restore the pages of a snapshot, updating those that still exist and recreating the others",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := m.ValidateConnection(); err != nil {
+			log.Fatal(err)
+		}
+		errors := m.RestoreSnapshot(args[0], restoreParentID)
+		for _, err := range errors {
+			fmt.Println(err)
+		}
+		if len(errors) > 0 {
+			exit(1)
+		}
+	},
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	purgeTrashDryRun bool
+	purgeTrashAll    bool
+)
+
+func init() {
+	purgeTrashCmd.Flags().BoolVar(&purgeTrashDryRun, "dry-run", false, "Only list the trashed pages that would be purged")
+	purgeTrashCmd.Flags().BoolVar(&purgeTrashAll, "all", false, "Purge the whole trash of --space instead of the pages below --parent-id")
+	rootCmd.AddCommand(purgeTrashCmd)
+}
+
+// purgeTrashCmd permanently deletes trashed pages so their titles can be reused
+var purgeTrashCmd = &cobra.Command{
+	Use:   "purge-trash",
+	Short: "Permanently delete trashed pages in --space that were below --parent-id, or all of them with --all",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if m.Space == "" {
+			log.Fatal("--space is not defined")
+		}
+		if m.ParentId == "" && !purgeTrashAll {
+			log.Fatal("--parent-id is not defined, pass --all to purge the whole trash of --space")
+		}
+		if m.ParentId != "" && purgeTrashAll {
+			log.Fatal("--all purges the whole trash, leave out --parent-id")
+		}
+		if err := m.ValidateConnection(); err != nil {
+			log.Fatal(err)
+		}
+		errors := m.PurgeTrash(context.Background(), m.ParentId, purgeTrashDryRun)
+		for _, err := range errors {
+			fmt.Println(err)
+		}
+		if len(errors) > 0 {
+			exit(1)
+		}
+	},
+}
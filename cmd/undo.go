@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/justmiles/go-markdown2confluence/lib"
+	"github.com/spf13/cobra"
+)
+
+var undoDryRun bool
+
+func init() {
+	undoCmd.Flags().BoolVar(&undoDryRun, "dry-run", false, "Only list what would be rolled back")
+	rootCmd.AddCommand(undoCmd)
+}
+
+// undoCmd rolls back the changes of a run recorded with --report or
+// --audit-log
+var undoCmd = &cobra.Command{
+	Use:   "undo <report.json>",
+	Short: "Roll back a run recorded with --report or --audit-log, restoring updated pages and attachments and deleting created ones",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		plan, err := lib.LoadUndoPlan(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := m.ValidateConnection(); err != nil {
+			log.Fatal(err)
+		}
+		errors := m.Undo(plan, undoDryRun)
+		for _, err := range errors {
+			fmt.Println(err)
+		}
+		if len(errors) > 0 {
+			exit(1)
+		}
+	},
+}
@@ -5,6 +5,7 @@ go 1.19
 require (
 	github.com/justmiles/go-confluence v0.2.0
 	github.com/spf13/cobra v1.6.1
+	github.com/spf13/pflag v1.0.5
 	github.com/yuin/goldmark v1.5.2
 )
 
@@ -13,6 +14,7 @@ require (
 	github.com/inconshreveable/mousetrap v1.0.1 // indirect
 	github.com/naminomare/gogutil v0.0.0-20220326064723-17315315cf0e // indirect
 	github.com/sirupsen/logrus v1.9.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/sys v0.1.0 // indirect
 )
+
+replace github.com/justmiles/go-confluence => ./third_party/go-confluence
@@ -0,0 +1,271 @@
+package lib
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/justmiles/go-confluence"
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// ADRLabel is applied to every decision record, next to ADRLabel-<status>
+const ADRLabel = "adr"
+
+var (
+	// adrFileName matches adr-tools and MADR file names, e.g. 0001-use-postgresql.md
+	adrFileName = regexp.MustCompile(`^(\d+)-.+\.md$`)
+	// adrTitle matches the title heading, numbered by adr-tools as "# 1. Title"
+	adrTitle = regexp.MustCompile(`^#\s+(?:(\d+)\.\s+)?(.+?)\s*#*$`)
+	// adrField matches MADR metadata bullets and adr-tools lines such as
+	// "* Status: accepted" or "Date: 2023-01-31"
+	adrField = regexp.MustCompile(`^(?:[*-]\s+)?(?i:(status|date|deciders|decision-makers|consulted|informed)):\s*(.*)$`)
+	// adrSection matches second level headings
+	adrSection = regexp.MustCompile(`^##\s+(.+?)\s*#*$`)
+	// adrLink matches markdown links in a status such as "Superseded by [3. Use X](0003-use-x.md)"
+	adrLink = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+)
+
+// ADR is an architecture decision record
+type ADR struct {
+	Number   int
+	Title    string
+	Status   string
+	Date     string
+	Deciders string
+	Path     string
+	// Content is the markdown without the metadata moved to the page
+	// properties, blanked so source positions stay correct
+	Content []byte
+}
+
+// PageTitle is the title an ADR is published under
+func (a ADR) PageTitle() string {
+	return fmt.Sprintf("ADR-%04d: %s", a.Number, a.Title)
+}
+
+// StatusLabel is the label for the status of an ADR, e.g. adr-accepted
+func (a ADR) StatusLabel() string {
+	status := strings.ToLower(strings.Fields(a.Status + " unknown")[0])
+	status = strings.Trim(status, ".,:;")
+	return ADRLabel + "-" + status
+}
+
+// ParseADR reads the number, title and MADR or adr-tools metadata of a
+// decision record
+func ParseADR(path string, markdown []byte) ADR {
+	adr := ADR{Path: path}
+	if match := adrFileName.FindStringSubmatch(filepath.Base(path)); match != nil {
+		adr.Number, _ = strconv.Atoi(match[1])
+	}
+
+	fm, body := ParseFrontMatter(markdown)
+	adr.Status = fm.Get("status")
+	adr.Date = fm.Get("date")
+	adr.Deciders = strings.Join(fm.List("deciders"), ", ")
+	if adr.Deciders == "" {
+		adr.Deciders = strings.Join(fm.List("decision-makers"), ", ")
+	}
+
+	lines := strings.Split(string(body), "\n")
+	section := ""
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if inFence {
+			continue
+		}
+
+		if match := adrTitle.FindStringSubmatch(trimmed); match != nil && adr.Title == "" {
+			adr.Title = match[2]
+			if match[1] != "" && adr.Number == 0 {
+				adr.Number, _ = strconv.Atoi(match[1])
+			}
+			lines[i] = ""
+			continue
+		}
+		if match := adrSection.FindStringSubmatch(trimmed); match != nil {
+			section = strings.ToLower(match[1])
+			continue
+		}
+		// adr-tools keeps the status in its own section
+		if section == "status" && adr.Status == "" && trimmed != "" {
+			adr.Status = adrLink.ReplaceAllString(trimmed, "$1")
+			continue
+		}
+		if section != "" {
+			continue
+		}
+		if match := adrField.FindStringSubmatch(trimmed); match != nil {
+			value := strings.TrimSpace(match[2])
+			switch strings.ToLower(match[1]) {
+			case "status":
+				if adr.Status == "" {
+					adr.Status = value
+				}
+			case "date":
+				if adr.Date == "" {
+					adr.Date = value
+				}
+			case "deciders", "decision-makers":
+				if adr.Deciders == "" {
+					adr.Deciders = value
+				}
+			}
+			lines[i] = ""
+		}
+	}
+	if adr.Title == "" {
+		adr.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	adr.Content = []byte(strings.Join(lines, "\n"))
+	return adr
+}
+
+// adrProperties renders the metadata of an ADR as a page properties table,
+// which the index and page properties report macros can query
+func adrProperties(a ADR) string {
+	var b strings.Builder
+	b.WriteString(`<ac:structured-macro ac:name="details" ac:schema-version="1"><ac:rich-text-body><table><tbody>`)
+	for _, row := range [][2]string{{"Status", a.Status}, {"Date", a.Date}, {"Deciders", a.Deciders}} {
+		if row[1] == "" {
+			continue
+		}
+		b.WriteString(`<tr><th>` + row[0] + `</th><td>` + html.EscapeString(row[1]) + `</td></tr>`)
+	}
+	b.WriteString(`</tbody></table></ac:rich-text-body></ac:structured-macro>`)
+	return b.String()
+}
+
+// adrIndex renders a table of the ADRs sorted by number
+func adrIndex(adrs []ADR) string {
+	var b strings.Builder
+	b.WriteString(`<table><tbody><tr><th>Number</th><th>Decision</th><th>Status</th><th>Date</th></tr>`)
+	for _, a := range adrs {
+		b.WriteString(fmt.Sprintf(`<tr><td>%d</td>`, a.Number))
+		b.WriteString(`<td><ac:link><ri:page ri:content-title="` + html.EscapeString(a.PageTitle()) + `"/></ac:link></td>`)
+		b.WriteString(`<td>` + html.EscapeString(a.Status) + `</td><td>` + html.EscapeString(a.Date) + `</td></tr>`)
+	}
+	b.WriteString(`</tbody></table>`)
+	return b.String()
+}
+
+// ReadADRs parses the numbered decision records of a directory, sorted by
+// number
+func ReadADRs(dir string) ([]ADR, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var adrs []ADR
+	for _, entry := range entries {
+		if entry.IsDir() || !adrFileName.MatchString(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		dat, err := readMarkdown(path)
+		if err != nil {
+			return nil, fmt.Errorf("Could not open file %s:\n\t%s", path, err)
+		}
+		adrs = append(adrs, ParseADR(path, dat))
+	}
+	sort.SliceStable(adrs, func(i, j int) bool { return adrs[i].Number < adrs[j].Number })
+	return adrs, nil
+}
+
+// PublishADRs publishes the decision records of dir below an index page
+// listing them by number. Every record gets its metadata as a page
+// properties table and the adr and adr-<status> labels.
+func (m *Markdown2Confluence) PublishADRs(dir, indexTitle string) []error {
+	m.CreateClient()
+	defer renderer.UseAttachmentDir()()
+	m.Report = &Report{}
+
+	adrs, err := ReadADRs(dir)
+	if err != nil {
+		return []error{fmt.Errorf("Unable to read decision records in %s: %s", dir, err)}
+	}
+	if len(adrs) == 0 {
+		return []error{fmt.Errorf("no numbered decision records found in %s", dir)}
+	}
+
+	var indexID string
+	if !m.ValidateOnly {
+		if !m.SkipPreflight {
+			if err := m.Preflight(); err != nil {
+				return []error{err}
+			}
+		}
+		parentID, err := m.resolveParentID()
+		if err != nil {
+			return []error{err}
+		}
+		index, err := m.upsertStoragePage(indexTitle, parentID, adrIndex(adrs))
+		if err != nil {
+			return []error{fmt.Errorf("Unable to publish ADR index %s: %s", indexTitle, err)}
+		}
+		indexID = index.ID
+	}
+
+	var errs []error
+	for _, adr := range adrs {
+		if m.Interrupted() {
+			errs = append(errs, fmt.Errorf("%w: %s was not published", ErrInterrupted, adr.PageTitle()))
+			break
+		}
+		f := MarkdownFile{
+			Path:     adr.Path,
+			Title:    adr.PageTitle(),
+			Ancestor: indexID,
+			Content:  adr.Content,
+			Preamble: adrProperties(adr),
+		}
+		url, err := f.Upload(m)
+		if err == nil && !m.ValidateOnly && f.Action != ActionUnchanged {
+			err = m.setADRLabels(f.PageID, adr)
+		}
+		m.Report.Add(newPageResult(&f, url, err))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Unable to upload decision record %s: \n\t%s", adr.Path, err))
+			continue
+		}
+		if m.ValidateOnly {
+			fmt.Printf("%s: valid\n", f.Title)
+		} else {
+			fmt.Printf("%s: %s\n", f.Title, url)
+		}
+	}
+
+	if m.NotifyWebhook != "" && !m.ValidateOnly {
+		if err := m.Notify(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// setADRLabels applies the adr and status labels and removes the labels of
+// earlier statuses
+func (m *Markdown2Confluence) setADRLabels(pageID string, adr ADR) error {
+	labels, err := m.client.GetLabels(pageID)
+	if err != nil {
+		return fmt.Errorf("Unable to read labels: %s", err)
+	}
+	status := adr.StatusLabel()
+	for _, l := range labels {
+		if strings.HasPrefix(l.Name, ADRLabel+"-") && l.Name != status {
+			if err := m.client.RemoveLabel(pageID, l.Name); err != nil {
+				return fmt.Errorf("Unable to remove label %s: %s", l.Name, err)
+			}
+		}
+	}
+	return m.client.AddLabels(pageID, []string{ADRLabel, status}, confluence.GlobalPrefix)
+}
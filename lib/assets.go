@@ -0,0 +1,108 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/justmiles/go-confluence"
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// assetsPageBody lists the attachments of the shared assets page
+const assetsPageBody = `<p><ac:structured-macro ac:name="attachments" ac:schema-version="1"></ac:structured-macro></p>`
+
+// PublishSharedAssets attaches images referenced by more than one markdown
+// file once to the assets page, so pages reference them from there instead
+// of carrying their own copy
+func (m *Markdown2Confluence) PublishSharedAssets(files []MarkdownFile) error {
+	usage := make(map[string]int)
+	for _, f := range files {
+		dat, err := readMarkdown(f.Path)
+		if err != nil {
+			return fmt.Errorf("Could not open file %s:\n\t%s", f.Path, err)
+		}
+		seen := make(map[string]bool)
+		for _, image := range renderer.LocalImages(f.Path, dat) {
+			if !seen[image] {
+				seen[image] = true
+				usage[image]++
+			}
+		}
+	}
+
+	var shared []string
+	for image, count := range usage {
+		if count > 1 {
+			shared = append(shared, image)
+		}
+	}
+	if len(shared) == 0 {
+		return nil
+	}
+	sort.Strings(shared)
+
+	pageID, err := m.findOrCreateAssetsPage()
+	if err != nil {
+		return err
+	}
+
+	_, errors := m.client.AddUpdateAttachments(pageID, shared)
+	if len(errors) > 0 {
+		return fmt.Errorf("Unable to attach shared assets to %s: %s", m.AssetsPage, errors[0])
+	}
+
+	renderer.AssetsPageTitle = m.AssetsPage
+	renderer.AssetsSpaceKey = m.Space
+	for _, image := range shared {
+		renderer.SharedAssets[image] = true
+	}
+	if m.Debug {
+		fmt.Printf("Attached %d shared assets to %s\n", len(shared), m.AssetsPage)
+	}
+	return nil
+}
+
+func (m *Markdown2Confluence) findOrCreateAssetsPage() (string, error) {
+	contentResults, err := m.client.GetContent(&confluence.GetContentQueryParameters{
+		Title:    m.AssetsPage,
+		Spacekey: m.Space,
+		Limit:    1,
+		Type:     "page",
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error checking for assets page: %s", err)
+	}
+	if len(contentResults) > 0 {
+		return contentResults[0].ID, nil
+	}
+
+	ancestorID := m.ParentId
+	if ancestorID == "" && m.Parent != "" {
+		md := MarkdownFile{Title: m.AssetsPage, Parents: deleteEmpty(strings.Split(m.Parent, "/"))}
+		ancestorID, err = md.FindOrCreateAncestors(m)
+		m.Report.AddCreatedPages(md.CreatedPages...)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	bp := confluence.CreateContentBodyParameters{}
+	bp.Title = m.AssetsPage
+	bp.Type = "page"
+	bp.Space.Key = m.Space
+	bp.Body.Storage.Representation = "storage"
+	bp.Body.Storage.Value = assetsPageBody
+	if ancestorID != "" {
+		bp.Ancestors = append(bp.Ancestors, Ancestor{
+			ID: ancestorID,
+		})
+	}
+
+	content, err := m.client.CreateContent(&bp, nil)
+	if err != nil {
+		return "", fmt.Errorf("Error creating assets page %s: %s", m.AssetsPage, err)
+	}
+	m.Report.AddCreatedPages(content.ID)
+	return content.ID, nil
+}
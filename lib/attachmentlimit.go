@@ -0,0 +1,138 @@
+package lib
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// Policies for attachments larger than the attachment size limit
+const (
+	OversizedFail = "fail"
+	OversizedSkip = "skip"
+	OversizedZip  = "zip"
+)
+
+// DefaultMaxAttachmentSize is the attachment size limit Confluence Cloud and
+// Server ship with. Confluence has no API exposing the configured limit.
+const DefaultMaxAttachmentSize = 100 << 20
+
+// limitAttachments applies the oversized attachment policy to the files of a
+// page before anything is uploaded. Skipped files are dropped and recorded on
+// f, zipped files replace the originals in images and in the references of
+// body. The returned cleanup removes the archives.
+func (m *Markdown2Confluence) limitAttachments(f *MarkdownFile, body string, images []string) (string, []string, func(), error) {
+	cleanup := func() {}
+	if m.MaxAttachmentSize <= 0 {
+		return body, images, cleanup, nil
+	}
+
+	var kept []string
+	var dir string
+	for _, image := range images {
+		info, err := os.Stat(image)
+		if err != nil || info.Size() <= m.MaxAttachmentSize {
+			kept = append(kept, image)
+			continue
+		}
+
+		switch m.OversizedAttachments {
+		case OversizedSkip:
+			diagnostics := renderer.NewDiagnostics()
+			diagnostics.Warn(renderer.Warning{
+				Class:    renderer.WarningImage,
+				Position: renderer.Position{File: f.Path},
+				Message:  fmt.Sprintf("skipping attachment %s, %s is over the %s limit", image, formatByteSize(info.Size()), formatByteSize(m.MaxAttachmentSize)),
+			})
+			// rendering is over, strict warnings fail the page here
+			if err := diagnostics.Err(); err != nil {
+				return body, images, cleanup, err
+			}
+			f.SkippedAttachments = append(f.SkippedAttachments, image)
+		case OversizedZip:
+			if dir == "" {
+				if dir, err = os.MkdirTemp("", "m2c-zip"); err != nil {
+					return body, images, cleanup, err
+				}
+				cleanup = func() { os.RemoveAll(dir) }
+			}
+			archive, err := zipFile(image, dir)
+			if err != nil {
+				return body, images, cleanup, fmt.Errorf("Unable to compress attachment %s: %s", image, err)
+			}
+			if size := fileSize(archive); size > m.MaxAttachmentSize {
+				return body, images, cleanup, fmt.Errorf("attachment %s is %s compressed, still over the %s limit", image, formatByteSize(size), formatByteSize(m.MaxAttachmentSize))
+			}
+			if m.Debug {
+				fmt.Printf("compressed attachment %s to %s\n", image, formatByteSize(fileSize(archive)))
+			}
+			body = strings.ReplaceAll(body, `ri:filename="`+renderer.AttachmentName(image)+`"`, `ri:filename="`+renderer.AttachmentName(archive)+`"`)
+			kept = append(kept, archive)
+		default:
+			return body, images, cleanup, fmt.Errorf("attachment %s is %s, over the %s limit. Use --oversized-attachments skip or zip to publish anyway", image, formatByteSize(info.Size()), formatByteSize(m.MaxAttachmentSize))
+		}
+	}
+	return body, kept, cleanup, nil
+}
+
+// zipFile compresses path into dir/<name>.zip. The entry keeps the file's
+// modification time so unchanged files produce identical archives.
+func zipFile(path, dir string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	archive := filepath.Join(dir, filepath.Base(path)+".zip")
+	out, err := os.Create(archive)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return "", err
+	}
+	header.Method = zip.Deflate
+	w := zip.NewWriter(out)
+	entry, err := w.CreateHeader(header)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(entry, src); err != nil {
+		return "", err
+	}
+	return archive, w.Close()
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// formatByteSize formats a size with a binary unit, e.g. "1.5M"
+func formatByteSize(n int64) string {
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}} {
+		if n >= unit.size {
+			return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/float64(unit.size)), ".0") + unit.suffix
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
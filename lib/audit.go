@@ -0,0 +1,258 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// auditedPath matches the content endpoints of audited requests: the page
+// id and what below the page was changed
+var auditedPath = regexp.MustCompile(`/rest/api/content(?:/([^/?]+)(?:/(child/attachment|label|property|restriction|move)(?:/[^?]*)?)?)?$`)
+
+// AuditEntry is a create, update or delete made through the API, a line of
+// the --audit-log file
+type AuditEntry struct {
+	Time      string `json:"time"`
+	Actor     string `json:"actor"`
+	Operation string `json:"operation"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Status    int    `json:"status"`
+	PageID    string `json:"pageId,omitempty"`
+	// AttachmentID is the id of an uploaded attachment
+	AttachmentID string `json:"attachmentId,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Version      int    `json:"version,omitempty"`
+	// Labels are the names of added labels
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Auditor appends every mutating API call of the process to a log file
+type Auditor struct {
+	next    http.RoundTripper
+	file    *os.File
+	actor   string
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+// AuditHTTP logs all POST, PUT and DELETE requests to the Confluence content
+// API as JSON lines appended to path, until Stop is called
+func AuditHTTP(path string) (*Auditor, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open audit log %s: %s", path, err)
+	}
+	a := &Auditor{next: http.DefaultTransport, file: file}
+	http.DefaultTransport = a
+	return a, nil
+}
+
+// Stop stops logging and closes the log file
+func (a *Auditor) Stop() error {
+	http.DefaultTransport = a.next
+	return a.file.Close()
+}
+
+// Entries returns the calls logged so far
+func (a *Auditor) Entries() []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]AuditEntry{}, a.entries...)
+}
+
+func (a *Auditor) RoundTrip(req *http.Request) (*http.Response, error) {
+	match := auditedPath.FindStringSubmatch(req.URL.Path)
+	if req.Method == http.MethodGet || req.Method == http.MethodHead || match == nil {
+		return a.next.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil && !strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/") {
+		body, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	res, err := a.next.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+	resBody, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	entry := AuditEntry{
+		Time:      time.Now().UTC().Format(time.RFC3339),
+		Actor:     a.actorOf(req),
+		Operation: auditOperation(req.Method, match[1], match[2]),
+		Method:    req.Method,
+		Path:      req.URL.Path,
+		Status:    res.StatusCode,
+		PageID:    match[1],
+	}
+	if id, err := strconv.Atoi(entry.PageID); err != nil || id == 0 {
+		entry.PageID = ""
+	}
+	// the response names the page or attachment, the request the version
+	var content struct {
+		ID      string `json:"id"`
+		Title   string `json:"title"`
+		Version struct {
+			Number int `json:"number"`
+		} `json:"version"`
+		Results []struct {
+			ID      string `json:"id"`
+			Title   string `json:"title"`
+			Version struct {
+				Number int `json:"number"`
+			} `json:"version"`
+		} `json:"results"`
+	}
+	_ = json.Unmarshal(body, &content)
+	_ = json.Unmarshal(resBody, &content)
+	if len(content.Results) > 0 && match[2] == "child/attachment" {
+		content.Title, content.Version.Number = content.Results[0].Title, content.Results[0].Version.Number
+		entry.AttachmentID = content.Results[0].ID
+	} else if match[2] == "child/attachment" && req.Method == http.MethodPost {
+		// a new version of an attachment returns just the attachment
+		entry.AttachmentID = content.ID
+	}
+	if entry.PageID == "" && match[2] == "" {
+		entry.PageID = content.ID
+	}
+	entry.Title = content.Title
+	entry.Version = content.Version.Number
+	if match[2] == "label" && req.Method == http.MethodPost {
+		var labels []confluence.Label
+		_ = json.Unmarshal(body, &labels)
+		for _, l := range labels {
+			entry.Labels = append(entry.Labels, l.Name)
+		}
+	}
+
+	a.log(entry)
+	return res, nil
+}
+
+// log appends an entry to the file right away, so entries of a run that
+// crashes are kept
+func (a *Auditor) log(entry AuditEntry) {
+	line, _ := json.Marshal(entry)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, entry)
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		fmt.Printf("Warning: unable to write audit log: %s\n", err)
+	}
+}
+
+// actorOf returns the user a request authenticates as: the basic auth user,
+// or else the user an API token belongs to, looked up once
+func (a *Auditor) actorOf(req *http.Request) string {
+	if user, _, ok := req.BasicAuth(); ok {
+		return user
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.actor != "" {
+		return a.actor
+	}
+	a.actor = "unknown"
+	base := strings.SplitN(req.URL.String(), "/rest/api/", 2)[0]
+	lookup, err := http.NewRequest(http.MethodGet, base+"/rest/api/user/current", nil)
+	if err != nil {
+		return a.actor
+	}
+	lookup.Header.Set("Authorization", req.Header.Get("Authorization"))
+	res, err := a.next.RoundTrip(lookup)
+	if err != nil {
+		return a.actor
+	}
+	defer res.Body.Close()
+	var user confluence.User
+	if json.NewDecoder(res.Body).Decode(&user) == nil {
+		for _, name := range []string{user.Email, user.Username, user.DisplayName, user.AccountID} {
+			if name != "" {
+				a.actor = name
+				break
+			}
+		}
+	}
+	return a.actor
+}
+
+// auditOperation names the change a request makes
+func auditOperation(method, id, sub string) string {
+	object := "page"
+	switch sub {
+	case "child/attachment":
+		object = "attachment"
+	case "label":
+		object = "label"
+	case "property":
+		object = "property"
+	case "restriction":
+		object = "restrictions"
+	case "move":
+		return "move page"
+	}
+	switch {
+	case method == http.MethodDelete:
+		return "delete " + object
+	case method == http.MethodPost && id == "":
+		return "create page"
+	case method == http.MethodPost && object == "attachment":
+		return "upload attachment"
+	case method == http.MethodPost && sub != "":
+		return "add " + object
+	}
+	return "update " + object
+}
+
+// PublishAuditLog appends the calls of a run as a table to the page titled
+// title in m.Space, creating it if needed
+func (m *Markdown2Confluence) PublishAuditLog(title string, entries []AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("<h2>" + html.EscapeString(runStarted.UTC().Format(time.RFC3339)) + "</h2>")
+	b.WriteString("<table><tbody><tr><th>Time</th><th>Actor</th><th>Operation</th><th>Page</th><th>Title</th><th>Version</th><th>Status</th></tr>")
+	for _, e := range entries {
+		version := ""
+		if e.Version > 0 {
+			version = strconv.Itoa(e.Version)
+		}
+		b.WriteString("<tr>")
+		for _, cell := range []string{e.Time, e.Actor, e.Operation, e.PageID, e.Title, version, strconv.Itoa(e.Status)} {
+			b.WriteString("<td>" + html.EscapeString(cell) + "</td>")
+		}
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</tbody></table>")
+
+	body := b.String()
+	page, err := m.client.GetPageByTitle(m.Space, title, "body.storage")
+	if err == nil {
+		body = page.Body.Storage.Value + body
+	} else if !errors.Is(err, confluence.ErrPageNotFound) {
+		return fmt.Errorf("Unable to fetch audit page %s: %s", title, err)
+	}
+	if _, err := m.upsertStoragePage(title, "", body); err != nil {
+		return fmt.Errorf("Unable to publish audit page %s: %s", title, err)
+	}
+	return nil
+}
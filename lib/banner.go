@@ -0,0 +1,149 @@
+package lib
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// wordsPerMinute is the reading speed ReadingTime assumes
+const wordsPerMinute = 200
+
+// DefaultBannerTemplate is the markdown of the --banner info panel
+const DefaultBannerTemplate = `{{ with .Published }}Last published {{ . }} · {{ end }}` +
+	`{{ with .SourceURL }}[Source]({{ . }}) · {{ end }}` +
+	`{{ .ReadingTime }} min read` +
+	`{{ with .Owners }} · Owned by {{ join ", " . }}{{ end }}`
+
+// ManagedNotice is the markdown of the --managed-notice info panel
+const ManagedNotice = `This page is generated from ` +
+	`{{ with .SourceURL }}[{{ $.SourcePath }}]({{ . }}){{ else }}{{ .Path }}{{ end }}, ` +
+	`edits made in Confluence will be overwritten.`
+
+// readingTimeExcluded are the elements whose text is not read
+var readingTimeExcluded = map[string]bool{"ac:parameter": true}
+
+// repoWebURL turns a git remote URL into the URL of the repository's web
+// page, e.g. git@github.com:org/repo.git into https://github.com/org/repo
+func repoWebURL(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSpace(remote), ".git")
+	if remote == "" {
+		return ""
+	}
+	// scp-like syntax, user@host:path
+	if !strings.Contains(remote, "://") {
+		host, path, ok := strings.Cut(remote, ":")
+		if !ok {
+			return ""
+		}
+		if i := strings.LastIndex(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+		return "https://" + host + "/" + strings.TrimPrefix(path, "/")
+	}
+	u, err := url.Parse(remote)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	scheme := u.Scheme
+	if scheme != "http" {
+		scheme = "https"
+	}
+	return scheme + "://" + u.Hostname() + u.Path
+}
+
+// sourceURL returns the web URL of the repository containing path and of
+// path in it at branch, or at commit when HEAD is detached
+func sourceURL(path, branch, commit string) (repo, source string) {
+	repo, rel, ref := repoFile(path, branch, commit)
+	if rel == "" {
+		return repo, ""
+	}
+	tree := "/blob/"
+	if strings.Contains(repo, "bitbucket") {
+		tree = "/src/"
+	}
+	return repo, repo + tree + ref + "/" + rel
+}
+
+// repoFile returns the web URL of the repository containing path, the
+// slash separated path of the file in it and the ref to link to: branch, or
+// commit when HEAD is detached. rel is empty when it can not be linked.
+func repoFile(path, branch, commit string) (repo, rel, ref string) {
+	root, remote := gitRepo(filepath.Dir(path))
+	repo = repoWebURL(remote)
+	if repo == "" {
+		return "", "", ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return repo, "", ""
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	rel, err = filepath.Rel(root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return repo, "", ""
+	}
+	ref = branch
+	if ref == "" || ref == "HEAD" {
+		ref = commit
+	}
+	if ref == "" {
+		return repo, "", ""
+	}
+	return repo, filepath.ToSlash(rel), ref
+}
+
+// countWords counts the words of the text of rendered storage format
+func countWords(body string) int {
+	words := 0
+	for _, s := range storageTextSegments(body, readingTimeExcluded) {
+		words += len(strings.Fields(html.UnescapeString(body[s.Start:s.End])))
+	}
+	return words
+}
+
+// readingTime is the minutes it takes to read words, at least one
+func readingTime(words int) int {
+	minutes := (words + wordsPerMinute - 1) / wordsPerMinute
+	if minutes < 1 {
+		return 1
+	}
+	return minutes
+}
+
+// renderBanner renders the --banner template as an info panel
+func (m *Markdown2Confluence) renderBanner(f *MarkdownFile, data TemplateData) (string, []string, error) {
+	text := DefaultBannerTemplate
+	if m.BannerTemplate != "" {
+		dat, err := ioutil.ReadFile(m.BannerTemplate)
+		if err != nil {
+			return "", nil, fmt.Errorf("Could not open banner template %s:\n\t%s", m.BannerTemplate, err)
+		}
+		text = string(dat)
+	}
+	return m.renderInfoPanel(f, "banner", text, data)
+}
+
+// renderInfoPanel renders a markdown template as an info panel, nothing
+// when it renders blank
+func (m *Markdown2Confluence) renderInfoPanel(f *MarkdownFile, name, text string, data TemplateData) (string, []string, error) {
+	markdown, err := executeTemplate(name, text, data)
+	if err != nil {
+		return "", nil, err
+	}
+	if strings.TrimSpace(markdown) == "" {
+		return "", nil, nil
+	}
+	content, images, err := renderContent(f.Path, markdown, m.WithHardWraps)
+	if err != nil {
+		return "", nil, err
+	}
+	return `<ac:structured-macro ac:name="info" ac:schema-version="1"><ac:rich-text-body>` + content +
+		`</ac:rich-text-body></ac:structured-macro>`, images, nil
+}
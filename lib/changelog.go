@@ -0,0 +1,152 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/justmiles/go-confluence"
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// changelogHeading matches release headings such as "## [1.2.0] - 2023-01-31",
+// "## v1.2.0" or "# 1.2.0 (2023-01-31)"
+var changelogHeading = regexp.MustCompile(`^(#{1,6})\s+\[?v?(\d+\.\d+[^\]\s]*|[Uu]nreleased)\]?(.*)$`)
+
+// Release is a version section of a changelog
+type Release struct {
+	Version string
+	// Line is the 0-based line of the release heading
+	Line    int
+	Content string
+}
+
+// ParseChangelog splits a changelog into its releases, newest first as they
+// appear in the file. Unreleased sections are left out.
+func ParseChangelog(markdown string) []Release {
+	lines := strings.Split(markdown, "\n")
+	var releases []Release
+	level := ""
+	current := -1
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inFence = !inFence
+		}
+		if inFence {
+			continue
+		}
+		match := changelogHeading.FindStringSubmatch(line)
+		// a heading of a higher level ends the releases
+		if level != "" && strings.HasPrefix(line, "#") && len(strings.SplitN(line, " ", 2)[0]) < len(level) {
+			if current >= 0 {
+				releases[current].Content = strings.Join(lines[releases[current].Line+1:i], "\n")
+			}
+			current = -1
+			continue
+		}
+		if match == nil || (level != "" && match[1] != level) {
+			continue
+		}
+		level = match[1]
+		if current >= 0 {
+			releases[current].Content = strings.Join(lines[releases[current].Line+1:i], "\n")
+			current = -1
+		}
+		if strings.EqualFold(match[2], "unreleased") {
+			continue
+		}
+		releases = append(releases, Release{Version: match[2], Line: i})
+		current = len(releases) - 1
+	}
+	if current >= 0 {
+		releases[current].Content = strings.Join(lines[releases[current].Line+1:], "\n")
+	}
+	return releases
+}
+
+// PublishChangelog publishes every release of a changelog as a child page of
+// releasesTitle. Only the newest release is updated when its older siblings
+// already exist, unless all is set.
+func (m *Markdown2Confluence) PublishChangelog(path, releasesTitle, titlePrefix string, all bool) []error {
+	m.CreateClient()
+	defer renderer.UseAttachmentDir()()
+	m.Report = &Report{}
+
+	dat, err := readMarkdown(path)
+	if err != nil {
+		return []error{fmt.Errorf("Could not open file %s:\n\t%s", path, err)}
+	}
+	_, dat = ParseFrontMatter(dat)
+	releases := ParseChangelog(string(dat))
+	if len(releases) == 0 {
+		return []error{fmt.Errorf("no release headings found in %s", path)}
+	}
+
+	var releasesID string
+	if !m.ValidateOnly {
+		if !m.SkipPreflight {
+			if err := m.Preflight(); err != nil {
+				return []error{err}
+			}
+		}
+		parentID, err := m.resolveParentID()
+		if err != nil {
+			return []error{err}
+		}
+		f := MarkdownFile{Title: releasesTitle}
+		releasesID, err = f.FindOrCreateAncestor(m, m.client, parentID, releasesTitle)
+		if err != nil {
+			return []error{err}
+		}
+	}
+
+	var errs []error
+	for i, release := range releases {
+		if m.Interrupted() {
+			errs = append(errs, fmt.Errorf("%w: %d releases were not published", ErrInterrupted, len(releases)-i))
+			break
+		}
+		f := MarkdownFile{
+			Path:     path,
+			Title:    titlePrefix + release.Version,
+			Ancestor: releasesID,
+			// keep the release at its line so positions in messages match the file
+			Content: []byte(strings.Repeat("\n", release.Line+1) + release.Content),
+		}
+
+		if i > 0 && !all && !m.ValidateOnly {
+			_, err := m.client.GetPageByTitle(m.Space, f.Title, "version")
+			if err == nil {
+				if m.Debug {
+					fmt.Printf("skipping %s: already published\n", f.Title)
+				}
+				continue
+			}
+			if !errors.Is(err, confluence.ErrPageNotFound) {
+				errs = append(errs, fmt.Errorf("Error checking for existing page %s: %s", f.Title, err))
+				continue
+			}
+		}
+
+		url, err := f.Upload(m)
+		m.Report.Add(newPageResult(&f, url, err))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Unable to upload release %s: \n\t%s", release.Version, err))
+			continue
+		}
+		if m.ValidateOnly {
+			fmt.Printf("%s: valid\n", f.Title)
+		} else {
+			fmt.Printf("%s: %s\n", f.Title, url)
+		}
+	}
+
+	if m.NotifyWebhook != "" && !m.ValidateOnly {
+		if err := m.Notify(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
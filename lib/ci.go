@@ -0,0 +1,146 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// CI providers detected by DetectCI
+const (
+	CIGitHubActions = "github"
+	CIGitLab        = "gitlab"
+)
+
+// DetectCI returns the CI provider the process runs in, or an empty string
+func DetectCI() string {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return CIGitHubActions
+	case os.Getenv("GITLAB_CI") == "true":
+		return CIGitLab
+	}
+	return ""
+}
+
+// StartCIGroup opens a collapsible log group
+func StartCIGroup(ci, name string) {
+	switch ci {
+	case CIGitHubActions:
+		fmt.Printf("::group::%s\n", name)
+	case CIGitLab:
+		fmt.Printf("\x1b[0Ksection_start:%d:markdown2confluence[collapsed=true]\r\x1b[0K%s\n", time.Now().Unix(), name)
+	}
+}
+
+// EndCIGroup closes the log group opened by StartCIGroup
+func EndCIGroup(ci string) {
+	switch ci {
+	case CIGitHubActions:
+		fmt.Println("::endgroup::")
+	case CIGitLab:
+		fmt.Printf("\x1b[0Ksection_end:%d:markdown2confluence\r\x1b[0K\n", time.Now().Unix())
+	}
+}
+
+// WriteCIResults emits per-file error annotations, a job summary and step
+// outputs for the report
+func WriteCIResults(ci string, r *Report) error {
+	if r == nil {
+		return nil
+	}
+	for _, p := range r.Pages {
+		if p.Error == "" {
+			continue
+		}
+		switch ci {
+		case CIGitHubActions:
+			fmt.Printf("::error file=%s,line=%d::%s\n", p.Path, p.Line, escapeGitHubAnnotation(p.Error))
+		case CIGitLab:
+			fmt.Printf("ERROR %s:%d: %s\n", p.Path, p.Line, p.Error)
+		}
+	}
+
+	if ci != CIGitHubActions {
+		return nil
+	}
+
+	if summary := os.Getenv("GITHUB_STEP_SUMMARY"); summary != "" {
+		if err := appendToFile(summary, ciSummary(r)); err != nil {
+			return fmt.Errorf("Unable to write job summary: %s", err)
+		}
+	}
+	if output := os.Getenv("GITHUB_OUTPUT"); output != "" {
+		if err := appendToFile(output, ciOutputs(r)); err != nil {
+			return fmt.Errorf("Unable to write step outputs: %s", err)
+		}
+	}
+	return nil
+}
+
+func ciSummary(r *Report) string {
+	var b strings.Builder
+	b.WriteString("### markdown2confluence\n\n")
+	b.WriteString("| File | Page | Result |\n")
+	b.WriteString("| --- | --- | --- |\n")
+	for _, p := range r.Pages {
+		page := p.Title
+		if p.URL != "" {
+			page = fmt.Sprintf("[%s](%s)", p.Title, p.URL)
+		}
+		result := p.Action
+		if len(p.SkippedAttachments) > 0 {
+			result += fmt.Sprintf(", %d oversized attachments skipped", len(p.SkippedAttachments))
+		}
+		b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", p.Path, page, result))
+	}
+	return b.String()
+}
+
+func ciOutputs(r *Report) string {
+	var urls []string
+	for _, p := range r.Pages {
+		if p.URL != "" {
+			urls = append(urls, p.URL)
+		}
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("created=%d\n", r.Count(ActionCreated)))
+	b.WriteString(fmt.Sprintf("updated=%d\n", r.Count(ActionUpdated)))
+	b.WriteString(fmt.Sprintf("failed=%d\n", r.Count(ActionFailed)))
+	b.WriteString("page-urls<<M2C_EOF\n" + strings.Join(urls, "\n") + "\nM2C_EOF\n")
+	return b.String()
+}
+
+// PrintCIAnnotation prints a problem at a source position, as a workflow
+// command on GitHub so it shows up on the diff. level is error or warning.
+func PrintCIAnnotation(ci, level string, p renderer.Position, message string) {
+	switch ci {
+	case CIGitHubActions:
+		fmt.Printf("::%s file=%s,line=%d,col=%d::%s\n", level, p.File, p.Line, p.Column, escapeGitHubAnnotation(message))
+	case CIGitLab:
+		fmt.Printf("%s %s: %s\n", strings.ToUpper(level), p, message)
+	default:
+		fmt.Printf("%s: %s\n", p, message)
+	}
+}
+
+// escapeGitHubAnnotation encodes characters workflow commands treat specially
+func escapeGitHubAnnotation(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+func appendToFile(path, s string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(s)
+	return err
+}
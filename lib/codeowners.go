@@ -0,0 +1,253 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// codeOwnersLocations are where GitHub and GitLab look for a CODEOWNERS file,
+// relative to the repository root
+var codeOwnersLocations = []string{"CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+var (
+	codeOwnersMu    sync.Mutex
+	codeOwnersCache = map[string]CodeOwners{}
+)
+
+// CodeOwnersRule assigns owners to the files matching a pattern
+type CodeOwnersRule struct {
+	Pattern string
+	Owners  []string
+	match   *regexp.Regexp
+}
+
+// CodeOwners are the rules of a CODEOWNERS file, in file order
+type CodeOwners []CodeOwnersRule
+
+// ParseCodeOwners reads CODEOWNERS rules. GitLab sections are ignored, their
+// rules apply like any other.
+func ParseCodeOwners(text string) CodeOwners {
+	var rules CodeOwners
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		fields := strings.Fields(line)
+		rules = append(rules, CodeOwnersRule{
+			Pattern: fields[0],
+			Owners:  fields[1:],
+			match:   codeOwnersPattern(fields[0]),
+		})
+	}
+	return rules
+}
+
+// codeOwnersPattern compiles a gitignore style pattern to a regexp matching
+// slash separated paths relative to the repository root
+func codeOwnersPattern(pattern string) *regexp.Regexp {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	// patterns with a slash before their last character are relative to the root
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		// a pattern naming a directory owns everything below it
+		b.WriteString("(?:/.*)?$")
+	}
+	return regexp.MustCompile(b.String())
+}
+
+// Owners returns the owners of path, relative to the repository root. The
+// last matching rule wins, and a rule without owners unassigns the path.
+func (c CodeOwners) Owners(path string) []string {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "./")
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].match.MatchString(path) {
+			return c[i].Owners
+		}
+	}
+	return nil
+}
+
+// codeOwners returns the CODEOWNERS rules of the git repository at root,
+// empty if it has none
+func codeOwners(root string) CodeOwners {
+	codeOwnersMu.Lock()
+	defer codeOwnersMu.Unlock()
+	if rules, ok := codeOwnersCache[root]; ok {
+		return rules
+	}
+	var rules CodeOwners
+	for _, location := range codeOwnersLocations {
+		if dat, err := os.ReadFile(filepath.Join(root, location)); err == nil {
+			rules = ParseCodeOwners(string(dat))
+			break
+		}
+	}
+	codeOwnersCache[root] = rules
+	return rules
+}
+
+// fileOwners returns the owners CODEOWNERS assigns to a file, empty outside
+// of a git work tree
+func fileOwners(path string) []string {
+	root, _ := gitRepo(filepath.Dir(path))
+	if root == "" {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	// git reports the root with symlinks resolved
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return nil
+	}
+	return codeOwners(root).Owners(rel)
+}
+
+// OwnerLabelPrefix starts the label naming the owning team of a page
+const OwnerLabelPrefix = "owner-"
+
+// LoadOwnerGroups reads a JSON or YAML file mapping CODEOWNERS teams such as
+// "@acme/docs" to Confluence group names
+func LoadOwnerGroups(file string) (map[string]string, error) {
+	var groups map[string]string
+	if err := decodeConfigFile(file, &groups); err != nil {
+		return nil, fmt.Errorf("Unable to read owner groups %s: %s", file, err)
+	}
+	return groups, nil
+}
+
+// ownerTeams returns the teams, written @org/team, among owners. Users and
+// email addresses are left out.
+func ownerTeams(owners []string) []string {
+	var teams []string
+	for _, o := range owners {
+		if strings.HasPrefix(o, "@") && strings.Contains(o, "/") {
+			teams = append(teams, o)
+		}
+	}
+	return teams
+}
+
+// teamSlug is the name of a team without its organization, e.g. docs for
+// @acme/docs
+func teamSlug(team string) string {
+	return strings.ToLower(team[strings.LastIndex(team, "/")+1:])
+}
+
+// ownerGroups returns the Confluence groups of the teams owning a file,
+// sorted. Teams missing from OwnerGroups map to a group named like the team.
+func (m *Markdown2Confluence) ownerGroups(teams []string) []string {
+	seen := map[string]bool{}
+	var groups []string
+	for _, team := range teams {
+		group, ok := m.OwnerGroups[team]
+		if !ok {
+			group = teamSlug(team)
+		}
+		if group != "" && !seen[group] {
+			seen[group] = true
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// applyOwnership labels a page with the teams owning its file and restricts
+// editing to their groups and the publishing user, who must keep the right
+// to publish. Pages of files without owning teams are left alone.
+func (m *Markdown2Confluence) applyOwnership(pageID string, teams []string) error {
+	if len(teams) == 0 {
+		return nil
+	}
+
+	labels := map[string]bool{}
+	for _, team := range teams {
+		labels[OwnerLabelPrefix+teamSlug(team)] = true
+	}
+	existing, err := m.client.GetLabels(pageID)
+	if err != nil {
+		return fmt.Errorf("Unable to read labels: %s", err)
+	}
+	for _, l := range existing {
+		if strings.HasPrefix(l.Name, OwnerLabelPrefix) && !labels[l.Name] {
+			if err := m.client.RemoveLabel(pageID, l.Name); err != nil {
+				return fmt.Errorf("Unable to remove label %s: %s", l.Name, err)
+			}
+		}
+	}
+	var names []string
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if err := m.client.AddLabels(pageID, names, confluence.GlobalPrefix); err != nil {
+		return fmt.Errorf("Unable to add owner labels: %s", err)
+	}
+
+	me, err := m.client.CurrentUser()
+	if err != nil {
+		return fmt.Errorf("Unable to look up the publishing user: %s", err)
+	}
+	if err := m.client.SetEditRestrictions(pageID, []confluence.User{*me}, m.ownerGroups(teams)); err != nil {
+		return fmt.Errorf("Unable to restrict editing: %s", err)
+	}
+	return nil
+}
+
+// lockEditing lets only the publishing user edit a page, for --edit-lock
+func (m *Markdown2Confluence) lockEditing(pageID string) error {
+	me, err := m.client.CurrentUser()
+	if err != nil {
+		return fmt.Errorf("Unable to look up the publishing user: %s", err)
+	}
+	if err := m.client.SetEditRestrictions(pageID, []confluence.User{*me}, nil); err != nil {
+		return fmt.Errorf("Unable to restrict editing: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package lib
+
+import (
+	"html"
+	"path/filepath"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// tocMacro lists the headings of a combined page
+const tocMacro = `<ac:structured-macro ac:name="toc" ac:schema-version="1"><ac:parameter ac:name="maxLevel">2</ac:parameter></ac:structured-macro>`
+
+// combineFiles replaces files with a single page combining them in order
+// with --combine. The page takes the title of --title or else of the first
+// file, and its parent and space.
+func (m *Markdown2Confluence) combineFiles(files []MarkdownFile) []MarkdownFile {
+	if len(files) < 2 {
+		return files
+	}
+	page := MarkdownFile{
+		Path:     files[0].Path,
+		Title:    files[0].Title,
+		Parents:  files[0].Parents,
+		Ancestor: files[0].Ancestor,
+		Space:    files[0].Space,
+		Combined: files,
+	}
+	if m.Title != "" {
+		// every file has the title of the page, their sections are named
+		// after their document title or file name instead
+		for i := range page.Combined {
+			path := page.Combined[i].Path
+			if page.Combined[i].Title = getDocumentTitle(path); page.Combined[i].Title == "" {
+				page.Combined[i].Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			}
+		}
+	}
+	return []MarkdownFile{page}
+}
+
+// renderCombined renders the files combined into f one after the other,
+// after a table of contents. Each file starts with an anchor named after its
+// path and a heading with its title, unless it starts with an H1 of its own.
+// The front matter of the first file is the front matter of the page.
+func (f *MarkdownFile) renderCombined(m *Markdown2Confluence) (FrontMatter, string, []string, error) {
+	var fm FrontMatter
+	var images []string
+	var b strings.Builder
+	b.WriteString(tocMacro)
+	for i := range f.Combined {
+		part := &f.Combined[i]
+		partFM, body, partImages, err := part.renderBody(m)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if i == 0 {
+			fm = partFM
+		}
+		b.WriteString(`<ac:structured-macro ac:name="anchor" ac:schema-version="1"><ac:parameter ac:name="">`)
+		b.WriteString(combinedAnchor(part.Path))
+		b.WriteString(`</ac:parameter></ac:structured-macro>`)
+		if !strings.HasPrefix(strings.TrimSpace(body), "<h1") {
+			b.WriteString("<h1>" + html.EscapeString(part.Title) + "</h1>\n")
+		}
+		b.WriteString(body)
+		images = append(images, partImages...)
+	}
+	return fm, b.String(), images, nil
+}
+
+// combinedAnchor is the anchor of a file on a combined page, its path
+// without extension as a heading id, such as docs-install for
+// docs/install.md
+func combinedAnchor(path string) string {
+	path = strings.TrimSuffix(filepath.ToSlash(path), filepath.Ext(path))
+	return renderer.Slug(strings.ReplaceAll(path, "/", "-"))
+}
+
+// sourcePaths are the files f is rendered from
+func (f *MarkdownFile) sourcePaths() []string {
+	if len(f.Combined) == 0 {
+		return []string{f.Path}
+	}
+	paths := make([]string, len(f.Combined))
+	for i, part := range f.Combined {
+		paths[i] = part.Path
+	}
+	return paths
+}
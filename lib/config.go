@@ -0,0 +1,33 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/yaml"
+)
+
+// decodeConfigFile decodes a JSON or YAML file into v using its json tags
+func decodeConfigFile(file string, v interface{}) error {
+	dat, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		return json.Unmarshal(dat, v)
+	}
+
+	doc, err := yaml.Unmarshal(dat)
+	if err != nil {
+		return err
+	}
+	// round trip through JSON so the json tags apply to YAML files as well
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("unable to convert %s: %s", file, err)
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,159 @@
+package lib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// ErrNotConfirmed is returned when --interactive changes were declined
+var ErrNotConfirmed = errors.New("changes were not confirmed")
+
+var (
+	// confirmInput answers confirmation questions
+	confirmInput io.Reader = os.Stdin
+	confirmMu    sync.Mutex
+	confirmLines *bufio.Reader
+)
+
+// confirm asks a yes/no question on stdin, no unless answered with y or
+// yes. m.AssumeYes answers yes without asking.
+func (m *Markdown2Confluence) confirm(question string) bool {
+	if m.AssumeYes {
+		return true
+	}
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+	if confirmLines == nil {
+		confirmLines = bufio.NewReader(confirmInput)
+	}
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := confirmLines.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// previewChanges lists the pages publishing files creates and overwrites,
+// with the number of changed lines, and asks whether to go on
+func (m *Markdown2Confluence) previewChanges(files []MarkdownFile) error {
+	var creates, overwrites int
+	for i := range files {
+		f := files[i]
+		local, _, err := f.Render(m)
+		if err != nil {
+			return err
+		}
+
+		var existing *confluence.Content
+		if cached, exists, known := m.cachedPage(f.Title); known && exists && cached.Content.Body.Storage.Value != "" {
+			existing = &cached.Content
+		} else if !known || exists {
+			results, err := m.client.GetContent(&confluence.GetContentQueryParameters{
+				Title:    f.Title,
+				Spacekey: m.Space,
+				Limit:    1,
+				Type:     "page",
+				Expand:   []string{"version", "body.storage"},
+			})
+			if err != nil {
+				return fmt.Errorf("Error checking for existing page: %s", err)
+			}
+			if len(results) > 0 {
+				existing = &results[0]
+			}
+		}
+
+		if existing == nil {
+			creates++
+			fmt.Printf("  create     %s\n", f.FormattedPath())
+			continue
+		}
+		added, removed, err := changedLines(existing.Body.Storage.Value, local)
+		if err != nil {
+			return err
+		}
+		if added == 0 && removed == 0 {
+			continue
+		}
+		overwrites++
+		fmt.Printf("  overwrite  %s (page %s, version %d): +%d -%d lines\n", f.FormattedPath(), existing.ID, existing.Version.Number, added, removed)
+	}
+
+	if creates == 0 && overwrites == 0 {
+		return nil
+	}
+	if !m.confirm(fmt.Sprintf("Create %d and overwrite %d pages in %s?", creates, overwrites, m.Space)) {
+		return ErrNotConfirmed
+	}
+	return nil
+}
+
+// changedLines counts the lines a publish adds to and removes from a page
+// body, normalized like the diff command does
+func changedLines(remote, local string) (added, removed int, err error) {
+	before, err := storageLines(remote)
+	if err != nil {
+		return 0, 0, err
+	}
+	after, err := storageLines(local)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, l := range diffLines(before, after) {
+		switch l.Op {
+		case '+':
+			added++
+		case '-':
+			removed++
+		}
+	}
+	return added, removed, nil
+}
+
+// confirmGuard is the confluence.Guard of --interactive, asking before
+// every delete and move
+func (m *Markdown2Confluence) confirmGuard(operation, position string, ids []string) error {
+	what := "page " + ids[0]
+	if operation == confluence.GuardDeleteAttachment {
+		what = "an attachment of page " + ids[0]
+	}
+	if operation == confluence.GuardPurge {
+		what = "trashed page " + ids[0]
+	}
+	if page, err := m.client.GetPage(ids[0], "version"); err == nil && operation != confluence.GuardDeleteAttachment {
+		what = fmt.Sprintf("page %q (%s)", page.Title, page.ID)
+	}
+	question := fmt.Sprintf("%s %s?", strings.ToUpper(operation[:1])+operation[1:], what)
+	if operation == confluence.GuardMove {
+		question = fmt.Sprintf("Move %s %s page %s?", what, position, ids[1])
+	}
+	if !m.confirm(question) {
+		return ErrNotConfirmed
+	}
+	return nil
+}
+
+// chainGuards runs guards in order, stopping at the first error
+func chainGuards(guards ...confluence.Guard) confluence.Guard {
+	return func(operation, position string, ids []string) error {
+		for _, g := range guards {
+			if err := g(operation, position, ids); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,107 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// Policies for pages edited by someone else while a run updates them
+const (
+	// ConflictFail fails the page
+	ConflictFail = "fail"
+	// ConflictSkip leaves the edited page alone and reports it
+	ConflictSkip = "skip"
+	// ConflictRetry fetches the edited page and updates it again unless the
+	// edit conflicts with the markdown, carrying inline comments over with
+	// --preserve-inline-comments
+	ConflictRetry = "retry"
+	// ConflictForce overwrites the edit with the next version number
+	ConflictForce = "force"
+)
+
+// conflictRetries is how often retry and force update a page again before
+// giving up on another writer
+const conflictRetries = 3
+
+// errConflictSkipped tells Upload that ConflictSkip left the page alone
+var errConflictSkipped = errors.New("conflict skipped")
+
+// updateContent updates a page to the rendered body local, resolving
+// version conflicts by m.OnConflict. content is the page as it was looked
+// up, with local merged in.
+func (m *Markdown2Confluence) updateContent(f *MarkdownFile, content confluence.Content, local string) (confluence.Content, error) {
+	updated, err := m.client.UpdateContent(&content, nil)
+	for attempt := 0; errors.Is(err, confluence.ErrVersionConflict); attempt++ {
+		switch {
+		case m.OnConflict == ConflictSkip:
+			fmt.Printf("Warning: %s was edited by someone else during the run, leaving it as it is\n", f.Title)
+			return content, errConflictSkipped
+		case m.OnConflict != ConflictRetry && m.OnConflict != ConflictForce:
+			return content, fmt.Errorf("%s was edited by someone else during the run, use --on-conflict to retry, skip or force: %s", f.Title, err)
+		case attempt == conflictRetries:
+			return content, fmt.Errorf("%s kept being edited during the run, gave up after %d attempts: %s", f.Title, attempt+1, err)
+		}
+
+		current, fetchErr := m.client.GetPage(content.ID, "version", "body.storage")
+		if fetchErr != nil {
+			return content, fmt.Errorf("Unable to fetch %s after a version conflict: %s", f.Title, fetchErr)
+		}
+		if m.OnConflict == ConflictRetry {
+			if err := m.checkEditDuringRun(f, f.PreviousVersion, current, local); err != nil {
+				return content, err
+			}
+		}
+		fmt.Printf("Warning: %s was edited by someone else during the run, overwriting version %d\n", f.Title, current.Version.Number)
+		if m.OnConflict == ConflictRetry && m.PreserveInlineComments {
+			body, lost := preserveInlineComments(current.Body.Storage.Value, local)
+			for _, c := range lost {
+				fmt.Printf("Warning: %s: inline comment on %q could not be preserved\n", f.Title, c.Text)
+			}
+			content.Body.Storage.Value = body
+		}
+		f.PreviousVersion = current.Version.Number
+		content.Version.Number = current.Version.Number + 1
+		updated, err = m.client.UpdateContent(&content, nil)
+	}
+	return updated, err
+}
+
+// checkEditDuringRun merges the edit made to a page during the run with the
+// markdown, with the version the run looked up as the base, and fails when
+// both changed the same part of the page. The conflicts go to the
+// --merge-report.
+func (m *Markdown2Confluence) checkEditDuringRun(f *MarkdownFile, baseVersion int, current *confluence.Page, local string) error {
+	base, err := m.client.GetPageVersion(current.ID, baseVersion)
+	if err != nil {
+		return fmt.Errorf("Unable to fetch version %d of %s after a version conflict: %s", baseVersion, f.Title, err)
+	}
+	edit := manualEdit{
+		path:        f.Path,
+		title:       f.Title,
+		pageID:      current.ID,
+		baseVersion: baseVersion,
+		version:     current.Version.Number,
+		base:        base.Body.Storage.Value,
+		remote:      current.Body.Storage.Value,
+		local:       local,
+	}
+	chunks, err := mergeChunks(edit)
+	if err != nil {
+		return fmt.Errorf("Unable to merge the edit of %s made during the run: %s", f.Title, err)
+	}
+	conflicts := 0
+	for _, c := range chunks {
+		if c.conflict {
+			conflicts++
+		}
+	}
+	if conflicts == 0 {
+		return nil
+	}
+	if m.MergeReport != "" && m.manualEdits != nil {
+		m.manualEdits.add(edit)
+	}
+	return fmt.Errorf("%s was edited by someone else during the run where the markdown changed too, %d conflicts, use --on-conflict force to overwrite the edit", f.Title, conflicts)
+}
@@ -0,0 +1,76 @@
+package lib
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// TestUpdateContentRetry updates a page edited during the run with
+// --on-conflict retry, which gives up when the edit and the markdown changed
+// the same paragraph
+func TestUpdateContentRetry(t *testing.T) {
+	const (
+		base  = "<p>one</p><p>two</p>"
+		local = "<p>one, from markdown</p><p>two</p>"
+	)
+	tests := []struct {
+		name, remote string
+		updated      bool
+	}{
+		{name: "other paragraph", remote: "<p>one</p><p>two, from Confluence</p>", updated: true},
+		{name: "same paragraph", remote: "<p>one, from Confluence</p><p>two</p>", updated: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			updated := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				page := func(version int, body string) {
+					json.NewEncoder(w).Encode(confluence.Page{ID: "7", Title: "Page", Version: confluence.PageVersion{Number: version},
+						Body: confluence.PageBody{Storage: confluence.PageStorage{Value: body}}})
+				}
+				switch {
+				case r.Method == http.MethodGet && r.URL.Query().Get("version") == "2":
+					page(2, base)
+				case r.Method == http.MethodGet:
+					page(4, test.remote)
+				case r.Method == http.MethodPut:
+					dat, _ := io.ReadAll(r.Body)
+					if !strings.Contains(string(dat), `"number":5`) {
+						w.WriteHeader(http.StatusConflict)
+						w.Write([]byte(`{"statusCode":409,"message":"Version must be incremented on update"}`))
+						return
+					}
+					updated = true
+					page(5, local)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			m := &Markdown2Confluence{Endpoint: server.URL, OnConflict: ConflictRetry}
+			m.CreateClient()
+			f := &MarkdownFile{Path: "page.md", Title: "Page", PreviousVersion: 2}
+			content := confluence.Content{ID: "7", Type: "page", Title: "Page"}
+			content.Version.Number = 3
+			content.Body.Storage.Value = local
+			_, err := m.updateContent(f, content, local)
+			if updated != test.updated {
+				t.Errorf("updated = %t, want %t", updated, test.updated)
+			}
+			if test.updated && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if !test.updated && (err == nil || !strings.Contains(err.Error(), "1 conflicts")) {
+				t.Errorf("expected a conflict error, got %v", err)
+			}
+		})
+	}
+}
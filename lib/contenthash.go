@@ -0,0 +1,43 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// ContentHashProperty is the page property --content-hash stores the hash
+// of the published body in
+const ContentHashProperty = "m2c-content-hash"
+
+// contentHash identifies a rendered body and the parent it is published
+// below. Attachment names contain the hash of their file, so changed images
+// change the body as well.
+func contentHash(body, ancestorID string) string {
+	sum := sha256.Sum256([]byte(ancestorID + "\x00" + body))
+	return hex.EncodeToString(sum[:])
+}
+
+// publishedContentHash returns the hash stored on a page, or an empty string
+// when there is none
+func (m *Markdown2Confluence) publishedContentHash(pageID string) string {
+	property, err := m.client.GetContentProperty(pageID, ContentHashProperty)
+	if err != nil {
+		if !errors.Is(err, confluence.ErrPropertyNotFound) && m.Debug {
+			fmt.Printf("unable to fetch the content hash of page %s: %s\n", pageID, err)
+		}
+		return ""
+	}
+	return propertyString(*property)
+}
+
+// propertyString returns the value of a property holding a JSON string
+func propertyString(property confluence.ContentProperty) string {
+	var s string
+	_ = json.Unmarshal(property.Value, &s)
+	return s
+}
@@ -0,0 +1,92 @@
+package lib
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxContributors is the number of contributors named in the footer, the
+// others are counted
+const maxContributors = 10
+
+// contributor is an author of commits to a file
+type contributor struct {
+	Name    string
+	Commits int
+}
+
+// fileHistory returns the authors of the commits to path, most commits
+// first, and the date of the last commit. Renames are followed.
+func fileHistory(path string) ([]contributor, time.Time) {
+	out := git(filepath.Dir(path), "log", "--follow", "--format=%aN%x09%aI", "--", filepath.Base(path))
+	if out == "" {
+		return nil, time.Time{}
+	}
+	var contributors []contributor
+	index := map[string]int{}
+	var modified time.Time
+	for _, line := range strings.Split(out, "\n") {
+		name, date, _ := strings.Cut(line, "\t")
+		if modified.IsZero() {
+			modified, _ = time.Parse(time.RFC3339, date)
+		}
+		if i, ok := index[name]; ok {
+			contributors[i].Commits++
+			continue
+		}
+		index[name] = len(contributors)
+		contributors = append(contributors, contributor{Name: name, Commits: 1})
+	}
+	sort.SliceStable(contributors, func(i, j int) bool { return contributors[i].Commits > contributors[j].Commits })
+	return contributors, modified
+}
+
+// historyURL returns the web URL of the commit history of path, empty if it
+// can not be linked
+func historyURL(path string) string {
+	branch, commit := gitHead(filepath.Dir(path))
+	repo, rel, ref := repoFile(path, branch, commit)
+	if rel == "" {
+		return ""
+	}
+	switch {
+	case strings.Contains(repo, "gitlab"):
+		return repo + "/-/commits/" + ref + "/" + rel
+	case strings.Contains(repo, "bitbucket"):
+		return repo + "/history-node/" + ref + "/" + rel
+	}
+	return repo + "/commits/" + ref + "/" + rel
+}
+
+// contributorsFooter renders the contributors of a file from its git
+// history, empty outside of a git work tree
+func contributorsFooter(f *MarkdownFile) string {
+	if f.Path == StdinPath {
+		return ""
+	}
+	contributors, modified := fileHistory(f.Path)
+	if len(contributors) == 0 {
+		return ""
+	}
+
+	var names []string
+	for i, c := range contributors {
+		if i == maxContributors {
+			names = append(names, fmt.Sprintf("%d more", len(contributors)-maxContributors))
+			break
+		}
+		names = append(names, html.EscapeString(c.Name))
+	}
+	footer := `<hr/><p><em>Contributors: ` + strings.Join(names, ", ")
+	if !modified.IsZero() {
+		footer += ` · Last modified ` + modified.Format("2006-01-02")
+	}
+	if history := historyURL(f.Path); history != "" {
+		footer += ` · <a href="` + html.EscapeString(history) + `">History</a>`
+	}
+	return footer + `</em></p>`
+}
@@ -0,0 +1,50 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// CopyTree clones the page tree rooted at sourceID below destinationID, e.g.
+// into a "vNext" parent before publishing breaking changes. The Cloud page
+// hierarchy copy is used when available, otherwise pages are recreated one
+// by one.
+func (m *Markdown2Confluence) CopyTree(ctx context.Context, sourceID, destinationID, titlePrefix string) error {
+	m.CreateClient()
+	opts := confluence.CopyPageOptions{
+		CopyAttachments: true,
+		CopyLabels:      true,
+		CopyProperties:  true,
+		TitlePrefix:     titlePrefix,
+	}
+
+	taskID, err := m.client.CopyPageHierarchy(sourceID, destinationID, opts)
+	if err == nil {
+		fmt.Printf("Started copy of page %s to %s as task %s\n", sourceID, destinationID, taskID)
+		_, err = m.client.WaitForTask(ctx, taskID, confluence.WaitOptions{
+			Progress: func(task *confluence.LongTask) {
+				fmt.Printf("Copying page tree %s: %d%%\n", sourceID, task.PercentageComplete)
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("Unable to copy page tree %s: %s", sourceID, err)
+		}
+		fmt.Printf("Copied page tree %s to %s\n", sourceID, destinationID)
+		return nil
+	}
+	if errors.Is(err, confluence.ErrNotSupported) {
+		fmt.Println("Confluence Server and Data Center can not copy page trees, copying pages one by one")
+	} else if m.Debug {
+		fmt.Printf("page hierarchy copy unavailable (%s), copying pages one by one\n", err)
+	}
+
+	copiedID, err := m.client.CopyPageTree(ctx, sourceID, destinationID, opts)
+	if err != nil {
+		return fmt.Errorf("Unable to copy page tree %s: %s", sourceID, err)
+	}
+	fmt.Printf("Copied page tree %s to %s: %s/pages/viewpage.action?pageId=%s\n", sourceID, destinationID, m.Endpoint, copiedID)
+	return nil
+}
@@ -0,0 +1,267 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+
+	"github.com/justmiles/go-confluence"
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// Diff formats
+const (
+	DiffUnified = "unified"
+	DiffHTML    = "html"
+)
+
+// diffContext is the number of unchanged lines around each unified diff hunk
+const diffContext = 3
+
+// diffLine is a line of a diff: ' ' unchanged, '-' removed or '+' added
+type diffLine struct {
+	Op   byte
+	Text string
+}
+
+// Diff renders a markdown file and compares it with the body of its
+// published page. Both are normalized like --verify does, so only changes a
+// publish would make are shown. It returns the diff in the given format,
+// empty when there are no differences.
+func (m *Markdown2Confluence) Diff(path, format string) (string, error) {
+	m.CreateClient()
+	defer renderer.UseAttachmentDir()()
+	f := MarkdownFile{Path: path, Title: m.fileTitle(path)}
+	if m.Obsidian {
+		if err := m.IndexVault([]MarkdownFile{f}); err != nil {
+			return "", fmt.Errorf("Unable to index Obsidian vault: %s", err)
+		}
+	}
+
+	local, _, err := f.Render(m)
+	if err != nil {
+		return "", err
+	}
+
+	var remote string
+	remoteName := "/dev/null"
+	page, err := m.client.GetPageByTitle(m.Space, f.Title, "body.storage")
+	if err == nil {
+		remote = page.Body.Storage.Value
+		remoteName = fmt.Sprintf("%s (page %s, version %d)", f.Title, page.ID, page.Version.Number)
+	} else if !errors.Is(err, confluence.ErrPageNotFound) {
+		return "", fmt.Errorf("Error fetching page %s: %s", f.Title, err)
+	}
+
+	before, err := storageLines(remote)
+	if err != nil {
+		return "", fmt.Errorf("unable to normalize page %s: %s", f.Title, err)
+	}
+	after, err := storageLines(local)
+	if err != nil {
+		return "", fmt.Errorf("unable to normalize rendered %s: %s", path, err)
+	}
+
+	lines := diffLines(before, after)
+	changed := false
+	for _, l := range lines {
+		if l.Op != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return "", nil
+	}
+	if format == DiffHTML {
+		return htmlDiff(f.Title, lines), nil
+	}
+	return unifiedDiff(remoteName, path, lines), nil
+}
+
+// fileTitle returns the page title of a single markdown file: --title, the
+// document title with --use-document-title or the file name
+func (m *Markdown2Confluence) fileTitle(path string) string {
+	if m.Title != "" {
+		return m.Title
+	}
+	if m.UseDocumentTitle {
+		if title := getDocumentTitle(path); title != "" {
+			return title
+		}
+	}
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// storageLines normalizes a storage format body to one element or text per
+// line, indented by nesting depth
+func storageLines(body string) ([]string, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, nil
+	}
+	tokens, err := normalizeStorage(body)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(tokens))
+	depth := 0
+	for _, t := range tokens {
+		if strings.HasPrefix(t, "</") && depth > 0 {
+			depth--
+		}
+		lines = append(lines, strings.Repeat("  ", depth)+strings.TrimSuffix(strings.Replace(t, " >", ">", 1), " "))
+		if strings.HasPrefix(t, "<") && !strings.HasPrefix(t, "</") {
+			depth++
+		}
+	}
+	return lines, nil
+}
+
+// diffLines computes the shortest edit script from a to b with the Myers
+// algorithm
+func diffLines(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	var lines []diffLine
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			lines = append(lines, diffLine{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			lines = append(lines, diffLine{'+', b[y-1]})
+			y--
+		} else {
+			lines = append(lines, diffLine{'-', a[x-1]})
+			x--
+		}
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+// unifiedDiff formats a diff as hunks with diffContext lines of context
+func unifiedDiff(from, to string, lines []diffLine) string {
+	var b strings.Builder
+	b.WriteString("--- " + from + "\n")
+	b.WriteString("+++ " + to + "\n")
+
+	// line numbers in a and b before each diff line
+	aLine, bLine := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for i, l := range lines {
+		aLine[i+1], bLine[i+1] = aLine[i], bLine[i]
+		if l.Op != '+' {
+			aLine[i+1]++
+		}
+		if l.Op != '-' {
+			bLine[i+1]++
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == ' ' {
+			i++
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		// extend the hunk while the next change is close enough to share context
+		end := i
+		for j := i; j < len(lines) && j <= end+2*diffContext; j++ {
+			if lines[j].Op != ' ' {
+				end = j
+			}
+		}
+		end += diffContext + 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n",
+			hunkRange(aLine[start], aLine[end]-aLine[start]),
+			hunkRange(bLine[start], bLine[end]-bLine[start]))
+		for _, l := range lines[start:end] {
+			b.WriteString(string(l.Op) + l.Text + "\n")
+		}
+		i = end
+	}
+	return b.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// htmlDiff formats a diff as a standalone HTML page
+func htmlDiff(title string, lines []diffLine) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>" + html.EscapeString(title) + "</title>\n")
+	b.WriteString("<style>pre{margin:0}.del{background:#ffebe9}.ins{background:#e6ffec}</style></head><body>\n")
+	b.WriteString("<h1>" + html.EscapeString(title) + "</h1>\n")
+	for _, l := range lines {
+		class := ""
+		switch l.Op {
+		case '-':
+			class = ` class="del"`
+		case '+':
+			class = ` class="ins"`
+		}
+		b.WriteString("<pre" + class + ">" + string(l.Op) + html.EscapeString(l.Text) + "</pre>\n")
+	}
+	b.WriteString("</body></html>\n")
+	return b.String()
+}
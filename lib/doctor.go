@@ -0,0 +1,212 @@
+package lib
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/justmiles/go-confluence"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// DoctorCheck is the result of one check of the doctor command
+type DoctorCheck struct {
+	Name string
+	// Detail is what was found when the check passed
+	Detail string
+	// Err is why the check failed, nil when it passed
+	Err error
+	// Remedy is how to fix a failed check
+	Remedy string
+	// Optional checks are about features that are not needed by every run,
+	// they only warn when they fail
+	Optional bool
+}
+
+// Failed reports whether the check failed and fails the doctor command
+func (c DoctorCheck) Failed() bool {
+	return c.Err != nil && !c.Optional
+}
+
+// doctorTimeout bounds the requests of the doctor command, so an endpoint
+// dropping packets is reported instead of hanging
+const doctorTimeout = 15 * time.Second
+
+// Doctor checks the configuration, the endpoint, the credentials and the
+// space of m, and the external programs publishing may need. Checks of the
+// endpoint stop at the first failure, as the later ones would fail with it.
+// The version is optional, publishing works without it.
+func (m *Markdown2Confluence) Doctor() []DoctorCheck {
+	checks := []DoctorCheck{m.checkConfiguration()}
+	if checks[0].Err == nil {
+		m.CreateClient()
+		for _, check := range []func() DoctorCheck{m.checkConnectivity, m.checkServer, m.checkAuthentication, m.checkSpace} {
+			c := check()
+			checks = append(checks, c)
+			if c.Failed() {
+				break
+			}
+		}
+	}
+	return append(checks, checkPrograms()...)
+}
+
+func (m *Markdown2Confluence) checkConfiguration() DoctorCheck {
+	c := DoctorCheck{Name: "configuration", Err: m.ValidateConnection()}
+	if c.Err != nil {
+		c.Remedy = "set --endpoint, and --username with --password or --access-token, as flags, CONFLUENCE_* environment variables or a profile of the repo config"
+		return c
+	}
+	c.Detail = m.Endpoint
+	return c
+}
+
+// checkConnectivity requests the endpoint without credentials, any HTTP
+// response means it is reachable
+func (m *Markdown2Confluence) checkConnectivity() DoctorCheck {
+	c := DoctorCheck{Name: "connectivity"}
+	client := &http.Client{Timeout: doctorTimeout}
+	res, err := client.Get(m.Endpoint)
+	if err != nil {
+		c.Err = err
+		var dnsErr *net.DNSError
+		var certErr x509.UnknownAuthorityError
+		var hostErr x509.HostnameError
+		switch {
+		case errors.As(err, &dnsErr):
+			c.Remedy = "check the host name of --endpoint, and your proxy or VPN when it is an internal host"
+		case errors.As(err, &certErr), errors.As(err, &hostErr):
+			c.Remedy = "install the certificate authority of the endpoint, or skip certificate validation with --insecuretls"
+		case errors.Is(err, syscall.ECONNREFUSED):
+			c.Remedy = "check the port of --endpoint and that Confluence is running"
+		default:
+			c.Remedy = "check --endpoint, and that HTTPS_PROXY is set when the endpoint is only reachable through a proxy"
+		}
+		return c
+	}
+	res.Body.Close()
+	c.Detail = fmt.Sprintf("%s answered %s", m.Endpoint, res.Status)
+	return c
+}
+
+// checkServer detects the deployment and version of Confluence, which also
+// tells whether --endpoint is its base URL
+func (m *Markdown2Confluence) checkServer() DoctorCheck {
+	c := DoctorCheck{Name: "api version"}
+	info, err := m.client.ServerInfo()
+	if err != nil {
+		c.Err = err
+		c.Remedy = "set --endpoint to the base URL of Confluence, such as https://example.atlassian.net/wiki for Confluence Cloud, unless a proxy hides /rest/applinks from you"
+		c.Optional = true
+		return c
+	}
+	c.Detail = fmt.Sprintf("Confluence %s %s, build %s", info.Deployment, info.Version, info.BuildNumber)
+	if info.Deployment == confluence.DeploymentCloud && m.AccessToken != "" && m.Username == "" {
+		c.Err = fmt.Errorf("%s is Confluence Cloud, which does not accept personal access tokens", m.Endpoint)
+		c.Remedy = "pass your email address as --username and an API token from https://id.atlassian.com/manage-profile/security/api-tokens as --password"
+	}
+	return c
+}
+
+func (m *Markdown2Confluence) checkAuthentication() DoctorCheck {
+	c := DoctorCheck{Name: "authentication"}
+	user, err := m.client.CurrentUser()
+	if err == nil && (user.Type == "anonymous" || user.Username == "" && user.AccountID == "") {
+		err = errors.New("the credentials were not accepted, requests are anonymous")
+	}
+	if err != nil {
+		c.Err = err
+		switch {
+		case m.client.IsCloud():
+			c.Remedy = "Confluence Cloud signs in with your email address as --username and an API token as --password, not your account password"
+		case m.AccessToken != "":
+			c.Remedy = "check that the personal access token in --access-token has not expired or been revoked"
+		default:
+			c.Remedy = "check --username and --password, or create a personal access token in your Confluence profile for --access-token"
+		}
+		return c
+	}
+	c.Detail = "signed in as " + firstNonEmpty(user.DisplayName, user.Username, user.Email, user.AccountID)
+	return c
+}
+
+func (m *Markdown2Confluence) checkSpace() DoctorCheck {
+	c := DoctorCheck{Name: "space"}
+	if m.Space == "" {
+		c.Err = errors.New("--space is not defined")
+		c.Remedy = "pass --space to check the space pages are published to"
+		c.Optional = true
+		return c
+	}
+	space, err := m.client.GetSpace(m.Space, "operations")
+	if err == nil && space.Key == "" {
+		err = fmt.Errorf("space %s not found", m.Space)
+	}
+	if err != nil {
+		c.Err = err
+		c.Remedy = fmt.Sprintf("check the key of --space, it is the part after /spaces/ in the URL of the space, and that you may view %s", m.Space)
+		return c
+	}
+	c.Detail = space.Key + " " + space.Name
+	// Server and Data Center do not expand operations
+	if missing := missingOperations(space); len(space.Operations) > 0 && len(missing) > 0 {
+		c.Err = fmt.Errorf("missing permissions in space %s: %s", m.Space, strings.Join(missing, ", "))
+		c.Remedy = "ask a space admin to grant you these permissions in the space settings"
+	}
+	return c
+}
+
+// checkPrograms looks for the external programs used by some features.
+// Mermaid, PlantUML and other diagrams are rendered by the Kroki server or
+// Confluence macros, not by local programs.
+func checkPrograms() []DoctorCheck {
+	checks := []DoctorCheck{
+		checkProgram("git", "git", "install git for the git template variables, --contributors and --link-base-url auto"),
+		checkProgram("drawio", renderer.DrawioCommand, "install draw.io desktop, or point --drawio-command at it, to publish .drawio files without an exported .drawio.png next to them"),
+	}
+	if renderer.KrokiServer != "" {
+		checks = append(checks, checkKroki())
+	}
+	return checks
+}
+
+func checkProgram(name, command, remedy string) DoctorCheck {
+	c := DoctorCheck{Name: name, Optional: true}
+	path, err := exec.LookPath(command)
+	if err != nil {
+		c.Err = err
+		c.Remedy = remedy
+		return c
+	}
+	c.Detail = path
+	return c
+}
+
+// checkKroki renders a minimal diagram with the Kroki server
+func checkKroki() DoctorCheck {
+	c := DoctorCheck{Name: "kroki"}
+	if err := renderer.CheckKrokiServer(); err != nil {
+		c.Err = err
+		c.Remedy = "check --kroki-server, or leave it out to publish diagram code blocks as code"
+		return c
+	}
+	c.Detail = renderer.KrokiServer
+	return c
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+var (
+	// draftBegin and draftEnd mark a section of a file that is not published
+	draftBegin = regexp.MustCompile(`^<!--\s*m2c:begin-draft\s*-->$`)
+	draftEnd   = regexp.MustCompile(`^<!--\s*m2c:end-draft\s*-->$`)
+)
+
+// dropDrafts leaves out the files with draft: true in their front matter,
+// unless m.Drafts is set
+func (m *Markdown2Confluence) dropDrafts(files []MarkdownFile) ([]MarkdownFile, error) {
+	if m.Drafts {
+		return files, nil
+	}
+	var published []MarkdownFile
+	for _, f := range files {
+		dat := f.Content
+		if dat == nil {
+			var err error
+			dat, err = readMarkdown(f.Path)
+			if err != nil {
+				return nil, fmt.Errorf("Could not open file %s:\n\t%s", f.Path, err)
+			}
+		}
+		if fm, _ := ParseFrontMatter(dat); fm.Bool("draft") {
+			if m.Debug {
+				fmt.Printf("skipping %s: draft\n", f.Path)
+			}
+			continue
+		}
+		published = append(published, f)
+	}
+	return published, nil
+}
+
+// stripDrafts blanks the lines from <!-- m2c:begin-draft --> to
+// <!-- m2c:end-draft -->, keeping line numbers for messages. With keep only
+// the markers are removed. Markers in code blocks are left alone. Unbalanced
+// markers are reported to diagnostics.
+func stripDrafts(path string, dat []byte, keep bool, diagnostics *renderer.Diagnostics) []byte {
+	lines := strings.Split(string(dat), "\n")
+	fence := ""
+	depth, begin := 0, 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")) {
+			fence = trimmed[:3]
+		} else if fence != "" && strings.HasPrefix(trimmed, fence) {
+			fence = ""
+		} else if fence == "" && draftBegin.MatchString(trimmed) {
+			if depth == 0 {
+				begin = i + 1
+			}
+			depth++
+			lines[i] = ""
+			continue
+		} else if fence == "" && draftEnd.MatchString(trimmed) {
+			if depth == 0 {
+				warnDraft(diagnostics, path, i+1, "m2c:end-draft without m2c:begin-draft")
+			} else {
+				depth--
+			}
+			lines[i] = ""
+			continue
+		}
+		if depth > 0 && !keep {
+			lines[i] = ""
+		}
+	}
+	if depth > 0 {
+		warnDraft(diagnostics, path, begin, "m2c:begin-draft is not closed, the rest of the file is a draft")
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// warnDraft warns about a draft marker on a line of the file at path
+func warnDraft(diagnostics *renderer.Diagnostics, path string, line int, message string) {
+	diagnostics.Warn(renderer.Warning{
+		Class:    renderer.WarningDirective,
+		Position: renderer.Position{File: path, Line: line, Column: 1},
+		Message:  message,
+	})
+}
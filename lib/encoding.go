@@ -0,0 +1,229 @@
+package lib
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Source encodings, see SourceEncoding
+const (
+	EncodingAuto        = "auto"
+	EncodingUTF8        = "utf-8"
+	EncodingUTF16LE     = "utf-16le"
+	EncodingUTF16BE     = "utf-16be"
+	EncodingGBK         = "gbk"
+	EncodingLatin1      = "latin1"
+	EncodingWindows1252 = "windows-1252"
+)
+
+// SourceEncodings are the encodings markdown sources can be read in
+var SourceEncodings = []string{EncodingAuto, EncodingUTF8, EncodingUTF16LE, EncodingUTF16BE, EncodingGBK, EncodingLatin1, EncodingWindows1252}
+
+// SourceEncoding is the encoding of markdown sources. With EncodingAuto a
+// byte order mark or else the content decides, see DetectEncoding.
+var SourceEncoding = EncodingAuto
+
+var (
+	// gbkTable maps the two byte GBK sequences, lead byte 0x81-0xFE and trail
+	// byte 0x40-0xFE, to runes, 0 where unassigned
+	//go:embed gbk.bin.gz
+	gbkTableGz []byte
+	gbkTable   []uint16
+	gbkOnce    sync.Once
+
+	// windows1252 are the characters Windows-1252 puts at 0x80-0x9F, where
+	// Latin-1 has control characters
+	windows1252 = [32]rune{
+		'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+		0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+	}
+
+	// convertedSources are the files already warned about
+	convertedSources sync.Map
+)
+
+// readMarkdown reads a markdown source and converts it to UTF-8, warning
+// once per file about sources that were not UTF-8
+func readMarkdown(path string) ([]byte, error) {
+	dat, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSource(path, dat)
+}
+
+// decodeSource converts a source read from path to UTF-8 without a byte
+// order mark
+func decodeSource(path string, dat []byte) ([]byte, error) {
+	encoding := SourceEncoding
+	if encoding == EncodingAuto || encoding == "" {
+		encoding = DetectEncoding(dat)
+	}
+	text, err := decode(encoding, dat)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s as %s: %s", path, encoding, err)
+	}
+	if encoding != EncodingUTF8 {
+		if _, warned := convertedSources.LoadOrStore(path, encoding); !warned {
+			fmt.Printf("Warning: %s is encoded in %s, converted to UTF-8\n", path, encoding)
+		}
+	}
+	return text, nil
+}
+
+// sourceEncoding returns the encoding path was converted from, empty for
+// UTF-8 sources
+func sourceEncoding(path string) string {
+	if encoding, ok := convertedSources.Load(path); ok {
+		return encoding.(string)
+	}
+	return ""
+}
+
+// DetectEncoding guesses the encoding of a source: a byte order mark, NUL
+// bytes of UTF-16 encoded ASCII, valid UTF-8, GBK decoding to mostly Chinese
+// characters, or else Windows-1252, a superset of Latin-1's printable
+// characters
+func DetectEncoding(dat []byte) string {
+	switch {
+	case bytes.HasPrefix(dat, []byte{0xEF, 0xBB, 0xBF}):
+		return EncodingUTF8
+	case bytes.HasPrefix(dat, []byte{0xFF, 0xFE}):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(dat, []byte{0xFE, 0xFF}):
+		return EncodingUTF16BE
+	}
+
+	var even, odd int
+	for i, b := range dat {
+		if b == 0 {
+			if i%2 == 0 {
+				even++
+			} else {
+				odd++
+			}
+		}
+	}
+	if len(dat) >= 2 && (even+odd)*4 >= len(dat) {
+		if even > odd {
+			return EncodingUTF16BE
+		}
+		return EncodingUTF16LE
+	}
+
+	if utf8.Valid(dat) {
+		return EncodingUTF8
+	}
+	if text, err := decodeGBK(dat); err == nil && mostlyHan(text) {
+		return EncodingGBK
+	}
+	return EncodingWindows1252
+}
+
+// mostlyHan tells whether most non-ASCII characters of text are Chinese
+// characters or CJK punctuation
+func mostlyHan(text []byte) bool {
+	var han, other int
+	for _, r := range string(text) {
+		switch {
+		case r < utf8.RuneSelf:
+		case unicode.Is(unicode.Han, r), r >= 0x3000 && r <= 0x303F, r >= 0xFF00 && r <= 0xFFEF:
+			han++
+		default:
+			other++
+		}
+	}
+	return han > 0 && han >= other*4
+}
+
+func decode(encoding string, dat []byte) ([]byte, error) {
+	switch encoding {
+	case EncodingUTF8:
+		dat = bytes.TrimPrefix(dat, []byte{0xEF, 0xBB, 0xBF})
+		if !utf8.Valid(dat) {
+			return nil, fmt.Errorf("invalid UTF-8")
+		}
+		return dat, nil
+	case EncodingUTF16LE, EncodingUTF16BE:
+		return decodeUTF16(dat, encoding == EncodingUTF16BE)
+	case EncodingGBK:
+		return decodeGBK(dat)
+	case EncodingLatin1, EncodingWindows1252:
+		var b strings.Builder
+		for _, c := range dat {
+			if c >= 0x80 && c < 0xA0 && encoding == EncodingWindows1252 {
+				b.WriteRune(windows1252[c-0x80])
+			} else {
+				b.WriteRune(rune(c))
+			}
+		}
+		return []byte(b.String()), nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q, use one of %s", encoding, strings.Join(SourceEncodings, ", "))
+}
+
+func decodeUTF16(dat []byte, bigEndian bool) ([]byte, error) {
+	if len(dat)%2 != 0 {
+		return nil, fmt.Errorf("odd number of bytes in UTF-16")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	units := make([]uint16, 0, len(dat)/2)
+	for i := 0; i < len(dat); i += 2 {
+		units = append(units, order.Uint16(dat[i:]))
+	}
+	if len(units) > 0 && units[0] == 0xFEFF {
+		units = units[1:]
+	}
+	return []byte(string(utf16.Decode(units))), nil
+}
+
+func decodeGBK(dat []byte) ([]byte, error) {
+	gbkOnce.Do(func() {
+		r, err := gzip.NewReader(bytes.NewReader(gbkTableGz))
+		if err != nil {
+			panic(err)
+		}
+		table, err := io.ReadAll(r)
+		if err != nil {
+			panic(err)
+		}
+		gbkTable = make([]uint16, len(table)/2)
+		for i := range gbkTable {
+			gbkTable[i] = binary.LittleEndian.Uint16(table[2*i:])
+		}
+	})
+
+	var b strings.Builder
+	for i := 0; i < len(dat); i++ {
+		c := dat[i]
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case c == 0x80:
+			b.WriteRune('€')
+		case c == 0xFF || i+1 == len(dat) || dat[i+1] < 0x40 || dat[i+1] == 0xFF:
+			return nil, fmt.Errorf("invalid GBK sequence at byte %d", i)
+		default:
+			r := gbkTable[int(c-0x81)*191+int(dat[i+1]-0x40)]
+			if r == 0 {
+				return nil, fmt.Errorf("invalid GBK sequence at byte %d", i)
+			}
+			b.WriteRune(rune(r))
+			i++
+		}
+	}
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+)
+
+// EndpointConfig is a named Confluence instance the same content is
+// published to. Empty fields fall back to the command line settings, and
+// ${VAR} references are expanded so credentials can stay in the environment.
+type EndpointConfig struct {
+	Name        string `json:"name"`
+	Endpoint    string `json:"endpoint"`
+	Space       string `json:"space"`
+	Parent      string `json:"parent"`
+	ParentID    string `json:"parent-id"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	AccessToken string `json:"access-token"`
+}
+
+// LoadEndpoints reads the endpoints of a JSON or YAML file of the form
+// {"endpoints": [{"name": "cloud", "endpoint": "...", "space": "..."}]}
+func LoadEndpoints(file string) ([]EndpointConfig, error) {
+	var config struct {
+		Endpoints []EndpointConfig `json:"endpoints"`
+	}
+	if err := decodeConfigFile(file, &config); err != nil {
+		return nil, fmt.Errorf("Unable to read endpoints file %s: %s", file, err)
+	}
+	if len(config.Endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoints defined in %s", file)
+	}
+	for i, e := range config.Endpoints {
+		if e.Name == "" {
+			return nil, fmt.Errorf("endpoint %d in %s has no name", i+1, file)
+		}
+	}
+	return config.Endpoints, nil
+}
+
+// forEndpoint returns a copy of the settings that publishes to e
+func (m Markdown2Confluence) forEndpoint(e EndpointConfig) Markdown2Confluence {
+	override := func(setting *string, value string) {
+		if value = os.ExpandEnv(value); value != "" {
+			*setting = value
+		}
+	}
+	override(&m.Endpoint, e.Endpoint)
+	override(&m.Space, e.Space)
+	override(&m.Username, e.Username)
+	override(&m.Password, e.Password)
+	override(&m.AccessToken, e.AccessToken)
+	if e.Parent != "" || e.ParentID != "" {
+		m.Parent = os.ExpandEnv(e.Parent)
+		m.ParentId = os.ExpandEnv(e.ParentID)
+	}
+	m.client = nil
+	m.Report = nil
+	return m
+}
+
+// RunEndpoints publishes the same files to every endpoint, one after the
+// other. Each endpoint keeps its own parent page lookups, and the pages of
+// all endpoints are collected in m.Report.
+func (m *Markdown2Confluence) RunEndpoints(endpoints []EndpointConfig) []error {
+	var errors []error
+	report := &Report{}
+	for _, e := range endpoints {
+		if m.Interrupted() {
+			errors = append(errors, fmt.Errorf("[%s] %s: not published", e.Name, ErrInterrupted))
+			continue
+		}
+		fmt.Printf("Publishing to %s\n", e.Name)
+		run := m.forEndpoint(e)
+		if err := run.Validate(); err != nil {
+			errors = append(errors, fmt.Errorf("[%s] %s", e.Name, err))
+			continue
+		}
+
+		// parent pages are looked up by title, which must not leak across instances
+		ParentIndex = make(map[string]string)
+		for _, err := range run.Run() {
+			errors = append(errors, fmt.Errorf("[%s] %s", e.Name, err))
+		}
+		if run.Report != nil {
+			for _, p := range run.Report.Pages {
+				p.Endpoint = e.Name
+				report.Add(p)
+			}
+			report.AddCreatedPages(run.Report.CreatedPages...)
+		}
+	}
+	m.Report = report
+	return errors
+}
@@ -0,0 +1,67 @@
+package lib
+
+import (
+	"encoding/xml"
+	"html"
+	"io"
+	"strings"
+)
+
+// excerptMacro wraps storage format in the excerpt macro. Hidden excerpts are
+// only shown where the excerpt is included, e.g. by the children macro.
+func excerptMacro(body string, hidden bool) string {
+	h := "false"
+	if hidden {
+		h = "true"
+	}
+	return `<ac:structured-macro ac:name="excerpt" ac:schema-version="1">` +
+		`<ac:parameter ac:name="hidden">` + h + `</ac:parameter>` +
+		`<ac:parameter ac:name="atlassian-macro-output-type">BLOCK</ac:parameter>` +
+		`<ac:rich-text-body>` + body + `</ac:rich-text-body></ac:structured-macro>`
+}
+
+// addExcerpt marks the summary of a page with the excerpt macro: the summary
+// from the front matter as a hidden excerpt, or else the first top level
+// paragraph of the body
+func addExcerpt(body string, fm FrontMatter) string {
+	for _, key := range indexSummaryKeys {
+		if summary := fm.Get(key); summary != "" {
+			return excerptMacro("<p>"+html.EscapeString(summary)+"</p>", true) + "\n" + body
+		}
+	}
+
+	start, end, ok := firstParagraph(body)
+	if !ok {
+		return body
+	}
+	return body[:start] + excerptMacro(body[start:end], false) + body[end:]
+}
+
+// firstParagraph returns the byte range of the first <p> element that is not
+// nested in another element
+func firstParagraph(body string) (int, int, bool) {
+	decoder := xml.NewDecoder(strings.NewReader(storageRootStart + body + storageRootEnd))
+	decoder.Entity = xml.HTMLEntity
+	decoder.Strict = false
+
+	depth, start := 0, -1
+	for {
+		offset := int(decoder.InputOffset()) - len(storageRootStart)
+		token, err := decoder.Token()
+		if err == io.EOF || err != nil {
+			return 0, 0, false
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 && t.Name.Local == "p" && t.Name.Space == "" {
+				start = offset
+			}
+		case xml.EndElement:
+			depth--
+			if depth == 1 && start >= 0 {
+				return start, int(decoder.InputOffset()) - len(storageRootStart), true
+			}
+		}
+	}
+}
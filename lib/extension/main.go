@@ -2,6 +2,7 @@ package extension
 
 import (
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/util"
 
@@ -10,29 +11,93 @@ import (
 
 // Confluence is a Goldmark extension that renders markdown content compatable with Confluence
 type Confluence struct {
-	imageHTMLRender *r.ConfluenceImageHTMLRender
+	imageHTMLRender           *r.ConfluenceImageHTMLRender
+	fencedCodeBlockHTMLRender *r.ConfluenceFencedCodeBlockHTMLRender
+	codeBlockHTMLRender       *r.ConfluenceCodeBlockHTMLRender
+	wikiLinkHTMLRender        *r.ConfluenceWikiLinkHTMLRender
+	filePath                  string
+	diagnostics               *r.Diagnostics
 }
 
 // NewConfluenceExtension returns an instanciated instance of Confluence
 func NewConfluenceExtension(filePath string) *Confluence {
+	diagnostics := r.NewDiagnostics()
 	c := &Confluence{
-		imageHTMLRender: r.NewConfluenceImageHTMLRender(filePath),
+		imageHTMLRender:           r.NewConfluenceImageHTMLRender(filePath, diagnostics),
+		fencedCodeBlockHTMLRender: r.NewConfluenceFencedCodeBlockHTMLRender(filePath, diagnostics),
+		codeBlockHTMLRender:       r.NewConfluenceCodeBlockHTMLRender(filePath, diagnostics),
+		wikiLinkHTMLRender:        r.NewConfluenceWikiLinkHTMLRender(filePath),
+		filePath:                  filePath,
+		diagnostics:               diagnostics,
 	}
 	return c
 }
 
-// Images returns a slice of image paths for later upload
+// Images returns a slice of image and generated attachment paths for later upload
 func (c *Confluence) Images() []string {
-	return c.imageHTMLRender.Images
+	images := append(c.imageHTMLRender.Images, c.fencedCodeBlockHTMLRender.Attachments...)
+	images = append(images, c.codeBlockHTMLRender.Attachments...)
+	return append(images, c.wikiLinkHTMLRender.Attachments...)
+}
+
+// Diagnostics returns the warnings of the render
+func (c *Confluence) Diagnostics() *r.Diagnostics {
+	return c.diagnostics
 }
 
 // Extend markdown custom HTML render
 func (c *Confluence) Extend(m goldmark.Markdown) {
 
 	m.Renderer().AddOptions(renderer.WithNodeRenderers(
-		util.Prioritized(r.NewConfluenceFencedCodeBlockHTMLRender(), 100),
-		util.Prioritized(r.NewConfluenceCodeBlockHTMLRender(), 100),
+		util.Prioritized(c.fencedCodeBlockHTMLRender, 100),
+		util.Prioritized(c.codeBlockHTMLRender, 100),
 		util.Prioritized(c.imageHTMLRender, 100),
+		util.Prioritized(r.NewConfluenceLayoutHTMLRender(), 100),
+		util.Prioritized(r.NewConfluenceExpandHTMLRender(), 100),
+		util.Prioritized(r.NewConfluenceHTMLTableHTMLRender(), 100),
+		util.Prioritized(r.NewConfluenceTaskListHTMLRender(), 100),
+		util.Prioritized(r.NewConfluenceQuoteHTMLRender(), 100),
 	))
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(r.NewLayoutParser(), 150)),
+		parser.WithASTTransformers(util.Prioritized(r.NewDirectiveTransformer(c.filePath, c.diagnostics), 50)),
+		parser.WithASTTransformers(util.Prioritized(r.NewHTMLTableTransformer(c.filePath, c.diagnostics), 60)),
+		parser.WithASTTransformers(util.Prioritized(r.NewListTransformer(), 70)),
+		parser.WithASTTransformers(util.Prioritized(r.NewQuoteTransformer(), 110)),
+		parser.WithASTTransformers(util.Prioritized(r.NewRawHTMLTransformer(c.filePath, c.diagnostics), 200)),
+	)
+
+	if r.Badges == r.BadgesStrip {
+		m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(r.NewBadgeTransformer(), 60)))
+	}
+	if r.HorizontalRules == r.HorizontalRulesSection {
+		m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(r.NewSectionBreakTransformer(), 120)))
+	}
+
+	if r.RewriteLink != nil {
+		m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(r.NewLinkRewriteTransformer(c.filePath), 60)))
+	}
+
+	if r.ResolvePageLink != nil {
+		m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(r.NewConfluencePageLinkHTMLRender(c.filePath, c.diagnostics), 100)))
+	}
+
+	if r.InlineCode != r.InlineCodeCode {
+		m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(r.NewConfluenceCodeSpanHTMLRender(), 100)))
+	}
+
+	if r.HeadingAnchors {
+		m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(r.NewConfluenceHeadingHTMLRender(), 100)))
+	}
+
+	if r.Obsidian {
+		m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(r.NewWikiLinkParser(), 199)))
+		m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(c.wikiLinkHTMLRender, 100)))
+	}
+
+	if r.Obsidian || r.MDX {
+		m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(r.NewCalloutTransformer(), 100)))
+		m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(r.NewConfluenceCalloutHTMLRender(), 100)))
+	}
 
 }
@@ -2,10 +2,10 @@ package lib
 
 import (
 	"fmt"
-	"io/ioutil"
 	"strings"
 
 	"github.com/justmiles/go-confluence"
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
 )
 
 // MarkdownFile contains information about the file to upload
@@ -14,6 +14,44 @@ type MarkdownFile struct {
 	Title    string
 	Parents  []string
 	Ancestor string
+	// Content is rendered instead of the file at Path when set. Path is
+	// still used to resolve relative links and report positions.
+	Content []byte
+	// Preamble is storage format put before the rendered content
+	Preamble string
+	// Locale is the language of the file with --locales, Space the space it
+	// publishes to when not m.Space, and Translations its other languages
+	Locale       string
+	Space        string
+	Translations []Translation
+	// PageID, WebURL, Action, Attachments and SkippedAttachments are set by
+	// Upload
+	PageID             string
+	WebURL             string
+	Action             string
+	Attachments        int
+	SkippedAttachments []string
+	// PreviousVersion is the version of an updated page before the run,
+	// CreatedPages the parent pages created for the file, CreatedRedirects
+	// the stub left at its old title, CreatedAttachments the new attachments
+	// and PreviousAttachmentVersions the versions of the updated attachments
+	// before the run, by id, all for undo
+	PreviousVersion            int
+	CreatedPages               []string
+	CreatedRedirects           []string
+	CreatedAttachments         []string
+	PreviousAttachmentVersions map[string]int
+	// Parts are the sections split off the file by --split-large-pages,
+	// published as child pages of its page. Section is the heading id of
+	// such a part, with the ids of the sections it was split off before.
+	Parts   []MarkdownFile
+	Section string
+	// Combined are the files rendered one after the other on the page with
+	// --combine
+	Combined []MarkdownFile
+
+	// rendered is set by renderAll
+	rendered *renderedFile
 }
 
 func (f *MarkdownFile) String() (urlPath string) {
@@ -31,44 +69,62 @@ func (f *MarkdownFile) FormattedPath() (s string) {
 // Upload a markdown file
 func (f *MarkdownFile) Upload(m *Markdown2Confluence) (urlPath string, err error) {
 	var ancestorID string
-	// Content of Wiki
-	dat, err := ioutil.ReadFile(f.Path)
+	wikiContent, images, err := f.Render(m)
 	if err != nil {
-		return urlPath, fmt.Errorf("Could not open file %s:\n\t%s", f.Path, err)
+		return urlPath, err
 	}
-
-	if m.Debug {
-		fmt.Println(f.Path)
+	if err := m.checkBodySize(f, wikiContent); err != nil {
+		return urlPath, err
 	}
 
-	wikiContent := string(dat)
-	var images []string
-	wikiContent, images, err = renderContent(f.Path, wikiContent, m.WithHardWraps)
-
+	wikiContent, images, cleanup, err := m.limitAttachments(f, wikiContent, images)
+	defer cleanup()
 	if err != nil {
-		return urlPath, fmt.Errorf("unable to render content from %s: %s", f.Path, err)
+		return urlPath, err
 	}
 
-	if m.Debug {
-		fmt.Println("---- RENDERED CONTENT START ---------------------------------")
-		fmt.Println(wikiContent)
-		fmt.Println("---- RENDERED CONTENT END -----------------------------------")
+	if m.ValidateOnly {
+		f.Action = ActionValidated
+		return urlPath, nil
+	}
 
-		for _, image := range images {
-			fmt.Printf("LOCAL IMAGE FOUND: %s\n", image)
+	expand := []string{"version", "body.storage"}
+	if m.ContentHash && !m.PreserveInlineComments {
+		// the hash property tells whether the page changed, the body is not needed
+		expand = []string{"version"}
+	}
+
+	// search for existing page, unless it was prefetched
+	var contentResults []confluence.Content
+	cached, exists, known := m.cachedPage(f.Title)
+	if known && !m.PreserveInlineComments {
+		if exists {
+			contentResults = []confluence.Content{cached.Content}
+		}
+	} else {
+		known = false
+		contentResults, err = m.client.GetContent(&confluence.GetContentQueryParameters{
+			Title:    f.Title,
+			Spacekey: m.Space,
+			Limit:    1,
+			Type:     "page",
+			Expand:   expand,
+		})
+		if err != nil {
+			return urlPath, fmt.Errorf("Error checking for existing page: %s", err)
 		}
 	}
 
-	// search for existing page
-	contentResults, err := m.client.GetContent(&confluence.GetContentQueryParameters{
-		Title:    f.Title,
-		Spacekey: m.Space,
-		Limit:    1,
-		Type:     "page",
-		Expand:   []string{"version", "body.storage"},
-	})
-	if err != nil {
-		return urlPath, fmt.Errorf("Error checking for existing page: %s", err)
+	// a file published under another title before renames its page
+	renamed, isRenamed := m.renamedPage(f)
+	if isRenamed && len(contentResults) == 0 {
+		old, err := m.renamedContent(renamed)
+		if err != nil {
+			return urlPath, err
+		}
+		contentResults = []confluence.Content{old}
+	} else {
+		isRenamed = false
 	}
 
 	// if ancestor was set because parent is a page id
@@ -84,11 +140,72 @@ func (f *MarkdownFile) Upload(m *Markdown2Confluence) (urlPath string, err error
 		}
 	}
 
+	var teams []string
+	if m.CodeOwners {
+		teams = ownerTeams(fileOwners(f.Path))
+	}
+
+	var hash string
+	if m.ContentHash {
+		hash = contentHash(wikiContent, ancestorID)
+		if len(teams) > 0 {
+			// a change of owners has to reach the page too
+			hash = contentHash(wikiContent+"\x00"+strings.Join(m.ownerGroups(teams), ","), ancestorID)
+		}
+		if len(m.Labels) > 0 {
+			// and so do new labels
+			hash = contentHash(hash+"\x00"+strings.Join(m.Labels, ","), ancestorID)
+		}
+		published := cached.Hash
+		if !known && len(contentResults) > 0 {
+			published = m.publishedContentHash(contentResults[0].ID)
+		}
+		if len(contentResults) > 0 && published == hash && !isRenamed {
+			f.PageID = contentResults[0].ID
+			f.WebURL = m.webURL(contentResults[0].Links.Webui)
+			f.Action = ActionUnchanged
+			return m.client.Endpoint + contentResults[0].Links.Tinyui, nil
+		}
+	}
+
+	// contributors change with every commit, they are not part of the hash
+	if m.Contributors {
+		wikiContent = renderer.AppendLayout(wikiContent, contributorsFooter(f))
+	}
+
 	var content confluence.Content
 	var currContentID string
+	var publishedVersion int
 	// if page exists, update it
 	if len(contentResults) > 0 {
 		content = contentResults[0]
+		if m.SafeMode.Enabled() {
+			if err := m.guard(guardUpdate, "", []string{content.ID}); err != nil {
+				return urlPath, err
+			}
+		}
+		if m.ManualEdits != "" {
+			edited, err := m.checkManualEdit(f, content, wikiContent)
+			if err != nil {
+				return urlPath, err
+			}
+			if edited && m.ManualEdits == ManualEditsSkip {
+				f.PageID = content.ID
+				f.WebURL = m.webURL(content.Links.Webui)
+				f.Action = ActionEdited
+				return m.client.Endpoint + content.Links.Tinyui, nil
+			}
+		}
+		local := wikiContent
+		if m.PreserveInlineComments {
+			var lost []inlineComment
+			wikiContent, lost = preserveInlineComments(content.Body.Storage.Value, wikiContent)
+			for _, c := range lost {
+				fmt.Printf("Warning: %s: inline comment on %q could not be preserved\n", f.Title, c.Text)
+			}
+		}
+		content.Title = f.Title
+		f.PreviousVersion = content.Version.Number
 		content.Version.Number++
 		content.Version.Message = m.Comment
 		content.Body.Storage.Representation = "storage"
@@ -100,12 +217,22 @@ func (f *MarkdownFile) Upload(m *Markdown2Confluence) (urlPath string, err error
 			})
 		}
 
-		content, err = m.client.UpdateContent(&content, nil)
+		content, err = m.updateContent(f, content, local)
+		if err == errConflictSkipped {
+			f.PageID = content.ID
+			f.WebURL = m.webURL(content.Links.Webui)
+			f.Action = ActionConflict
+			f.PreviousVersion = 0
+			return m.client.Endpoint + content.Links.Tinyui, nil
+		}
 		if err != nil {
 			return urlPath, fmt.Errorf("Error updating content: %s", err)
 		}
 		urlPath = m.client.Endpoint + content.Links.Tinyui
+		f.WebURL = m.webURL(content.Links.Webui)
 		currContentID = content.ID
+		publishedVersion = content.Version.Number
+		f.Action = ActionUpdated
 
 		// if page does not exist, create it
 	} else {
@@ -128,18 +255,173 @@ func (f *MarkdownFile) Upload(m *Markdown2Confluence) (urlPath string, err error
 			return urlPath, fmt.Errorf("Error creating page: %s", err)
 		}
 		urlPath = m.client.Endpoint + content.Links.Tinyui
+		f.WebURL = m.webURL(content.Links.Webui)
 		currContentID = content.ID
+		publishedVersion = content.Version.Number
+		f.Action = ActionCreated
 	}
+	f.PageID = currContentID
 
-	_, errors := m.client.AddUpdateAttachments(currContentID, images)
+	if m.ManualEdits != "" {
+		m.storePublishedBody(f, currContentID, publishedVersion, wikiContent)
+	}
+
+	attachments, errors := m.client.AddUpdateAttachments(currContentID, images)
+	f.Attachments = len(images) - len(errors)
+	for _, a := range attachments {
+		if a.Version.Number == 1 {
+			f.CreatedAttachments = append(f.CreatedAttachments, a.ID)
+		} else if a.Version.Number > 1 {
+			if f.PreviousAttachmentVersions == nil {
+				f.PreviousAttachmentVersions = map[string]int{}
+			}
+			f.PreviousAttachmentVersions[a.ID] = a.Version.Number - 1
+		}
+	}
 	if len(errors) > 0 {
 		fmt.Println(errors)
 		err = errors[0]
 	}
 
+	if err == nil && len(m.Labels) > 0 {
+		if err = m.client.AddLabels(currContentID, m.Labels, confluence.GlobalPrefix); err != nil {
+			err = fmt.Errorf("Unable to add labels: %s", err)
+		}
+	}
+
+	if err == nil && m.CodeOwners && len(teams) > 0 {
+		err = m.applyOwnership(currContentID, teams)
+	} else if err == nil && m.EditLock {
+		err = m.lockEditing(currContentID)
+	}
+
+	if err == nil && m.Verify {
+		err = f.VerifyPage(m, wikiContent)
+	}
+
+	if err == nil && isRenamed {
+		var redirectID string
+		redirectID, err = m.createRedirect(renamed.Title, f.Title, ancestorID)
+		if redirectID != "" {
+			f.CreatedRedirects = append(f.CreatedRedirects, redirectID)
+		}
+	}
+
+	if err == nil {
+		err = m.runPostPublishHooks(f, currContentID, urlPath)
+	}
+
+	if err == nil && m.ContentHash {
+		if err := m.client.SetContentProperty(currContentID, ContentHashProperty, hash); err != nil {
+			fmt.Printf("Warning: unable to store the content hash of %s: %s\n", f.Title, err)
+		}
+	}
+
 	return urlPath, err
 }
 
+// webURL returns the full URL of a page from its webui link, empty if the
+// link is missing
+func (m *Markdown2Confluence) webURL(webui string) string {
+	if webui == "" {
+		return ""
+	}
+	return m.client.Endpoint + webui
+}
+
+// Render converts the markdown file to Confluence storage format and validates
+// the result. It returns the rendered body and local files to attach.
+func (f *MarkdownFile) Render(m *Markdown2Confluence) (wikiContent string, images []string, err error) {
+	if f.rendered != nil {
+		return f.rendered.body, f.rendered.images, f.rendered.err
+	}
+
+	var fm FrontMatter
+	if len(f.Combined) > 0 {
+		fm, wikiContent, images, err = f.renderCombined(m)
+	} else {
+		fm, wikiContent, images, err = f.renderBody(m)
+	}
+	if err == nil {
+		wikiContent, images, err = m.applyTemplates(f, fm, wikiContent, images)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	wikiContent = renderer.WrapLayout(f.Preamble + translationLinks(f, m.Space) + wikiContent)
+	if renderer.Deterministic {
+		wikiContent = renderer.Canonicalize(wikiContent)
+	}
+
+	if m.Debug {
+		fmt.Println("---- RENDERED CONTENT START ---------------------------------")
+		fmt.Println(wikiContent)
+		fmt.Println("---- RENDERED CONTENT END -----------------------------------")
+
+		for _, image := range images {
+			fmt.Printf("LOCAL IMAGE FOUND: %s\n", image)
+		}
+	}
+
+	if errors := ValidateStorageFormat(wikiContent); len(errors) > 0 {
+		var messages []string
+		for _, e := range errors {
+			messages = append(messages, e.Error())
+		}
+		return "", nil, fmt.Errorf("invalid storage format rendered from %s:\n\t%s", f.Path, strings.Join(messages, "\n\t"))
+	}
+
+	return wikiContent, images, nil
+}
+
+// renderBody converts the markdown of the file to storage format, without
+// the templates and layout of its page. It returns the front matter, the
+// body and the local files to attach.
+func (f *MarkdownFile) renderBody(m *Markdown2Confluence) (fm FrontMatter, wikiContent string, images []string, err error) {
+	// Content of Wiki
+	dat := f.Content
+	if dat == nil {
+		dat, err = readMarkdown(f.Path)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("Could not open file %s:\n\t%s", f.Path, err)
+		}
+	}
+
+	if m.Debug {
+		fmt.Println(f.Path)
+	}
+
+	dat, err = m.runPreRenderHooks(f.Path, dat)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	dat = m.Normalization.Apply(dat)
+
+	// front matter is metadata for static site generators, never page content
+	fm, dat = ParseFrontMatter(dat)
+	diagnostics := renderer.NewDiagnostics()
+	dat = stripDrafts(f.Path, dat, m.Drafts, diagnostics)
+
+	if renderer.MDX {
+		dat = preprocessMDX(dat)
+	}
+
+	wikiContent, images, err = renderContent(f.Path, string(dat), m.WithHardWraps)
+	if err == nil {
+		err = diagnostics.Err()
+	}
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("unable to render content from %s: %w", f.Path, err)
+	}
+	if len(m.Glossary) > 0 && !strings.EqualFold(fm.Get("glossary"), "false") {
+		wikiContent = linkGlossaryTerms(wikiContent, f.Title, m.Glossary)
+	}
+	if m.Excerpt {
+		wikiContent = addExcerpt(wikiContent, fm)
+	}
+	return fm, wikiContent, images, nil
+}
+
 // FindOrCreateAncestors creates an empty page to represent a local "folder" name
 func (f *MarkdownFile) FindOrCreateAncestors(m *Markdown2Confluence) (ancestorID string, err error) {
 
@@ -210,6 +492,7 @@ func (f *MarkdownFile) FindOrCreateAncestor(m *Markdown2Confluence, client *conf
 		return "", fmt.Errorf("Error creating parent page %s for %s: %s", f.Path, bp.Title, err)
 	}
 	ParentIndex[parent] = content.ID
+	f.CreatedPages = append(f.CreatedPages, content.ID)
 	return content.ID, nil
 }
 
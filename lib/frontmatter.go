@@ -0,0 +1,129 @@
+package lib
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// FrontMatter holds the top level scalar and list values of a YAML (---) or
+// TOML (+++) front matter block. Nested maps and tables are ignored.
+type FrontMatter map[string][]string
+
+// Get returns the first value of key
+func (fm FrontMatter) Get(key string) string {
+	if v := fm[strings.ToLower(key)]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// List returns all values of key
+func (fm FrontMatter) List(key string) []string {
+	return fm[strings.ToLower(key)]
+}
+
+// Int returns key as an integer, 0 when missing or invalid
+func (fm FrontMatter) Int(key string) int {
+	i, _ := strconv.Atoi(fm.Get(key))
+	return i
+}
+
+// Bool returns key as a boolean, false when missing or invalid
+func (fm FrontMatter) Bool(key string) bool {
+	b, _ := strconv.ParseBool(fm.Get(key))
+	return b
+}
+
+// ParseFrontMatter splits a leading front matter block from markdown. The
+// block is replaced with blank lines so source positions stay correct.
+func ParseFrontMatter(markdown []byte) (FrontMatter, []byte) {
+	fm := FrontMatter{}
+	var delimiter, separator string
+	switch {
+	case bytes.HasPrefix(markdown, []byte("---\n")), bytes.HasPrefix(markdown, []byte("---\r\n")):
+		delimiter, separator = "---", ":"
+	case bytes.HasPrefix(markdown, []byte("+++\n")), bytes.HasPrefix(markdown, []byte("+++\r\n")):
+		delimiter, separator = "+++", "="
+	default:
+		return fm, markdown
+	}
+
+	lines := strings.SplitAfter(string(markdown), "\n")
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == delimiter || (delimiter == "---" && line == "...") {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return fm, markdown
+	}
+
+	var listKey string
+	for _, line := range lines[1:end] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		// TOML tables end the top level keys
+		if separator == "=" && strings.HasPrefix(trimmed, "[") {
+			break
+		}
+		indented := line[0] == ' ' || line[0] == '\t'
+		if indented {
+			if listKey != "" && strings.HasPrefix(trimmed, "- ") {
+				fm[listKey] = append(fm[listKey], unquote(strings.TrimSpace(trimmed[2:])))
+			}
+			continue
+		}
+		// a YAML front matter must only contain keys at the top level
+		i := strings.Index(trimmed, separator)
+		if i <= 0 {
+			return FrontMatter{}, markdown
+		}
+		key := strings.ToLower(strings.TrimSpace(trimmed[:i]))
+		value := strings.TrimSpace(trimmed[i+1:])
+		listKey = ""
+		switch {
+		case value == "":
+			listKey = key
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			fm[key] = splitList(value[1 : len(value)-1])
+		default:
+			fm[key] = []string{unquote(value)}
+		}
+	}
+
+	blank := strings.Repeat("\n", end+1)
+	rest := strings.Join(lines[end+1:], "")
+	return fm, []byte(blank + rest)
+}
+
+func splitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = unquote(strings.TrimSpace(v)); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		if s[0] == '"' {
+			if u, err := strconv.Unquote(s); err == nil {
+				return u
+			}
+		}
+		return s[1 : len(s)-1]
+	}
+	// drop trailing comments from unquoted scalars
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
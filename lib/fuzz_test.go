@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzRender renders arbitrary markdown. Rendering may fail, but must not
+// panic, and what it renders must be valid storage format. Sources are
+// decoded to UTF-8 before they are rendered, so inputs are valid UTF-8.
+func FuzzRender(f *testing.F) {
+	for _, seed := range []string{
+		"# Title\n\nSome *text* with `code` and a [link](other.md).\n",
+		"- [ ] task\n- [x] done\n  - nested\n",
+		"> quote\n>\n> — Author\n",
+		"| a | b |\n| --- | --- |\n| 1 | 2 |\n",
+		"<table><tr><td>a<td>b</table>\n",
+		"::: columns\n::: column width=30%\na\n:::\n::: column\nb\n:::\n:::\n",
+		"<!-- m2c:collapse=\"x\" width=10 -->\n```go\n]]>\n```\n",
+		"    indented ]]>\n",
+		"![img](https://img.shields.io/badge/build-passing-green)\n",
+		"Term\n: definition\n\n---\n",
+		"``` \f",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, markdown string) {
+		if !utf8.ValidString(markdown) {
+			return
+		}
+		storage, _, err := renderContent("fuzz.md", markdown, false)
+		if err != nil {
+			return
+		}
+		if errs := ValidateStorageFormat(storage); len(errs) > 0 {
+			t.Errorf("invalid storage format: %s\n%q\n%s", errs[0], markdown, storage)
+		}
+	})
+}
+
+// FuzzMacro renders arbitrary CONFLUENCE-MACRO blocks in both syntaxes.
+// Macros may be rejected, but must not panic, and what they render must be
+// valid storage format.
+func FuzzMacro(f *testing.F) {
+	for _, seed := range []string{
+		"name: toc\n  minLevel: 2\n",
+		"name: info\n  title: Note\nrich-text-body: <p>text</p>\n",
+		"name: code\nplain-text-body: ]]>\n",
+		":\n  :\nname\n",
+		"name: expand\nparameters:\n  title: Details\nbody: <p>Hidden</p>\n",
+		"name: section\nbody:\n  - name: column\n    body: <p>a</p>\n",
+		"name: [\n",
+	} {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+	f.Fuzz(func(t *testing.T, body string, yaml bool) {
+		info := "CONFLUENCE-MACRO"
+		if yaml {
+			info += " yaml"
+		}
+		fence := "~~~~~~~~"
+		if !utf8.ValidString(body) || strings.Contains(body, fence) {
+			return
+		}
+		storage, _, err := renderContent("fuzz.md", fence+info+"\n"+body+"\n"+fence+"\n", false)
+		if err != nil {
+			return
+		}
+		if errs := ValidateStorageFormat(storage); len(errs) > 0 {
+			t.Errorf("invalid storage format: %s\n%q\n%s", errs[0], body, storage)
+		}
+	})
+}
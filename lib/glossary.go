@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// glossaryExcluded are the elements terms are never linked in: headings,
+// code, existing links and macro parameters or plain text bodies
+var glossaryExcluded = map[string]bool{
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"code": true, "pre": true, "a": true,
+	"ac:link": true, "ac:parameter": true, "ac:plain-text-body": true, "ac:inline-comment-marker": true,
+}
+
+// GlossaryTerm links a term to the page, and optionally the anchor on that
+// page, defining it
+type GlossaryTerm struct {
+	Term    string
+	Page    string
+	Anchor  string
+	pattern *regexp.Regexp
+}
+
+// LoadGlossary reads a JSON or YAML file mapping terms to "Page Title" or
+// "Page Title#anchor"
+func LoadGlossary(file string) ([]GlossaryTerm, error) {
+	var entries map[string]string
+	if err := decodeConfigFile(file, &entries); err != nil {
+		return nil, fmt.Errorf("Unable to read glossary %s: %s", file, err)
+	}
+
+	var terms []GlossaryTerm
+	for term, target := range entries {
+		term = strings.TrimSpace(term)
+		page, anchor, _ := strings.Cut(target, "#")
+		if term == "" || strings.TrimSpace(page) == "" {
+			return nil, fmt.Errorf("glossary %s: term %q needs a page", file, term)
+		}
+		terms = append(terms, GlossaryTerm{
+			Term:    term,
+			Page:    strings.TrimSpace(page),
+			Anchor:  strings.TrimSpace(anchor),
+			pattern: regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(html.EscapeString(term)) + `\b`),
+		})
+	}
+	// longer terms first, so "service level agreement" wins over "service"
+	sort.Slice(terms, func(i, j int) bool {
+		if len(terms[i].Term) != len(terms[j].Term) {
+			return len(terms[i].Term) > len(terms[j].Term)
+		}
+		return terms[i].Term < terms[j].Term
+	})
+	return terms, nil
+}
+
+// linkGlossaryTerms links the first occurrence of every term in body to its
+// definition. Terms defined on the page itself are left alone.
+func linkGlossaryTerms(body, title string, terms []GlossaryTerm) string {
+	for _, t := range terms {
+		if t.Page == title {
+			continue
+		}
+		for _, s := range storageTextSegments(body, glossaryExcluded) {
+			match := t.pattern.FindStringIndex(body[s.Start:s.End])
+			if match == nil {
+				continue
+			}
+			start, end := s.Start+match[0], s.Start+match[1]
+			body = body[:start] + glossaryLink(t, html.UnescapeString(body[start:end])) + body[end:]
+			break
+		}
+	}
+	return body
+}
+
+func glossaryLink(t GlossaryTerm, text string) string {
+	var b strings.Builder
+	b.WriteString(`<ac:link`)
+	if t.Anchor != "" {
+		b.WriteString(` ac:anchor="` + html.EscapeString(t.Anchor) + `"`)
+	}
+	b.WriteString(`><ri:page ri:content-title="` + html.EscapeString(t.Page) + `"/>`)
+	b.WriteString(`<ac:plain-text-link-body>` + renderer.CDATA(text) + `</ac:plain-text-link-body></ac:link>`)
+	return b.String()
+}
@@ -0,0 +1,286 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// harRedacted replaces credentials in recorded headers
+const harRedacted = "REDACTED"
+
+// harSensitiveHeaders are never written to a recording
+var harSensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// harFile is the subset of the HAR 1.2 format needed to record and replay
+// API calls
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Headers     []harNVP     `json:"headers"`
+	QueryString []harNVP     `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int          `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Headers     []harNVP   `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int        `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harNVP struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harRecorder records every request made through it
+type harRecorder struct {
+	next    http.RoundTripper
+	version string
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// RecordHTTP records all HTTP requests of the process, with credentials
+// redacted, until the returned func writes them to path as a HAR file
+func RecordHTTP(path, version string) (stop func() error) {
+	recorder := &harRecorder{next: http.DefaultTransport, version: version}
+	http.DefaultTransport = recorder
+	return func() error {
+		http.DefaultTransport = recorder.next
+		return recorder.write(path)
+	}
+}
+
+func (r *harRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := harEntry{
+		StartedDateTime: time.Now().Format(time.RFC3339Nano),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Headers:     harHeaders(req.Header),
+			QueryString: []harNVP{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNVP{name, value})
+		}
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		entry.Request.BodySize = len(body)
+		entry.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: harText(body)}
+	}
+
+	started := time.Now()
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	entry.Time = float64(time.Since(started).Milliseconds())
+	entry.Response = harResponse{
+		Status:      res.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(res.Status, fmt.Sprint(res.StatusCode))),
+		HTTPVersion: res.Proto,
+		Headers:     harHeaders(res.Header),
+		Content:     harContent{Size: len(body), MimeType: res.Header.Get("Content-Type")},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if utf8.Valid(body) {
+		entry.Response.Content.Text = string(body)
+	} else {
+		entry.Response.Content.Text = base64.StdEncoding.EncodeToString(body)
+		entry.Response.Content.Encoding = "base64"
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+	return res, nil
+}
+
+func (r *harRecorder) write(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	har := harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "markdown2confluence", Version: r.version},
+		Entries: r.entries,
+	}}
+	if har.Log.Entries == nil {
+		har.Log.Entries = []harEntry{}
+	}
+	dat, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, dat, 0600); err != nil {
+		return fmt.Errorf("Unable to write recording %s: %s", path, err)
+	}
+	return nil
+}
+
+// harHeaders converts headers, redacting credentials
+func harHeaders(header http.Header) []harNVP {
+	headers := []harNVP{}
+	for name, values := range header {
+		for _, value := range values {
+			if harSensitiveHeaders[http.CanonicalHeaderKey(name)] {
+				value = harRedacted
+			}
+			headers = append(headers, harNVP{name, value})
+		}
+	}
+	return headers
+}
+
+// harText returns a request body as text. Binary uploads such as attachments
+// are replaced by their size to keep recordings small and shareable.
+func harText(body []byte) string {
+	if utf8.Valid(body) {
+		return string(body)
+	}
+	return fmt.Sprintf("[%d bytes of binary data]", len(body))
+}
+
+// harReplayer answers requests with the responses of a recording
+type harReplayer struct {
+	mu      sync.Mutex
+	entries []harEntry
+	used    []bool
+}
+
+// ReplayHTTP answers all HTTP requests of the process from a HAR recording
+// instead of the network. Requests are matched on method and URL in recorded
+// order; a request without a recorded response fails.
+func ReplayHTTP(path string) error {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Unable to read recording %s: %s", path, err)
+	}
+	var har harFile
+	if err := json.Unmarshal(dat, &har); err != nil {
+		return fmt.Errorf("Unable to parse recording %s: %s", path, err)
+	}
+	http.DefaultTransport = &harReplayer{entries: har.Log.Entries, used: make([]bool, len(har.Log.Entries))}
+	return nil
+}
+
+func (r *harReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the first unused match, or the last match again when all were used
+	match := -1
+	for i, e := range r.entries {
+		if e.Request.Method != req.Method || e.Request.URL != req.URL.String() {
+			continue
+		}
+		match = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL)
+	}
+	r.used[match] = true
+
+	recorded := r.entries[match].Response
+	body := []byte(recorded.Content.Text)
+	if recorded.Content.Encoding == "base64" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(recorded.Content.Text); err != nil {
+			return nil, fmt.Errorf("invalid recorded response for %s %s: %s", req.Method, req.URL, err)
+		}
+	}
+	header := http.Header{}
+	for _, h := range recorded.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, recorded.StatusText),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// PreRenderHook runs before a markdown file is rendered. It receives the
+// source path and markdown and returns the markdown to render.
+type PreRenderHook func(path string, markdown []byte) ([]byte, error)
+
+// PostPublishHook runs after a page has been published
+type PostPublishHook func(path, pageID, url string) error
+
+var (
+	preRenderHooks   []PreRenderHook
+	postPublishHooks []PostPublishHook
+)
+
+// RegisterPreRenderHook adds a Go function that is called before every file is rendered
+func RegisterPreRenderHook(h PreRenderHook) {
+	preRenderHooks = append(preRenderHooks, h)
+}
+
+// RegisterPostPublishHook adds a Go function that is called after every page is published
+func RegisterPostPublishHook(h PostPublishHook) {
+	postPublishHooks = append(postPublishHooks, h)
+}
+
+// runPreRenderHooks passes markdown through the registered hooks and the
+// --pre-render-hook command
+func (m *Markdown2Confluence) runPreRenderHooks(path string, markdown []byte) ([]byte, error) {
+	var err error
+	for _, h := range preRenderHooks {
+		markdown, err = h(path, markdown)
+		if err != nil {
+			return nil, fmt.Errorf("pre-render hook failed for %s: %s", path, err)
+		}
+	}
+
+	if m.PreRenderHook != "" {
+		cmd := hookCommand(m.PreRenderHook)
+		cmd.Stdin = bytes.NewReader(markdown)
+		cmd.Env = append(os.Environ(), "M2C_SOURCE_PATH="+path)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		markdown, err = cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("pre-render hook failed for %s: %s\n%s", path, err, stderr.String())
+		}
+	}
+	return markdown, nil
+}
+
+// runPostPublishHooks calls the registered hooks and the --post-publish-hook command
+func (m *Markdown2Confluence) runPostPublishHooks(f *MarkdownFile, pageID, url string) error {
+	for _, h := range postPublishHooks {
+		if err := h(f.Path, pageID, url); err != nil {
+			return fmt.Errorf("post-publish hook failed for %s: %s", f.Path, err)
+		}
+	}
+
+	if m.PostPublishHook != "" {
+		cmd := hookCommand(m.PostPublishHook)
+		cmd.Env = append(os.Environ(),
+			"M2C_SOURCE_PATH="+f.Path,
+			"M2C_PAGE_TITLE="+f.Title,
+			"M2C_PAGE_ID="+pageID,
+			"M2C_PAGE_URL="+url,
+		)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("post-publish hook failed for %s: %s\n%s", f.Path, err, output)
+		}
+	}
+	return nil
+}
+
+func hookCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
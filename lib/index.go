@@ -0,0 +1,159 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// indexSummaryKeys are the front matter keys shown next to a page in the index
+var indexSummaryKeys = []string{"summary", "description", "excerpt"}
+
+// indexNode is a page of the index tree. Folders have no Path.
+type indexNode struct {
+	Title    string
+	Path     string
+	Summary  string
+	Children []*indexNode
+}
+
+// PublishIndex creates or refreshes the m.IndexPage page below the parent
+// page with a nested list of links to every published file, each followed
+// by the summary of its front matter
+func (m *Markdown2Confluence) PublishIndex(files []MarkdownFile) error {
+	published := map[string]bool{}
+	for _, p := range m.Report.Pages {
+		if p.Action == ActionCreated || p.Action == ActionUpdated || p.Action == ActionUnchanged {
+			published[p.Path] = true
+		}
+	}
+
+	// the index is placed below --parent, so its titles are left out
+	root := &indexNode{}
+	skip := len(deleteEmpty(strings.Split(m.Parent, "/")))
+	for _, f := range files {
+		if !published[f.Path] {
+			continue
+		}
+		parents := f.Parents
+		if len(parents) >= skip {
+			parents = parents[skip:]
+		}
+		node := root
+		for _, title := range parents {
+			node = node.child(title)
+		}
+		page := node.child(f.Title)
+		page.Path = f.Path
+		page.Summary = fileSummary(f)
+	}
+	if len(root.Children) == 0 {
+		return nil
+	}
+
+	var body strings.Builder
+	writeIndexList(&body, root.Children)
+
+	ancestorID, err := m.resolveParentID()
+	if err != nil {
+		return err
+	}
+	page, err := m.upsertStoragePage(m.IndexPage, ancestorID, body.String())
+	if err != nil {
+		return fmt.Errorf("Unable to publish index page %s: %s", m.IndexPage, err)
+	}
+	fmt.Printf("%s: %s\n", m.IndexPage, m.client.Endpoint+page.Links.Tinyui)
+	return nil
+}
+
+func (n *indexNode) child(title string) *indexNode {
+	for _, c := range n.Children {
+		if c.Title == title {
+			return c
+		}
+	}
+	c := &indexNode{Title: title}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// fileSummary returns the summary front matter of a file
+func fileSummary(f MarkdownFile) string {
+	dat := f.Content
+	if dat == nil {
+		var err error
+		if dat, err = readMarkdown(f.Path); err != nil {
+			return ""
+		}
+	}
+	fm, _ := ParseFrontMatter(dat)
+	for _, key := range indexSummaryKeys {
+		if summary := fm.Get(key); summary != "" {
+			return summary
+		}
+	}
+	return ""
+}
+
+func writeIndexList(b *strings.Builder, nodes []*indexNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return strings.ToLower(nodes[i].Title) < strings.ToLower(nodes[j].Title)
+	})
+	b.WriteString("<ul>")
+	for _, n := range nodes {
+		b.WriteString(`<li><ac:link><ri:page ri:content-title="` + html.EscapeString(n.Title) + `"/></ac:link>`)
+		if n.Summary != "" {
+			b.WriteString(" – " + html.EscapeString(n.Summary))
+		}
+		if len(n.Children) > 0 {
+			writeIndexList(b, n.Children)
+		}
+		b.WriteString("</li>")
+	}
+	b.WriteString("</ul>")
+}
+
+// upsertStoragePage creates a page with a storage format body below
+// ancestorID, recorded in m.Report for undo, or updates it when its body
+// changed
+func (m *Markdown2Confluence) upsertStoragePage(title, ancestorID, body string) (*confluence.Page, error) {
+	page, err := m.client.GetPageByTitle(m.Space, title, "version", "body.storage")
+	if errors.Is(err, confluence.ErrPageNotFound) {
+		page = &confluence.Page{
+			Title: title,
+			Space: confluence.PageSpace{Key: m.Space},
+			Body:  confluence.PageBody{Storage: confluence.PageStorage{Value: body}},
+		}
+		if ancestorID != "" {
+			page.Ancestors = []confluence.PageAncestor{{ID: ancestorID}}
+		}
+		page, err = m.client.CreatePage(page)
+		if err != nil {
+			return nil, err
+		}
+		m.Report.AddCreatedPages(page.ID)
+		return page, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if page.Body.Storage.Value == body {
+		return page, nil
+	}
+
+	if m.SafeMode.Enabled() {
+		if err := m.guard(guardUpdate, "", []string{page.ID}); err != nil {
+			return nil, err
+		}
+	}
+	page.Body.Storage = confluence.PageStorage{Value: body}
+	page.Version = confluence.PageVersion{Number: page.Version.Number + 1, Message: m.Comment}
+	if ancestorID != "" {
+		page.Ancestors = []confluence.PageAncestor{{ID: ancestorID}}
+	}
+	return m.client.UpdatePage(page)
+}
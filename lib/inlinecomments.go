@@ -0,0 +1,150 @@
+package lib
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+var (
+	inlineCommentMarker = regexp.MustCompile(`(?s)<ac:inline-comment-marker\s+ac:ref="([^"]+)"\s*>(.*?)</ac:inline-comment-marker>`)
+	storageTag          = regexp.MustCompile(`<[^>]*>`)
+)
+
+// inlineComment is the anchor of an inline comment in a page body
+type inlineComment struct {
+	Ref  string
+	Text string
+	// Position is the relative position of the marker in the page text,
+	// used to pick between several matches
+	Position float64
+}
+
+// textSegment is a run of text between tags that comments can be anchored in
+type textSegment struct {
+	Start, End int
+}
+
+// preserveInlineComments re-anchors the inline comment markers of the
+// published body in the new body. The marked text is searched for exactly,
+// then ignoring case and whitespace, and the occurrence closest to the old
+// position wins. It returns the new body and the comments that were lost.
+func preserveInlineComments(published, body string) (string, []inlineComment) {
+	var lost []inlineComment
+	for _, c := range findInlineComments(published) {
+		var placed bool
+		body, placed = anchorInlineComment(body, c)
+		if !placed {
+			lost = append(lost, c)
+		}
+	}
+	return body, lost
+}
+
+func findInlineComments(body string) []inlineComment {
+	var comments []inlineComment
+	length := float64(len(body))
+	for _, m := range inlineCommentMarker.FindAllStringSubmatchIndex(body, -1) {
+		text := storageTag.ReplaceAllString(body[m[4]:m[5]], "")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		comments = append(comments, inlineComment{
+			Ref:      body[m[2]:m[3]],
+			Text:     text,
+			Position: float64(m[0]) / length,
+		})
+	}
+	return comments
+}
+
+func anchorInlineComment(body string, c inlineComment) (string, bool) {
+	words := strings.Fields(c.Text)
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	patterns := []*regexp.Regexp{
+		regexp.MustCompile(regexp.QuoteMeta(c.Text)),
+		regexp.MustCompile(`(?i)` + strings.Join(words, `\s+`)),
+	}
+
+	segments := commentableSegments(body)
+	for _, pattern := range patterns {
+		best, bestDistance := []int(nil), math.MaxFloat64
+		for _, s := range segments {
+			for _, m := range pattern.FindAllStringIndex(body[s.Start:s.End], -1) {
+				distance := math.Abs(float64(s.Start+m[0])/float64(len(body)) - c.Position)
+				if distance < bestDistance {
+					best, bestDistance = []int{s.Start + m[0], s.Start + m[1]}, distance
+				}
+			}
+		}
+		if best != nil {
+			marker := fmt.Sprintf(`<ac:inline-comment-marker ac:ref="%s">`, c.Ref)
+			return body[:best[0]] + marker + body[best[0]:best[1]] + `</ac:inline-comment-marker>` + body[best[1]:], true
+		}
+	}
+	return body, false
+}
+
+// commentableExcluded are the elements comments can not be anchored in
+var commentableExcluded = map[string]bool{"ac:parameter": true, "ac:plain-text-body": true, "ac:inline-comment-marker": true}
+
+// commentableSegments returns the text between tags, leaving out CDATA,
+// macro parameters and plain text bodies, and text already marked
+func commentableSegments(body string) []textSegment {
+	return storageTextSegments(body, commentableExcluded)
+}
+
+// storageTextSegments returns the text between the tags of a storage format
+// body, leaving out CDATA and the content of excluded elements
+func storageTextSegments(body string, excluded map[string]bool) []textSegment {
+	var segments []textSegment
+	skip := 0
+	pos := 0
+	for pos < len(body) {
+		lt := strings.IndexByte(body[pos:], '<')
+		if lt < 0 {
+			lt = len(body) - pos
+		}
+		if lt > 0 && skip == 0 {
+			segments = append(segments, textSegment{pos, pos + lt})
+		}
+		pos += lt
+		if pos >= len(body) {
+			break
+		}
+
+		if strings.HasPrefix(body[pos:], "<![CDATA[") {
+			end := strings.Index(body[pos:], "]]>")
+			if end < 0 {
+				break
+			}
+			pos += end + len("]]>")
+			continue
+		}
+		gt := strings.IndexByte(body[pos:], '>')
+		if gt < 0 {
+			break
+		}
+		tag := body[pos : pos+gt+1]
+		pos += gt + 1
+		if strings.HasSuffix(tag, "/>") {
+			continue
+		}
+		name := strings.TrimPrefix(tag[1:len(tag)-1], "/")
+		if i := strings.IndexAny(name, " \t\n"); i >= 0 {
+			name = name[:i]
+		}
+		if !excluded[name] {
+			continue
+		}
+		if strings.HasPrefix(tag, "</") {
+			skip--
+		} else {
+			skip++
+		}
+	}
+	return segments
+}
@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ErrInterrupted is returned by runs stopped by SIGINT or SIGTERM
+var ErrInterrupted = errors.New("interrupted")
+
+// HandleInterrupts makes SIGINT and SIGTERM stop a run gracefully: no new
+// pages are started, uploads in flight are finished and the run returns
+// ErrInterrupted with the pages it did not get to. A second signal exits
+// immediately. The returned func restores the default behaviour.
+func (m *Markdown2Confluence) HandleInterrupts() (stop func()) {
+	interrupt := make(chan struct{})
+	m.interrupt = interrupt
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		fmt.Fprintln(os.Stderr, "\nInterrupted, finishing the uploads in progress. Interrupt again to exit immediately.")
+		close(interrupt)
+		select {
+		case <-signals:
+			fmt.Fprintln(os.Stderr, "Exiting, pages being uploaded may be incomplete")
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// Interrupted reports whether the run was asked to stop
+func (m *Markdown2Confluence) Interrupted() bool {
+	if m.interrupt == nil {
+		return false
+	}
+	select {
+	case <-m.interrupt:
+		return true
+	default:
+		return false
+	}
+}
+
+// WasInterrupted reports whether any of the errors of a run is ErrInterrupted
+func WasInterrupted(errs []error) bool {
+	for _, err := range errs {
+		if errors.Is(err, ErrInterrupted) {
+			return true
+		}
+	}
+	return false
+}
+
+// interruptedError summarizes an interrupted run, listing the files that
+// were not published so the run can be resumed
+func interruptedError(pending []MarkdownFile) error {
+	if len(pending) == 0 {
+		return ErrInterrupted
+	}
+	msg := fmt.Sprintf("%d files were not published:", len(pending))
+	for _, f := range pending {
+		msg += "\n\t" + f.Path
+	}
+	msg += "\nRun again to publish them, with --content-hash to skip the pages already up to date"
+	return fmt.Errorf("%w: %s", ErrInterrupted, msg)
+}
@@ -0,0 +1,77 @@
+package lib
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// LinkBaseAuto as --link-base-url links to the origin remote of the git
+// repository at the checked out branch, or commit when HEAD is detached
+const LinkBaseAuto = "auto"
+
+// rewriteLink is the renderer.RewriteLink of --link-base-url. Links to files
+// and folders of the repository other than markdown files, which become
+// pages, are rewritten to m.LinkBaseURL followed by their path from the
+// root of the repository, keeping the query and fragment, such as a line
+// anchor. Outside of a git repository paths are taken from the working
+// directory.
+func (m *Markdown2Confluence) rewriteLink(filePath, destination string) (string, bool) {
+	if !renderer.IsLocalLink(destination) {
+		return "", false
+	}
+	u, err := url.Parse(destination)
+	if err != nil || u.Path == "" {
+		return "", false
+	}
+	switch strings.ToLower(filepath.Ext(u.Path)) {
+	case ".md", ".markdown":
+		return "", false
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return "", false
+	}
+	target := filepath.FromSlash(u.Path)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(dir, target)
+	}
+	if _, err := os.Stat(target); err != nil {
+		// a dead link, left to --check-links
+		return "", false
+	}
+
+	var link string
+	if m.LinkBaseURL == LinkBaseAuto {
+		branch, commit := gitHead(dir)
+		if _, link = sourceURL(target, branch, commit); link == "" {
+			return "", false
+		}
+	} else {
+		root, _ := gitRepo(dir)
+		if root == "" {
+			if root, err = os.Getwd(); err != nil {
+				return "", false
+			}
+		}
+		if resolved, err := filepath.EvalSymlinks(target); err == nil {
+			target = resolved
+		}
+		rel, err := filepath.Rel(root, target)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return "", false
+		}
+		link = strings.TrimSuffix(m.LinkBaseURL, "/") + "/" + (&url.URL{Path: filepath.ToSlash(rel)}).EscapedPath()
+	}
+	if u.RawQuery != "" {
+		link += "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		link += "#" + u.EscapedFragment()
+	}
+	return link, true
+}
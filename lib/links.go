@@ -0,0 +1,305 @@
+package lib
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/justmiles/go-confluence"
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// Broken link kinds reported by CheckLinks
+const (
+	LinkDead              = "dead link"
+	LinkMissingImage      = "missing image"
+	LinkMissingAttachment = "missing attachment"
+	LinkUnresolvedPage    = "unresolved page"
+)
+
+// LinkProblem is a broken link found by CheckLinks
+type LinkProblem struct {
+	Position renderer.Position
+	Kind     string
+	Target   string
+}
+
+func (p LinkProblem) String() string {
+	return fmt.Sprintf("%s: %s %s", p.Position, p.Kind, p.Target)
+}
+
+// storageReference is a page or attachment referenced by a storage body
+type storageReference struct {
+	Title string
+	Space string
+	// Filename is set for attachments, Title then names the page they are
+	// attached to when it is not the referencing page
+	Filename string
+}
+
+// linkChecker resolves page titles and attachments once per run
+type linkChecker struct {
+	m           *Markdown2Confluence
+	titles      map[string]bool
+	pages       map[string]*confluence.Page
+	attachments map[string]map[string]bool
+}
+
+// CheckLinks reports relative links to missing files, attachments that are
+// referenced but not uploaded and links to pages that are neither published
+// by the run nor exist in --space. With live, the bodies of the published
+// pages are checked instead of the rendered files.
+func (m *Markdown2Confluence) CheckLinks(live bool) ([]LinkProblem, []error) {
+	m.CreateClient()
+	defer renderer.UseAttachmentDir()()
+	files, err := m.collectMarkdownFiles()
+	if err != nil {
+		return nil, []error{err}
+	}
+	if m.Obsidian {
+		if err := m.IndexVault(files); err != nil {
+			return nil, []error{fmt.Errorf("Unable to index Obsidian vault: %s", err)}
+		}
+	}
+
+	c := &linkChecker{
+		m:           m,
+		titles:      map[string]bool{},
+		pages:       map[string]*confluence.Page{},
+		attachments: map[string]map[string]bool{},
+	}
+	for _, f := range files {
+		c.titles[f.Title] = true
+		for _, parent := range f.Parents {
+			c.titles[parent] = true
+		}
+	}
+	if m.AssetsPage != "" {
+		c.titles[m.AssetsPage] = true
+	}
+
+	var problems []LinkProblem
+	var errs []error
+	for i := range files {
+		f := &files[i]
+		source := f.Content
+		if source == nil {
+			if source, err = readMarkdown(f.Path); err != nil {
+				errs = append(errs, fmt.Errorf("Could not open file %s:\n\t%s", f.Path, err))
+				continue
+			}
+		}
+		_, source = ParseFrontMatter(source)
+		if renderer.MDX {
+			source = preprocessMDX(source)
+		}
+		links := renderer.FindLinks(f.Path, source)
+		problems = append(problems, c.checkLocalLinks(f.Path, links)...)
+
+		var body string
+		attached := map[string]bool{}
+		if live {
+			page, err := c.page(m.Space, f.Title)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if page == nil {
+				if m.Debug {
+					fmt.Printf("skipping %s: page %s is not published\n", f.Path, f.Title)
+				}
+				continue
+			}
+			body = page.Body.Storage.Value
+			if attached, err = c.pageAttachments(page); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		} else {
+			var images []string
+			body, images, err = f.Render(m)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, image := range images {
+				attached[renderer.AttachmentName(image)] = true
+			}
+		}
+
+		refs, err := storageReferences(body)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to read the storage format of %s: %s", f.Path, err))
+			continue
+		}
+		for _, ref := range refs {
+			problem, err := c.checkReference(f, ref, attached, links)
+			if err != nil {
+				errs = append(errs, err)
+			} else if problem != nil {
+				problems = append(problems, *problem)
+			}
+		}
+	}
+	return problems, errs
+}
+
+// checkLocalLinks reports relative links and images to missing files
+func (c *linkChecker) checkLocalLinks(path string, links []renderer.Link) []LinkProblem {
+	var problems []LinkProblem
+	for _, l := range links {
+		if l.WikiLink || !renderer.IsLocalLink(l.Destination) || renderer.LocalLinkExists(path, l.Destination) {
+			continue
+		}
+		kind := LinkDead
+		if l.Image {
+			kind = LinkMissingImage
+		}
+		problems = append(problems, LinkProblem{Position: l.Position, Kind: kind, Target: l.Destination})
+	}
+	return problems
+}
+
+// checkReference resolves a page or attachment reference of f's body. links
+// locate wikilinks to the referenced page in the source.
+func (c *linkChecker) checkReference(f *MarkdownFile, ref storageReference, attached map[string]bool, links []renderer.Link) (*LinkProblem, error) {
+	position := renderer.Position{File: f.Path}
+	for _, l := range links {
+		if l.Title != "" && l.Title == ref.Title {
+			position = l.Position
+			break
+		}
+	}
+
+	if ref.Filename == "" {
+		ok, err := c.pageExists(ref.Space, ref.Title)
+		if err != nil || ok {
+			return nil, err
+		}
+		return &LinkProblem{Position: position, Kind: LinkUnresolvedPage, Target: ref.Title}, nil
+	}
+
+	if ref.Title != "" && ref.Title != f.Title {
+		page, err := c.page(ref.Space, ref.Title)
+		if err != nil {
+			return nil, err
+		}
+		if page == nil {
+			// pages published by this run can not be checked before they exist
+			if c.titles[ref.Title] {
+				return nil, nil
+			}
+			return &LinkProblem{Position: position, Kind: LinkUnresolvedPage, Target: ref.Title}, nil
+		}
+		if attached, err = c.pageAttachments(page); err != nil {
+			return nil, err
+		}
+	}
+	if attached[ref.Filename] {
+		return nil, nil
+	}
+	return &LinkProblem{Position: position, Kind: LinkMissingAttachment, Target: ref.Filename}, nil
+}
+
+// pageExists reports whether a title is published by this run or exists in
+// Confluence. Without --space only titles of the run are known.
+func (c *linkChecker) pageExists(space, title string) (bool, error) {
+	if (space == "" || space == c.m.Space) && c.titles[title] {
+		return true, nil
+	}
+	page, err := c.page(space, title)
+	return page != nil, err
+}
+
+// page fetches a page by title, returning nil when it does not exist or
+// no space is known
+func (c *linkChecker) page(space, title string) (*confluence.Page, error) {
+	if space == "" {
+		space = c.m.Space
+	}
+	if space == "" {
+		return nil, nil
+	}
+	key := space + "/" + title
+	if page, ok := c.pages[key]; ok {
+		return page, nil
+	}
+	page, err := c.m.client.GetPageByTitle(space, title, "body.storage")
+	if errors.Is(err, confluence.ErrPageNotFound) {
+		page, err = nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching page %s: %s", title, err)
+	}
+	c.pages[key] = page
+	return page, nil
+}
+
+func (c *linkChecker) pageAttachments(page *confluence.Page) (map[string]bool, error) {
+	if names, ok := c.attachments[page.ID]; ok {
+		return names, nil
+	}
+	attachments, err := c.m.client.GetAttachmentsFiltered(page.ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching attachments of page %s: %s", page.Title, err)
+	}
+	names := map[string]bool{}
+	for _, a := range attachments {
+		names[a.Title] = true
+	}
+	c.attachments[page.ID] = names
+	return names, nil
+}
+
+// storageReferences lists the pages and attachments a storage body refers to
+func storageReferences(body string) ([]storageReference, error) {
+	decoder := xml.NewDecoder(strings.NewReader(storageRootStart + body + storageRootEnd))
+	decoder.Entity = xml.HTMLEntity
+	decoder.Strict = false
+
+	var refs []storageReference
+	var attachment *storageReference
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return refs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Space != riNamespace {
+				continue
+			}
+			switch t.Name.Local {
+			case "attachment":
+				attachment = &storageReference{Filename: storageAttribute(t, "filename")}
+			case "page":
+				if attachment != nil {
+					attachment.Title = storageAttribute(t, "content-title")
+					attachment.Space = storageAttribute(t, "space-key")
+				} else if title := storageAttribute(t, "content-title"); title != "" {
+					refs = append(refs, storageReference{Title: title, Space: storageAttribute(t, "space-key")})
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Space == riNamespace && t.Name.Local == "attachment" && attachment != nil {
+				refs = append(refs, *attachment)
+				attachment = nil
+			}
+		}
+	}
+}
+
+func storageAttribute(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Space == riNamespace && a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
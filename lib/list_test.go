@@ -0,0 +1,24 @@
+package lib
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+	"github.com/justmiles/go-markdown2confluence/lib/renderer/renderertest"
+)
+
+// TestListGolden renders the lists of testdata/lists/*.md and compares them
+// with the .storage.xml next to them. Fixtures named tight-* are rendered
+// with ListSpacingTight.
+func TestListGolden(t *testing.T) {
+	renderertest.Run(t, "testdata/lists/*.md", func(file string, source []byte) (string, error) {
+		if strings.HasPrefix(filepath.Base(file), "tight-") {
+			renderer.ListSpacing = renderer.ListSpacingTight
+			defer func() { renderer.ListSpacing = renderer.ListSpacingKeep }()
+		}
+		got, _, err := renderContent(file, string(source), false)
+		return got, err
+	})
+}
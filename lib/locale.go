@@ -0,0 +1,201 @@
+package lib
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// localeSuffix matches file names with a locale, like guide.de or guide.pt-BR
+var localeSuffix = regexp.MustCompile(`^(.+)\.([a-z]{2,3}(?:[-_][A-Za-z]{2,4})?)$`)
+
+// languageNames name the parent page of a locale and its links
+var languageNames = map[string]string{
+	"ar": "العربية",
+	"cs": "Čeština",
+	"da": "Dansk",
+	"de": "Deutsch",
+	"en": "English",
+	"es": "Español",
+	"fi": "Suomi",
+	"fr": "Français",
+	"he": "עברית",
+	"hu": "Magyar",
+	"it": "Italiano",
+	"ja": "日本語",
+	"ko": "한국어",
+	"nl": "Nederlands",
+	"no": "Norsk",
+	"pl": "Polski",
+	"pt": "Português",
+	"ru": "Русский",
+	"sv": "Svenska",
+	"tr": "Türkçe",
+	"uk": "Українська",
+	"zh": "中文",
+}
+
+// Translation is another language version of a page
+type Translation struct {
+	Locale string
+	Title  string
+	Space  string
+}
+
+// languageName returns the name of a locale in its own language, or the
+// locale itself
+func languageName(locale string) string {
+	if name, ok := languageNames[strings.ToLower(locale)]; ok {
+		return name
+	}
+	base, _, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	if name, ok := languageNames[strings.ToLower(base)]; ok {
+		return name + " (" + locale + ")"
+	}
+	return locale
+}
+
+// localeTargets parses m.Locales, "de" or "de=SPACE", into the space each
+// locale publishes to, empty for m.Space
+func (m *Markdown2Confluence) localeTargets() (map[string]string, error) {
+	targets := map[string]string{}
+	for _, l := range m.Locales {
+		locale, space, _ := strings.Cut(strings.TrimSpace(l), "=")
+		if !localeSuffix.MatchString("x." + locale) {
+			return nil, fmt.Errorf("invalid locale %q, expected a code like de or pt-BR", locale)
+		}
+		if locale == m.DefaultLocale {
+			return nil, fmt.Errorf("locale %s is the default locale", locale)
+		}
+		targets[locale] = strings.TrimSpace(space)
+	}
+	return targets, nil
+}
+
+// applyLocales routes files named like guide.de.md to the parent page or
+// space of their locale, and lists the language versions of every file in
+// its Translations
+func (m *Markdown2Confluence) applyLocales(files []MarkdownFile) error {
+	if len(m.Locales) == 0 {
+		return nil
+	}
+	targets, err := m.localeTargets()
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	groups := map[string][]int{}
+	for i := range files {
+		f := &files[i]
+		if f.Path == StdinPath {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(f.Path), filepath.Ext(f.Path))
+		f.Locale = m.DefaultLocale
+		if match := localeSuffix.FindStringSubmatch(name); match != nil {
+			if space, ok := targets[match[2]]; ok {
+				f.Locale = match[2]
+				m.routeLocale(f, name, match[1], space)
+				name = match[1]
+			}
+		}
+
+		key := filepath.Join(filepath.Dir(f.Path), name)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		for _, i := range group {
+			for _, j := range group {
+				if i == j {
+					continue
+				}
+				space := files[j].Space
+				if space == "" {
+					space = m.Space
+				}
+				files[i].Translations = append(files[i].Translations, Translation{
+					Locale: files[j].Locale,
+					Title:  files[j].Title,
+					Space:  space,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// routeLocale places a translated file. In the space of its locale it
+// mirrors the tree of the default locale. Otherwise it goes below a page
+// named after the language, with the locale appended to its title and
+// folders, as titles are unique within a space.
+func (m *Markdown2Confluence) routeLocale(f *MarkdownFile, name, base, space string) {
+	switch {
+	case strings.EqualFold(base, "README") && len(f.Parents) > 0 && f.Title == name &&
+		f.Parents[len(f.Parents)-1] == filepath.Base(filepath.Dir(f.Path)):
+		// README.de.md stands for its folder like README.md does
+		f.Title = f.Parents[len(f.Parents)-1]
+		f.Parents = f.Parents[:len(f.Parents)-1]
+	case f.Title == name:
+		f.Title = base
+	}
+
+	if space != "" {
+		f.Space = space
+		return
+	}
+
+	// the pages of --parent are shared by all locales
+	shared := 0
+	if id, _ := strconv.Atoi(m.Parent); id == 0 {
+		shared = len(deleteEmpty(strings.Split(m.Parent, "/")))
+	}
+	if shared > len(f.Parents) {
+		shared = len(f.Parents)
+	}
+	parents := append([]string{}, f.Parents[:shared]...)
+	parents = append(parents, languageName(f.Locale))
+	for _, p := range f.Parents[shared:] {
+		parents = append(parents, localizedTitle(p, f.Locale))
+	}
+	f.Parents = parents
+	f.Title = localizedTitle(f.Title, f.Locale)
+}
+
+// localizedTitle is the title of a translated page sharing a space with the
+// default locale, e.g. "Guide (de)"
+func localizedTitle(title, locale string) string {
+	return fmt.Sprintf("%s (%s)", title, locale)
+}
+
+// translationLinks renders the links to the other language versions of a
+// page published to space
+func translationLinks(f *MarkdownFile, space string) string {
+	if len(f.Translations) == 0 {
+		return ""
+	}
+	var links []string
+	for _, t := range f.Translations {
+		link := `<ac:link><ri:page`
+		if t.Space != "" && t.Space != space {
+			link += ` ri:space-key="` + html.EscapeString(t.Space) + `"`
+		}
+		link += ` ri:content-title="` + html.EscapeString(t.Title) + `"/>`
+		link += `<ac:plain-text-link-body>` + renderer.CDATA(languageName(t.Locale)) + `</ac:plain-text-link-body></ac:link>`
+		links = append(links, link)
+	}
+	return `<p><em>This page in other languages: ` + strings.Join(links, " · ") + `</em></p>`
+}
@@ -0,0 +1,213 @@
+package lib
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// lockProperty is the content property of the root page holding the lock
+const lockProperty = "m2c-lock"
+
+// lockPoll is how often a run waiting for the lock checks it again
+var lockPoll = 15 * time.Second
+
+// syncLock is the value of the lock property
+type syncLock struct {
+	Owner   string `json:"owner"`
+	Token   string `json:"token"`
+	Expires string `json:"expires"`
+}
+
+func (l syncLock) expired(now time.Time) bool {
+	expires, err := time.Parse(time.RFC3339, l.Expires)
+	return err != nil || now.After(expires)
+}
+
+// heldLock is a lock acquired by this run, renewed until it is released
+type heldLock struct {
+	m      *Markdown2Confluence
+	pageID string
+	lock   syncLock
+	ttl    time.Duration
+
+	version int
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// AcquireLock takes the advisory lock of the tree a run publishes to, a
+// content property of its root page, so concurrent syncs do not interleave
+// their updates. A lock held by another run is waited for up to
+// m.LockWait minutes, an expired one is taken over. The lock lasts
+// m.LockTTL minutes and is renewed until release is called.
+func (m *Markdown2Confluence) AcquireLock() (release func(), err error) {
+	pageID, err := m.lockPageID()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to lock the sync: %s", err)
+	}
+
+	l := &heldLock{
+		m:      m,
+		pageID: pageID,
+		lock:   syncLock{Owner: lockOwner(m.Username), Token: lockToken()},
+		ttl:    time.Duration(m.LockTTL) * time.Minute,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	deadline := time.Now().Add(time.Duration(m.LockWait) * time.Minute)
+	for {
+		holder, err := l.tryAcquire()
+		if errors.Is(err, confluence.ErrPropertyConflict) {
+			// another run was faster, wait for its lock
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Unable to lock the sync on page %s: %s", pageID, err)
+		}
+		if holder == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Unable to lock the sync on page %s: locked by %s until %s", pageID, holder.Owner, holder.Expires)
+		}
+		if m.Interrupted() {
+			return nil, ErrInterrupted
+		}
+		fmt.Printf("waiting for the lock on page %s held by %s until %s\n", pageID, holder.Owner, holder.Expires)
+		time.Sleep(lockPoll)
+	}
+	if m.Debug {
+		fmt.Printf("locked the sync on page %s until %s\n", pageID, l.lock.Expires)
+	}
+
+	go l.renew()
+	return l.release, nil
+}
+
+// tryAcquire creates or takes over the lock property. It returns the lock of
+// the other run holding it, nil when this run holds it now, and
+// ErrPropertyConflict when another run changed the lock in the meantime.
+func (l *heldLock) tryAcquire() (*syncLock, error) {
+	client := l.m.client
+	l.lock.Expires = time.Now().Add(l.ttl).UTC().Format(time.RFC3339)
+
+	property, err := client.GetContentProperty(l.pageID, lockProperty)
+	switch {
+	case errors.Is(err, confluence.ErrPropertyNotFound):
+		err = client.CreateContentProperty(l.pageID, lockProperty, l.lock)
+		l.version = 1
+	case err != nil:
+		return nil, err
+	default:
+		var current syncLock
+		if err := json.Unmarshal(property.Value, &current); err == nil && !current.expired(time.Now()) {
+			return &current, nil
+		}
+		err = client.UpdateContentProperty(l.pageID, lockProperty, l.lock, property.Version.Number+1)
+		l.version = property.Version.Number + 1
+	}
+	return nil, err
+}
+
+// renew extends the lock every third of its time to live
+func (l *heldLock) renew() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+		l.lock.Expires = time.Now().Add(l.ttl).UTC().Format(time.RFC3339)
+		err := l.m.client.UpdateContentProperty(l.pageID, lockProperty, l.lock, l.version+1)
+		if err == nil {
+			l.version++
+		} else {
+			fmt.Printf("Warning: unable to renew the lock on page %s: %s\n", l.pageID, err)
+		}
+	}
+}
+
+// release stops renewing the lock and deletes it, unless another run took
+// it over
+func (l *heldLock) release() {
+	close(l.stop)
+	<-l.done
+	property, err := l.m.client.GetContentProperty(l.pageID, lockProperty)
+	if err != nil {
+		return
+	}
+	var current syncLock
+	if json.Unmarshal(property.Value, &current) != nil || current.Token != l.lock.Token {
+		return
+	}
+	if err := l.m.client.DeleteContentProperty(l.pageID, lockProperty); err != nil {
+		fmt.Printf("Warning: unable to release the lock on page %s: %s\n", l.pageID, err)
+	}
+}
+
+// lockPageID returns the root page of the tree to lock: --parent-id,
+// --parent as an id or the title of its top page, the --safe-root or else
+// the home page of the space
+func (m *Markdown2Confluence) lockPageID() (string, error) {
+	if m.ParentId != "" {
+		return m.ParentId, nil
+	}
+	if m.Parent != "" {
+		if _, err := strconv.Atoi(m.Parent); err == nil {
+			return m.Parent, nil
+		}
+		top := strings.Split(m.Parent, "/")[0]
+		page, err := m.client.GetPageByTitle(m.Space, top)
+		if err == nil {
+			return page.ID, nil
+		}
+		if !errors.Is(err, confluence.ErrPageNotFound) {
+			return "", err
+		}
+	}
+	if m.SafeMode.RootID != "" {
+		return m.SafeMode.RootID, nil
+	}
+	space, err := m.client.GetSpace(m.Space, "homepage")
+	if err != nil {
+		return "", err
+	}
+	if space.Homepage == nil || space.Homepage.ID == "" {
+		return "", fmt.Errorf("space %s has no home page to hold the lock", m.Space)
+	}
+	return space.Homepage.ID, nil
+}
+
+// lockOwner describes this run in the lock, for runs waiting for it
+func lockOwner(username string) string {
+	if username == "" {
+		username = os.Getenv("USER")
+	}
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s on %s (pid %d)", username, host, os.Getpid())
+	if url := os.Getenv("CI_JOB_URL"); url != "" {
+		owner += " " + url
+	} else if server, repo, run := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"); run != "" {
+		owner += " " + server + "/" + repo + "/actions/runs/" + run
+	}
+	return owner
+}
+
+// lockToken tells this run's lock apart from others with the same owner
+func lockToken() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
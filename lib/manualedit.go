@@ -0,0 +1,208 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// PublishedBodyProperty is the page property --manual-edits stores the
+// version and body hash of the last publish in
+const PublishedBodyProperty = "m2c-published-body"
+
+// Values of --manual-edits
+const (
+	// ManualEditsWarn publishes over manual edits with a warning
+	ManualEditsWarn = "warn"
+	// ManualEditsSkip leaves manually edited pages alone and reports them
+	ManualEditsSkip = "skip"
+)
+
+// publishedBody is the value of PublishedBodyProperty
+type publishedBody struct {
+	Version int    `json:"version"`
+	Hash    string `json:"hash"`
+}
+
+// manualEdit is a page edited in Confluence since it was last published
+type manualEdit struct {
+	path, title, pageID  string
+	baseVersion, version int
+	base, remote, local  string
+}
+
+// manualEdits collects the manual edits of a run for the merge report. It is
+// safe for concurrent use.
+type manualEdits struct {
+	mu    sync.Mutex
+	edits []manualEdit
+}
+
+func (e *manualEdits) add(edit manualEdit) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.edits = append(e.edits, edit)
+}
+
+// bodyHash identifies a storage format body by its normalized lines, so
+// markup Confluence rewrites on save does not count as an edit
+func bodyHash(body string) string {
+	if lines, err := storageLines(body); err == nil {
+		body = strings.Join(lines, "\n")
+	}
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkManualEdit tells whether the page content was edited since it was
+// last published, comparing its body with the PublishedBodyProperty. Pages
+// published before without --manual-edits are taken as not edited.
+func (m *Markdown2Confluence) checkManualEdit(f *MarkdownFile, content confluence.Content, local string) (bool, error) {
+	property, err := m.client.GetContentProperty(content.ID, PublishedBodyProperty)
+	if errors.Is(err, confluence.ErrPropertyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Unable to fetch the published body hash of %s: %s", f.Title, err)
+	}
+	var published publishedBody
+	if err := json.Unmarshal(property.Value, &published); err != nil {
+		return false, fmt.Errorf("Unable to read the published body hash of %s: %s", f.Title, err)
+	}
+
+	remote := content.Body.Storage.Value
+	if remote == "" {
+		// the body was not fetched by the lookup
+		page, err := m.client.GetPage(content.ID, "body.storage")
+		if err != nil {
+			return false, fmt.Errorf("Unable to fetch %s: %s", f.Title, err)
+		}
+		remote = page.Body.Storage.Value
+	}
+	if bodyHash(remote) == published.Hash {
+		return false, nil
+	}
+
+	fmt.Printf("Warning: %s was edited in Confluence since version %d was published\n", f.Title, published.Version)
+	if m.MergeReport == "" {
+		return true, nil
+	}
+	edit := manualEdit{
+		path:        f.Path,
+		title:       f.Title,
+		pageID:      content.ID,
+		baseVersion: published.Version,
+		version:     content.Version.Number,
+		remote:      remote,
+		local:       local,
+	}
+	base, err := m.client.GetPageVersion(content.ID, published.Version)
+	if err != nil {
+		return true, fmt.Errorf("Unable to fetch version %d of %s for the merge report: %s", published.Version, f.Title, err)
+	}
+	edit.base = base.Body.Storage.Value
+	m.manualEdits.add(edit)
+	return true, nil
+}
+
+// storePublishedBody records the version and body hash of a publish
+func (m *Markdown2Confluence) storePublishedBody(f *MarkdownFile, pageID string, version int, body string) {
+	if err := m.client.SetContentProperty(pageID, PublishedBodyProperty, publishedBody{Version: version, Hash: bodyHash(body)}); err != nil {
+		fmt.Printf("Warning: unable to store the published body hash of %s: %s\n", f.Title, err)
+	}
+}
+
+// WriteMergeReport writes a three-way merge of every manually edited page to
+// m.MergeReport: the last published version as the base, the page as edited
+// in Confluence and the rendered markdown. Changes on one side are merged,
+// changes on both are marked like git conflicts.
+func (m *Markdown2Confluence) WriteMergeReport() error {
+	if m.MergeReport == "" || m.manualEdits == nil || len(m.manualEdits.edits) == 0 {
+		return nil
+	}
+	edits := m.manualEdits.edits
+	sort.Slice(edits, func(i, j int) bool { return edits[i].path < edits[j].path })
+
+	var b strings.Builder
+	for _, e := range edits {
+		fmt.Fprintf(&b, "=== %s (page %s): %s\n", e.title, e.pageID, e.path)
+		report, err := mergeEdit(e)
+		if err != nil {
+			fmt.Fprintf(&b, "unable to merge: %s\n\n", err)
+			continue
+		}
+		b.WriteString(report)
+		b.WriteString("\n")
+	}
+	if err := ioutil.WriteFile(m.MergeReport, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("Unable to write merge report %s: %s", m.MergeReport, err)
+	}
+	return nil
+}
+
+// mergeChunks merges the lines of the manual edit and the markdown of e
+func mergeChunks(e manualEdit) ([]mergeChunk, error) {
+	base, err := storageLines(e.base)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := storageLines(e.remote)
+	if err != nil {
+		return nil, err
+	}
+	local, err := storageLines(e.local)
+	if err != nil {
+		return nil, err
+	}
+	return merge3(base, local, remote), nil
+}
+
+// mergeEdit formats the three-way merge of a manual edit
+func mergeEdit(e manualEdit) (string, error) {
+	chunks, err := mergeChunks(e)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	var remoteOnly, localOnly, conflicts int
+	for _, c := range chunks {
+		switch {
+		case c.conflict:
+			conflicts++
+			fmt.Fprintf(&b, "<<<<<<< version %d, edited in Confluence\n", e.version)
+			writeLines(&b, c.theirs)
+			fmt.Fprintf(&b, "||||||| version %d, last published\n", e.baseVersion)
+			writeLines(&b, c.base)
+			b.WriteString("=======\n")
+			writeLines(&b, c.ours)
+			fmt.Fprintf(&b, ">>>>>>> %s\n", e.path)
+		case c.changedTheirs:
+			remoteOnly++
+			writeLines(&b, c.theirs)
+		case c.changedOurs:
+			localOnly++
+			writeLines(&b, c.ours)
+		default:
+			writeLines(&b, c.base)
+		}
+	}
+	summary := fmt.Sprintf("edited in Confluence since version %d, now version %d: %d changes only in Confluence, %d only in markdown, %d conflicts\n",
+		e.baseVersion, e.version, remoteOnly, localOnly, conflicts)
+	return summary + b.String(), nil
+}
+
+func writeLines(b *strings.Builder, lines []string) {
+	for _, l := range lines {
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,14 +21,18 @@ import (
 	"github.com/yuin/goldmark/renderer/html"
 
 	e "github.com/justmiles/go-markdown2confluence/lib/extension"
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
 )
 
 const (
 	// DefaultEndpoint provides an example endpoint for users
 	DefaultEndpoint = "https://mydomain.atlassian.net/wiki"
 
-	// Parallelism determines how many files to convert and upload at a time
+	// Parallelism determines how many files to upload at a time
 	Parallelism = 5
+
+	// StdinPath is the source argument that reads markdown from stdin
+	StdinPath = "-"
 )
 
 // Markdown2Confluence stores the settings for each run
@@ -54,16 +59,148 @@ type Markdown2Confluence struct {
 	CodeBlockTheme           string
 	CodeBlockShowLineNumbers bool
 	CodeBlockCollapse        bool
+	CodeBlockWrap            bool
+	MacroMappingFile         string
+	ValidateOnly             bool
+	Verify                   bool
+	PreRenderHook            string
+	PostPublishHook          string
+	NotifyWebhook            string
+	CI                       string
+	AssetsPage               string
+	SkipPreflight            bool
+	Obsidian                 bool
+	EndpointsFile            string
+	IndexPage                string
+	Excerpt                  bool
+	PreserveInlineComments   bool
+	ContentHash              bool
+	Prefetch                 bool
+	Progress                 bool
+	// MaxUploadRate limits attachment transfers to this many bytes per second
+	MaxUploadRate int64
+	// MaxAttachmentSize is the largest attachment in bytes handled by the
+	// OversizedAttachments policy, no limit when zero
+	MaxAttachmentSize    int64
+	OversizedAttachments string
+	// MaxBodySize is the largest rendered page body in bytes, no limit when
+	// zero. SplitLargePages publishes the sections of larger files as child
+	// pages instead of failing them.
+	MaxBodySize     int64
+	SplitLargePages bool
+	// Labels are added to every published page
+	Labels []string
+	// SplitH1 publishes every H1 heading of a file as a page of its own,
+	// Combine all files as one page
+	SplitH1 bool
+	Combine bool
+	// TitleTemplate is a Go template for page titles, HeaderTemplate and
+	// FooterTemplate are markdown template files wrapped around every page
+	TitleTemplate  string
+	HeaderTemplate string
+	FooterTemplate string
+	// Banner adds an info panel with the publish date, source link, reading
+	// time and owners at the top of every page, from BannerTemplate if set
+	Banner         bool
+	BannerTemplate string
+	// ManagedNotice adds an info panel saying the page is generated from its
+	// file and edits will be overwritten at the top of every page, EditLock
+	// lets only the publishing user edit the pages
+	ManagedNotice bool
+	EditLock      bool
+	// CodeOwners labels pages with the teams CODEOWNERS assigns their file
+	// to and restricts editing to the teams' groups, see OwnerGroups
+	CodeOwners  bool
+	OwnerGroups map[string]string
+	// Normalization cleans up line endings and whitespace before rendering
+	Normalization Normalization
+	// TransliterateTitles spells page titles in ASCII, see
+	// renderer.Transliterate
+	TransliterateTitles bool
+	// Locales are published from files named like guide.de.md, below a page
+	// named after the language or, given as de=SPACE, to their own space
+	Locales       []string
+	DefaultLocale string
+	// Glossary terms are linked to their definition on every page
+	Glossary []GlossaryTerm
+	// Contributors appends the authors of a file from its git history to its
+	// page, without counting as a change of the page
+	Contributors bool
+	// Redirects leaves a stub page, RedirectLink or RedirectMacro, at the old
+	// title of a page whose file got another title since it was published to
+	// PublishedPages, the pages of an earlier run by source path
+	Redirects      string
+	PublishedPages map[string]PageLink
+	// SafeMode refuses to delete, move or update pages outside of the
+	// managed spaces and tree
+	SafeMode SafeMode
+	// Interactive previews the pages a run creates and overwrites and asks
+	// before publishing them and before every delete and move, AssumeYes
+	// answers yes
+	Interactive bool
+	AssumeYes   bool
+	// Lock holds an advisory lock on the root page during Run, for LockTTL
+	// minutes at a time and waiting up to LockWait minutes for other runs
+	Lock     bool
+	LockTTL  int
+	LockWait int
+	// OnConflict is ConflictFail, ConflictSkip, ConflictRetry or
+	// ConflictForce, for pages edited by someone else during the run
+	OnConflict string
+	// ManualEdits is ManualEditsWarn or ManualEditsSkip to track the body
+	// of published pages and detect edits made in Confluence since, with a
+	// three-way merge of them written to MergeReport
+	ManualEdits string
+	MergeReport string
+	manualEdits *manualEdits
+	// ResolveLinks renders links to tiny links and page URLs of the
+	// Confluence published to and to markdown files, of the run or published
+	// before, as links to the page, in other spaces too
+	ResolveLinks bool
+	linkTargets  map[string]linkTarget
+	// LinkBaseURL is the URL links to other files of the repository are
+	// published as, followed by their path in it, or LinkBaseAuto
+	LinkBaseURL string
+	// RenderParallel is how many files to render at a time before uploading,
+	// one per CPU when 0
+	RenderParallel int
+	// Drafts publishes files with draft: true front matter and the
+	// m2c:begin-draft sections of files
+	Drafts bool
+	// Report holds the results of the last Run
+	Report *Report
+
+	pageCache *pageCache
+	progress  *progress
+	interrupt chan struct{}
 }
 
 // CreateClient returns a new markdown client
 func (m *Markdown2Confluence) CreateClient() {
+	m.Endpoint = confluence.NormalizeEndpoint(m.Endpoint)
 	m.client = new(confluence.Client)
 	m.client.Username = m.Username
 	m.client.Password = m.Password
 	m.client.AccessToken = m.AccessToken
 	m.client.Endpoint = m.Endpoint
 	m.client.Debug = m.Debug
+	m.client.MaxTransferRate = m.MaxUploadRate
+	var guards []confluence.Guard
+	if m.SafeMode.Enabled() {
+		guards = append(guards, m.guard)
+	}
+	if m.Interactive {
+		guards = append(guards, m.confirmGuard)
+	}
+	if len(guards) > 0 {
+		m.client.Guard = chainGuards(guards...)
+	}
+	if m.ResolveLinks {
+		renderer.ResolvePageLink = m.resolvePageLink
+	}
+	if m.LinkBaseURL != "" {
+		renderer.RewriteLink = m.rewriteLink
+	}
 }
 
 // SourceEnvironmentVariables overrides Markdown2Confluence with any environment variables that are set
@@ -95,9 +232,29 @@ func (m *Markdown2Confluence) SourceEnvironmentVariables() {
 
 // Validate required configs are set
 func (m Markdown2Confluence) Validate() error {
+	if len(m.SourceMarkdown) == 0 {
+		return fmt.Errorf("please pass a markdown file or directory of markdown files")
+	}
+	if len(m.SourceMarkdown) > 1 && m.Title != "" && !m.Combine {
+		return fmt.Errorf("You can not set the title for multiple files")
+	}
+	for _, f := range m.SourceMarkdown {
+		if f == StdinPath && m.Title == "" {
+			return fmt.Errorf("--title is required when reading markdown from stdin")
+		}
+	}
+	if m.ValidateOnly {
+		// nothing is sent to Confluence, so no connection settings are needed
+		return nil
+	}
 	if m.Space == "" {
 		return fmt.Errorf("--space is not defined")
 	}
+	return m.ValidateConnection()
+}
+
+// ValidateConnection checks the credentials and endpoint needed to talk to Confluence
+func (m Markdown2Confluence) ValidateConnection() error {
 	if m.Username == "" && m.AccessToken == "" {
 		return fmt.Errorf("--username is not defined")
 	}
@@ -110,12 +267,6 @@ func (m Markdown2Confluence) Validate() error {
 	if m.Endpoint == DefaultEndpoint {
 		return fmt.Errorf("--endpoint is not defined")
 	}
-	if len(m.SourceMarkdown) == 0 {
-		return fmt.Errorf("please pass a markdown file or directory of markdown files")
-	}
-	if len(m.SourceMarkdown) > 1 && m.Title != "" {
-		return fmt.Errorf("You can not set the title for multiple files")
-	}
 	if m.AccessToken == "" && m.Username == "" {
 		return fmt.Errorf("--access-token is not defined")
 	}
@@ -134,22 +285,40 @@ func (m *Markdown2Confluence) IsExcluded(p string) bool {
 	return false
 }
 
-// Run the sync
-func (m *Markdown2Confluence) Run() []error {
+// collectMarkdownFiles lists the markdown files of m.SourceMarkdown with
+// their titles and parents
+func (m *Markdown2Confluence) collectMarkdownFiles() ([]MarkdownFile, error) {
 	var markdownFiles []MarkdownFile
 	var now = time.Now()
-	m.CreateClient()
 
 	for _, f := range m.SourceMarkdown {
+		if f == StdinPath {
+			content, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				return nil, fmt.Errorf("Error reading markdown from stdin: %s", err)
+			}
+			if content, err = decodeSource(StdinPath, content); err != nil {
+				return nil, err
+			}
+			md := MarkdownFile{
+				Path:    StdinPath,
+				Title:   m.Title,
+				Content: content,
+			}
+			m.applyParent(&md)
+			markdownFiles = append(markdownFiles, md)
+			continue
+		}
+
 		file, err := os.Open(f)
 		defer file.Close()
 		if err != nil {
-			return []error{fmt.Errorf("Error opening file %s", err)}
+			return nil, fmt.Errorf("Error opening file %s", err)
 		}
 
 		stat, err := file.Stat()
 		if err != nil {
-			return []error{fmt.Errorf("Error reading file meta %s", err)}
+			return nil, fmt.Errorf("Error reading file meta %s", err)
 		}
 
 		var md MarkdownFile
@@ -158,7 +327,7 @@ func (m *Markdown2Confluence) Run() []error {
 
 			// prevent someone from accidently uploading everything under the same title
 			if m.Title != "" {
-				return []error{fmt.Errorf("--title not supported for directories")}
+				return nil, fmt.Errorf("--title not supported for directories")
 			}
 
 			err := filepath.Walk(f,
@@ -167,7 +336,7 @@ func (m *Markdown2Confluence) Run() []error {
 						return err
 					}
 
-					if strings.HasSuffix(path, ".md") && !m.IsExcluded(path) {
+					if isMarkdownFile(path) && !m.IsExcluded(path) {
 
 						// Only include this file if it was modified m.Since minutes ago
 						if m.Since != 0 {
@@ -186,7 +355,7 @@ func (m *Markdown2Confluence) Run() []error {
 							tempTitle = strings.Split(path, "/")[len(strings.Split(path, "/"))-2]
 							tempParents = deleteFromSlice(deleteFromSlice(strings.Split(filepath.Dir(strings.TrimPrefix(filepath.ToSlash(path), filepath.ToSlash(f))), "/"), "."), tempTitle)
 						} else {
-							tempTitle = strings.TrimSuffix(filepath.Base(path), ".md")
+							tempTitle = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 							tempParents = deleteFromSlice(strings.Split(filepath.Dir(strings.TrimPrefix(filepath.ToSlash(path), filepath.ToSlash(f))), "/"), ".")
 						}
 
@@ -219,63 +388,181 @@ func (m *Markdown2Confluence) Run() []error {
 					return nil
 				})
 			if err != nil {
-				return []error{fmt.Errorf("Unable to walk path: %s", f)}
+				return nil, fmt.Errorf("Unable to walk path: %s", f)
 			}
 
 		} else {
 			md = MarkdownFile{
 				Path:  f,
-				Title: m.Title,
+				Title: m.fileTitle(f),
 			}
 
-			if md.Title == "" {
-				if m.UseDocumentTitle == true {
-					md.Title = getDocumentTitle(f)
-				}
-				if md.Title == "" {
-					md.Title = strings.TrimSuffix(filepath.Base(f), ".md")
-				}
-			}
+			m.applyParent(&md)
+			markdownFiles = append(markdownFiles, md)
+		}
 
-			if m.Parent != "" {
-				// If parent was passed as page id
-				id, _ := strconv.Atoi(m.Parent)
-				if id != 0 {
-					md.Ancestor = fmt.Sprintf("%d", id)
-				} else {
-					// Otherwise split parents
-					parents := strings.Split(m.Parent, "/")
-					md.Parents = append(parents, md.Parents...)
-					md.Parents = deleteEmpty(md.Parents)
-				}
-			}
+	}
 
-			markdownFiles = append(markdownFiles, md)
+	m.normalizeTitles(markdownFiles)
+	if err := m.applyLocales(markdownFiles); err != nil {
+		return nil, err
+	}
+	for i := range markdownFiles {
+		if err := applyFrontMatterParent(&markdownFiles[i]); err != nil {
+			return nil, err
+		}
+	}
+	markdownFiles, err := m.dropDrafts(markdownFiles)
+	if err == nil && m.SplitH1 {
+		markdownFiles, err = m.splitByH1(markdownFiles)
+	}
+	if err == nil && m.Combine {
+		markdownFiles = m.combineFiles(markdownFiles)
+	}
+	return markdownFiles, err
+}
+
+// Run the sync
+func (m *Markdown2Confluence) Run() []error {
+	m.CreateClient()
+	defer renderer.UseAttachmentDir()()
+
+	markdownFiles, err := m.collectMarkdownFiles()
+	if err != nil {
+		return []error{err}
+	}
+
+	m.Report = &Report{}
+
+	if m.Lock && !m.ValidateOnly {
+		release, err := m.AcquireLock()
+		if err != nil {
+			return []error{err}
+		}
+		defer release()
+	}
+
+	if m.Obsidian {
+		if err := m.IndexVault(markdownFiles); err != nil {
+			return []error{fmt.Errorf("Unable to index Obsidian vault: %s", err)}
 		}
+	}
+
+	m.manualEdits = &manualEdits{}
+	if m.ResolveLinks {
+		m.indexLinkTargets(markdownFiles)
+	}
 
+	// translations with a space of their own are published after the others
+	var spaces []string
+	bySpace := map[string][]MarkdownFile{}
+	for _, f := range markdownFiles {
+		if _, ok := bySpace[f.Space]; !ok {
+			spaces = append(spaces, f.Space)
+		}
+		bySpace[f.Space] = append(bySpace[f.Space], f)
 	}
+	sort.SliceStable(spaces, func(i, j int) bool { return spaces[i] == "" && spaces[j] != "" })
 
+	var errors []error
+	for _, space := range spaces {
+		run := m
+		if space != "" && space != m.Space {
+			inSpace := *m
+			inSpace.Space = space
+			ParentIndex = make(map[string]string)
+			run = &inSpace
+		}
+		errs := run.publishFiles(bySpace[space])
+		errors = append(errors, errs...)
+		if WasInterrupted(errs) {
+			return errors
+		}
+	}
+
+	if m.IndexPage != "" && !m.ValidateOnly {
+		if err := m.PublishIndex(bySpace[""]); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if err := m.WriteMergeReport(); err != nil {
+		errors = append(errors, err)
+	}
+
+	if m.NotifyWebhook != "" && !m.ValidateOnly {
+		if err := m.Notify(); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return errors
+}
+
+// publishFiles publishes markdownFiles to m.Space
+func (m *Markdown2Confluence) publishFiles(markdownFiles []MarkdownFile) []error {
 	var (
-		wg    = sync.WaitGroup{}
-		queue = make(chan MarkdownFile)
+		wg       = sync.WaitGroup{}
+		errorsMu = sync.Mutex{}
+		queue    = make(chan MarkdownFile)
 	)
 
 	var errors []error
 
+	m.renderAll(markdownFiles)
+	m.splitLargePages(markdownFiles)
+
+	if !m.ValidateOnly && !m.SkipPreflight {
+		if err := m.Preflight(); err != nil {
+			return []error{err}
+		}
+	}
+
+	if m.Prefetch && !m.ValidateOnly {
+		if err := m.PrefetchPages(markdownFiles); err != nil {
+			return []error{err}
+		}
+	}
+
+	if m.Interactive && !m.ValidateOnly {
+		if err := m.previewChanges(markdownFiles); err != nil {
+			return []error{err}
+		}
+	}
+
+	if m.AssetsPage != "" && !m.ValidateOnly {
+		if err := m.PublishSharedAssets(markdownFiles); err != nil {
+			return []error{err}
+		}
+	}
+
+	if m.Progress && !m.ValidateOnly {
+		m.progress = newProgress(countPages(markdownFiles))
+	}
+
 	// Process the queue
 	for worker := 0; worker < Parallelism; worker++ {
 		wg.Add(1)
-		go m.queueProcessor(&wg, &queue, &errors)
+		go m.queueProcessor(&wg, &queue, &errors, &errorsMu)
 	}
 
-	for _, markdownFile := range markdownFiles {
+	var pending []MarkdownFile
+	for i, markdownFile := range markdownFiles {
+		if m.Interrupted() {
+			pending = markdownFiles[i:]
+			break
+		}
 
 		// Create parent pages synchronously
-		if markdownFile.Ancestor == "" && len(markdownFile.Parents) > 0 {
+		if !m.ValidateOnly && markdownFile.Ancestor == "" && len(markdownFile.Parents) > 0 {
 			var err error
 			markdownFile.Ancestor, err = markdownFile.FindOrCreateAncestors(m)
 			if err != nil {
+				m.Report.Add(newPageResult(&markdownFile, "", err))
+				m.progress.Done(0, err)
+				errorsMu.Lock()
 				errors = append(errors, err)
+				errorsMu.Unlock()
 				continue
 			}
 		}
@@ -286,20 +573,128 @@ func (m *Markdown2Confluence) Run() []error {
 	close(queue)
 
 	wg.Wait()
+	m.progress.Finish()
+	m.progress = nil
+
+	if len(pending) > 0 {
+		for i := range pending {
+			pending[i].Action = ActionSkipped
+			m.Report.Add(newPageResult(&pending[i], "", nil))
+		}
+		return append(errors, interruptedError(pending))
+	}
 
 	return errors
 }
 
-func (m *Markdown2Confluence) queueProcessor(wg *sync.WaitGroup, queue *chan MarkdownFile, errors *[]error) {
+// applyParent places a single file below --parent, given as a page id or a
+// path of page titles
+func (m *Markdown2Confluence) applyParent(md *MarkdownFile) {
+	if m.Parent == "" {
+		return
+	}
+	// If parent was passed as page id
+	id, _ := strconv.Atoi(m.Parent)
+	if id != 0 {
+		md.Ancestor = fmt.Sprintf("%d", id)
+	} else {
+		// Otherwise split parents
+		parents := strings.Split(m.Parent, "/")
+		md.Parents = append(parents, md.Parents...)
+		md.Parents = deleteEmpty(md.Parents)
+	}
+}
+
+// applyFrontMatterParent places a file below the page named by its front
+// matter parent, a page id or a path of page titles like --parent, instead of
+// its place in the mirrored tree
+func applyFrontMatterParent(md *MarkdownFile) error {
+	dat := md.Content
+	if dat == nil {
+		var err error
+		dat, err = readMarkdown(md.Path)
+		if err != nil {
+			return fmt.Errorf("Could not open file %s:\n\t%s", md.Path, err)
+		}
+	}
+	fm, _ := ParseFrontMatter(dat)
+	parent := fm.Get("parent")
+	if parent == "" {
+		return nil
+	}
+
+	md.Ancestor = ""
+	md.Parents = nil
+	if id, _ := strconv.Atoi(parent); id != 0 {
+		md.Ancestor = parent
+	} else {
+		md.Parents = deleteEmpty(strings.Split(parent, "/"))
+	}
+	return nil
+}
+
+func (m *Markdown2Confluence) queueProcessor(wg *sync.WaitGroup, queue *chan MarkdownFile, errors *[]error, errorsMu *sync.Mutex) {
 	defer wg.Done()
 
 	for markdownFile := range *queue {
-		url, err := markdownFile.Upload(m)
-		if err != nil {
-			*errors = append(*errors, fmt.Errorf("Unable to upload markdown file %s: \n\t%s", markdownFile.Path, err))
+		m.publishFile(markdownFile, errors, errorsMu)
+	}
+}
+
+// publishFile uploads a file of the queue and prints its outcome, then
+// publishes the parts split off it below its page
+func (m *Markdown2Confluence) publishFile(markdownFile MarkdownFile, errors *[]error, errorsMu *sync.Mutex) {
+	m.progress.Start(markdownFile.FormattedPath())
+	url, err := markdownFile.Upload(m)
+	m.Report.Add(newPageResult(&markdownFile, url, err))
+	m.progress.Done(markdownFile.Attachments, err)
+	if err != nil {
+		errorsMu.Lock()
+		*errors = append(*errors, fmt.Errorf("Unable to upload markdown file %s: \n\t%s", markdownFile.Path, err))
+		errorsMu.Unlock()
+	}
+	switch {
+	case m.ValidateOnly:
+		if err == nil {
+			fmt.Printf("%s: valid\n", markdownFile.FormattedPath())
+		}
+	case markdownFile.Path == StdinPath:
+		// a single machine readable line for pipelines
+		if err == nil {
+			m.progress.Printf("%s %s\n", markdownFile.PageID, url)
 		}
-		fmt.Printf("%s: %s\n", markdownFile.FormattedPath(), url)
+	case markdownFile.Action == ActionUnchanged:
+		m.progress.Printf("%s: %s (unchanged)\n", markdownFile.FormattedPath(), url)
+	case markdownFile.Action == ActionEdited:
+		m.progress.Printf("%s: %s (edited in Confluence, not updated)\n", markdownFile.FormattedPath(), url)
+	case markdownFile.Action == ActionConflict:
+		m.progress.Printf("%s: %s (edited by someone else, not updated)\n", markdownFile.FormattedPath(), url)
+	default:
+		m.progress.Printf("%s: %s\n", markdownFile.FormattedPath(), url)
+	}
+
+	if len(markdownFile.Parts) == 0 {
+		return
+	}
+	if err != nil {
+		errorsMu.Lock()
+		*errors = append(*errors, fmt.Errorf("%d pages split off %s were not published", countPages(markdownFile.Parts), markdownFile.Path))
+		errorsMu.Unlock()
+		return
 	}
+	for _, part := range markdownFile.Parts {
+		part.Ancestor = markdownFile.PageID
+		m.publishFile(part, errors, errorsMu)
+	}
+}
+
+// countPages counts files and the parts split off them
+func countPages(files []MarkdownFile) int {
+	n := len(files)
+	for _, f := range files {
+		n += countPages(f.Parts)
+	}
+	return n
 }
 
 func validateInput(s string, msg string) {
@@ -331,14 +726,29 @@ func renderContent(filePath, s string, withHardWraps bool) (content string, imag
 		),
 	)
 
-	var buf bytes.Buffer
-	if err := md.Convert([]byte(s), &buf); err != nil {
+	buf := renderBuffers.Get().(*bytes.Buffer)
+	defer renderBuffers.Put(buf)
+	buf.Reset()
+	// removed before rendering, as removing them from the output could join
+	// the ]] and > of a CDATA section the renderer split
+	source := []byte(removeInvalidXMLChars(s))
+	defer renderer.IndexLines(source)()
+	ctx := parser.NewContext(parser.WithIDs(renderer.NewSlugger()))
+	if err := md.Convert(source, buf, parser.WithContext(ctx)); err != nil {
+		return "", nil, err
+	}
+	// warnings that are errors with --strict
+	if err := confluenceExtension.Diagnostics().Err(); err != nil {
 		return "", nil, err
 	}
 
 	return buf.String(), confluenceExtension.Images(), nil
 }
 
+// renderBuffers are reused between renders, so that the output buffer of a
+// large file is not grown from scratch for every file
+var renderBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
 func deleteEmpty(s []string) []string {
 	var r []string
 	for _, str := range s {
@@ -359,9 +769,12 @@ func deleteFromSlice(s []string, del string) []string {
 	return s
 }
 
+// closingHashes matches the optional closing sequence of an ATX heading
+var closingHashes = regexp.MustCompile(`\s+#+\s*$`)
+
 func getDocumentTitle(p string) string {
 	// Read file to check for the content
-	file_content, err := ioutil.ReadFile(p)
+	file_content, err := readMarkdown(p)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -373,8 +786,8 @@ func getDocumentTitle(p string) string {
 	r := regexp.MustCompile(e)
 	result := r.FindStringSubmatch(text)
 	if len(result) > 1 {
-		// assign the Title to the matching group
-		return result[1]
+		// assign the Title to the matching group, without a closing sequence
+		return strings.TrimSpace(closingHashes.ReplaceAllString(result[1], ""))
 	}
 
 	return ""
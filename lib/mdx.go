@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+var (
+	// mdxModuleLine matches top level ES module statements
+	mdxModuleLine = regexp.MustCompile(`^(import\s.+\sfrom\s|import\s+['"]|export\s)`)
+	// mdxComment matches a JSX comment on its own line
+	mdxComment = regexp.MustCompile(`^\{/\*.*\*/\}$`)
+	// mdxComponentLine matches a line holding only a JSX component tag
+	mdxComponentLine = regexp.MustCompile(`^</?[A-Z][A-Za-z0-9.]*(\s[^>]*)?/?>$`)
+	// mdxTabItem matches a Docusaurus <TabItem> opening tag, labelled by its
+	// label or else its value attribute
+	mdxTabItem      = regexp.MustCompile(`^<TabItem\s[^>]*>$`)
+	mdxTabItemLabel = regexp.MustCompile(`\slabel=["']([^"']+)["']`)
+	mdxTabItemValue = regexp.MustCompile(`\svalue=["']([^"']+)["']`)
+	// mdxAdmonition matches :::type, :::type Title and :::type[Title]
+	mdxAdmonition    = regexp.MustCompile(`^(:{3,})([A-Za-z]+)(?:\[(.*)\]|\s+(.*))?$`)
+	mdxAdmonitionEnd = regexp.MustCompile(`^:{3,}$`)
+)
+
+// isMarkdownFile reports whether a file in a source directory is published
+func isMarkdownFile(path string) bool {
+	return strings.HasSuffix(path, ".md") || (renderer.MDX && strings.HasSuffix(path, ".mdx"))
+}
+
+// preprocessMDX makes Docusaurus MDX sources renderable as markdown: module
+// statements, JSX comments and component tags are dropped, tab items become
+// bold labels and :::note containers become callouts rendered as panels.
+// Lines are replaced one for one so source positions stay correct.
+func preprocessMDX(markdown []byte) []byte {
+	lines := strings.Split(string(markdown), "\n")
+	var fence string
+	depth := 0
+	// layouts are the open containers, true for ::: columns and ::: column
+	// containers, which are kept for the layout parser
+	var layouts []bool
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		prefix := strings.Repeat("> ", depth)
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			lines[i] = prefix + line
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			lines[i] = prefix + line
+			continue
+		}
+
+		switch {
+		case depth == 0 && mdxModuleLine.MatchString(line):
+			lines[i] = ""
+		case mdxComment.MatchString(trimmed):
+			lines[i] = strings.TrimSuffix(prefix, " ")
+		case mdxAdmonitionEnd.MatchString(trimmed) && len(layouts) > 0 && layouts[len(layouts)-1]:
+			layouts = layouts[:len(layouts)-1]
+		case mdxAdmonitionEnd.MatchString(trimmed) && depth > 0:
+			layouts = layouts[:len(layouts)-1]
+			depth--
+			lines[i] = strings.TrimSuffix(strings.Repeat("> ", depth), " ")
+		case mdxAdmonition.MatchString(trimmed):
+			m := mdxAdmonition.FindStringSubmatch(trimmed)
+			if kind := strings.ToLower(m[2]); kind == "columns" || kind == "column" {
+				layouts = append(layouts, true)
+				lines[i] = prefix + line
+				break
+			}
+			layouts = append(layouts, false)
+			title := m[3]
+			if title == "" {
+				title = m[4]
+			}
+			lines[i] = strings.TrimRight(prefix+"> [!"+strings.ToLower(m[2])+"] "+title, " ")
+			depth++
+		case mdxTabItem.MatchString(trimmed):
+			label := mdxTabItemLabel.FindStringSubmatch(trimmed)
+			if label == nil {
+				label = mdxTabItemValue.FindStringSubmatch(trimmed)
+			}
+			if label == nil {
+				lines[i] = strings.TrimSuffix(prefix, " ")
+			} else {
+				lines[i] = prefix + "**" + label[1] + "**"
+			}
+		case mdxComponentLine.MatchString(trimmed):
+			lines[i] = strings.TrimSuffix(prefix, " ")
+		default:
+			lines[i] = prefix + line
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
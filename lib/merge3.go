@@ -0,0 +1,79 @@
+package lib
+
+// mergeChunk is a part of a three-way merge. Unchanged chunks only have
+// base lines.
+type mergeChunk struct {
+	base, ours, theirs []string
+	// changedOurs and changedTheirs tell which side changed the base,
+	// conflict that both did differently
+	changedOurs, changedTheirs, conflict bool
+}
+
+// matchLines maps each line of a to the line of b it is kept as, -1 for
+// removed lines
+func matchLines(a, b []string) []int {
+	matches := make([]int, len(a))
+	i, j := 0, 0
+	for _, l := range diffLines(a, b) {
+		switch l.Op {
+		case ' ':
+			matches[i] = j
+			i++
+			j++
+		case '-':
+			matches[i] = -1
+			i++
+		case '+':
+			j++
+		}
+	}
+	return matches
+}
+
+// merge3 merges the changes of ours and theirs to base, like diff3. Lines
+// kept by both sides split the merge into chunks, each taken from the side
+// that changed it.
+func merge3(base, ours, theirs []string) []mergeChunk {
+	toOurs, toTheirs := matchLines(base, ours), matchLines(base, theirs)
+	var chunks []mergeChunk
+	i, o, t := 0, 0, 0
+	for {
+		// the next base line kept by both sides
+		k, oe, te := i, len(ours), len(theirs)
+		for ; k < len(base); k++ {
+			if toOurs[k] >= 0 && toTheirs[k] >= 0 {
+				oe, te = toOurs[k], toTheirs[k]
+				break
+			}
+		}
+
+		c := mergeChunk{base: base[i:k], ours: ours[o:oe], theirs: theirs[t:te]}
+		c.changedOurs = !equalLines(c.base, c.ours)
+		c.changedTheirs = !equalLines(c.base, c.theirs)
+		if c.changedOurs && c.changedTheirs && equalLines(c.ours, c.theirs) {
+			c.changedTheirs = false
+		}
+		c.conflict = c.changedOurs && c.changedTheirs
+		if len(c.base) > 0 || len(c.ours) > 0 || len(c.theirs) > 0 {
+			chunks = append(chunks, c)
+		}
+
+		if k == len(base) {
+			return chunks
+		}
+		chunks = append(chunks, mergeChunk{base: base[k : k+1]})
+		i, o, t = k+1, oe+1, te+1
+	}
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Normalization is the whitespace clean up applied to sources before
+// rendering
+type Normalization struct {
+	// LineEndings converts CRLF and CR line endings to LF
+	LineEndings bool
+	// TrailingWhitespace removes whitespace at the end of lines, except the
+	// two spaces of a hard line break
+	TrailingWhitespace bool
+	// TabWidth expands tabs to spaces with tab stops every TabWidth columns,
+	// 0 keeps tabs
+	TabWidth int
+}
+
+// DefaultNormalization only converts line endings
+var DefaultNormalization = []string{"crlf"}
+
+// ParseNormalization parses --normalize values: crlf, trailing-whitespace,
+// tabs=N or none
+func ParseNormalization(values []string) (Normalization, error) {
+	var n Normalization
+	for _, v := range values {
+		name, arg, _ := strings.Cut(strings.TrimSpace(v), "=")
+		switch name {
+		case "none":
+			n = Normalization{}
+		case "crlf":
+			n.LineEndings = true
+		case "trailing-whitespace":
+			n.TrailingWhitespace = true
+		case "tabs":
+			width := 4
+			if arg != "" {
+				var err error
+				if width, err = strconv.Atoi(arg); err != nil || width < 1 {
+					return n, fmt.Errorf("invalid tab width %q", arg)
+				}
+			}
+			n.TabWidth = width
+		default:
+			return n, fmt.Errorf("unknown normalization %q, use crlf, trailing-whitespace, tabs=N or none", v)
+		}
+	}
+	return n, nil
+}
+
+// Apply normalizes a source. Line endings are converted first, so the other
+// normalizations see every line.
+func (n Normalization) Apply(dat []byte) []byte {
+	if n.LineEndings {
+		dat = bytes.ReplaceAll(dat, []byte("\r\n"), []byte("\n"))
+		dat = bytes.ReplaceAll(dat, []byte("\r"), []byte("\n"))
+	}
+	if !n.TrailingWhitespace && n.TabWidth == 0 {
+		return dat
+	}
+
+	lines := strings.Split(string(dat), "\n")
+	fence := ""
+	for i, line := range lines {
+		if n.TabWidth > 0 {
+			line = expandTabs(line, n.TabWidth)
+		}
+		trimmed := strings.TrimLeft(line, " \t")
+		if fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")) {
+			fence = trimmed[:3]
+		} else if fence != "" && strings.HasPrefix(trimmed, fence) {
+			fence = ""
+		}
+		if n.TrailingWhitespace {
+			stripped := strings.TrimRight(line, " \t")
+			// two or more spaces end a line with a hard break outside of code
+			if fence == "" && stripped != "" && strings.HasSuffix(line, "  ") && i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+				stripped += "  "
+			}
+			line = stripped
+		}
+		lines[i] = line
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// expandTabs replaces the tabs of line with spaces up to the next tab stop
+func expandTabs(line string, width int) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var b strings.Builder
+	column := 0
+	for _, r := range line {
+		if r == '\t' {
+			spaces := width - column%width
+			b.WriteString(strings.Repeat(" ", spaces))
+			column += spaces
+			continue
+		}
+		b.WriteRune(r)
+		column++
+	}
+	return b.String()
+}
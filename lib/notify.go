@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Notify posts a summary of the run report to m.NotifyWebhook. Slack and
+// Microsoft Teams incoming webhooks are detected by host, any other URL
+// receives the report as JSON.
+func (m *Markdown2Confluence) Notify() error {
+	var payload interface{}
+	switch webhookKind(m.NotifyWebhook) {
+	case "slack":
+		payload = map[string]string{"text": m.notificationText("<%s|%s>")}
+	case "teams":
+		payload = map[string]string{
+			"@type":    "MessageCard",
+			"@context": "http://schema.org/extensions",
+			"summary":  m.notificationSummary(),
+			"text":     m.notificationText("[%[2]s](%[1]s)"),
+		}
+	default:
+		payload = m.Report
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	res, err := http.Post(m.NotifyWebhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Unable to send notification: %s", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		response, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("Unable to send notification: %s %s", res.Status, response)
+	}
+	return nil
+}
+
+func webhookKind(webhook string) string {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case u.Host == "hooks.slack.com":
+		return "slack"
+	case strings.HasSuffix(u.Host, "webhook.office.com"), u.Host == "outlook.office.com", strings.HasSuffix(u.Host, "logic.azure.com"):
+		return "teams"
+	}
+	return ""
+}
+
+func (m *Markdown2Confluence) notificationSummary() string {
+	return fmt.Sprintf("markdown2confluence: %d created, %d updated, %d failed in space %s",
+		m.Report.Count(ActionCreated), m.Report.Count(ActionUpdated), m.Report.Count(ActionFailed), m.Space)
+}
+
+// notificationText renders the report as markdown, linkFormat receives the
+// page URL and title
+func (m *Markdown2Confluence) notificationText(linkFormat string) string {
+	var b strings.Builder
+	b.WriteString(m.notificationSummary())
+	for _, p := range m.Report.Pages {
+		b.WriteString("\n- ")
+		b.WriteString(p.Action)
+		b.WriteString(": ")
+		if p.URL != "" {
+			b.WriteString(fmt.Sprintf(linkFormat, p.URL, p.Title))
+		} else {
+			b.WriteString(p.Title)
+		}
+		if p.Error != "" {
+			b.WriteString(" (" + strings.SplitN(p.Error, "\n", 2)[0] + ")")
+		}
+	}
+	return b.String()
+}
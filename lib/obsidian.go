@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// IndexVault prepares the renderer to resolve Obsidian wikilinks and embeds:
+// note names and front matter aliases are mapped to page titles and every
+// other file in the source directories is made available for embedding
+func (m *Markdown2Confluence) IndexVault(files []MarkdownFile) error {
+	renderer.Obsidian = true
+
+	for _, f := range files {
+		name := strings.ToLower(strings.TrimSuffix(filepath.Base(f.Path), ".md"))
+		renderer.WikiLinkTitles[name] = f.Title
+
+		dat, err := readMarkdown(f.Path)
+		if err != nil {
+			return err
+		}
+		fm, _ := ParseFrontMatter(dat)
+		for _, alias := range append(fm.List("aliases"), fm.List("alias")...) {
+			renderer.WikiLinkTitles[strings.ToLower(alias)] = f.Title
+		}
+	}
+
+	for _, source := range m.SourceMarkdown {
+		err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// skip the vault settings and other hidden directories
+			if info.IsDir() && path != source && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if info.IsDir() || strings.HasSuffix(path, ".md") {
+				return nil
+			}
+			if _, ok := renderer.VaultFiles[info.Name()]; !ok {
+				renderer.VaultFiles[info.Name()] = path
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
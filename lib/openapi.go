@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// PublishOpenAPI publishes an OpenAPI or Swagger spec as a page, titled
+// after the spec unless --title is set
+func (m *Markdown2Confluence) PublishOpenAPI(spec string) []error {
+	m.CreateClient()
+	defer renderer.UseAttachmentDir()()
+	m.Report = &Report{}
+
+	dat, err := ioutil.ReadFile(spec)
+	if err != nil {
+		return []error{fmt.Errorf("Could not open file %s:\n\t%s", spec, err)}
+	}
+	title := m.Title
+	if title == "" {
+		title, err = renderer.OpenAPITitle(dat)
+		if err != nil {
+			return []error{fmt.Errorf("%s: %s", spec, err)}
+		}
+	}
+	if title == "" {
+		return []error{fmt.Errorf("%s has no info.title, set --title", spec)}
+	}
+
+	var ancestorID string
+	if !m.ValidateOnly {
+		if !m.SkipPreflight {
+			if err := m.Preflight(); err != nil {
+				return []error{err}
+			}
+		}
+		ancestorID, err = m.resolveParentID()
+		if err != nil {
+			return []error{err}
+		}
+	}
+
+	f := MarkdownFile{
+		Path:     spec,
+		Title:    title,
+		Ancestor: ancestorID,
+		Content:  []byte("```openapi\n" + filepath.Base(spec) + "\n```\n"),
+	}
+	url, err := f.Upload(m)
+	m.Report.Add(newPageResult(&f, url, err))
+	if err != nil {
+		return []error{fmt.Errorf("Unable to upload OpenAPI spec %s: \n\t%s", spec, err)}
+	}
+	if m.ValidateOnly {
+		fmt.Printf("%s: valid\n", f.Title)
+	} else {
+		fmt.Printf("%s: %s\n", f.Title, url)
+	}
+	return nil
+}
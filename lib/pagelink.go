@@ -0,0 +1,191 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/justmiles/go-confluence"
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// unresolvedLinks are the links to Confluence a warning was printed for
+var unresolvedLinks sync.Map
+
+// linkedHeadings caches the headings of linked markdown files by path, see
+// renderer.FindHeadings
+var linkedHeadings sync.Map
+
+// linkTarget is the page a markdown file of the run is published to
+type linkTarget struct {
+	title, space string
+	// anchor is the anchor of a file on a combined page
+	anchor string
+}
+
+// indexLinkTargets records the page of every file of the run by absolute
+// path, for links between them
+func (m *Markdown2Confluence) indexLinkTargets(files []MarkdownFile) {
+	m.linkTargets = map[string]linkTarget{}
+	for _, f := range files {
+		space := f.Space
+		if space == "" {
+			space = m.Space
+		}
+		if path, err := filepath.Abs(f.Path); err == nil && len(f.Combined) == 0 {
+			m.linkTargets[path] = linkTarget{title: f.Title, space: space}
+		}
+		for _, part := range f.Combined {
+			if path, err := filepath.Abs(part.Path); err == nil {
+				m.linkTargets[path] = linkTarget{title: f.Title, space: space, anchor: combinedAnchor(part.Path)}
+			}
+		}
+	}
+}
+
+// resolvePageLink is the renderer.ResolvePageLink of --resolve-links
+func (m *Markdown2Confluence) resolvePageLink(filePath, destination string, diagnostics *renderer.Diagnostics) (renderer.PageLink, bool) {
+	if target, ok := markdownLinkTarget(filePath, destination); ok {
+		return m.resolveFileLink(filePath, target, destination, diagnostics)
+	}
+
+	page, err := m.client.ResolveLink(destination)
+	if errors.Is(err, confluence.ErrNotPageLink) {
+		return renderer.PageLink{}, false
+	}
+	if err != nil {
+		if _, warned := unresolvedLinks.LoadOrStore(destination, true); !warned {
+			warnLink(diagnostics, filePath, "unable to resolve the link to %s, keeping it as it is: %s", destination, err)
+		}
+		return renderer.PageLink{}, false
+	}
+
+	link := renderer.PageLink{Title: page.Title, SpaceKey: page.Space.Key}
+	if u, err := url.Parse(destination); err == nil && u.Fragment != "" {
+		// Confluence prefixes heading ids with the page title
+		link.Anchor = strings.TrimPrefix(u.Fragment, strings.ReplaceAll(page.Title, " ", "")+"-")
+	}
+	return link, true
+}
+
+// markdownLinkTarget returns the absolute path of the markdown file a local
+// link of the file at filePath points to
+func markdownLinkTarget(filePath, destination string) (string, bool) {
+	if !renderer.IsLocalLink(destination) {
+		return "", false
+	}
+	u, err := url.Parse(destination)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(filepath.Ext(u.Path)) {
+	case ".md", ".markdown":
+	default:
+		return "", false
+	}
+	target := filepath.FromSlash(u.Path)
+	if !filepath.IsAbs(target) {
+		dir, err := filepath.Abs(filepath.Dir(filePath))
+		if err != nil {
+			return "", false
+		}
+		target = filepath.Join(dir, target)
+	}
+	return target, true
+}
+
+// resolveFileLink resolves a link to the markdown file target to the page it
+// is published to: by this run, or before when the page exists. Files that
+// are not part of the run are looked up by the title and space of their
+// front matter, their document title with --use-document-title or their
+// file name, in the space of the linking file by default. The space key is
+// only set for pages in another space than the linking file.
+func (m *Markdown2Confluence) resolveFileLink(filePath, target, destination string, diagnostics *renderer.Diagnostics) (renderer.PageLink, bool) {
+	space := m.Space
+	if path, err := filepath.Abs(filePath); err == nil {
+		if from, ok := m.linkTargets[path]; ok {
+			space = from.space
+		}
+	}
+
+	page, ok := m.linkTargets[target]
+	if !ok {
+		dat, err := ioutil.ReadFile(target)
+		if err != nil {
+			// a dead link, left to --check-links
+			return renderer.PageLink{}, false
+		}
+		fm, _ := ParseFrontMatter(dat)
+		page = linkTarget{title: fm.Get("title"), space: fm.Get("space")}
+		if page.title == "" && m.UseDocumentTitle {
+			page.title = getDocumentTitle(target)
+		}
+		if page.title == "" {
+			page.title = strings.TrimSuffix(filepath.Base(target), filepath.Ext(target))
+		}
+		if page.space == "" {
+			page.space = space
+		}
+		if _, err := m.client.LookupPage(page.space, page.title); err != nil {
+			if _, warned := unresolvedLinks.LoadOrStore(target, true); !warned {
+				warnLink(diagnostics, filePath, "links to %s, which is not published by this run and has no page %q in space %s, keeping the link as it is: %s",
+					destination, page.title, page.space, err)
+			}
+			return renderer.PageLink{}, false
+		}
+	}
+
+	link := renderer.PageLink{Title: page.title}
+	if page.space != space {
+		link.SpaceKey = page.space
+	}
+	if u, err := url.Parse(destination); err == nil && u.Fragment != "" {
+		link.Anchor = headingAnchor(filePath, target, destination, u.Fragment, diagnostics)
+	}
+	if link.Anchor == "" {
+		link.Anchor = page.anchor
+	}
+	return link, true
+}
+
+// headingAnchor returns the anchor of the heading of the markdown file
+// target with the ID fragment: the anchor macro of --heading-anchors, or else
+// the heading text Confluence names its anchors after. Links to headings that
+// do not exist point to the page, with a warning.
+func headingAnchor(filePath, target, destination, fragment string, diagnostics *renderer.Diagnostics) string {
+	v, ok := linkedHeadings.Load(target)
+	if !ok {
+		headings := map[string]string{}
+		if source, err := readMarkdown(target); err == nil {
+			_, source = ParseFrontMatter(source)
+			headings = renderer.FindHeadings(source)
+		}
+		v, _ = linkedHeadings.LoadOrStore(target, headings)
+	}
+	text, ok := v.(map[string]string)[fragment]
+	if !ok {
+		if _, warned := unresolvedLinks.LoadOrStore(target+"#"+fragment, true); !warned {
+			warnLink(diagnostics, filePath, "links to %s, which has no heading #%s, linking to the page instead", destination, fragment)
+		}
+		return ""
+	}
+	if renderer.HeadingAnchors {
+		return fragment
+	}
+	return text
+}
+
+// warnLink warns about a link of the file at filePath that is not resolved.
+// Links are warned about once per run, so only the first file linking to a
+// missing page fails with --strict.
+func warnLink(diagnostics *renderer.Diagnostics, filePath, format string, args ...interface{}) {
+	diagnostics.Warn(renderer.Warning{
+		Class:    renderer.WarningLink,
+		Position: renderer.Position{File: filePath},
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// prefetchedPage is an existing page with the content hash stored on it
+type prefetchedPage struct {
+	Content confluence.Content
+	Hash    string
+}
+
+// pageCache holds the pages fetched by PrefetchPages by title. When it covers
+// the whole space, titles missing from it do not exist, except for parent
+// pages the run creates while publishing.
+type pageCache struct {
+	pages    map[string]prefetchedPage
+	parents  map[string]bool
+	complete bool
+}
+
+// PrefetchPages loads the version and content hash of every page below the
+// parent page, or of the whole space without one, with a few paginated CQL
+// searches instead of a request per file
+func (m *Markdown2Confluence) PrefetchPages(files []MarkdownFile) error {
+	cache := &pageCache{pages: map[string]prefetchedPage{}, parents: map[string]bool{}}
+	for _, f := range files {
+		for _, parent := range f.Parents {
+			cache.parents[parent] = true
+		}
+	}
+	cql := fmt.Sprintf("space = %q and type = page", m.Space)
+	parentID := m.ParentId
+	if parentID == "" {
+		if id, _ := strconv.Atoi(m.Parent); id != 0 {
+			parentID = m.Parent
+		}
+	}
+	if parentID != "" {
+		cql += " and ancestor = " + parentID
+	} else {
+		// titles are unique in a space, so a space wide search is complete
+		cache.complete = true
+	}
+
+	expand := []string{"version"}
+	if m.ContentHash {
+		expand = append(expand, "metadata.properties."+ContentHashProperty)
+	}
+	results, err := m.client.SearchContent(cql, expand...)
+	if err != nil {
+		return fmt.Errorf("Unable to prefetch pages of space %s: %s", m.Space, err)
+	}
+
+	for _, c := range results {
+		page := prefetchedPage{Content: c}
+		if c.Metadata != nil {
+			if property, ok := c.Metadata.Properties[ContentHashProperty]; ok {
+				page.Hash = propertyString(property)
+			}
+		}
+		page.Content.Metadata = nil
+		cache.pages[c.Title] = page
+	}
+	if m.Debug {
+		fmt.Printf("prefetched %d pages with: %s\n", len(cache.pages), cql)
+	}
+	m.pageCache = cache
+	return nil
+}
+
+// cachedPage looks a title up in the prefetched pages. known is false when
+// the cache can not tell whether the page exists.
+func (m *Markdown2Confluence) cachedPage(title string) (page prefetchedPage, exists, known bool) {
+	if m.pageCache == nil {
+		return page, false, false
+	}
+	page, exists = m.pageCache.pages[title]
+	return page, exists, exists || (m.pageCache.complete && !m.pageCache.parents[title])
+}
@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// preflightOperations must be permitted in the target space for a sync
+var preflightOperations = []struct {
+	operation, targetType string
+}{
+	{"read", "space"},
+	{"create", "page"},
+	{"update", "page"},
+	{"create", "attachment"},
+}
+
+// Preflight verifies the authenticated user can publish to m.Space before
+// any page is touched, so runs fail fast instead of halfway with 403s
+func (m *Markdown2Confluence) Preflight() error {
+	m.checkDeployment()
+
+	space, err := m.client.GetSpace(m.Space, "operations")
+	if err != nil {
+		return fmt.Errorf("Unable to access space %s, check the space key and your credentials: %s", m.Space, err)
+	}
+
+	// Server and Data Center do not expand operations, nothing more to check there
+	if len(space.Operations) == 0 {
+		if m.Debug {
+			fmt.Printf("space %s did not report permitted operations, skipping permission check\n", m.Space)
+		}
+		return nil
+	}
+
+	if missing := missingOperations(space); len(missing) > 0 {
+		return fmt.Errorf("Missing permissions in space %s: %s", m.Space, strings.Join(missing, ", "))
+	}
+	if !space.CanPerform("delete", "page") {
+		fmt.Printf("Warning: you can not delete pages in space %s\n", m.Space)
+	}
+	return nil
+}
+
+// missingOperations returns the preflightOperations not permitted in space
+func missingOperations(space *confluence.Space) []string {
+	var missing []string
+	for _, o := range preflightOperations {
+		if !space.CanPerform(o.operation, o.targetType) {
+			missing = append(missing, o.operation+" "+o.targetType)
+		}
+	}
+	return missing
+}
+
+// checkDeployment warns about credentials and features the detected
+// Confluence deployment does not support
+func (m *Markdown2Confluence) checkDeployment() {
+	info, err := m.client.ServerInfo()
+	if err != nil {
+		if m.Debug {
+			fmt.Printf("unable to detect the Confluence deployment: %s\n", err)
+		}
+		return
+	}
+	if m.Debug {
+		fmt.Printf("%s is Confluence %s %s\n", m.Endpoint, info.Deployment, info.Version)
+	}
+
+	switch info.Deployment {
+	case confluence.DeploymentCloud:
+		if m.AccessToken != "" && m.Username == "" {
+			fmt.Println("Warning: personal access tokens are a Server and Data Center feature, Confluence Cloud expects --username with an API token as --password")
+		}
+	case confluence.DeploymentServer:
+		if strings.Contains(m.Username, "@") {
+			fmt.Printf("Warning: Confluence Server and Data Center sign in with usernames, %s looks like an email address\n", m.Username)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+)
+
+// StartProfile writes a CPU profile of the process to dir/cpu.pprof until
+// the returned func stops it and adds a heap profile as dir/heap.pprof, for
+// `go tool pprof`
+func StartProfile(dir string) (stop func() error, err error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Unable to create profile directory %s: %s", dir, err)
+	}
+	cpu, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create CPU profile: %s", err)
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		cpu.Close()
+		return nil, fmt.Errorf("Unable to start CPU profile: %s", err)
+	}
+	return func() error {
+		pprof.StopCPUProfile()
+		if err := cpu.Close(); err != nil {
+			return fmt.Errorf("Unable to write CPU profile: %s", err)
+		}
+		heap, err := os.Create(filepath.Join(dir, "heap.pprof"))
+		if err != nil {
+			return fmt.Errorf("Unable to create heap profile: %s", err)
+		}
+		defer heap.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(heap); err != nil {
+			return fmt.Errorf("Unable to write heap profile: %s", err)
+		}
+		return heap.Close()
+	}, nil
+}
@@ -0,0 +1,187 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressInterval is how often the progress is redrawn on a terminal and
+// logged otherwise
+var progressInterval = map[bool]time.Duration{true: 200 * time.Millisecond, false: 10 * time.Second}
+
+// progress tracks a sync run. On a terminal a status line with the pages
+// done, attachments, current file and ETA is kept below the output, other
+// writers get a plain log line every few seconds instead.
+type progress struct {
+	mu          sync.Mutex
+	out         io.Writer
+	tty         bool
+	total       int
+	done        int
+	failed      int
+	attachments int
+	current     string
+	started     time.Time
+	drawn       bool
+	stop        chan struct{}
+	stopped     sync.WaitGroup
+}
+
+// newProgress starts reporting the progress of total pages on stderr
+func newProgress(total int) *progress {
+	p := &progress{
+		out:     os.Stderr,
+		tty:     isTerminal(os.Stderr),
+		total:   total,
+		started: time.Now(),
+		stop:    make(chan struct{}),
+	}
+	p.stopped.Add(1)
+	go p.loop()
+	return p
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *progress) loop() {
+	defer p.stopped.Done()
+	ticker := time.NewTicker(progressInterval[p.tty])
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			if p.tty {
+				p.draw()
+			} else {
+				fmt.Fprintln(p.out, p.status())
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// Start records the file a worker is publishing
+func (p *progress) Start(path string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.current = path
+	p.mu.Unlock()
+}
+
+// Done counts a finished page and its attachments
+func (p *progress) Done(attachments int, err error) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.done++
+	p.attachments += attachments
+	if err != nil {
+		p.failed++
+	}
+	p.mu.Unlock()
+}
+
+// Printf writes a line of regular output above the status line
+func (p *progress) Printf(format string, a ...interface{}) {
+	if p == nil {
+		fmt.Printf(format, a...)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	fmt.Printf(format, a...)
+	p.draw()
+}
+
+// Finish stops reporting and prints the final counts
+func (p *progress) Finish() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	p.stopped.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	p.current = ""
+	fmt.Fprintf(p.out, "%s in %s\n", p.status(), time.Since(p.started).Round(time.Second))
+}
+
+func (p *progress) clear() {
+	if p.tty && p.drawn {
+		fmt.Fprint(p.out, "\r\x1b[K")
+		p.drawn = false
+	}
+}
+
+func (p *progress) draw() {
+	if !p.tty {
+		return
+	}
+	line := p.status()
+	if width := terminalWidth(); len(line) > width-1 {
+		line = line[:width-1]
+	}
+	fmt.Fprint(p.out, "\r\x1b[K"+line)
+	p.drawn = true
+}
+
+// status describes the progress in one line, e.g.
+// "[=====     ] 120/240 pages, 3 failed, 45 attachments, ETA 2m10s docs/setup.md"
+func (p *progress) status() string {
+	var b strings.Builder
+	if p.tty {
+		const width = 20
+		filled := 0
+		if p.total > 0 {
+			filled = width * p.done / p.total
+		}
+		b.WriteString("[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "] ")
+	}
+	fmt.Fprintf(&b, "%d/%d pages", p.done, p.total)
+	if p.failed > 0 {
+		fmt.Fprintf(&b, ", %d failed", p.failed)
+	}
+	fmt.Fprintf(&b, ", %d attachments", p.attachments)
+	if eta, ok := p.eta(); ok {
+		fmt.Fprintf(&b, ", ETA %s", eta)
+	}
+	if p.current != "" && p.done < p.total {
+		b.WriteString(" " + p.current)
+	}
+	return b.String()
+}
+
+// eta extrapolates the remaining time from the pages done so far
+func (p *progress) eta() (time.Duration, bool) {
+	if p.done == 0 || p.done >= p.total {
+		return 0, false
+	}
+	elapsed := time.Since(p.started)
+	remaining := time.Duration(float64(elapsed) / float64(p.done) * float64(p.total-p.done))
+	return remaining.Round(time.Second), true
+}
+
+// terminalWidth returns $COLUMNS, or 80 when it is not set
+func terminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return 80
+}
@@ -0,0 +1,89 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"html"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// Redirect stubs left at the old title of a renamed page, see
+// Markdown2Confluence.Redirects
+const (
+	RedirectLink  = "link"
+	RedirectMacro = "macro"
+)
+
+// RedirectLabel labels the stub pages left behind by renames
+const RedirectLabel = "m2c-redirect"
+
+// renamedPage returns the page a file was published to by an earlier run
+// under another title, from m.PublishedPages
+func (m *Markdown2Confluence) renamedPage(f *MarkdownFile) (PageLink, bool) {
+	if m.Redirects == "" || f.Path == StdinPath {
+		return PageLink{}, false
+	}
+	link, ok := m.PublishedPages[pageKey(f.Path, f.Section)]
+	if !ok || link.PageID == "" || link.Title == "" || link.Title == f.Title {
+		return PageLink{}, false
+	}
+	return link, true
+}
+
+// renamedContent fetches the page published under an old title, to update
+// it with the new title instead of creating another page
+func (m *Markdown2Confluence) renamedContent(link PageLink) (confluence.Content, error) {
+	var content confluence.Content
+	page, err := m.client.GetPage(link.PageID, "version", "body.storage")
+	if err != nil {
+		return content, fmt.Errorf("Unable to fetch renamed page %s: %s", link.PageID, err)
+	}
+	content.ID = page.ID
+	content.Type = page.Type
+	content.Title = page.Title
+	content.Version.Number = page.Version.Number
+	content.Body.Storage.Value = page.Body.Storage.Value
+	return content, nil
+}
+
+// createRedirect leaves a stub page at the old title of a renamed page,
+// below the same parent, unless the old title is taken again. It returns the
+// id of the stub, empty when none was created.
+func (m *Markdown2Confluence) createRedirect(oldTitle, newTitle, ancestorID string) (string, error) {
+	_, err := m.client.GetPageByTitle(m.Space, oldTitle, "version")
+	if err == nil {
+		return "", nil
+	}
+	if !errors.Is(err, confluence.ErrPageNotFound) {
+		return "", fmt.Errorf("Unable to check for a page titled %s: %s", oldTitle, err)
+	}
+
+	bp := confluence.CreateContentBodyParameters{}
+	bp.Title = oldTitle
+	bp.Type = "page"
+	bp.Space.Key = m.Space
+	bp.Body.Storage.Representation = "storage"
+	bp.Body.Storage.Value = redirectBody(m.Redirects, newTitle)
+	if ancestorID != "" {
+		bp.Ancestors = append(bp.Ancestors, Ancestor{ID: ancestorID})
+	}
+	content, err := m.client.CreateContent(&bp, nil)
+	if err != nil {
+		return "", fmt.Errorf("Unable to create redirect page %s: %s", oldTitle, err)
+	}
+	return content.ID, m.client.AddLabels(content.ID, []string{RedirectLabel}, confluence.GlobalPrefix)
+}
+
+// redirectBody is the body of a stub page pointing to newTitle: a note with
+// a link, after a redirect macro with RedirectMacro
+func redirectBody(mode, newTitle string) string {
+	link := `<ac:link><ri:page ri:content-title="` + html.EscapeString(newTitle) + `"/></ac:link>`
+	body := `<ac:structured-macro ac:name="note" ac:schema-version="1"><ac:rich-text-body>` +
+		`<p>This page has moved to ` + link + `.</p></ac:rich-text-body></ac:structured-macro>`
+	if mode == RedirectMacro {
+		body = `<ac:structured-macro ac:name="redirect" ac:schema-version="1"><ac:parameter ac:name="location">` +
+			link + `</ac:parameter></ac:structured-macro>` + body
+	}
+	return body
+}
@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// benchmarkSection is a representative part of a documentation page
+const benchmarkSection = `## Section %[1]d
+
+Some *emphasized* and **strong** text with ` + "`inline code`" + `, a [relative link](other-%[1]d.md)
+and a [remote link](https://example.com/%[1]d). A second sentence wraps
+onto a new line to make the paragraph longer, like hand written docs do.
+
+- first item
+- second item with ` + "`code`" + `
+  - nested item
+  - another nested item
+- third item
+
+1. step one
+2. step two
+
+| Name | Type | Description |
+| --- | --- | --- |
+| id | string | The identifier of the thing |
+| count | int | How many there are |
+
+> A quoted note about section %[1]d.
+
+` + "```java" + `
+public void section%[1]d() {
+	for (int i = 0; i < 10; i++) {
+		System.out.println(i);
+	}
+}
+` + "```" + `
+
+` + "```CONFLUENCE-MACRO" + `
+name: info
+  title: Note %[1]d
+` + "```" + `
+
+![diagram](https://example.com/diagram-%[1]d.png)
+
+`
+
+// benchmarkDocument returns a markdown document of about size bytes
+func benchmarkDocument(size int) string {
+	var b strings.Builder
+	b.WriteString("# Benchmark\n\n")
+	for i := 0; b.Len() < size; i++ {
+		fmt.Fprintf(&b, benchmarkSection, i)
+	}
+	return b.String()
+}
+
+func BenchmarkRenderContent(b *testing.B) {
+	for _, size := range []int{16 << 10, 256 << 10, 4 << 20} {
+		doc := benchmarkDocument(size)
+		b.Run(formatByteSize(int64(size)), func(b *testing.B) {
+			b.SetBytes(int64(len(doc)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := renderContent("bench.md", doc, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// renderAllocsPerKB is the budget of allocations per KB of markdown for a
+// representative document
+const renderAllocsPerKB = 400
+
+func TestRenderAllocationBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("renders a large document")
+	}
+	doc := benchmarkDocument(256 << 10)
+	allocs := testing.AllocsPerRun(2, func() {
+		if _, _, err := renderContent("bench.md", doc, false); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if perKB := allocs / float64(len(doc)>>10); perKB > renderAllocsPerKB {
+		t.Errorf("rendering takes %.0f allocations per KB, over the budget of %d", perKB, renderAllocsPerKB)
+	}
+}
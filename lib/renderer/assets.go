@@ -0,0 +1,49 @@
+package renderer
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	// SharedAssets holds local image paths that are attached to the assets
+	// page instead of every page using them
+	SharedAssets = map[string]bool{}
+	// AssetsPageTitle is the title of the page holding SharedAssets
+	AssetsPageTitle = ""
+	// AssetsSpaceKey is the space of the assets page
+	AssetsSpaceKey = ""
+)
+
+// LocalImages returns the local image files referenced by a markdown document
+func LocalImages(filePath string, source []byte) []string {
+	var images []string
+	doc := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader(source))
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if image, ok := n.(*ast.Image); ok && entering {
+			if f, err := localFile(filePath, image.Destination); err == nil && !isDrawioFile(f) {
+				images = append(images, f)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return images
+}
+
+func isSharedAsset(f string) bool {
+	return AssetsPageTitle != "" && SharedAssets[f]
+}
+
+// writeSharedAssetImage references an image attached to the assets page
+func writeSharedAssetImage(w util.BufWriter, f string) {
+	_, _ = w.WriteString(`<ac:image><ri:attachment ri:filename="`)
+	_, _ = w.WriteString(AttachmentName(f))
+	_, _ = w.WriteString(`"><ri:page ri:content-title="`)
+	_, _ = w.Write(util.EscapeHTML([]byte(AssetsPageTitle)))
+	_, _ = w.WriteString(`" ri:space-key="`)
+	_, _ = w.Write(util.EscapeHTML([]byte(AssetsSpaceKey)))
+	_, _ = w.WriteString(`"/></ri:attachment></ac:image>`)
+}
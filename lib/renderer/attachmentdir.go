@@ -0,0 +1,39 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// attachmentDir is the directory of the run that files generated while
+// rendering are written below, the default temporary directory when empty
+var attachmentDir string
+
+// UseAttachmentDir writes the files generated while rendering, such as
+// diagrams, badges, macro bodies and notebook outputs, below one new
+// temporary directory until the returned func removes it with them
+func UseAttachmentDir() (cleanup func()) {
+	dir, err := os.MkdirTemp("", "m2c")
+	if err != nil {
+		return func() {}
+	}
+	previous := attachmentDir
+	attachmentDir = dir
+	return func() {
+		attachmentDir = previous
+		badgeDownloads.Range(func(destination, f interface{}) bool {
+			if strings.HasPrefix(f.(string), dir+string(filepath.Separator)) {
+				badgeDownloads.Delete(destination)
+			}
+			return true
+		})
+		os.RemoveAll(dir)
+	}
+}
+
+// newAttachmentDir creates a directory for generated files below the
+// directory of the run
+func newAttachmentDir(prefix string) (string, error) {
+	return os.MkdirTemp(attachmentDir, prefix)
+}
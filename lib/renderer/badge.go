@@ -0,0 +1,274 @@
+package renderer
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Badge policies
+const (
+	// BadgesKeep publishes badge images as remote images
+	BadgesKeep = "keep"
+	// BadgesStrip leaves badges and the links around them out
+	BadgesStrip = "strip"
+	// BadgesStatus turns badges into status macros showing their label and
+	// message, colored by the message, and keeps badges it can not read
+	BadgesStatus = "status"
+	// BadgesAttach downloads badges and attaches them to the page
+	BadgesAttach = "attach"
+)
+
+var (
+	// Badges is the policy for badge images such as shields.io SVGs
+	Badges = BadgesKeep
+
+	// badgeHosts serve nothing but badges
+	badgeHosts = []string{"shields.io", "badgen.net", "badge.fury.io", "travis-ci.org", "travis-ci.com", "circleci.com"}
+
+	badgeClient = &http.Client{Timeout: 15 * time.Second}
+	// badgeDownloads caches downloaded badges by URL, see fetchBadge
+	badgeDownloads sync.Map
+
+	badgeAriaLabel = regexp.MustCompile(`aria-label="([^"]*)"`)
+	badgeTitle     = regexp.MustCompile(`<title>([^<]*)</title>`)
+	badgeText      = regexp.MustCompile(`<text[^>]*>([^<]+)</text>`)
+)
+
+// badgeColours maps badge messages and shields.io colors to the colours of
+// the status macro
+var badgeColours = map[string]string{
+	"passing": "Green", "passed": "Green", "success": "Green", "succeeded": "Green", "ok": "Green",
+	"failing": "Red", "failed": "Red", "failure": "Red", "error": "Red", "broken": "Red",
+	"pending": "Yellow", "running": "Yellow", "queued": "Yellow", "unstable": "Yellow",
+	"brightgreen": "Green", "green": "Green",
+	"red": "Red", "critical": "Red",
+	"yellow": "Yellow", "yellowgreen": "Yellow", "orange": "Yellow", "important": "Yellow",
+	"blue": "Blue", "informational": "Blue", "lightblue": "Blue", "blueviolet": "Purple",
+	"purple": "Purple",
+}
+
+// isBadge reports whether an image URL points to a badge: an image of a
+// badge service or with badge in its path, like GitHub Actions, GitLab and
+// Codecov badges
+func isBadge(destination []byte) bool {
+	u, err := url.Parse(string(destination))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range badgeHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return strings.Contains(strings.ToLower(u.Path), "badge")
+}
+
+type badgeTransformer struct{}
+
+// NewBadgeTransformer returns an AST transformer removing badge images, the
+// links around them and paragraphs left with nothing but whitespace, for
+// BadgesStrip
+func NewBadgeTransformer() parser.ASTTransformer {
+	return &badgeTransformer{}
+}
+
+func (t *badgeTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var badges []ast.Node
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if img, ok := n.(*ast.Image); ok && entering && isBadge(img.Destination) {
+			badges = append(badges, img)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, n := range badges {
+		parent := n.Parent()
+		parent.RemoveChild(parent, n)
+		for _, ok := parent.(*ast.Link); ok && !parent.HasChildren(); _, ok = parent.(*ast.Link) {
+			n, parent = parent, parent.Parent()
+			parent.RemoveChild(parent, n)
+		}
+		if p, ok := parent.(*ast.Paragraph); ok && blankInline(p, source) {
+			p.Parent().RemoveChild(p.Parent(), p)
+		}
+	}
+}
+
+// blankInline reports whether the inline children of n are whitespace only
+func blankInline(n ast.Node, source []byte) bool {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		t, ok := c.(*ast.Text)
+		if !ok || len(util.TrimLeftSpace(t.Segment.Value(source))) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// renderBadge writes a badge as a status macro or attached image by
+// Badges, and reports false for badges to keep as remote images
+func (r *ConfluenceImageHTMLRender) renderBadge(w util.BufWriter, source []byte, n *ast.Image) bool {
+	switch Badges {
+	case BadgesStatus:
+		label, message, color := staticBadge(n.Destination)
+		if message == "" {
+			f, err := fetchBadge(string(n.Destination))
+			if err != nil {
+				r.diagnostics.warn(WarningImage, r.filePath, source, n, "unable to read badge %s, keeping the image: %s", n.Destination, err)
+				return false
+			}
+			if label, message = badgeSVGText(f); message == "" {
+				return false
+			}
+		}
+		writeStatusMacro(w, label, message, color)
+		return true
+	case BadgesAttach:
+		f, err := fetchBadge(string(n.Destination))
+		if err != nil {
+			r.diagnostics.warn(WarningImage, r.filePath, source, n, "unable to download badge %s, keeping the remote image: %s", n.Destination, err)
+			return false
+		}
+		r.Images = append(r.Images, f)
+		_, _ = w.WriteString(`<ac:image`)
+		writeImageWidth(w, n)
+		writeStrings(w, `><ri:attachment ri:filename="`, AttachmentName(f), `"/></ac:image>`)
+		return true
+	}
+	return false
+}
+
+// writeStatusMacro writes a status macro titled label: message
+func writeStatusMacro(w util.BufWriter, label, message, color string) {
+	colour, ok := badgeColours[strings.ToLower(message)]
+	if !ok {
+		if colour, ok = badgeColours[strings.ToLower(color)]; !ok {
+			colour = "Grey"
+		}
+	}
+	title := message
+	if label != "" {
+		title = label + ": " + message
+	}
+	writeStrings(w, `<ac:structured-macro ac:name="status" ac:schema-version="1"><ac:parameter ac:name="colour">`, colour,
+		`</ac:parameter><ac:parameter ac:name="title">`, string(util.EscapeHTML([]byte(title))), `</ac:parameter></ac:structured-macro>`)
+}
+
+// staticBadge reads the label, message and color of a shields.io static
+// badge, /badge/label-message-color, where -- and __ stand for - and _ and
+// _ for a space
+func staticBadge(destination []byte) (label, message, color string) {
+	u, err := url.Parse(string(destination))
+	if err != nil || !strings.HasSuffix(strings.ToLower(u.Hostname()), "shields.io") || !strings.HasPrefix(u.Path, "/badge/") {
+		return "", "", ""
+	}
+	content := strings.TrimPrefix(u.Path, "/badge/")
+	content = strings.TrimSuffix(content, filepath.Ext(content))
+	content = strings.NewReplacer("--", "\x00", "__", "\x01").Replace(content)
+	unescape := strings.NewReplacer("\x00", "-", "\x01", "_", "_", " ")
+	parts := strings.Split(content, "-")
+	for i := range parts {
+		parts[i] = unescape.Replace(parts[i])
+	}
+	switch len(parts) {
+	case 2:
+		return "", parts[0], parts[1]
+	case 3:
+		return parts[0], parts[1], parts[2]
+	}
+	return "", "", ""
+}
+
+// badgeSVGText reads the label and message of a downloaded SVG badge from
+// its accessible label, its title or its text elements
+func badgeSVGText(f string) (label, message string) {
+	dat, err := os.ReadFile(f)
+	if err != nil {
+		return "", ""
+	}
+	svg := string(dat)
+	var title string
+	if match := badgeAriaLabel.FindStringSubmatch(svg); match != nil {
+		title = match[1]
+	} else if match := badgeTitle.FindStringSubmatch(svg); match != nil {
+		title = match[1]
+	}
+	title = strings.TrimSpace(html.UnescapeString(title))
+	for _, separator := range []string{": ", " - "} {
+		if i := strings.LastIndex(title, separator); i > 0 {
+			return title[:i], title[i+len(separator):]
+		}
+	}
+
+	// badges draw every text twice, once as its shadow
+	var texts []string
+	for _, match := range badgeText.FindAllStringSubmatch(svg, -1) {
+		t := strings.TrimSpace(html.UnescapeString(match[1]))
+		if t != "" && (len(texts) == 0 || texts[len(texts)-1] != t) {
+			texts = append(texts, t)
+		}
+	}
+	if len(texts) == 2 {
+		return texts[0], texts[1]
+	}
+	return "", title
+}
+
+// fetchBadge downloads a badge once per run and returns the file it was
+// saved to
+func fetchBadge(destination string) (string, error) {
+	if f, ok := badgeDownloads.Load(destination); ok {
+		return f.(string), nil
+	}
+	res, err := badgeClient.Get(destination)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s", res.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+
+	extension := ".svg"
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	switch mediaType {
+	case "image/png":
+		extension = ".png"
+	case "image/gif":
+		extension = ".gif"
+	}
+	dir, err := newAttachmentDir("badge")
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(destination))
+	f := filepath.Join(dir, "badge-"+hex.EncodeToString(sum[:])[:12]+extension)
+	if err := os.WriteFile(f, data, 0644); err != nil {
+		return "", err
+	}
+	actual, _ := badgeDownloads.LoadOrStore(destination, f)
+	return actual.(string), nil
+}
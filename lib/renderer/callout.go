@@ -0,0 +1,154 @@
+package renderer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// calloutMacros maps Obsidian callout types to Confluence panel macros
+var calloutMacros = map[string]string{
+	"note":      "info",
+	"info":      "info",
+	"abstract":  "info",
+	"summary":   "info",
+	"tldr":      "info",
+	"todo":      "info",
+	"example":   "info",
+	"quote":     "info",
+	"cite":      "info",
+	"tip":       "tip",
+	"hint":      "tip",
+	"important": "tip",
+	"success":   "tip",
+	"check":     "tip",
+	"done":      "tip",
+	"question":  "note",
+	"help":      "note",
+	"faq":       "note",
+	"warning":   "note",
+	"caution":   "note",
+	"attention": "note",
+	"failure":   "warning",
+	"fail":      "warning",
+	"missing":   "warning",
+	"danger":    "warning",
+	"error":     "warning",
+	"bug":       "warning",
+}
+
+var calloutPattern = regexp.MustCompile(`^\[!([A-Za-z-]+)\][+-]?[ \t]*(.*?)\s*$`)
+
+// KindCallout is the NodeKind of Callout nodes
+var KindCallout = ast.NewNodeKind("Callout")
+
+// Callout is an Obsidian > [!type] Title block quote
+type Callout struct {
+	ast.BaseBlock
+	CalloutType string
+	Title       string
+}
+
+// Kind implements ast.Node.Kind
+func (n *Callout) Kind() ast.NodeKind {
+	return KindCallout
+}
+
+// Dump implements ast.Node.Dump
+func (n *Callout) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"CalloutType": n.CalloutType,
+		"Title":       n.Title,
+	}, nil)
+}
+
+type calloutTransformer struct{}
+
+// NewCalloutTransformer returns an AST transformer that turns callout block
+// quotes into Callout nodes
+func NewCalloutTransformer() parser.ASTTransformer {
+	return &calloutTransformer{}
+}
+
+func (t *calloutTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var quotes []*ast.Blockquote
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if q, ok := n.(*ast.Blockquote); ok && entering {
+			quotes = append(quotes, q)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, q := range quotes {
+		p, ok := q.FirstChild().(*ast.Paragraph)
+		if !ok || p.Lines().Len() == 0 {
+			continue
+		}
+		first := p.Lines().At(0)
+		match := calloutPattern.FindStringSubmatch(string(first.Value(source)))
+		if match == nil {
+			continue
+		}
+
+		callout := &Callout{CalloutType: strings.ToLower(match[1]), Title: match[2]}
+		// drop the inline nodes of the [!type] Title line
+		for c := p.FirstChild(); c != nil; {
+			next := c.NextSibling()
+			if offset, ok := nodeOffset(c); ok && offset >= first.Stop {
+				break
+			}
+			p.RemoveChild(p, c)
+			c = next
+		}
+		if p.ChildCount() == 0 {
+			q.RemoveChild(q, p)
+		}
+
+		for c := q.FirstChild(); c != nil; {
+			next := c.NextSibling()
+			callout.AppendChild(callout, c)
+			c = next
+		}
+		q.Parent().ReplaceChild(q.Parent(), q, callout)
+	}
+}
+
+// ConfluenceCalloutHTMLRender renders Callout nodes as panel macros
+type ConfluenceCalloutHTMLRender struct{}
+
+// NewConfluenceCalloutHTMLRender returns a new ConfluenceCalloutHTMLRender.
+func NewConfluenceCalloutHTMLRender() *ConfluenceCalloutHTMLRender {
+	return &ConfluenceCalloutHTMLRender{}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *ConfluenceCalloutHTMLRender) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindCallout, r.renderCallout)
+}
+
+func (r *ConfluenceCalloutHTMLRender) renderCallout(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Callout)
+	if !entering {
+		_, _ = w.WriteString("</ac:rich-text-body></ac:structured-macro>\n")
+		return ast.WalkContinue, nil
+	}
+
+	macro, ok := calloutMacros[n.CalloutType]
+	if !ok {
+		macro = "info"
+	}
+	writeMacroStart(w, macro)
+	if n.Title != "" {
+		_, _ = w.WriteString(`<ac:parameter ac:name="title">`)
+		_, _ = w.Write(util.EscapeHTML([]byte(n.Title)))
+		_, _ = w.WriteString(`</ac:parameter>`)
+	}
+	_, _ = w.WriteString("<ac:rich-text-body>\n")
+	return ast.WalkContinue, nil
+}
@@ -8,15 +8,22 @@ import (
 )
 
 // ConfluenceCodeBlockHTMLRender is a renderer.NodeRenderer implementation that
-// renders KindCodeBlock nodes.
+// renders KindCodeBlock nodes, indented code blocks, like fenced code blocks
+// without a language.
 type ConfluenceCodeBlockHTMLRender struct {
 	html.Config
+	filePath    string
+	diagnostics *Diagnostics
+	// Attachments collects files generated while rendering that must be uploaded with the page
+	Attachments []string
 }
 
 // NewConfluenceCodeBlockHTMLRender returns a new ConfluenceCodeBlockHTMLRender.
-func NewConfluenceCodeBlockHTMLRender(opts ...html.Option) renderer.NodeRenderer {
+func NewConfluenceCodeBlockHTMLRender(filePath string, diagnostics *Diagnostics, opts ...html.Option) *ConfluenceCodeBlockHTMLRender {
 	r := &ConfluenceCodeBlockHTMLRender{
-		Config: html.NewConfig(),
+		Config:      html.NewConfig(),
+		filePath:    filePath,
+		diagnostics: diagnostics,
 	}
 	for _, opt := range opts {
 		opt.SetHTMLOption(&r.Config)
@@ -33,23 +40,10 @@ func (r *ConfluenceCodeBlockHTMLRender) RegisterFuncs(reg renderer.NodeRendererF
 }
 
 func (r *ConfluenceCodeBlockHTMLRender) renderConfluenceCodeBlock(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
-	if entering {
-		s := `<ac:structured-macro ac:name="code" ac:schema-version="1">`
-		s = s + `<ac:parameter ac:name="theme">Confluence</ac:parameter>`
-		s = s + `<ac:plain-text-body><![CDATA[`
-		_, _ = w.WriteString(s)
-		r.writeLines(w, source, n)
-	} else {
-		s := `]]></ac:plain-text-body></ac:structured-macro>`
-		_, _ = w.WriteString(s)
+	attachments, err := renderCode(w, r.filePath, r.diagnostics, source, n, "", defaultCodeOptions(), entering)
+	if err != nil {
+		return ast.WalkStop, newPositionError(r.filePath, source, n, err)
 	}
+	r.Attachments = append(r.Attachments, attachments...)
 	return ast.WalkContinue, nil
 }
-
-func (r *ConfluenceCodeBlockHTMLRender) writeLines(w util.BufWriter, source []byte, n ast.Node) {
-	l := n.Lines().Len()
-	for i := 0; i < l; i++ {
-		line := n.Lines().At(i)
-		w.WriteString(string(line.Value(source)))
-	}
-}
@@ -0,0 +1,179 @@
+package renderer
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// CodeBlockThemes are the themes of the code macro
+var CodeBlockThemes = []string{"Confluence", "DJango", "Eclipse", "Emacs", "FadeToGrey", "Midnight", "RDark"}
+
+// codeMacroOptions are the parameters of a code macro
+type codeMacroOptions struct {
+	Theme       string
+	LineNumbers bool
+	Wrap        bool
+}
+
+// defaultCodeOptions returns the code macro parameters of code blocks
+// without options
+func defaultCodeOptions() codeMacroOptions {
+	return codeMacroOptions{Theme: CodeBlockTheme, LineNumbers: CodeBlockShowLineNumbers, Wrap: CodeBlockWrap}
+}
+
+// codeOptions returns the code macro parameters of a fenced code block: the
+// CodeBlockTheme, CodeBlockShowLineNumbers and CodeBlockWrap defaults,
+// overridden by the words after the language in its info string, like
+// ```go theme=Midnight linenumbers=false wrap. Braces around them, as in
+// ```go {wrap}, are allowed. Unknown words are reported and ignored.
+func (r *ConfluenceFencedCodeBlockHTMLRender) codeOptions(source []byte, n *ast.FencedCodeBlock) codeMacroOptions {
+	options := defaultCodeOptions()
+	if n.Info == nil {
+		return options
+	}
+	fields := strings.Fields(string(n.Info.Text(source)))
+	// an info string of whitespace has no language, let alone options
+	if len(fields) < 2 {
+		return options
+	}
+	for _, field := range fields[1:] {
+		field = strings.Trim(field, "{},")
+		if field == "" {
+			continue
+		}
+		key, value, hasValue := strings.Cut(field, "=")
+		value = strings.Trim(value, `"'`)
+		switch strings.ToLower(key) {
+		case "theme":
+			options.Theme = codeTheme(value)
+			if options.Theme == "" {
+				r.diagnostics.warn(WarningCode, r.filePath, source, n, "unknown code block theme %q, use one of %s", value, strings.Join(CodeBlockThemes, ", "))
+				options.Theme = CodeBlockTheme
+			}
+			continue
+		case "linenumbers":
+			if on, ok := codeSwitch(value, hasValue); ok {
+				options.LineNumbers = on
+				continue
+			}
+		case "wrap":
+			if on, ok := codeSwitch(value, hasValue); ok {
+				options.Wrap = on
+				continue
+			}
+		}
+		r.diagnostics.warn(WarningCode, r.filePath, source, n, "ignoring code block option %q, use theme=, linenumbers= or wrap=", field)
+	}
+	return options
+}
+
+// codeSwitch reads the value of an on/off option, which is on when given
+// without a value
+func codeSwitch(value string, hasValue bool) (bool, bool) {
+	if !hasValue {
+		return true, true
+	}
+	on, err := strconv.ParseBool(value)
+	return on, err == nil
+}
+
+// codeTheme returns the code macro theme named name in any case, or ""
+func codeTheme(name string) string {
+	for _, theme := range CodeBlockThemes {
+		if strings.EqualFold(theme, name) {
+			return theme
+		}
+	}
+	return ""
+}
+
+// writeCodeParameters writes the theme, line numbers and wrap parameters of
+// a code macro. Wrapping is only written when on, so code blocks without it
+// keep their storage format.
+func writeCodeParameters(w io.StringWriter, options codeMacroOptions) {
+	writeParameter(w, "theme", options.Theme)
+	writeParameter(w, "linenumbers", strconv.FormatBool(options.LineNumbers))
+	if options.Wrap {
+		writeParameter(w, "wrap", "true")
+	}
+}
+
+// renderCode renders a fenced or indented code block in language, "" if it
+// has none: as notebook output, highlighted HTML, an attached file or a code
+// macro with options. It returns the files to attach.
+func renderCode(w util.BufWriter, filePath string, diagnostics *Diagnostics, source []byte, n ast.Node, language string, options codeMacroOptions, entering bool) ([]string, error) {
+	if Notebook && language == "" {
+		if entering {
+			renderNotebookOutput(w, codeLines(source, n))
+		}
+		return nil, nil
+	}
+	if shouldHighlight(language) {
+		if entering {
+			return nil, renderHighlightedCode(w, language, codeLines(source, n))
+		}
+		return nil, nil
+	}
+	if shouldAttachCodeBlock(n.Lines().Len()) {
+		if entering {
+			return renderCodeBlockAttachment(w, language, codeLines(source, n))
+		}
+		return nil, nil
+	}
+
+	if !entering {
+		_ = w.WriteByte(' ')
+		_, _ = w.Write(plainTextBodyEnd)
+		_, _ = w.Write(macroEnd)
+		if isRunbookCodeBlock(language) {
+			writeCopyBlock(w, runbookCommands(language, codeLines(source, n)))
+		}
+		return nil, nil
+	}
+	if isRunbookCodeBlock(language) {
+		writeDestructiveWarning(w, runbookCommands(language, codeLines(source, n)))
+	}
+	writeMacroStart(w, "code")
+	writeCodeParameters(w, options)
+	writeParameter(w, "collapse", strconv.FormatBool(shouldCollapseCodeBlock(n.Lines().Len()) || collapsed(n)))
+	if language != "" {
+		supportedLanguage, ok := getSupportLanguage(strings.ToLower(language))
+		if !ok {
+			diagnostics.warn(WarningLanguage, filePath, source, n, "Unsupported code block language: %s,Use %s default", language, DefaultCodeBlockLanguage)
+		}
+		writeParameter(w, "language", supportedLanguage)
+	}
+	_, _ = w.Write(plainTextBodyStart)
+	_ = w.WriteByte(' ')
+	writeCodeLines(w, source, n)
+	return nil, nil
+}
+
+// writeCodeLines writes the lines of a code block into an open CDATA section
+func writeCodeLines(w util.BufWriter, source []byte, n ast.Node) {
+	l := n.Lines().Len()
+	for i := 0; i < l; i++ {
+		line := n.Lines().At(i)
+		writeCDATAContent(w, line.Value(source))
+	}
+}
+
+// codeLines returns the lines of a code block
+func codeLines(source []byte, n ast.Node) []byte {
+	l := n.Lines().Len()
+	size := 0
+	for i := 0; i < l; i++ {
+		line := n.Lines().At(i)
+		size += line.Len()
+	}
+	b := make([]byte, 0, size)
+	for i := 0; i < l; i++ {
+		line := n.Lines().At(i)
+		b = append(b, line.Value(source)...)
+	}
+	return b
+}
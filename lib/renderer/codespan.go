@@ -0,0 +1,83 @@
+package renderer
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// Inline code styles
+const (
+	// InlineCodeCode renders code spans as code elements
+	InlineCodeCode = "code"
+	// InlineCodeMonospace renders code spans as text in a monospace font,
+	// without the background some Confluence themes give code elements
+	InlineCodeMonospace = "monospace"
+	// InlineCodeStatus renders code spans as subtle grey status macros
+	InlineCodeStatus = "status"
+)
+
+// InlineCode is the style of code spans
+var InlineCode = InlineCodeCode
+
+// ConfluenceCodeSpanHTMLRender renders code spans in the InlineCode style
+type ConfluenceCodeSpanHTMLRender struct{}
+
+// NewConfluenceCodeSpanHTMLRender returns a new ConfluenceCodeSpanHTMLRender.
+func NewConfluenceCodeSpanHTMLRender() renderer.NodeRenderer {
+	return &ConfluenceCodeSpanHTMLRender{}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *ConfluenceCodeSpanHTMLRender) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindCodeSpan, r.renderCodeSpan)
+}
+
+func (r *ConfluenceCodeSpanHTMLRender) renderCodeSpan(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	code := util.EscapeHTML(codeSpanText(source, n))
+	switch {
+	case InlineCode == InlineCodeStatus && !inLink(n):
+		// macros can not be put in links, they keep the code element
+		writeMacroStart(w, "status")
+		writeParameter(w, "colour", "Grey")
+		writeParameter(w, "subtle", "true")
+		writeStrings(w, `<ac:parameter ac:name="title">`, string(code), `</ac:parameter></ac:structured-macro>`)
+	case InlineCode == InlineCodeMonospace:
+		writeStrings(w, `<span style="font-family: monospace;">`, string(code), `</span>`)
+	default:
+		writeStrings(w, `<code>`, string(code), `</code>`)
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// codeSpanText returns the text of a code span, with line endings turned
+// into spaces like the goldmark HTML renderer does
+func codeSpanText(source []byte, n ast.Node) []byte {
+	var b bytes.Buffer
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		value := c.(*ast.Text).Segment.Value(source)
+		if bytes.HasSuffix(value, []byte("\n")) {
+			b.Write(value[:len(value)-1])
+			b.WriteByte(' ')
+		} else {
+			b.Write(value)
+		}
+	}
+	return b.Bytes()
+}
+
+// inLink reports whether n is inside a link
+func inLink(n ast.Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		switch p.Kind() {
+		case ast.KindLink, ast.KindAutoLink:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package renderer
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Deterministic makes identical input render to byte-identical storage
+// format on every machine, see Canonicalize. Features that would add run
+// specific content such as timestamps must leave it out when set.
+var Deterministic = false
+
+var (
+	// storageStartTag matches the start tag of a Confluence element
+	storageStartTag = regexp.MustCompile(`<((?:ac|ri):[A-Za-z-]+)((?:\s+[A-Za-z:-]+="[^"]*")*)\s*(/?)>`)
+	storageAttr     = regexp.MustCompile(`([A-Za-z:-]+)="([^"]*)"`)
+)
+
+// Canonicalize normalizes rendered storage format: line endings become \n,
+// the attributes of Confluence elements are sorted and every macro gets an
+// ac:macro-id derived from its name and position, so Confluence does not
+// assign random ones. CDATA sections are left untouched.
+func Canonicalize(storage string) string {
+	storage = strings.ReplaceAll(storage, "\r\n", "\n")
+
+	seen := map[string]int{}
+	var b strings.Builder
+	for {
+		start := strings.Index(storage, "<![CDATA[")
+		if start < 0 {
+			b.WriteString(canonicalizeTags(storage, seen))
+			return b.String()
+		}
+		end := strings.Index(storage[start:], "]]>")
+		if end < 0 {
+			end = len(storage) - start
+		} else {
+			end += len("]]>")
+		}
+		b.WriteString(canonicalizeTags(storage[:start], seen))
+		b.WriteString(storage[start : start+end])
+		storage = storage[start+end:]
+	}
+}
+
+func canonicalizeTags(s string, seen map[string]int) string {
+	return storageStartTag.ReplaceAllStringFunc(s, func(tag string) string {
+		m := storageStartTag.FindStringSubmatch(tag)
+		attrs := map[string]string{}
+		for _, a := range storageAttr.FindAllStringSubmatch(m[2], -1) {
+			attrs[a[1]] = a[2]
+		}
+		if m[1] == "ac:structured-macro" {
+			name := attrs["ac:name"]
+			attrs["ac:macro-id"] = stableMacroID(name, seen[name])
+			seen[name]++
+		}
+
+		keys := make([]string, 0, len(attrs))
+		for k := range attrs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var b strings.Builder
+		b.WriteString("<" + m[1])
+		for _, k := range keys {
+			b.WriteString(" " + k + `="` + attrs[k] + `"`)
+		}
+		b.WriteString(m[3] + ">")
+		return b.String()
+	})
+}
+
+// stableMacroID returns a UUID for the nth macro of a name on a page
+func stableMacroID(name string, n int) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s/%d", name, n)))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
@@ -0,0 +1,171 @@
+package renderer
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// WarningClass classifies the warnings about markdown that can not be
+// published as it is
+type WarningClass string
+
+const (
+	// WarningLanguage is a code block language Confluence can not highlight
+	WarningLanguage WarningClass = "language"
+	// WarningHTML is raw HTML left out of the page, or an HTML table that can
+	// not be converted
+	WarningHTML WarningClass = "html"
+	// WarningLink is a link that can not be resolved to a page or heading
+	WarningLink WarningClass = "link"
+	// WarningImage is an image or badge that can not be attached, or an
+	// attachment over the size limit
+	WarningImage WarningClass = "image"
+	// WarningDirective is a m2c directive or draft marker that is not understood
+	WarningDirective WarningClass = "directive"
+	// WarningCode is a code block option that is not understood
+	WarningCode WarningClass = "code"
+	// WarningMacro is a CONFLUENCE-MACRO block not matching the macro schema
+	WarningMacro WarningClass = "macro"
+)
+
+// WarningClasses are all warning classes
+var WarningClasses = []WarningClass{WarningLanguage, WarningHTML, WarningLink, WarningImage, WarningDirective, WarningCode, WarningMacro}
+
+// WarningLevel is what happens to the warnings of a class
+type WarningLevel string
+
+const (
+	// WarningLevelIgnore does not report warnings
+	WarningLevelIgnore WarningLevel = "ignore"
+	// WarningLevelWarn prints warnings and goes on
+	WarningLevelWarn WarningLevel = "warn"
+	// WarningLevelError fails the file with Strict, and prints warnings
+	// without it
+	WarningLevelError WarningLevel = "error"
+)
+
+var (
+	// Strict fails the files with warnings of the classes WarningPolicy does
+	// not lower to warn or ignore
+	Strict = false
+	// WarningPolicy is the level of warning classes. Classes it does not list
+	// are errors with Strict and warnings without it.
+	WarningPolicy = map[WarningClass]WarningLevel{}
+)
+
+// ParseWarningClass returns the warning class named name
+func ParseWarningClass(name string) (WarningClass, error) {
+	for _, class := range WarningClasses {
+		if string(class) == name {
+			return class, nil
+		}
+	}
+	names := make([]string, len(WarningClasses))
+	for i, class := range WarningClasses {
+		names[i] = string(class)
+	}
+	return "", fmt.Errorf("unknown warning class %q, use one of %s", name, strings.Join(names, ", "))
+}
+
+// ParseWarningLevel returns the warning level named name
+func ParseWarningLevel(name string) (WarningLevel, error) {
+	switch level := WarningLevel(name); level {
+	case WarningLevelIgnore, WarningLevelWarn, WarningLevelError:
+		return level, nil
+	}
+	return "", fmt.Errorf("unknown warning level %q, use ignore, warn or error", name)
+}
+
+// Level returns what happens to warnings of the class under the warning
+// policy and Strict
+func (c WarningClass) Level() WarningLevel {
+	level, ok := WarningPolicy[c]
+	if !ok {
+		level = WarningLevelError
+	}
+	if level == WarningLevelError && !Strict {
+		return WarningLevelWarn
+	}
+	return level
+}
+
+// Warning is a problem with markdown that is published anyway, or fails its
+// file with Strict
+type Warning struct {
+	Class    WarningClass
+	Position Position
+	Message  string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Position, w.Message)
+}
+
+// Diagnostics collects the warnings of one render. Each render has its own,
+// so files rendered at the same time, and pages split off the same file, only
+// fail with their own warnings. A nil Diagnostics prints warnings and records
+// none.
+type Diagnostics struct {
+	mu       sync.Mutex
+	warnings []Warning
+}
+
+// NewDiagnostics returns an empty Diagnostics
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{}
+}
+
+// Warn reports a warning: it prints it, records it to fail the render when it
+// is an error, or drops it when its class is ignored
+func (d *Diagnostics) Warn(w Warning) {
+	switch level := w.Class.Level(); {
+	case level == WarningLevelWarn, level == WarningLevelError && d == nil:
+		fmt.Fprintln(os.Stderr, w.String())
+	case level == WarningLevelError:
+		d.mu.Lock()
+		d.warnings = append(d.warnings, w)
+		d.mu.Unlock()
+	}
+}
+
+// warn reports a warning about node n
+func (d *Diagnostics) warn(class WarningClass, filePath string, source []byte, n ast.Node, format string, args ...interface{}) {
+	d.Warn(Warning{Class: class, Position: nodePosition(filePath, source, n), Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns the error of the recorded warnings, or nil when there are none
+func (d *Diagnostics) Err() error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	failed := append([]Warning(nil), d.warnings...)
+	d.mu.Unlock()
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.SliceStable(failed, func(i, j int) bool {
+		a, b := failed[i].Position, failed[j].Position
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return &WarningsError{Warnings: failed}
+}
+
+// WarningsError fails a file with warnings that are errors under the warning
+// policy with Strict
+type WarningsError struct {
+	Warnings []Warning
+}
+
+func (e *WarningsError) Error() string {
+	lines := make([]string, len(e.Warnings))
+	for i, w := range e.Warnings {
+		lines[i] = fmt.Sprintf("%s [%s]", w, w.Class)
+	}
+	return fmt.Sprintf("%d strict warning(s):\n\t%s", len(lines), strings.Join(lines, "\n\t"))
+}
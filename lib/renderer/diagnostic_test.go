@@ -0,0 +1,54 @@
+package renderer_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/yuin/goldmark"
+
+	e "github.com/justmiles/go-markdown2confluence/lib/extension"
+	r "github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// TestUnknownMacroWarning reports a CONFLUENCE-MACRO block of a macro
+// without schema as a macro warning, which fails the render with --strict
+// unless the warning policy ignores it
+func TestUnknownMacroWarning(t *testing.T) {
+	source := []byte("# Macro\n\n```CONFLUENCE-MACRO\nname: not-a-macro\n```\n")
+	tests := []struct {
+		name   string
+		policy map[r.WarningClass]r.WarningLevel
+		failed bool
+	}{
+		{name: "strict", policy: map[r.WarningClass]r.WarningLevel{}, failed: true},
+		{name: "ignored", policy: map[r.WarningClass]r.WarningLevel{r.WarningMacro: r.WarningLevelIgnore}, failed: false},
+	}
+	defer set(&r.Strict, true)()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer set(&r.WarningPolicy, test.policy)()
+			confluence := e.NewConfluenceExtension("macro.md")
+			md := goldmark.New(goldmark.WithExtensions(confluence))
+			if err := md.Convert(source, &bytes.Buffer{}); err != nil {
+				t.Fatal(err)
+			}
+
+			var warnings *r.WarningsError
+			err := confluence.Diagnostics().Err()
+			if !test.failed {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if !errors.As(err, &warnings) || len(warnings.Warnings) != 1 {
+				t.Fatalf("expected one warning, got %v", err)
+			}
+			w := warnings.Warnings[0]
+			if w.Class != r.WarningMacro || w.Position.Line != 3 || w.Message != `CONFLUENCE-MACRO: unknown macro "not-a-macro", parameters can not be validated` {
+				t.Errorf("unexpected warning %s [%s]", w, w.Class)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package renderer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// directiveComment matches an HTML comment holding renderer hints for the
+// block after it, e.g. <!-- m2c:collapse width=800 -->
+var directiveComment = regexp.MustCompile(`^<!--\s*m2c:\s*(.*?)\s*-->$`)
+
+// directiveWidth matches the value of a width directive, in pixels
+var directiveWidth = regexp.MustCompile(`^\d+(?:px)?$`)
+
+// Directive attributes set on the nodes a directive applies to
+const (
+	attributeCollapse = "m2c-collapse"
+	attributeWidth    = "width"
+)
+
+// KindExpand is the NodeKind of Expand nodes
+var KindExpand = ast.NewNodeKind("Expand")
+
+// Expand is a block collapsed by a collapse directive, rendered as an expand
+// macro
+type Expand struct {
+	ast.BaseBlock
+	Title string
+}
+
+// Kind implements ast.Node.Kind
+func (n *Expand) Kind() ast.NodeKind {
+	return KindExpand
+}
+
+// Dump implements ast.Node.Dump
+func (n *Expand) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Title": n.Title}, nil)
+}
+
+type directiveTransformer struct {
+	filePath    string
+	diagnostics *Diagnostics
+}
+
+// NewDirectiveTransformer returns an AST transformer applying the renderer
+// hints of <!-- m2c:... --> comments to the block right after them:
+//
+//   - skip leaves the block out
+//   - collapse collapses code blocks, or puts other blocks in an expand
+//     macro, titled by its value: collapse="Full output"
+//   - width=800 sets the width of the images of the block
+//
+// Hints can be combined in one comment. The comments are removed.
+func NewDirectiveTransformer(filePath string, diagnostics *Diagnostics) parser.ASTTransformer {
+	return &directiveTransformer{filePath: filePath, diagnostics: diagnostics}
+}
+
+func (t *directiveTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var comments []*ast.HTMLBlock
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if b, ok := n.(*ast.HTMLBlock); ok && entering && b.HTMLBlockType == ast.HTMLBlockType2 {
+			comments = append(comments, b)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, c := range comments {
+		match := directiveComment.FindStringSubmatch(strings.TrimSpace(htmlBlockText(source, c)))
+		if match == nil {
+			continue
+		}
+		position := nodePosition(t.filePath, source, c)
+		target := c.NextSibling()
+		c.Parent().RemoveChild(c.Parent(), c)
+		if target == nil {
+			t.diagnostics.Warn(Warning{Class: WarningDirective, Position: position, Message: "m2c directive without a block after it"})
+			continue
+		}
+		for _, d := range parseDirectives(match[1]) {
+			if !t.apply(target, d[0], d[1], position) {
+				break
+			}
+		}
+	}
+}
+
+// apply applies a directive to the node it precedes, false once the node is
+// removed
+func (t *directiveTransformer) apply(n ast.Node, name, value string, position Position) bool {
+	switch name {
+	case "skip":
+		n.Parent().RemoveChild(n.Parent(), n)
+		return false
+	case "collapse":
+		switch n.Kind() {
+		case ast.KindFencedCodeBlock, ast.KindCodeBlock:
+			n.SetAttributeString(attributeCollapse, true)
+		default:
+			expand := &Expand{Title: value}
+			n.Parent().ReplaceChild(n.Parent(), n, expand)
+			expand.AppendChild(expand, n)
+		}
+	case "width":
+		if !directiveWidth.MatchString(value) {
+			t.diagnostics.Warn(Warning{Class: WarningDirective, Position: position, Message: fmt.Sprintf("invalid m2c width %q, expected pixels", value)})
+			return true
+		}
+		width := []byte(strings.TrimSuffix(value, "px"))
+		images := 0
+		_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+			if c.Kind() == ast.KindImage && entering {
+				c.SetAttributeString(attributeWidth, width)
+				images++
+			}
+			return ast.WalkContinue, nil
+		})
+		if images == 0 {
+			t.diagnostics.Warn(Warning{Class: WarningDirective, Position: position, Message: "m2c width applies to images, the next block has none"})
+		}
+	default:
+		t.diagnostics.Warn(Warning{Class: WarningDirective, Position: position, Message: fmt.Sprintf("unknown m2c directive %q, use skip, collapse or width", name)})
+	}
+	return true
+}
+
+// parseDirectives splits the hints of a directive comment into names and
+// values: collapse="Full log" width=800
+func parseDirectives(s string) [][2]string {
+	var directives [][2]string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		end := strings.IndexAny(s, " \t,=")
+		if end < 0 {
+			end = len(s)
+		}
+		d := [2]string{s[:end]}
+		s = s[end:]
+		if strings.HasPrefix(s, "=") {
+			s = s[1:]
+			if s != "" && (s[0] == '"' || s[0] == '\'') {
+				q := s[:1]
+				if i := strings.Index(s[1:], q); i >= 0 {
+					d[1], s = s[1:i+1], s[i+2:]
+				} else {
+					d[1], s = s[1:], ""
+				}
+			} else {
+				end = strings.IndexAny(s, " \t,")
+				if end < 0 {
+					end = len(s)
+				}
+				d[1], s = s[:end], s[end:]
+			}
+		}
+		s = strings.TrimLeft(s, ", \t")
+		if d[0] != "" {
+			directives = append(directives, d)
+		}
+	}
+	return directives
+}
+
+// htmlBlockText returns the source of an HTML block
+func htmlBlockText(source []byte, n *ast.HTMLBlock) string {
+	var b strings.Builder
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		b.Write(line.Value(source))
+	}
+	if n.HasClosure() {
+		b.Write(n.ClosureLine.Value(source))
+	}
+	return b.String()
+}
+
+// collapsed tells whether a collapse directive applies to a code block
+func collapsed(n ast.Node) bool {
+	_, ok := n.AttributeString(attributeCollapse)
+	return ok
+}
+
+// writeImageWidth writes the width a directive set for an image
+func writeImageWidth(w util.BufWriter, n ast.Node) {
+	if width, ok := n.AttributeString(attributeWidth); ok {
+		_, _ = w.WriteString(` ac:width="`)
+		_, _ = w.Write(width.([]byte))
+		_ = w.WriteByte('"')
+	}
+}
+
+// ConfluenceExpandHTMLRender renders Expand nodes as expand macros
+type ConfluenceExpandHTMLRender struct{}
+
+// NewConfluenceExpandHTMLRender returns a new ConfluenceExpandHTMLRender.
+func NewConfluenceExpandHTMLRender() *ConfluenceExpandHTMLRender {
+	return &ConfluenceExpandHTMLRender{}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *ConfluenceExpandHTMLRender) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindExpand, r.renderExpand)
+}
+
+func (r *ConfluenceExpandHTMLRender) renderExpand(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Expand)
+	if !entering {
+		_, _ = w.WriteString("</ac:rich-text-body></ac:structured-macro>\n")
+		return ast.WalkContinue, nil
+	}
+	_, _ = w.WriteString(`<ac:structured-macro ac:name="expand" ac:schema-version="1">`)
+	if n.Title != "" {
+		_, _ = w.WriteString(`<ac:parameter ac:name="title">`)
+		_, _ = w.Write(util.EscapeHTML([]byte(n.Title)))
+		_, _ = w.WriteString(`</ac:parameter>`)
+	}
+	_, _ = w.WriteString("<ac:rich-text-body>\n")
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,76 @@
+package renderer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	// DrawioMacro renders .drawio references with the draw.io app macro. When
+	// false the diagram is exported to PNG and embedded as a regular image.
+	DrawioMacro = true
+	// DrawioCommand is the draw.io desktop binary used for PNG exports
+	DrawioCommand = "drawio"
+)
+
+const drawioExtension = ".drawio"
+
+func isDrawioFile(f string) bool {
+	return strings.EqualFold(filepath.Ext(f), drawioExtension)
+}
+
+// AttachmentName returns the name go-confluence stores an attachment under,
+// it stays the same when the file changes so the attachment gets versions
+func AttachmentName(f string) string {
+	return path.Base(f)
+}
+
+// renderDrawio writes a drawio macro referencing the attached diagram, or an
+// image of its PNG export when the macro is disabled. It returns the files
+// that need to be attached to the page.
+func renderDrawio(w util.BufWriter, f string) ([]string, error) {
+	if !DrawioMacro {
+		png, err := exportDrawioPNG(f)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = w.WriteString(`<ac:image><ri:attachment ri:filename="`)
+		_, _ = w.WriteString(AttachmentName(png))
+		_, _ = w.WriteString(`"/></ac:image>`)
+		return []string{png}, nil
+	}
+
+	writeMacroStart(w, "drawio")
+	writeParameter(w, "diagramName", AttachmentName(f))
+	writeParameter(w, "simpleViewer", "false")
+	writeParameter(w, "lbox", "true")
+	writeParameter(w, "revision", "1")
+	_, _ = w.Write(macroEnd)
+	return []string{f}, nil
+}
+
+// exportDrawioPNG returns a PNG rendering of a .drawio file. A pre-exported
+// sibling (diagram.drawio.png) is preferred, otherwise DrawioCommand is used.
+func exportDrawioPNG(f string) (string, error) {
+	sibling := f + ".png"
+	if _, err := os.Stat(sibling); err == nil {
+		return sibling, nil
+	}
+
+	dir, err := newAttachmentDir("drawio")
+	if err != nil {
+		return "", err
+	}
+	out := filepath.Join(dir, strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))+".png")
+	cmd := exec.Command(DrawioCommand, "--export", "--format", "png", "--output", out, f)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("unable to export %s to png with %s: %s\n%s", f, DrawioCommand, err, output)
+	}
+	return out, nil
+}
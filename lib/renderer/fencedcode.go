@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
-	"strconv"
+	"io"
 	"strings"
 
 	"github.com/yuin/goldmark/ast"
@@ -19,6 +19,10 @@ import (
 type ConfluenceFencedCodeBlockHTMLRender struct {
 	html.Config
 	MacroContentKeys map[string]struct{}
+	filePath         string
+	diagnostics      *Diagnostics
+	// Attachments collects files generated while rendering that must be uploaded with the page
+	Attachments []string
 }
 
 const (
@@ -37,7 +41,8 @@ var (
 	DefaultCodeBlockLanguage    = "plain"
 	CodeBlockTheme              = "RDark"
 	CodeBlockShowLineNumbers    = true
-	CodeBlockCollapse           = true
+	CodeBlockCollapse           = false
+	CodeBlockWrap               = false
 )
 
 var (
@@ -48,9 +53,11 @@ var (
 )
 
 // NewConfluenceFencedCodeBlockHTMLRender returns a new ConfluenceFencedCodeBlockHTMLRender.
-func NewConfluenceFencedCodeBlockHTMLRender(opts ...html.Option) renderer.NodeRenderer {
+func NewConfluenceFencedCodeBlockHTMLRender(filePath string, diagnostics *Diagnostics, opts ...html.Option) *ConfluenceFencedCodeBlockHTMLRender {
 	r := &ConfluenceFencedCodeBlockHTMLRender{
-		Config: html.NewConfig(),
+		Config:      html.NewConfig(),
+		filePath:    filePath,
+		diagnostics: diagnostics,
 		MacroContentKeys: map[string]struct{}{
 			MacroContentKeyPlainTextBody: {},
 			MacroContentKeyRichTextBody:  {},
@@ -68,6 +75,14 @@ func (r *ConfluenceFencedCodeBlockHTMLRender) RegisterFuncs(reg renderer.NodeRen
 }
 
 func (r *ConfluenceFencedCodeBlockHTMLRender) renderConfluenceFencedCode(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	status, err := r.renderFencedCode(w, source, node, entering)
+	if err != nil {
+		return status, newPositionError(r.filePath, source, node, err)
+	}
+	return status, nil
+}
+
+func (r *ConfluenceFencedCodeBlockHTMLRender) renderFencedCode(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ast.FencedCodeBlock)
 	language := n.Language(source)
 	// Initialize the language string with an ampty string
@@ -76,35 +91,55 @@ func (r *ConfluenceFencedCodeBlockHTMLRender) renderConfluenceFencedCode(w util.
 	if language != nil {
 		langString = string(language)
 	}
-	if isPlantUmlCodeBlock(langString) {
-		return renderPlantUmlCodeBlock(w, source, node, entering)
+	if mapping, ok := getMacroMapping(langString); ok {
+		if entering {
+			attachments, err := renderMappedMacro(w, mapping, codeLines(source, n))
+			if err != nil {
+				return ast.WalkStop, err
+			}
+			r.Attachments = append(r.Attachments, attachments...)
+		}
+		return ast.WalkContinue, nil
 	}
-	switch langString {
-	case LanguageStringConfluenceMacro:
+	if isOpenAPICodeBlock(langString) {
 		if entering {
-			r.writeMacro(w, source, n)
+			if err := renderOpenAPI(w, r.filePath, codeLines(source, n)); err != nil {
+				return ast.WalkStop, err
+			}
 		}
-	default:
+		return ast.WalkContinue, nil
+	}
+	if diagramType, ok := getKrokiDiagramType(langString); ok {
 		if entering {
-			// insert a code-macro
-			s := `<ac:structured-macro ac:name="code" ac:schema-version="1">`
-			s = s + `<ac:parameter ac:name="theme">` + CodeBlockTheme + `</ac:parameter>`
-			s = s + `<ac:parameter ac:name="linenumbers">` + strconv.FormatBool(CodeBlockShowLineNumbers) + `</ac:parameter>`
-			s = s + `<ac:parameter ac:name="collapse">` + strconv.FormatBool(CodeBlockCollapse) + `</ac:parameter>`
-
-			if language != nil {
-				supportedLanguage := getSupportLanguage(strings.ToLower(langString))
-				s = s + `<ac:parameter ac:name="language">` + supportedLanguage + `</ac:parameter>`
+			attachments, err := renderKrokiDiagram(w, diagramType, codeLines(source, n))
+			if err != nil {
+				return ast.WalkStop, err
+			}
+			r.Attachments = append(r.Attachments, attachments...)
+		}
+		return ast.WalkContinue, nil
+	}
+	if isPlantUmlCodeBlock(langString) {
+		return renderPlantUmlCodeBlock(w, source, node, entering)
+	}
+	if langString == LanguageStringConfluenceMacro {
+		if entering {
+			if err := r.writeMacro(w, source, n); err != nil {
+				return ast.WalkStop, err
 			}
-
-			s = s + `<ac:plain-text-body><![CDATA[ `
-			_, _ = w.WriteString(s)
-			r.writeLines(w, source, n)
-		} else {
-			s := ` ]]></ac:plain-text-body></ac:structured-macro>`
-			_, _ = w.WriteString(s)
 		}
+		return ast.WalkContinue, nil
 	}
+	// options are only written, and warned about, on entering
+	var options codeMacroOptions
+	if entering {
+		options = r.codeOptions(source, n)
+	}
+	attachments, err := renderCode(w, r.filePath, r.diagnostics, source, n, langString, options, entering)
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	r.Attachments = append(r.Attachments, attachments...)
 	return ast.WalkContinue, nil
 }
 
@@ -112,46 +147,96 @@ func renderPlantUmlCodeBlock(w util.BufWriter, source []byte, node ast.Node, ent
 	n := node.(*ast.FencedCodeBlock)
 	if entering {
 		// insert a code-macro
-		s := `<ac:structured-macro ac:name="plantumlrender" ac:schema-version="1">`
-		s = s + `<ac:parameter ac:name="format">` + DefaultPlantUmlShowFormat + `</ac:parameter>`
-		s = s + `<ac:parameter ac:name="atlassian-macro-output-type">INLINE</ac:parameter>`
-		s = s + `<ac:rich-text-body><p style="text-align: left;">`
-		_, _ = w.WriteString(s)
+		writeMacroStart(w, "plantumlrender")
+		writeParameter(w, "format", DefaultPlantUmlShowFormat)
+		writeParameter(w, "atlassian-macro-output-type", "INLINE")
+		_, _ = w.WriteString(`<ac:rich-text-body><p style="text-align: left;">`)
 		l := n.Lines().Len()
-		content := strings.Builder{}
 		for i := 0; i < l; i++ {
 			line := n.Lines().At(i)
-			text := string(line.Value(source))
-			escapedLine := template.HTMLEscapeString(text)
-			wrappedLine := fmt.Sprintf("<code class=\"plain\" style=\"text-align: left;\">%s</code><br/>", escapedLine)
-			content.WriteString(wrappedLine)
+			_, _ = w.WriteString(`<code class="plain" style="text-align: left;">`)
+			template.HTMLEscape(w, line.Value(source))
+			_, _ = w.WriteString(`</code><br/>`)
 		}
-		w.WriteString(content.String())
 	} else {
-		s := `</p></ac:rich-text-body></ac:structured-macro>`
-		_, _ = w.WriteString(s)
+		_, _ = w.WriteString(`</p></ac:rich-text-body>`)
+		_, _ = w.Write(macroEnd)
 	}
 	return ast.WalkContinue, nil
 }
 
-func (r *ConfluenceFencedCodeBlockHTMLRender) writeLines(w util.BufWriter, source []byte, n ast.Node) {
-	l := n.Lines().Len()
-	for i := 0; i < l; i++ {
-		line := n.Lines().At(i)
-		w.WriteString(string(line.Value(source)))
+func (r *ConfluenceFencedCodeBlockHTMLRender) writeMacro(w util.BufWriter, source []byte, n ast.Node) error {
+	definition := macroDefinition{}
+	if isYAMLMacro(source, n.(*ast.FencedCodeBlock)) {
+		var err error
+		if definition, err = r.parseYAMLMacro(source, n); err != nil {
+			return &PositionError{Position: nodePosition(r.filePath, source, n), Err: fmt.Errorf("CONFLUENCE-MACRO: %s", err)}
+		}
+	} else {
+		definition = r.parseMacro(source, n)
+	}
+
+	// validate the macro before writing anything
+	if err := r.checkMacro(definition, source, n); err != nil {
+		return err
 	}
+	writeMacroStorage(w, definition)
+	return nil
+}
+
+// checkMacro validates a macro and the macros nested in it, reporting
+// warnings as WarningMacro and returning the first error with StrictMacros
+func (r *ConfluenceFencedCodeBlockHTMLRender) checkMacro(definition macroDefinition, source []byte, n ast.Node) error {
+	for _, problem := range validateMacro(definition) {
+		position := nodePosition(r.filePath, source, n)
+		if problem.Offset != 0 {
+			position = offsetPosition(r.filePath, source, problem.Offset)
+		}
+		err := &PositionError{Position: position, Err: fmt.Errorf("CONFLUENCE-MACRO: %s", problem.Message)}
+		if problem.Fatal || StrictMacros && !problem.Warning {
+			return err
+		}
+		r.diagnostics.Warn(Warning{Class: WarningMacro, Position: position, Message: err.Err.Error()})
+	}
+	for _, child := range definition.Children {
+		if err := r.checkMacro(child, source, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// macroStorage renders a macro definition as a structured macro
+func macroStorage(definition macroDefinition) string {
+	return buildString(func(b *strings.Builder) { writeMacroStorage(b, definition) })
 }
 
-func (r *ConfluenceFencedCodeBlockHTMLRender) writeMacro(w util.BufWriter, source []byte, n ast.Node) {
+// writeMacroStorage writes a macro definition as a structured macro to w,
+// piece by piece instead of concatenating it first
+func writeMacroStorage(w io.StringWriter, definition macroDefinition) {
+	_, _ = w.WriteString(`<ac:structured-macro`)
+	for _, a := range definition.Attributes {
+		// we append a new attribute to the macro
+		writeStrings(w, ` ac:`, a.Key, `="`, a.Value, `"`)
+	}
+	_, _ = w.WriteString(">")
+	for _, p := range definition.Parameters {
+		writeParameter(w, template.HTMLEscapeString(p.Key), p.Value)
+	}
+	for _, b := range definition.Bodies {
+		// we append this as a child element
+		writeStrings(w, `<ac:`, b.Key, `>`, b.Value, `</ac:`, b.Key, `>`)
+	}
+	_, _ = w.WriteString("</ac:structured-macro>")
+}
+
+func (r *ConfluenceFencedCodeBlockHTMLRender) parseMacro(source []byte, n ast.Node) macroDefinition {
+	var d macroDefinition
 	l := n.Lines().Len()
-	// prepare the macrostart
-	macrostart := strings.Builder{}
-	macrostart.WriteString(`<ac:structured-macro`)
-	// and initialize the parameters
-	parameters := strings.Builder{}
 	for i := 0; i < l; i++ {
 		line := n.Lines().At(i)
 		text := string(line.Value(source))
+		offset := line.Start + len(text) - len(strings.TrimLeft(text, " \t"))
 		// Split the line at the first colon
 		keyValue := strings.SplitN(text, ":", 2)
 		// Ignore lines which didn't split into two parts
@@ -160,44 +245,55 @@ func (r *ConfluenceFencedCodeBlockHTMLRender) writeMacro(w util.BufWriter, sourc
 			key := strings.TrimSpace(keyValue[0])
 			// value is to the right. We trim both
 			value := strings.TrimSpace(keyValue[1])
+			field := macroField{Key: key, Value: value, Offset: offset}
 			// If the key was not indented
-			if key[0] == keyValue[0][0] {
+			if key != "" && key[0] == keyValue[0][0] {
 				_, isContentKey := r.MacroContentKeys[key]
 				if isContentKey {
-					// we append this as a child element
-					parameters.WriteString(`<ac:` + key + `>` + value + `</ac:` + key + `>`)
+					field.Value = macroBodyValue(key, value)
+					d.Bodies = append(d.Bodies, field)
 				} else {
-					// we append a new attribute to the macro
-					macrostart.WriteString(` ac:` + key + `="` + value + `"`)
+					field.Value = template.HTMLEscapeString(value)
+					d.Attributes = append(d.Attributes, field)
 				}
 			} else {
 				// It is aparameter to the macro
-				parameters.WriteString(`<ac:parameter ac:name="` + key + `">` + value + `</ac:parameter>`)
+				field.Value = storageValue(value)
+				d.Parameters = append(d.Parameters, field)
 			}
 		} else if len(keyValue) == 1 {
 			value := strings.TrimSpace(keyValue[0])
+			if value == "" {
+				continue
+			}
 			// assume the name of the param is empty
-			parameters.WriteString(`<ac:parameter ac:name="">` + value + `</ac:parameter>`)
+			d.Parameters = append(d.Parameters, macroField{Value: storageValue(value), Offset: offset})
 		}
 	}
-	// write the macro start
-	w.WriteString(macrostart.String())
-	w.WriteString(">")
-	// and all parameters
-	w.WriteString(parameters.String())
-	// and finish it off
-	w.WriteString("</ac:structured-macro>")
+	return d
 }
 
-func getSupportLanguage(key string) string {
+// macroBodyValue returns the value of a body of a macro block: plain text
+// in CDATA, unless it is a CDATA section already, and rich text as storage
+// format
+func macroBodyValue(key, value string) string {
+	if key != MacroContentKeyPlainTextBody {
+		return storageValue(value)
+	}
+	if strings.HasPrefix(value, "<![CDATA[") && strings.HasSuffix(value, "]]>") && storageValue(value) == value {
+		return value
+	}
+	return CDATA(value)
+}
+
+func getSupportLanguage(key string) (string, bool) {
 	if SupportedCodeBlockLanguages == nil {
-		return key
+		return key, true
 	}
 	if value, ok := SupportedCodeBlockLanguages[key]; ok {
-		return value
+		return value, true
 	}
-	println(fmt.Sprintf("Unsupported code block language: %s,Use %s default", key, DefaultCodeBlockLanguage))
-	return DefaultCodeBlockLanguage
+	return DefaultCodeBlockLanguage, false
 }
 
 func loadSupportCodeLanguage(configFile string) StringMap {
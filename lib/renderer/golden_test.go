@@ -0,0 +1,87 @@
+package renderer_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+
+	r "github.com/justmiles/go-markdown2confluence/lib/renderer"
+	"github.com/justmiles/go-markdown2confluence/lib/renderer/renderertest"
+)
+
+// fixtureSettings are the renderer settings of the fixtures that need more
+// than the defaults, by fixture name. They return a func restoring the
+// defaults.
+var fixtureSettings = map[string]func() func(){
+	"horizontal-rules-section": func() func() { return set(&r.HorizontalRules, r.HorizontalRulesSection) },
+	"inline-code-monospace":    func() func() { return set(&r.InlineCode, r.InlineCodeMonospace) },
+	"inline-code-status":       func() func() { return set(&r.InlineCode, r.InlineCodeStatus) },
+	"quote-panel":              func() func() { return set(&r.QuoteStyle, r.QuotePanel) },
+	"heading-anchors":          func() func() { return set(&r.HeadingAnchors, true) },
+	"page-links":               func() func() { return set(&r.ResolvePageLink, resolveGuide) },
+	"wikilinks":                func() func() { return set(&r.Obsidian, true) },
+	"callouts":                 func() func() { return set(&r.Obsidian, true) },
+	"code-highlight":           func() func() { return set(&r.HighlightLanguages, []string{"*"}) },
+}
+
+// set sets a renderer setting and returns a func restoring it
+func set[T any](setting *T, value T) func() {
+	previous := *setting
+	*setting = value
+	return func() { *setting = previous }
+}
+
+// resolveGuide resolves links to guide.md to the page Guide
+func resolveGuide(filePath, destination string, diagnostics *r.Diagnostics) (r.PageLink, bool) {
+	page, fragment, _ := strings.Cut(destination, "#")
+	if page != "guide.md" {
+		return r.PageLink{}, false
+	}
+	return r.PageLink{Title: "Guide", Anchor: fragment}, true
+}
+
+// TestGolden renders testdata/*.md with the settings of fixtureSettings and
+// compares them with the .storage.xml next to them. When all fixtures ran,
+// it checks that they contain every node the Confluence renderers render.
+func TestGolden(t *testing.T) {
+	kinds := map[ast.NodeKind]bool{}
+	rendered := map[string]bool{}
+	renderertest.Run(t, "testdata/*.md", func(filePath string, source []byte) (string, error) {
+		name := strings.TrimSuffix(filepath.Base(filePath), ".md")
+		if settings, ok := fixtureSettings[name]; ok {
+			defer settings()()
+		}
+		rendered[name] = true
+		return renderertest.Render(filePath, source, renderertest.RecordKinds(kinds))
+	})
+
+	fixtures, _ := filepath.Glob("testdata/*.md")
+	if len(rendered) < len(fixtures) {
+		return
+	}
+	renderers := []renderer.NodeRenderer{
+		r.NewConfluenceFencedCodeBlockHTMLRender("", nil),
+		r.NewConfluenceCodeBlockHTMLRender("", nil),
+		r.NewConfluenceCodeSpanHTMLRender(),
+		r.NewConfluenceImageHTMLRender("", nil),
+		r.NewConfluenceLayoutHTMLRender(),
+		r.NewConfluenceExpandHTMLRender(),
+		r.NewConfluenceHTMLTableHTMLRender(),
+		r.NewConfluenceTaskListHTMLRender(),
+		r.NewConfluenceQuoteHTMLRender(),
+		r.NewConfluenceHeadingHTMLRender(),
+		r.NewConfluencePageLinkHTMLRender("", nil),
+		r.NewConfluenceWikiLinkHTMLRender(""),
+		r.NewConfluenceCalloutHTMLRender(),
+	}
+	for _, nr := range renderers {
+		for _, kind := range renderertest.RegisteredKinds(nr) {
+			if !kinds[kind] {
+				t.Errorf("no fixture in testdata has a %s node, rendered by %T", kind, nr)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package renderer
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// Heading slug algorithms
+const (
+	SlugGitHub = "github"
+	SlugGitLab = "gitlab"
+)
+
+var (
+	// HeadingAnchors adds an anchor macro named after the heading ID to every
+	// heading, so deep links keep working across republishes
+	HeadingAnchors = false
+	// HeadingSlug is the algorithm generating heading IDs
+	HeadingSlug = SlugGitHub
+
+	// inlineLink matches a markdown link or image in heading text
+	inlineLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+)
+
+// Slug converts heading text to an anchor name with HeadingSlug
+func Slug(text string) string {
+	text = NormalizeText(inlineLink.ReplaceAllString(text, "$1"))
+	if TransliterateSlugs {
+		text = Transliterate(text)
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsNumber(r), r == '_':
+			b.WriteRune(r)
+		case r == '-', unicode.IsSpace(r):
+			if HeadingSlug == SlugGitLab && strings.HasSuffix(b.String(), "-") {
+				continue
+			}
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// Slugger generates unique heading IDs for a page, numbering repeated
+// headings -1, -2, ... like GitHub and GitLab do. It implements parser.IDs.
+type Slugger struct {
+	seen map[string]bool
+}
+
+// NewSlugger returns a Slugger for a new page
+func NewSlugger() *Slugger {
+	return &Slugger{seen: map[string]bool{}}
+}
+
+// Generate implements parser.IDs.Generate
+func (s *Slugger) Generate(value []byte, kind ast.NodeKind) []byte {
+	slug := Slug(string(value))
+	if slug == "" {
+		slug = "heading"
+	}
+	id := slug
+	for i := 1; s.seen[id]; i++ {
+		id = slug + "-" + strconv.Itoa(i)
+	}
+	s.seen[id] = true
+	return []byte(id)
+}
+
+// Put implements parser.IDs.Put
+func (s *Slugger) Put(value []byte) {
+	s.seen[string(value)] = true
+}
+
+// ConfluenceHeadingHTMLRender renders headings with an anchor macro for their ID
+type ConfluenceHeadingHTMLRender struct{}
+
+// NewConfluenceHeadingHTMLRender returns a new ConfluenceHeadingHTMLRender.
+func NewConfluenceHeadingHTMLRender() *ConfluenceHeadingHTMLRender {
+	return &ConfluenceHeadingHTMLRender{}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *ConfluenceHeadingHTMLRender) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindHeading, r.renderHeading)
+}
+
+func (r *ConfluenceHeadingHTMLRender) renderHeading(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.Heading)
+	if !entering {
+		_, _ = w.WriteString("</h")
+		_, _ = w.WriteString(strconv.Itoa(n.Level))
+		_, _ = w.WriteString(">\n")
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = w.WriteString("<h")
+	_, _ = w.WriteString(strconv.Itoa(n.Level))
+	if n.Attributes() != nil {
+		html.RenderAttributes(w, node, html.HeadingAttributeFilter)
+	}
+	_ = w.WriteByte('>')
+	if id, ok := n.AttributeString("id"); ok {
+		_, _ = w.WriteString(`<ac:structured-macro ac:name="anchor" ac:schema-version="1"><ac:parameter ac:name="">`)
+		_, _ = w.Write(util.EscapeHTML(id.([]byte)))
+		_, _ = w.WriteString(`</ac:parameter></ac:structured-macro>`)
+	}
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,465 @@
+package renderer
+
+import (
+	"html/template"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	// HighlightLanguages lists fenced code languages that are pre-rendered to
+	// highlighted HTML instead of the code macro. "*" selects all languages.
+	HighlightLanguages []string
+	// HighlightUnsupported pre-renders languages the code macro does not support
+	HighlightUnsupported = false
+
+	// Highlight renders code to HTML with inline styles. It can be replaced
+	// to plug in a full lexer library such as Chroma.
+	Highlight = highlightCode
+)
+
+// highlightStyles are the inline styles per token class, close to the RDark theme
+var highlightStyles = map[string]string{
+	"comment": "color:#7f8c8d;font-style:italic",
+	"string":  "color:#2ecc71",
+	"number":  "color:#e67e22",
+	"keyword": "color:#3498db;font-weight:bold",
+}
+
+func init() {
+	for _, l := range highlightLanguages {
+		for _, language := range strings.Fields(l.languages) {
+			highlightSyntaxes[language] = l.syntax
+		}
+	}
+	for _, k := range highlightKeywordSets {
+		keywords := newKeywordSet(k.keywords, k.ignoreCase)
+		for _, language := range strings.Fields(k.languages) {
+			highlightKeywords[language] = keywords
+		}
+	}
+}
+
+// highlightSyntax is how a language writes comments and strings
+type highlightSyntax struct {
+	// lineComments start comments running to the end of the line
+	lineComments []string
+	// blockComments are the starts and ends of comments spanning lines
+	blockComments [][2]string
+	// quotes start strings ending on the same line, multiline quotes start
+	// strings that may span lines
+	quotes    []string
+	multiline []string
+	// charLiterals only takes ' for a string around a single character, as
+	// it also starts Rust lifetimes and labels
+	charLiterals bool
+	// hexColors highlights CSS colours such as #2ecc71 as numbers
+	hexColors bool
+}
+
+var (
+	slashComments = []string{"//"}
+	cComments     = [][2]string{{"/*", "*/"}}
+	hashComments  = []string{"#"}
+	dashComments  = []string{"--"}
+	bothQuotes    = []string{`"`, "'"}
+	doubleQuotes  = []string{`"`}
+
+	// defaultSyntax is used for languages without rules
+	defaultSyntax = &highlightSyntax{
+		lineComments:  []string{"//", "#"},
+		blockComments: cComments,
+		quotes:        bothQuotes,
+		multiline:     []string{"`"},
+	}
+
+	// highlightLanguages are the rules of languages by their names and aliases
+	highlightLanguages = []struct {
+		syntax    *highlightSyntax
+		languages string
+	}{
+		{&highlightSyntax{lineComments: slashComments, blockComments: cComments, quotes: bothQuotes},
+			"c cpp c++ cc cxx h hpp objc objective-c java scala groovy cs csharp c# d zig proto protobuf solidity sol jsonc json5"},
+		{&highlightSyntax{lineComments: slashComments, blockComments: cComments, quotes: bothQuotes, multiline: []string{"`"}},
+			"go golang js javascript jsx mjs cjs ts typescript tsx"},
+		{&highlightSyntax{lineComments: slashComments, blockComments: cComments, quotes: bothQuotes, multiline: []string{`"""`}},
+			"kotlin kt swift dart"},
+		{&highlightSyntax{lineComments: slashComments, blockComments: cComments, quotes: bothQuotes, charLiterals: true},
+			"rust rs"},
+		{&highlightSyntax{blockComments: cComments, quotes: bothQuotes, hexColors: true},
+			"css"},
+		{&highlightSyntax{lineComments: slashComments, blockComments: cComments, quotes: bothQuotes, hexColors: true},
+			"scss sass less"},
+		{&highlightSyntax{lineComments: hashComments, quotes: bothQuotes},
+			"sh bash shell zsh fish ksh console ruby rb perl pl r yaml yml toml dockerfile docker makefile make mk cmake elixir ex exs nim julia jl conf nginx gitignore properties crystal cr coffee coffeescript tcl awk"},
+		{&highlightSyntax{lineComments: hashComments, quotes: bothQuotes, multiline: []string{`"""`, "'''"}},
+			"python py python3 py3 starlark bzl"},
+		{&highlightSyntax{lineComments: hashComments, blockComments: [][2]string{{"<#", "#>"}}, quotes: bothQuotes},
+			"powershell ps ps1 pwsh"},
+		{&highlightSyntax{lineComments: []string{"#", "//"}, blockComments: cComments, quotes: doubleQuotes},
+			"hcl terraform tf tfvars"},
+		{&highlightSyntax{lineComments: dashComments, blockComments: cComments, quotes: bothQuotes},
+			"sql plsql postgresql postgres psql mysql sqlite tsql"},
+		{&highlightSyntax{lineComments: dashComments, blockComments: [][2]string{{"--[[", "]]"}}, quotes: bothQuotes},
+			"lua"},
+		{&highlightSyntax{lineComments: dashComments, blockComments: [][2]string{{"{-", "-}"}}, quotes: doubleQuotes},
+			"haskell hs elm purescript purs"},
+		{&highlightSyntax{lineComments: dashComments, quotes: doubleQuotes},
+			"ada vhdl"},
+		{&highlightSyntax{lineComments: []string{";"}, quotes: doubleQuotes},
+			"lisp clojure clj cljs edn scheme racket elisp emacs-lisp asm nasm ini"},
+		{&highlightSyntax{lineComments: []string{"%"}, quotes: bothQuotes},
+			"erlang erl matlab octave prolog"},
+		{&highlightSyntax{lineComments: []string{"%"}},
+			"tex latex"},
+		{&highlightSyntax{lineComments: []string{"'"}, quotes: doubleQuotes},
+			"vb vbnet vba vbscript"},
+		{&highlightSyntax{blockComments: [][2]string{{"<!--", "-->"}}, quotes: doubleQuotes},
+			"html xml xhtml svg xslt vue"},
+		{&highlightSyntax{quotes: doubleQuotes},
+			"json"},
+		{&highlightSyntax{},
+			"text plain txt markdown md"},
+	}
+	highlightSyntaxes = map[string]*highlightSyntax{}
+)
+
+// keywordSet is the keywords of a language
+type keywordSet struct {
+	words map[string]struct{}
+	// ignoreCase matches keywords in any case, as SQL does
+	ignoreCase bool
+}
+
+func newKeywordSet(keywords string, ignoreCase bool) *keywordSet {
+	set := &keywordSet{words: map[string]struct{}{}, ignoreCase: ignoreCase}
+	for _, k := range strings.Fields(keywords) {
+		if ignoreCase {
+			k = strings.ToLower(k)
+		}
+		set.words[k] = struct{}{}
+	}
+	return set
+}
+
+func (k *keywordSet) has(word string) bool {
+	if k.ignoreCase {
+		word = strings.ToLower(word)
+	}
+	_, ok := k.words[word]
+	return ok
+}
+
+var (
+	// defaultKeywords are keywords common to many languages, used for
+	// languages without a keyword set
+	defaultKeywords = newKeywordSet(`
+		abstract and as async await break case catch class const continue def default defer
+		del do elif else enum except export extends false final finally fn for foreach from
+		func function go if impl import in interface is let loop match mod module mut new nil
+		none not null or package pass private protected pub public raise return select self
+		static struct super switch then this throw true try type typeof use var void when
+		where while with yield`, false)
+
+	// highlightKeywordSets are the keywords of languages by their names and
+	// aliases
+	highlightKeywordSets = []struct {
+		keywords   string
+		ignoreCase bool
+		languages  string
+	}{
+		{`auto break case char class const continue default delete do double else enum extern
+			false float for goto if inline int long namespace new nullptr private protected public
+			return short signed sizeof static struct switch template this throw true try typedef
+			typename union unsigned using virtual void volatile while`, false,
+			"c cpp c++ cc cxx h hpp objc objective-c"},
+		{`abstract assert boolean break byte case catch char class const continue default do
+			double else enum extends false final finally float for if implements import
+			instanceof int interface long native new null package private protected public
+			record return short static super switch synchronized this throw throws transient
+			true try var void volatile while yield`, false,
+			"java groovy"},
+		{`abstract as async await base bool break byte case catch char class const continue
+			decimal default delegate do double else enum event false finally float for foreach
+			if in int interface internal is lock long namespace new null object out override
+			private protected public readonly ref return sealed short static string struct
+			switch this throw true try typeof uint ulong using var virtual void while yield`, false,
+			"cs csharp c#"},
+		{`abstract case catch class def do else extends false final finally for if implicit
+			import lazy match new null object override package private protected return sealed
+			super this throw trait true try type val var while with yield`, false,
+			"scala"},
+		{`break case chan const continue default defer else fallthrough false for func go goto
+			if import interface iota map nil package range return select struct switch true
+			type var`, false,
+			"go golang"},
+		{`async await break case catch class const continue debugger default delete do else
+			export extends false finally for from function if import in instanceof let new null
+			of return static super switch this throw true try typeof undefined var void while
+			with yield`, false,
+			"js javascript jsx mjs cjs"},
+		{`abstract any as async await boolean break case catch class const continue debugger
+			declare default delete do else enum export extends false finally for from function
+			if implements import in instanceof interface keyof let namespace never new null
+			number of private protected public readonly return static string super switch this
+			throw true try type typeof undefined unknown var void while with yield`, false,
+			"ts typescript tsx"},
+		{`abstract as break class companion continue data do else enum false for fun if import
+			in interface is null object open override package private protected public return
+			sealed super this throw true try typealias val var when while`, false,
+			"kotlin kt"},
+		{`as break case catch class continue default defer do else enum extension false for
+			func guard if import in init let nil private protocol public return self static
+			struct super switch throw throws true try var where while`, false,
+			"swift"},
+		{`as async await break case catch class const continue default do else enum extends
+			false final finally for if import in is late new null required return static super
+			switch this throw true try var void while with yield`, false,
+			"dart"},
+		{`as async await break const continue crate dyn else enum extern false fn for if impl in
+			let loop match mod move mut pub ref return self Self static struct super trait true
+			type unsafe use where while`, false,
+			"rust rs"},
+		{`break case continue do done elif else esac exit export fi for function if in local
+			readonly return select set shift source then unset until while`, false,
+			"sh bash shell zsh fish ksh console"},
+		{`alias and begin break case class def defined do else elsif end ensure false for if in
+			module next nil not or redo require rescue retry return self super then true undef
+			unless until when while yield`, false,
+			"ruby rb crystal cr"},
+		{`False None True and as assert async await break class continue def del elif else
+			except finally for from global if import in is lambda nonlocal not or pass raise
+			return try while with yield`, false,
+			"python py python3 py3 starlark bzl"},
+		{`begin break catch class continue do else elseif end exit filter finally for foreach
+			function if in param process return switch throw trap try until while`, true,
+			"powershell ps ps1 pwsh"},
+		{`data false for in locals module null output provider resource terraform true
+			variable`, false,
+			"hcl terraform tf tfvars"},
+		{`add all alter and as asc begin between by case commit create delete desc distinct drop
+			else end exists false from full group having if in index inner insert into is join
+			key left like limit not null on or order outer primary references return right
+			rollback select set table then true union unique update values view when where with`, true,
+			"sql plsql postgresql postgres psql mysql sqlite tsql"},
+		{`and break do else elseif end false for function goto if in local nil not or repeat
+			return then true until while`, false,
+			"lua"},
+		{`case class data deriving do else if import in infix infixl infixr instance let module
+			newtype of then type where`, false,
+			"haskell hs elm purescript purs"},
+		{`false no null off on true yes`, false,
+			"json jsonc json5 yaml yml toml"},
+		{``, false,
+			"css scss sass less html xml xhtml svg xslt vue text plain txt markdown md tex latex"},
+	}
+	highlightKeywords = map[string]*keywordSet{}
+)
+
+// keywordsOf returns the keywords of language, defaultKeywords for unknown
+// ones
+func keywordsOf(language string) *keywordSet {
+	if keywords, ok := highlightKeywords[strings.ToLower(language)]; ok {
+		return keywords
+	}
+	return defaultKeywords
+}
+
+// syntaxOf returns the rules of language, defaultSyntax for unknown ones
+func syntaxOf(language string) *highlightSyntax {
+	if syntax, ok := highlightSyntaxes[strings.ToLower(language)]; ok {
+		return syntax
+	}
+	return defaultSyntax
+}
+
+func shouldHighlight(language string) bool {
+	for _, l := range HighlightLanguages {
+		if l == "*" || strings.EqualFold(l, language) {
+			return true
+		}
+	}
+	if HighlightUnsupported && language != "" {
+		_, ok := SupportedCodeBlockLanguages[strings.ToLower(language)]
+		return !ok
+	}
+	return false
+}
+
+func renderHighlightedCode(w util.BufWriter, language string, body []byte) error {
+	s, err := Highlight(language, string(body))
+	if err != nil {
+		return err
+	}
+	_, _ = w.WriteString(s)
+	return nil
+}
+
+// highlightCode is a small highlighter for comments, strings, numbers and
+// keywords, with the comment and string rules and the keywords of the
+// language.
+func highlightCode(language, code string) (string, error) {
+	syntax, keywords := syntaxOf(language), keywordsOf(language)
+	var b strings.Builder
+	b.WriteString(`<pre style="background-color:#1e1e1e;color:#d4d4d4;padding:8px;overflow:auto"><code>`)
+
+	runes := []rune(code)
+	for i := 0; i < len(runes); {
+		if end := syntax.comment(runes, i); end > i {
+			writeToken(&b, "comment", string(runes[i:end]))
+			i = end
+			continue
+		}
+		if end := syntax.str(runes, i); end > i {
+			writeToken(&b, "string", string(runes[i:end]))
+			i = end
+			continue
+		}
+		c := runes[i]
+		switch {
+		case syntax.hexColors && c == '#' && hexColorEnd(runes, i) > i:
+			end := hexColorEnd(runes, i)
+			writeToken(&b, "number", string(runes[i:end]))
+			i = end
+		case unicode.IsDigit(c):
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.' || runes[end] == '_') {
+				end++
+			}
+			writeToken(&b, "number", string(runes[i:end]))
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			word := string(runes[i:end])
+			if keywords.has(word) {
+				writeToken(&b, "keyword", word)
+			} else {
+				b.WriteString(template.HTMLEscapeString(word))
+			}
+			i = end
+		default:
+			b.WriteString(template.HTMLEscapeString(string(c)))
+			i++
+		}
+	}
+
+	b.WriteString(`</code></pre>`)
+	return b.String(), nil
+}
+
+// comment returns the end of the comment starting at i, i when none does
+func (s *highlightSyntax) comment(runes []rune, i int) int {
+	for _, delimiters := range s.blockComments {
+		if hasRunePrefix(runes[i:], delimiters[0]) {
+			end := i + len([]rune(delimiters[0]))
+			for end < len(runes) && !hasRunePrefix(runes[end:], delimiters[1]) {
+				end++
+			}
+			if end += len([]rune(delimiters[1])); end > len(runes) {
+				end = len(runes)
+			}
+			return end
+		}
+	}
+	for _, start := range s.lineComments {
+		if hasRunePrefix(runes[i:], start) {
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			return end
+		}
+	}
+	return i
+}
+
+// str returns the end of the string starting at i, i when none does
+func (s *highlightSyntax) str(runes []rune, i int) int {
+	for _, quote := range s.multiline {
+		if hasRunePrefix(runes[i:], quote) {
+			return stringEnd(runes, i, quote, true)
+		}
+	}
+	for _, quote := range s.quotes {
+		if !hasRunePrefix(runes[i:], quote) {
+			continue
+		}
+		if quote == "'" && s.charLiterals {
+			return charLiteralEnd(runes, i)
+		}
+		return stringEnd(runes, i, quote, false)
+	}
+	return i
+}
+
+// stringEnd returns the end of the string starting with quote at i. Strings
+// that are not multiline end with their line when the quote is missing.
+func stringEnd(runes []rune, i int, quote string, multiline bool) int {
+	end := i + len([]rune(quote))
+	for end < len(runes) && !hasRunePrefix(runes[end:], quote) {
+		if runes[end] == '\n' && !multiline {
+			return end
+		}
+		if runes[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(runes) {
+		return len(runes)
+	}
+	return end + len([]rune(quote))
+}
+
+// charLiteralEnd returns the end of a character literal such as 'a' or '\n'
+// at i, i for a lifetime or label such as 'a
+func charLiteralEnd(runes []rune, i int) int {
+	end := i + 2
+	if end <= len(runes) && runes[i+1] == '\\' {
+		for end++; end < len(runes) && runes[end] != '\'' && runes[end] != '\n'; end++ {
+		}
+	}
+	if end < len(runes) && runes[end] == '\'' {
+		return end + 1
+	}
+	return i
+}
+
+// hexColorEnd returns the end of a CSS colour such as #fff at i, i when
+// there is none
+func hexColorEnd(runes []rune, i int) int {
+	end := i + 1
+	for end < len(runes) && unicode.Is(unicode.ASCII_Hex_Digit, runes[end]) {
+		end++
+	}
+	if n := end - i - 1; n != 3 && n != 4 && n != 6 && n != 8 {
+		return i
+	}
+	if end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '-') {
+		return i
+	}
+	return end
+}
+
+// hasRunePrefix reports whether runes start with prefix
+func hasRunePrefix(runes []rune, prefix string) bool {
+	i := 0
+	for _, r := range prefix {
+		if i >= len(runes) || runes[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func writeToken(b *strings.Builder, class, text string) {
+	b.WriteString(`<span style="` + highlightStyles[class] + `">`)
+	b.WriteString(template.HTMLEscapeString(text))
+	b.WriteString(`</span>`)
+}
@@ -0,0 +1,365 @@
+package renderer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// htmlTableStart matches the start of a raw HTML table
+var htmlTableStart = regexp.MustCompile(`(?i)^\s*<table[\s>]`)
+
+// htmlTableEnd matches the end of a raw HTML table
+var htmlTableEnd = regexp.MustCompile(`(?i)</table\s*>`)
+
+// KindHTMLTable is the NodeKind of HTMLTable nodes
+var KindHTMLTable = ast.NewNodeKind("HTMLTable")
+
+// HTMLTable is a raw HTML table converted to a storage format table
+type HTMLTable struct {
+	ast.BaseBlock
+	Storage string
+}
+
+// Kind implements ast.Node.Kind
+func (n *HTMLTable) Kind() ast.NodeKind {
+	return KindHTMLTable
+}
+
+// Dump implements ast.Node.Dump
+func (n *HTMLTable) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Storage": n.Storage}, nil)
+}
+
+// htmlTableElements maps the HTML elements kept in tables to the storage
+// format element they become. Other elements are left out, keeping their
+// content.
+var htmlTableElements = map[string]string{
+	"table": "table", "thead": "thead", "tbody": "tbody", "tfoot": "tfoot",
+	"tr": "tr", "th": "th", "td": "td", "colgroup": "colgroup", "col": "col",
+	"b": "strong", "strong": "strong", "i": "em", "em": "em", "u": "u",
+	"s": "s", "del": "s", "strike": "s", "code": "code", "tt": "code", "kbd": "code",
+	"sub": "sub", "sup": "sup", "br": "br", "p": "p", "pre": "pre",
+	"ul": "ul", "ol": "ol", "li": "li", "blockquote": "blockquote", "a": "a",
+	"h1": "h1", "h2": "h2", "h3": "h3", "h4": "h4", "h5": "h5", "h6": "h6",
+}
+
+// htmlTableSkipped are elements left out with their content
+var htmlTableSkipped = map[string]bool{"script": true, "style": true, "template": true}
+
+type htmlTableTransformer struct {
+	filePath    string
+	diagnostics *Diagnostics
+}
+
+// NewHTMLTableTransformer returns an AST transformer converting raw HTML
+// tables, which the HTML renderer would leave out, to storage format tables.
+// Row and column spans and basic formatting in cells are kept, rows outside
+// of a table section are put in a tbody and a caption becomes a bold
+// paragraph above the table. Tables interrupted by blank lines span several
+// blocks, which are joined.
+func NewHTMLTableTransformer(filePath string, diagnostics *Diagnostics) parser.ASTTransformer {
+	return &htmlTableTransformer{filePath: filePath, diagnostics: diagnostics}
+}
+
+func (t *htmlTableTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var tables []*ast.HTMLBlock
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if b, ok := n.(*ast.HTMLBlock); ok && entering && htmlTableStart.MatchString(htmlBlockText(source, b)) {
+			tables = append(tables, b)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, b := range tables {
+		if b.Parent() == nil {
+			// joined to an earlier table
+			continue
+		}
+		raw := htmlBlockText(source, b)
+		blocks := []ast.Node{b}
+		for next := b.NextSibling(); !htmlTableEnd.MatchString(raw) && next != nil; next = next.NextSibling() {
+			raw += "\n" + blockSource(source, next)
+			blocks = append(blocks, next)
+		}
+
+		storage, err := htmlTableStorage(raw)
+		if err != nil {
+			t.diagnostics.warn(WarningHTML, t.filePath, source, b, "unable to convert HTML table, leaving it out: %s", err)
+			continue
+		}
+		table := &HTMLTable{Storage: storage}
+		b.Parent().ReplaceChild(b.Parent(), b, table)
+		for _, n := range blocks[1:] {
+			n.Parent().RemoveChild(n.Parent(), n)
+		}
+	}
+}
+
+// blockSource returns the source lines of a block, or of the blocks in it
+func blockSource(source []byte, n ast.Node) string {
+	if b, ok := n.(*ast.HTMLBlock); ok {
+		return htmlBlockText(source, b)
+	}
+	if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+		var b strings.Builder
+		for i := 0; i < n.Lines().Len(); i++ {
+			line := n.Lines().At(i)
+			b.Write(line.Value(source))
+		}
+		return b.String()
+	}
+	var parts []string
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		parts = append(parts, blockSource(source, c))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// htmlTableElement is an open element of an HTML table
+type htmlTableElement struct {
+	// name is the HTML element, element the storage format element written
+	// for it, empty for elements left out
+	name, element string
+	// captionOf is the writer a caption interrupted
+	captionOf *strings.Builder
+}
+
+// htmlTableConverter converts the tokens of an HTML table to storage format
+type htmlTableConverter struct {
+	table, caption strings.Builder
+	w              *strings.Builder
+	open           []htmlTableElement
+	// cells counts the open cells and captions, text outside of them is
+	// layout whitespace
+	cells int
+}
+
+// htmlTableStorage converts the HTML of a table to storage format
+func htmlTableStorage(raw string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(raw))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	c := &htmlTableConverter{}
+	c.w = &c.table
+	for {
+		token, err := decoder.Token()
+		var syntaxErr *xml.SyntaxError
+		if err == io.EOF || errors.As(err, &syntaxErr) && syntaxErr.Msg == "unexpected EOF" {
+			// elements left open are closed below
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(token.Name.Local)
+			if htmlTableSkipped[name] {
+				if err := decoder.Skip(); err != nil {
+					return "", err
+				}
+				continue
+			}
+			c.start(name, token.Attr)
+		case xml.EndElement:
+			c.end(strings.ToLower(token.Name.Local))
+		case xml.CharData:
+			if c.cells > 0 {
+				c.w.Write(util.EscapeHTML(token))
+			}
+		}
+	}
+	c.closeTo(0)
+
+	if !strings.HasPrefix(c.table.String(), "<table") {
+		return "", fmt.Errorf("no table found")
+	}
+	if text := strings.TrimSpace(c.caption.String()); text != "" {
+		return "<p><strong>" + text + "</strong></p>\n" + c.table.String() + "\n", nil
+	}
+	return c.table.String() + "\n", nil
+}
+
+// start opens an element, closing the cells, rows and sections HTML lets
+// the element end implicitly
+func (c *htmlTableConverter) start(name string, attrs []xml.Attr) {
+	switch name {
+	case "thead", "tbody", "tfoot", "colgroup":
+		c.closeImplied("table", "thead", "tbody", "tfoot", "colgroup")
+	case "tr":
+		c.closeImplied("table", "tr")
+		if len(c.open) > 0 && c.open[len(c.open)-1].name == "table" {
+			c.push(htmlTableElement{name: "tbody", element: "tbody"})
+			c.w.WriteString("<tbody>")
+		}
+	case "td", "th":
+		c.closeImplied("tr", "td", "th")
+	case "img":
+		writeHTMLTableImage(c.w, attrs)
+		return
+	case "br", "col":
+		if element := htmlTableElements[name]; len(c.open) > 0 {
+			c.w.WriteString("<" + element)
+			writeHTMLTableAttributes(c.w, element, attrs)
+			c.w.WriteString("/>")
+		}
+		return
+	case "caption":
+		c.push(htmlTableElement{name: name, captionOf: c.w})
+		c.w = &c.caption
+		return
+	}
+
+	element := htmlTableElements[name]
+	if element == "a" && !safeHref(attrs) {
+		element = ""
+	}
+	if len(c.open) == 0 && element != "table" {
+		// content before the table
+		return
+	}
+	c.push(htmlTableElement{name: name, element: element})
+	if element != "" {
+		c.w.WriteString("<" + element)
+		writeHTMLTableAttributes(c.w, element, attrs)
+		c.w.WriteString(">")
+	}
+}
+
+// end closes the innermost open element name and the elements in it. End
+// tags of elements closed implicitly before are ignored.
+func (c *htmlTableConverter) end(name string) {
+	for i := len(c.open) - 1; i >= 0; i-- {
+		if c.open[i].name == name {
+			c.closeTo(i)
+			return
+		}
+		if c.open[i].name == "table" {
+			return
+		}
+	}
+}
+
+// closeImplied closes an open element of names, when it is inside of the
+// innermost open element of within
+func (c *htmlTableConverter) closeImplied(within string, names ...string) {
+	for i := len(c.open) - 1; i >= 0; i-- {
+		name := c.open[i].name
+		if name == within || name == "table" {
+			return
+		}
+		for _, n := range names {
+			if name == n {
+				c.closeTo(i)
+				return
+			}
+		}
+	}
+}
+
+func (c *htmlTableConverter) push(e htmlTableElement) {
+	if e.name == "td" || e.name == "th" || e.name == "caption" {
+		c.cells++
+	}
+	c.open = append(c.open, e)
+}
+
+// closeTo closes the open elements from the innermost down to index i
+func (c *htmlTableConverter) closeTo(i int) {
+	for len(c.open) > i {
+		e := c.open[len(c.open)-1]
+		c.open = c.open[:len(c.open)-1]
+		if e.name == "td" || e.name == "th" || e.name == "caption" {
+			c.cells--
+		}
+		if e.captionOf != nil {
+			c.w = e.captionOf
+		}
+		if e.element != "" {
+			c.w.WriteString("</" + e.element + ">")
+		}
+	}
+}
+
+// safeHref reports whether an a element links somewhere that does not run
+// code
+func safeHref(attrs []xml.Attr) bool {
+	for _, attr := range attrs {
+		if strings.EqualFold(attr.Name.Local, "href") {
+			return !html.IsDangerousURL([]byte(attr.Value))
+		}
+	}
+	return false
+}
+
+// writeHTMLTableAttributes writes the attributes kept for an element: spans
+// of cells and columns and link targets
+func writeHTMLTableAttributes(w *strings.Builder, element string, attrs []xml.Attr) {
+	for _, attr := range attrs {
+		name := strings.ToLower(attr.Name.Local)
+		switch {
+		case (element == "td" || element == "th") && (name == "rowspan" || name == "colspan"),
+			element == "col" && name == "span":
+			if n, err := strconv.Atoi(strings.TrimSpace(attr.Value)); err == nil && n > 0 {
+				fmt.Fprintf(w, ` %s="%d"`, name, n)
+			}
+		case element == "a" && name == "href":
+			w.WriteString(` href="` + string(util.EscapeHTML(util.URLEscape([]byte(attr.Value), true))) + `"`)
+		}
+	}
+}
+
+// writeHTMLTableImage writes an image of a table cell, remote images as
+// images and others as their alternative text, as they are not attached
+func writeHTMLTableImage(w *strings.Builder, attrs []xml.Attr) {
+	var src, alt string
+	for _, attr := range attrs {
+		switch strings.ToLower(attr.Name.Local) {
+		case "src":
+			src = attr.Value
+		case "alt":
+			alt = attr.Value
+		}
+	}
+	if strings.HasPrefix(src, "https://") || strings.HasPrefix(src, "http://") {
+		w.WriteString(`<ac:image><ri:url ri:value="` + string(util.EscapeHTML([]byte(src))) + `"/></ac:image>`)
+		return
+	}
+	w.Write(util.EscapeHTML([]byte(alt)))
+}
+
+// ConfluenceHTMLTableHTMLRender renders HTMLTable nodes
+type ConfluenceHTMLTableHTMLRender struct{}
+
+// NewConfluenceHTMLTableHTMLRender returns a new
+// ConfluenceHTMLTableHTMLRender.
+func NewConfluenceHTMLTableHTMLRender() renderer.NodeRenderer {
+	return &ConfluenceHTMLTableHTMLRender{}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *ConfluenceHTMLTableHTMLRender) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindHTMLTable, r.renderHTMLTable)
+}
+
+func (r *ConfluenceHTMLTableHTMLRender) renderHTMLTable(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(node.(*HTMLTable).Storage)
+	}
+	return ast.WalkSkipChildren, nil
+}
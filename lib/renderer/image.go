@@ -3,7 +3,6 @@ package renderer
 import (
 	"bytes"
 	"fmt"
-	"github.com/justmiles/go-confluence"
 	"net/url"
 	"os"
 	"path"
@@ -19,15 +18,17 @@ import (
 // renders KindImage nodes.
 type ConfluenceImageHTMLRender struct {
 	html.Config
-	Images   []string
-	filePath string
+	Images      []string
+	filePath    string
+	diagnostics *Diagnostics
 }
 
 // NewConfluenceImageHTMLRender returns a new ConfluenceImageHTMLRender.
-func NewConfluenceImageHTMLRender(filePath string, opts ...html.Option) *ConfluenceImageHTMLRender {
+func NewConfluenceImageHTMLRender(filePath string, diagnostics *Diagnostics, opts ...html.Option) *ConfluenceImageHTMLRender {
 	r := &ConfluenceImageHTMLRender{
-		Config:   html.NewConfig(),
-		filePath: filePath,
+		Config:      html.NewConfig(),
+		filePath:    filePath,
+		diagnostics: diagnostics,
 	}
 
 	for _, opt := range opts {
@@ -49,17 +50,51 @@ func (r *ConfluenceImageHTMLRender) renderConfluenceImage(w util.BufWriter, sour
 
 	n := node.(*ast.Image)
 
+	// Images embedded as data URIs, e.g. plots in notebook exports, are attached as files
+	if Notebook && isDataURI(n.Destination) {
+		f, err := dataURIFile(n.Destination)
+		if err != nil {
+			return ast.WalkStop, newPositionError(r.filePath, source, n, err)
+		}
+		r.Images = append(r.Images, f)
+		_, _ = w.WriteString(`<ac:image`)
+		writeImageWidth(w, n)
+		_, _ = w.WriteString(`><ri:attachment ri:filename="`)
+		_, _ = w.WriteString(AttachmentName(f))
+		_, _ = w.WriteString(`"/></ac:image>`)
+		return ast.WalkSkipChildren, nil
+	}
+
 	// If this is a local file and not an HTTP url, then let's render this for Confluence
 	if f, err := localFile(r.filePath, n.Destination); err == nil {
+		if isDrawioFile(f) {
+			attachments, err := renderDrawio(w, f)
+			if err != nil {
+				return ast.WalkStop, newPositionError(r.filePath, source, n, err)
+			}
+			r.Images = append(r.Images, attachments...)
+			return ast.WalkSkipChildren, nil
+		}
+
+		if isSharedAsset(f) {
+			writeSharedAssetImage(w, f)
+			return ast.WalkSkipChildren, nil
+		}
+
 		r.Images = append(r.Images, f)
-		_, _ = w.WriteString(`<ac:image><ri:attachment ri:filename="`)
-		fileMD5Hash, _ := confluence.GetFileMD5Hash(f)
-		_, _ = w.WriteString(fileMD5Hash + "_" + path.Base(f))
+		_, _ = w.WriteString(`<ac:image`)
+		writeImageWidth(w, n)
+		_, _ = w.WriteString(`><ri:attachment ri:filename="`)
+		_, _ = w.WriteString(AttachmentName(f))
 		_, _ = w.WriteString(`"/></ac:image>`)
 
 		return ast.WalkSkipChildren, nil
 	}
 
+	if Badges != BadgesKeep && isBadge(n.Destination) && r.renderBadge(w, source, n) {
+		return ast.WalkSkipChildren, nil
+	}
+
 	// This is a regular HTTP url, render it in normal XHTML
 	_, _ = w.WriteString("<img src=\"")
 	if r.Unsafe || !html.IsDangerousURL(n.Destination) {
@@ -67,7 +102,7 @@ func (r *ConfluenceImageHTMLRender) renderConfluenceImage(w util.BufWriter, sour
 	}
 
 	_, _ = w.WriteString(`" alt="`)
-	_, _ = w.Write(n.Text(source))
+	_, _ = w.Write(util.EscapeHTML(n.Text(source)))
 	_ = w.WriteByte('"')
 	if n.Title != nil {
 		_, _ = w.WriteString(` title="`)
@@ -0,0 +1,117 @@
+package renderer
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	// KrokiServer is the base URL of a Kroki server. When set, all recognized
+	// diagram languages are rendered to attachments through it.
+	KrokiServer = ""
+	// KrokiFormat is the output format requested from Kroki
+	KrokiFormat = "svg"
+
+	// KrokiDiagramTypes maps fenced code languages to Kroki diagram types
+	KrokiDiagramTypes = map[string]string{
+		"actdiag":     "actdiag",
+		"blockdiag":   "blockdiag",
+		"bpmn":        "bpmn",
+		"bytefield":   "bytefield",
+		"c4plantuml":  "c4plantuml",
+		"d2":          "d2",
+		"dbml":        "dbml",
+		"ditaa":       "ditaa",
+		"dot":         "graphviz",
+		"erd":         "erd",
+		"excalidraw":  "excalidraw",
+		"graphviz":    "graphviz",
+		"mermaid":     "mermaid",
+		"nomnoml":     "nomnoml",
+		"nwdiag":      "nwdiag",
+		"packetdiag":  "packetdiag",
+		"pikchr":      "pikchr",
+		"plant":       "plantuml",
+		"plantuml":    "plantuml",
+		"pu":          "plantuml",
+		"puml":        "plantuml",
+		"rackdiag":    "rackdiag",
+		"seqdiag":     "seqdiag",
+		"structurizr": "structurizr",
+		"svgbob":      "svgbob",
+		"tikz":        "tikz",
+		"umlet":       "umlet",
+		"vega":        "vega",
+		"vegalite":    "vegalite",
+		"wavedrom":    "wavedrom",
+		"wireviz":     "wireviz",
+	}
+)
+
+func getKrokiDiagramType(language string) (string, bool) {
+	if KrokiServer == "" {
+		return "", false
+	}
+	diagramType, ok := KrokiDiagramTypes[strings.ToLower(language)]
+	return diagramType, ok
+}
+
+// renderKrokiDiagram converts a diagram through the Kroki server, writes an
+// image referencing the result and returns the file to attach.
+func renderKrokiDiagram(w util.BufWriter, diagramType string, body []byte) ([]string, error) {
+	f, err := fetchKrokiDiagram(diagramType, body)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = w.WriteString(`<ac:image><ri:attachment ri:filename="`)
+	_, _ = w.WriteString(AttachmentName(f))
+	_, _ = w.WriteString(`"/></ac:image>`)
+	return []string{f}, nil
+}
+
+func fetchKrokiDiagram(diagramType string, body []byte) (string, error) {
+	url := strings.TrimSuffix(KrokiServer, "/") + "/" + diagramType + "/" + KrokiFormat
+	res, err := http.Post(url, "text/plain", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("unable to render %s diagram with kroki: %s", diagramType, err)
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to read kroki response: %s", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("kroki returned %s for %s diagram: %s", res.Status, diagramType, data)
+	}
+
+	dir, err := newAttachmentDir("kroki")
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(body)
+	f := filepath.Join(dir, diagramType+"-"+hex.EncodeToString(sum[:])[:12]+"."+KrokiFormat)
+	if err := os.WriteFile(f, data, 0644); err != nil {
+		return "", err
+	}
+	return f, nil
+}
+
+// CheckKrokiServer renders a minimal diagram with KrokiServer, returning
+// why it can not be used
+func CheckKrokiServer() error {
+	f, err := fetchKrokiDiagram("graphviz", []byte("digraph { a -> b }"))
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Dir(f))
+}
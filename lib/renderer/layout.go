@@ -0,0 +1,330 @@
+package renderer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	// layoutOpener matches "::: columns" and "::: column width=50%"
+	layoutOpener = regexp.MustCompile(`^:{3,}\s*(columns|column)(?:\s+(.*?))?\s*$`)
+	// layoutCloser matches the ":::" closing a container
+	layoutCloser = regexp.MustCompile(`^:{3,}\s*$`)
+	// layoutWidth matches the width attribute of a column
+	layoutWidth = regexp.MustCompile(`\bwidth=["']?(\d+(?:\.\d+)?)%?["']?`)
+)
+
+// KindColumns is the NodeKind of Columns nodes
+var KindColumns = ast.NewNodeKind("Columns")
+
+// KindColumn is the NodeKind of Column nodes
+var KindColumn = ast.NewNodeKind("Column")
+
+// Columns is a "::: columns" container, rendered as a layout section
+type Columns struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node.Kind
+func (n *Columns) Kind() ast.NodeKind {
+	return KindColumns
+}
+
+// Dump implements ast.Node.Dump
+func (n *Columns) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// Column is a "::: column" container in Columns, rendered as a layout cell
+type Column struct {
+	ast.BaseBlock
+	// Width is the share of the section in percent, 0 if not given
+	Width float64
+}
+
+// Kind implements ast.Node.Kind
+func (n *Column) Kind() ast.NodeKind {
+	return KindColumn
+}
+
+// Dump implements ast.Node.Dump
+func (n *Column) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Width": fmt.Sprint(n.Width)}, nil)
+}
+
+type layoutParser struct{}
+
+// NewLayoutParser returns a block parser for "::: columns" containers of
+// "::: column" containers. Columns are only recognized at the top level of
+// a page, where Confluence allows layouts.
+func NewLayoutParser() parser.BlockParser {
+	return &layoutParser{}
+}
+
+func (b *layoutParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func (b *layoutParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	match := layoutOpener.FindSubmatch(util.TrimRightSpace(line))
+	if match == nil {
+		return nil, parser.NoChildren
+	}
+
+	var node ast.Node
+	switch string(match[1]) {
+	case "columns":
+		if parent.Kind() != ast.KindDocument {
+			return nil, parser.NoChildren
+		}
+		node = &Columns{}
+	case "column":
+		if parent.Kind() != KindColumns {
+			return nil, parser.NoChildren
+		}
+		column := &Column{}
+		if width := layoutWidth.FindSubmatch(match[2]); width != nil {
+			column.Width, _ = strconv.ParseFloat(string(width[1]), 64)
+		}
+		node = column
+	}
+	advanceToLineEnd(reader, line, segment)
+	return node, parser.HasChildren
+}
+
+func (b *layoutParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if !layoutCloser.Match(util.TrimRightSpace(util.TrimLeftSpace(line))) || b.innerBlockOpen(node, pc) {
+		return parser.Continue | parser.HasChildren
+	}
+	advanceToLineEnd(reader, line, segment)
+	return parser.Close
+}
+
+// innerBlockOpen tells whether a ":::" line belongs to a block inside node:
+// an open column of a Columns node, or a code block
+func (b *layoutParser) innerBlockOpen(node ast.Node, pc parser.Context) bool {
+	inside := false
+	for _, block := range pc.OpenedBlocks() {
+		if block.Node == node {
+			inside = true
+			continue
+		}
+		if !inside {
+			continue
+		}
+		switch block.Node.Kind() {
+		case KindColumn, ast.KindFencedCodeBlock, ast.KindCodeBlock, ast.KindHTMLBlock:
+			return true
+		}
+	}
+	return false
+}
+
+// advanceToLineEnd moves the reader to the newline of the line, or past
+// the last line of the source without one
+func advanceToLineEnd(reader text.Reader, line []byte, segment text.Segment) {
+	n := segment.Len()
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		n--
+	}
+	reader.Advance(n)
+}
+
+func (b *layoutParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (b *layoutParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (b *layoutParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// ConfluenceLayoutHTMLRender renders Columns as layout sections and Column
+// as layout cells
+type ConfluenceLayoutHTMLRender struct{}
+
+// NewConfluenceLayoutHTMLRender returns a new ConfluenceLayoutHTMLRender.
+func NewConfluenceLayoutHTMLRender() *ConfluenceLayoutHTMLRender {
+	return &ConfluenceLayoutHTMLRender{}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *ConfluenceLayoutHTMLRender) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindColumns, r.renderColumns)
+	reg.Register(KindColumn, r.renderColumn)
+}
+
+func (r *ConfluenceLayoutHTMLRender) renderColumns(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</ac:layout-section>\n")
+		return ast.WalkContinue, nil
+	}
+	var widths []float64
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		column, ok := c.(*Column)
+		if !ok {
+			return ast.WalkStop, fmt.Errorf("only ::: column containers can be placed in ::: columns")
+		}
+		widths = append(widths, column.Width)
+	}
+	sectionType, err := layoutSectionType(widths)
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	writeStrings(w, `<ac:layout-section ac:type="`, sectionType, `">`)
+	return ast.WalkContinue, nil
+}
+
+func (r *ConfluenceLayoutHTMLRender) renderColumn(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("<ac:layout-cell>\n")
+	} else {
+		_, _ = w.WriteString("</ac:layout-cell>")
+	}
+	return ast.WalkContinue, nil
+}
+
+// layoutSectionType picks the Confluence section type closest to the column
+// widths: equal columns, or a narrow sidebar next to wider ones
+func layoutSectionType(widths []float64) (string, error) {
+	equal := true
+	for _, w := range widths {
+		equal = equal && (w == 0 || w == widths[0])
+	}
+	switch len(widths) {
+	case 1:
+		return "single", nil
+	case 2:
+		switch {
+		case equal || widths[0] == 0 || widths[1] == 0:
+			return "two_equal", nil
+		case widths[0] < widths[1]:
+			return "two_left_sidebar", nil
+		default:
+			return "two_right_sidebar", nil
+		}
+	case 3:
+		if !equal && widths[1] > widths[0] && widths[1] > widths[2] {
+			return "three_with_sidebars", nil
+		}
+		return "three_equal", nil
+	}
+	return "", fmt.Errorf("::: columns needs one to three ::: column containers, found %d", len(widths))
+}
+
+// WrapLayout puts the content around layout sections into single column
+// sections of one layout, as Confluence requires all content of a page with
+// a layout to be in its sections
+func WrapLayout(storage string) string {
+	const start, end = "<ac:layout-section", "</ac:layout-section>"
+	if !strings.Contains(storage, start) {
+		return storage
+	}
+	var b strings.Builder
+	b.WriteString("<ac:layout>")
+	loose := func(content string) {
+		if strings.TrimSpace(content) != "" {
+			b.WriteString(`<ac:layout-section ac:type="single"><ac:layout-cell>` + content + "</ac:layout-cell></ac:layout-section>")
+		}
+	}
+	for {
+		i := strings.Index(storage, start)
+		if i < 0 {
+			loose(storage)
+			break
+		}
+		j := strings.Index(storage[i:], end)
+		if j < 0 {
+			loose(storage)
+			break
+		}
+		j += i + len(end)
+		loose(storage[:i])
+		b.WriteString(storage[i:j])
+		storage = storage[j:]
+	}
+	b.WriteString("</ac:layout>")
+	return b.String()
+}
+
+// AppendLayout appends content to storage format, in a single column section
+// of its own when the storage has a layout
+func AppendLayout(storage, content string) string {
+	if content == "" || !strings.HasSuffix(storage, "</ac:layout>") {
+		return storage + content
+	}
+	return strings.TrimSuffix(storage, "</ac:layout>") +
+		`<ac:layout-section ac:type="single"><ac:layout-cell>` + content + "</ac:layout-cell></ac:layout-section></ac:layout>"
+}
+
+// Horizontal rule styles
+const (
+	// HorizontalRulesHR renders horizontal rules as hr elements
+	HorizontalRulesHR = "hr"
+	// HorizontalRulesSection starts a new layout section at each horizontal
+	// rule at the top level of a page
+	HorizontalRulesSection = "section"
+)
+
+// HorizontalRules is the style of horizontal rules
+var HorizontalRules = HorizontalRulesHR
+
+type sectionBreakTransformer struct{}
+
+// NewSectionBreakTransformer returns an AST transformer putting the content
+// between horizontal rules at the top level of a page into single column
+// layout sections, for HorizontalRulesSection. The rules themselves are left
+// out, ::: columns containers stay sections of their own and rules nested in
+// other blocks stay hr elements.
+func NewSectionBreakTransformer() parser.ASTTransformer {
+	return &sectionBreakTransformer{}
+}
+
+func (t *sectionBreakTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	breaks := false
+	for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+		breaks = breaks || c.Kind() == ast.KindThematicBreak
+	}
+	if !breaks {
+		return
+	}
+
+	var group []ast.Node
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+		section, column := &Columns{}, &Column{}
+		section.AppendChild(section, column)
+		doc.InsertBefore(doc, group[0], section)
+		for _, n := range group {
+			column.AppendChild(column, n)
+		}
+		group = nil
+	}
+	for c := doc.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		switch c.Kind() {
+		case ast.KindThematicBreak:
+			flush()
+			doc.RemoveChild(doc, c)
+		case KindColumns:
+			flush()
+		default:
+			group = append(group, c)
+		}
+		c = next
+	}
+	flush()
+}
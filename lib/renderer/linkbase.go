@@ -0,0 +1,33 @@
+package renderer
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// RewriteLink, when set, returns the URL a link destination of the markdown
+// file at filePath is published as. ok is false for links kept as they are.
+var RewriteLink func(filePath, destination string) (url string, ok bool)
+
+type linkRewriteTransformer struct {
+	filePath string
+}
+
+// NewLinkRewriteTransformer returns an AST transformer passing the links of
+// the markdown file at filePath through RewriteLink. Reference links are
+// resolved to their definition by then, so they are rewritten too.
+func NewLinkRewriteTransformer(filePath string) parser.ASTTransformer {
+	return &linkRewriteTransformer{filePath: filePath}
+}
+
+func (t *linkRewriteTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if link, ok := n.(*ast.Link); ok && entering {
+			if url, ok := RewriteLink(t.filePath, string(link.Destination)); ok {
+				link.Destination = []byte(url)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
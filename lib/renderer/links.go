@@ -0,0 +1,101 @@
+package renderer
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Link is a link, image or wikilink of a markdown source
+type Link struct {
+	Destination string
+	Image       bool
+	WikiLink    bool
+	// Title is the page a wikilink points to, empty for other links and
+	// embedded vault files
+	Title    string
+	Position Position
+}
+
+// FindLinks parses a markdown source and returns its links and images.
+// Wikilinks are included when Obsidian is set.
+func FindLinks(filePath string, source []byte) []Link {
+	p := goldmark.New(goldmark.WithExtensions(extension.GFM, extension.DefinitionList)).Parser()
+	if Obsidian {
+		p.AddOptions(parser.WithInlineParsers(util.Prioritized(NewWikiLinkParser(), 199)))
+	}
+	doc := p.Parse(text.NewReader(source))
+
+	var links []Link
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch v := n.(type) {
+		case *ast.Link:
+			links = append(links, Link{Destination: string(v.Destination), Position: nodePosition(filePath, source, v)})
+		case *ast.Image:
+			links = append(links, Link{Destination: string(v.Destination), Image: true, Position: nodePosition(filePath, source, v)})
+		case *WikiLink:
+			link := Link{Destination: v.Target, Image: v.Embed, WikiLink: true, Position: nodePosition(filePath, source, v)}
+			if _, ok := (&ConfluenceWikiLinkHTMLRender{filePath: filePath}).vaultFile(v.Target); !ok && v.Target != "" {
+				link.Title = wikiLinkTitle(v.Target)
+			}
+			links = append(links, link)
+		}
+		return ast.WalkContinue, nil
+	})
+	return links
+}
+
+// FindHeadings parses a markdown source and returns the text of its
+// headings by ID, the slugs Slugger generates when the source is rendered
+func FindHeadings(source []byte) map[string]string {
+	p := goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.DefinitionList),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	).Parser()
+	ctx := parser.NewContext(parser.WithIDs(NewSlugger()))
+	doc := p.Parse(text.NewReader(source), parser.WithContext(ctx))
+
+	headings := map[string]string{}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if h, ok := n.(*ast.Heading); ok && entering {
+			if id, ok := h.AttributeString("id"); ok {
+				headings[string(id.([]byte))] = string(h.Text(source))
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return headings
+}
+
+// IsLocalLink reports whether a link destination refers to a file next to
+// the markdown source rather than a URL or an anchor on the same page
+func IsLocalLink(destination string) bool {
+	if destination == "" || strings.HasPrefix(destination, "#") || strings.HasPrefix(destination, "//") || isDataURI([]byte(destination)) {
+		return false
+	}
+	u, err := url.Parse(destination)
+	return err == nil && u.Scheme == ""
+}
+
+// LocalLinkExists reports whether the file a local link destination points
+// to exists, relative to the markdown file or the working directory
+func LocalLinkExists(filePath, destination string) bool {
+	if i := strings.IndexAny(destination, "?#"); i >= 0 {
+		destination = destination[:i]
+	}
+	if destination == "" {
+		return true
+	}
+	_, err := localFile(filePath, []byte(destination))
+	return err == nil
+}
@@ -0,0 +1,214 @@
+package renderer
+
+import (
+	"strconv"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// List spacing
+const (
+	// ListSpacingKeep wraps the items of loose lists, with blank lines
+	// between them, in paragraphs like CommonMark does
+	ListSpacingKeep = "keep"
+	// ListSpacingTight renders every list tight, without a paragraph around
+	// the first text of an item
+	ListSpacingTight = "tight"
+)
+
+// ListSpacing is the spacing of list items
+var ListSpacing = ListSpacingKeep
+
+// KindTaskList is the NodeKind of TaskList nodes
+var KindTaskList = ast.NewNodeKind("TaskList")
+
+// TaskList is a list whose items are all tasks, rendered as a Confluence task
+// list. Its children are Tasks and the TaskLists nested in them.
+type TaskList struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node.Kind
+func (n *TaskList) Kind() ast.NodeKind {
+	return KindTaskList
+}
+
+// Dump implements ast.Node.Dump
+func (n *TaskList) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// KindTask is the NodeKind of Task nodes
+var KindTask = ast.NewNodeKind("Task")
+
+// Task is an item of a TaskList
+type Task struct {
+	ast.BaseBlock
+	Complete bool
+}
+
+// Kind implements ast.Node.Kind
+func (n *Task) Kind() ast.NodeKind {
+	return KindTask
+}
+
+// Dump implements ast.Node.Dump
+func (n *Task) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Complete": strconv.FormatBool(n.Complete)}, nil)
+}
+
+type listTransformer struct{}
+
+// NewListTransformer returns an AST transformer turning lists of tasks into
+// TaskLists, at any depth. Checkboxes of tasks in lists that also have other
+// items, which Confluence can not show, become ☐ and ☑. With
+// ListSpacingTight loose lists are made tight.
+func NewListTransformer() parser.ASTTransformer {
+	return &listTransformer{}
+}
+
+func (t *listTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var lists []*ast.List
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if l, ok := n.(*ast.List); ok && entering {
+			lists = append(lists, l)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// nested lists first, so they are task lists when their parent is
+	for i := len(lists) - 1; i >= 0; i-- {
+		l := lists[i]
+		if ListSpacing == ListSpacingTight && !l.IsTight {
+			l.IsTight = true
+			for item := l.FirstChild(); item != nil; item = item.NextSibling() {
+				if p, ok := item.FirstChild().(*ast.Paragraph); ok {
+					item.ReplaceChild(item, p, textBlock(p))
+				}
+			}
+		}
+
+		tasks := l.HasChildren()
+		for item := l.FirstChild(); item != nil; item = item.NextSibling() {
+			if taskCheckBox(item) == nil {
+				tasks = false
+			}
+		}
+		if tasks {
+			l.Parent().ReplaceChild(l.Parent(), l, taskList(l, source))
+			continue
+		}
+		for item := l.FirstChild(); item != nil; item = item.NextSibling() {
+			if box := taskCheckBox(item); box != nil {
+				symbol := "☐ "
+				if box.IsChecked {
+					symbol = "☑ "
+				}
+				box.Parent().ReplaceChild(box.Parent(), box, ast.NewString([]byte(symbol)))
+			}
+		}
+	}
+}
+
+// taskCheckBox returns the checkbox a list item starts with, if any
+func taskCheckBox(item ast.Node) *east.TaskCheckBox {
+	if first := item.FirstChild(); first != nil {
+		box, _ := first.FirstChild().(*east.TaskCheckBox)
+		return box
+	}
+	return nil
+}
+
+// taskList converts a list of tasks. Task lists nested in a task follow it
+// in the task list, other blocks stay in its body.
+func taskList(l *ast.List, source []byte) *TaskList {
+	tasks := &TaskList{}
+	for item := l.FirstChild(); item != nil; {
+		next := item.NextSibling()
+		box := taskCheckBox(item)
+		body := item.FirstChild()
+		body.RemoveChild(body, box)
+		if t, ok := body.FirstChild().(*ast.Text); ok {
+			t.Segment = t.Segment.TrimLeftSpace(source)
+		}
+		if p, ok := body.(*ast.Paragraph); ok {
+			item.ReplaceChild(item, p, textBlock(p))
+		}
+
+		task := &Task{Complete: box.IsChecked}
+		tasks.AppendChild(tasks, task)
+		var nested []ast.Node
+		for c := item.FirstChild(); c != nil; {
+			after := c.NextSibling()
+			if _, ok := c.(*TaskList); ok {
+				nested = append(nested, c)
+			} else {
+				task.AppendChild(task, c)
+			}
+			c = after
+		}
+		for _, n := range nested {
+			tasks.AppendChild(tasks, n)
+		}
+		item = next
+	}
+	return tasks
+}
+
+// textBlock returns a text block with the lines and content of a paragraph
+func textBlock(p *ast.Paragraph) *ast.TextBlock {
+	b := ast.NewTextBlock()
+	b.SetLines(p.Lines())
+	for c := p.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		b.AppendChild(b, c)
+		c = next
+	}
+	return b
+}
+
+// ConfluenceTaskListHTMLRender renders TaskLists as Confluence task lists,
+// numbering the tasks of a page
+type ConfluenceTaskListHTMLRender struct {
+	tasks int
+}
+
+// NewConfluenceTaskListHTMLRender returns a new ConfluenceTaskListHTMLRender.
+func NewConfluenceTaskListHTMLRender() renderer.NodeRenderer {
+	return &ConfluenceTaskListHTMLRender{}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *ConfluenceTaskListHTMLRender) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindTaskList, r.renderTaskList)
+	reg.Register(KindTask, r.renderTask)
+}
+
+func (r *ConfluenceTaskListHTMLRender) renderTaskList(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("<ac:task-list>\n")
+	} else {
+		_, _ = w.WriteString("</ac:task-list>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *ConfluenceTaskListHTMLRender) renderTask(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</ac:task-body>\n</ac:task>\n")
+		return ast.WalkContinue, nil
+	}
+	r.tasks++
+	status := "incomplete"
+	if node.(*Task).Complete {
+		status = "complete"
+	}
+	writeStrings(w, "<ac:task>\n<ac:task-id>", strconv.Itoa(r.tasks), "</ac:task-id>\n<ac:task-status>", status, "</ac:task-status>\n<ac:task-body>")
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,55 @@
+package renderer
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	// CodeBlockCollapseLines collapses code blocks longer than this many lines (0 disables)
+	CodeBlockCollapseLines = 0
+	// CodeBlockAttachLines attaches code blocks longer than this many lines as
+	// files shown with the view-file macro (0 disables)
+	CodeBlockAttachLines = 0
+)
+
+// codeBlockExtension is what a language may be to become the extension of an
+// attached code block, so it never names a path outside the attachment dir
+var codeBlockExtension = regexp.MustCompile(`^[a-z0-9+#-]+$`)
+
+func shouldCollapseCodeBlock(lines int) bool {
+	return CodeBlockCollapse || (CodeBlockCollapseLines > 0 && lines > CodeBlockCollapseLines)
+}
+
+func shouldAttachCodeBlock(lines int) bool {
+	return CodeBlockAttachLines > 0 && lines > CodeBlockAttachLines
+}
+
+// renderCodeBlockAttachment stores an overly long code block as a file and
+// writes a view-file macro referencing it. It returns the file to attach.
+func renderCodeBlockAttachment(w util.BufWriter, language string, body []byte) ([]string, error) {
+	dir, err := newAttachmentDir("codeblock")
+	if err != nil {
+		return nil, err
+	}
+	extension := ".txt"
+	if language := strings.ToLower(language); codeBlockExtension.MatchString(language) {
+		extension = "." + language
+	}
+	sum := sha1.Sum(body)
+	f := filepath.Join(dir, "code-"+hex.EncodeToString(sum[:])[:12]+extension)
+	if err := os.WriteFile(f, body, 0644); err != nil {
+		return nil, err
+	}
+
+	writeMacroStart(w, "view-file")
+	writeStrings(w, `<ac:parameter ac:name="name"><ri:attachment ri:filename="`, AttachmentName(f), `"/></ac:parameter>`)
+	_, _ = w.Write(macroEnd)
+	return []string{f}, nil
+}
@@ -0,0 +1,122 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+const (
+	// MacroBodyPlainText passes the fenced block as the macro's plain-text-body
+	MacroBodyPlainText string = "plain-text"
+	// MacroBodyAttachment attaches the fenced block as a file and references it by name
+	MacroBodyAttachment string = "attachment"
+)
+
+// MacroMapping maps a fenced code block language to an arbitrary Confluence macro
+type MacroMapping struct {
+	Language   string            `json:"language"`
+	Macro      string            `json:"macro"`
+	Body       string            `json:"body"`
+	Parameters map[string]string `json:"parameters"`
+	// AttachmentParameter names the macro parameter holding the attachment
+	// filename when Body is "attachment". Defaults to "name".
+	AttachmentParameter string `json:"attachmentParameter"`
+	// Extension is appended to generated attachment files, e.g. ".excalidraw"
+	Extension string `json:"extension"`
+}
+
+// MacroMappings holds the configured mappings keyed by fenced code language
+var MacroMappings = map[string]MacroMapping{}
+
+// LoadMacroMappings reads a JSON list of MacroMapping from configFile into MacroMappings
+func LoadMacroMappings(configFile string) error {
+	jsonData, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("unable to read macro mapping file %s: %s", configFile, err)
+	}
+
+	var mappings []MacroMapping
+	if err := json.Unmarshal(jsonData, &mappings); err != nil {
+		return fmt.Errorf("unable to parse macro mapping file %s: %s", configFile, err)
+	}
+
+	for _, mapping := range mappings {
+		if mapping.Language == "" || mapping.Macro == "" {
+			return fmt.Errorf("macro mapping in %s requires both language and macro", configFile)
+		}
+		switch mapping.Body {
+		case "":
+			mapping.Body = MacroBodyPlainText
+		case MacroBodyPlainText, MacroBodyAttachment:
+		default:
+			return fmt.Errorf("macro mapping for %s has unknown body type %q", mapping.Language, mapping.Body)
+		}
+		if mapping.AttachmentParameter == "" {
+			mapping.AttachmentParameter = "name"
+		}
+		MacroMappings[mapping.Language] = mapping
+	}
+	return nil
+}
+
+func getMacroMapping(language string) (MacroMapping, bool) {
+	mapping, ok := MacroMappings[language]
+	return mapping, ok
+}
+
+// renderMappedMacro writes the macro configured for a fenced block and
+// returns any file that has to be attached to the page.
+func renderMappedMacro(w util.BufWriter, mapping MacroMapping, body []byte) ([]string, error) {
+	var attachments []string
+	keys := make([]string, 0, len(mapping.Parameters))
+	for key := range mapping.Parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// write the attachment first, a failure must not leave half a macro
+	var f string
+	if mapping.Body == MacroBodyAttachment {
+		var err error
+		if f, err = writeBodyAttachment(mapping, body); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, f)
+	}
+
+	writeMacroStart(w, mapping.Macro)
+	for _, key := range keys {
+		writeParameter(w, key, mapping.Parameters[key])
+	}
+	if mapping.Body == MacroBodyAttachment {
+		writeParameter(w, mapping.AttachmentParameter, AttachmentName(f))
+	} else {
+		writePlainTextBody(w, body)
+	}
+	_, _ = w.Write(macroEnd)
+	return attachments, nil
+}
+
+// writeBodyAttachment stores a fenced block body in a temporary file so it can
+// be uploaded as an attachment.
+func writeBodyAttachment(mapping MacroMapping, body []byte) (string, error) {
+	dir, err := newAttachmentDir("macro")
+	if err != nil {
+		return "", err
+	}
+	extension := mapping.Extension
+	if extension == "" {
+		extension = "." + strings.ToLower(mapping.Language)
+	}
+	f := filepath.Join(dir, mapping.Macro+extension)
+	if err := os.WriteFile(f, body, 0644); err != nil {
+		return "", err
+	}
+	return f, nil
+}
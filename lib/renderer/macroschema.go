@@ -0,0 +1,171 @@
+package renderer
+
+import (
+	"embed"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// MacroSchema describes a known Confluence macro for CONFLUENCE-MACRO validation
+type MacroSchema struct {
+	Name       string   `json:"name"`
+	Parameters []string `json:"parameters"`
+	// Body is one of "none", "plain-text-body" or "rich-text-body"
+	Body string `json:"body"`
+}
+
+const macroBodyNone = "none"
+
+// Namespaces of the storage format prefixes
+const (
+	acNamespace = "http://atlassian.com/content"
+	riNamespace = "http://atlassian.com/resource/identifier"
+)
+
+//go:embed supported_macros.json
+var supportedMacrosFile embed.FS
+
+var (
+	// KnownMacros is the schema used to validate CONFLUENCE-MACRO blocks
+	KnownMacros = loadMacroSchemas("supported_macros.json")
+	// StrictMacros fails the conversion on CONFLUENCE-MACRO validation problems
+	// instead of printing a warning
+	StrictMacros = false
+
+	// macroAttributeName matches the names that can be written as macro
+	// attributes
+	macroAttributeName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+	// macroAttributes are the top level (not indented) keys a macro block accepts
+	macroAttributes = map[string]struct{}{
+		"name":           {},
+		"schema-version": {},
+		"macro-id":       {},
+	}
+)
+
+// macroDefinition is a parsed CONFLUENCE-MACRO block
+type macroDefinition struct {
+	Attributes []macroField
+	Parameters []macroField
+	Bodies     []macroField
+	// Children are the macros nested in the body, already rendered into
+	// its value and kept for validation
+	Children []macroDefinition
+}
+
+type macroField struct {
+	Key   string
+	Value string
+	// Offset is the source offset of the field, used in validation messages
+	Offset int
+}
+
+func (d macroDefinition) name() string {
+	for _, a := range d.Attributes {
+		if a.Key == "name" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// validateMacro checks a macro definition against KnownMacros and returns a
+// message per problem found.
+func validateMacro(d macroDefinition) []macroProblem {
+	var problems []macroProblem
+	for _, a := range d.Attributes {
+		if !macroAttributeName.MatchString(a.Key) {
+			problems = append(problems, macroProblem{Offset: a.Offset, Message: fmt.Sprintf("invalid macro attribute %q", a.Key), Fatal: true})
+		} else if _, ok := macroAttributes[a.Key]; !ok {
+			problems = append(problems, macroProblem{Offset: a.Offset, Message: fmt.Sprintf("unknown macro attribute %q (indent parameters to pass them to the macro)", a.Key)})
+		}
+	}
+	for _, b := range d.Bodies {
+		if b.Key != MacroContentKeyPlainTextBody && b.Key != MacroContentKeyRichTextBody {
+			problems = append(problems, macroProblem{Offset: b.Offset, Message: fmt.Sprintf("invalid macro body %q, use %s or %s", b.Key, MacroContentKeyPlainTextBody, MacroContentKeyRichTextBody), Fatal: true})
+		}
+	}
+
+	name := d.name()
+	if name == "" {
+		return append(problems, macroProblem{Message: "macro is missing the name attribute", Fatal: true})
+	}
+
+	schema, ok := KnownMacros[name]
+	if !ok {
+		return append(problems, macroProblem{Message: fmt.Sprintf("unknown macro %q, parameters can not be validated", name), Warning: true})
+	}
+
+	allowed := map[string]struct{}{}
+	for _, p := range schema.Parameters {
+		allowed[p] = struct{}{}
+	}
+	for _, p := range d.Parameters {
+		if _, ok := allowed[p.Key]; !ok {
+			problems = append(problems, macroProblem{Offset: p.Offset, Message: fmt.Sprintf("unknown parameter %q for macro %q, expected one of %s", p.Key, name, strings.Join(schema.Parameters, ", "))})
+		}
+	}
+	for _, b := range d.Bodies {
+		if schema.Body != b.Key {
+			problems = append(problems, macroProblem{Offset: b.Offset, Message: fmt.Sprintf("macro %q does not accept a %s, expected %s", name, b.Key, schema.Body)})
+		}
+	}
+	return problems
+}
+
+type macroProblem struct {
+	// Offset is the source offset of the offending field, 0 for the whole block
+	Offset  int
+	Message string
+	// Warning problems are reported as warnings even with StrictMacros
+	Warning bool
+	// Fatal problems always fail the conversion, as the macro can not be
+	// written
+	Fatal bool
+}
+
+// storageValue returns a macro value as is when it is well formed storage
+// format, like a page link in a parameter, and escaped as text otherwise
+func storageValue(value string) string {
+	decoder := xml.NewDecoder(strings.NewReader(`<value xmlns:ac="` + acNamespace + `" xmlns:ri="` + riNamespace + `">` + value + `</value>`))
+	decoder.Entity = xml.HTMLEntity
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return value
+		}
+		if err != nil {
+			return html.EscapeString(value)
+		}
+		if t, ok := token.(xml.StartElement); ok && t.Name.Space != "" && t.Name.Space != acNamespace && t.Name.Space != riNamespace {
+			return html.EscapeString(value)
+		}
+	}
+}
+
+func loadMacroSchemas(configFile string) map[string]MacroSchema {
+	jsonData, err := supportedMacrosFile.ReadFile(configFile)
+	if err != nil {
+		println(fmt.Sprintf("error reading file: %v", err))
+		return nil
+	}
+
+	var result []MacroSchema
+	err = json.Unmarshal(jsonData, &result)
+	if err != nil {
+		println(fmt.Sprintf("error parsing JSON data: %v", err))
+		return nil
+	}
+
+	schemas := make(map[string]MacroSchema)
+	for _, s := range result {
+		schemas[s.Name] = s
+	}
+	return schemas
+}
@@ -0,0 +1,164 @@
+package renderer
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/yaml"
+	"github.com/yuin/goldmark/ast"
+)
+
+// MacroSyntaxYAML is the info string suffix of CONFLUENCE-MACRO blocks
+// written in YAML, e.g. ```CONFLUENCE-MACRO yaml
+const MacroSyntaxYAML = "yaml"
+
+// isYAMLMacro tells whether a CONFLUENCE-MACRO block uses the YAML syntax
+func isYAMLMacro(source []byte, n *ast.FencedCodeBlock) bool {
+	if n.Info == nil {
+		return false
+	}
+	fields := strings.Fields(string(n.Info.Text(source)))
+	return len(fields) > 1 && strings.EqualFold(fields[1], MacroSyntaxYAML)
+}
+
+// parseYAMLMacro reads a CONFLUENCE-MACRO block written in YAML:
+//
+//	name: expand
+//	parameters:
+//	  title: Details
+//	body:
+//	  type: rich-text-body
+//	  content: <p>Hidden until expanded</p>
+//
+// Other top level keys, like schema-version, are macro attributes. A body
+// given as a string is a rich text body. Values are escaped, except rich
+// text bodies, which are storage format.
+//
+// A rich text body can nest macros, written the same way, under macros or
+// as a list instead of a mapping, e.g. the columns of a section macro.
+func (r *ConfluenceFencedCodeBlockHTMLRender) parseYAMLMacro(source []byte, n ast.Node) (macroDefinition, error) {
+	doc, err := yaml.Unmarshal(codeLines(source, n))
+	if err != nil {
+		return macroDefinition{}, err
+	}
+	return yamlMacro(doc, func(key string) int {
+		return yamlKeyOffset(source, n, key)
+	})
+}
+
+// yamlMacro reads a macro from its YAML mapping. offset locates keys in the
+// source for validation messages, nested macros are reported for the block.
+func yamlMacro(doc interface{}, offset func(key string) int) (macroDefinition, error) {
+	var d macroDefinition
+	fields, ok := doc.(map[string]interface{})
+	if !ok {
+		return d, fmt.Errorf("expected a mapping with name, parameters and body")
+	}
+
+	if name, ok := fields["name"]; ok {
+		d.Attributes = append(d.Attributes, macroField{Key: "name", Value: html.EscapeString(yamlScalar(name)), Offset: offset("name")})
+	}
+	for _, key := range sortedKeys(fields) {
+		switch key {
+		case "name":
+		case "parameters":
+			parameters, ok := fields[key].(map[string]interface{})
+			if !ok && fields[key] != nil {
+				return d, fmt.Errorf("parameters must be a mapping of parameter names to values")
+			}
+			for _, p := range sortedKeys(parameters) {
+				d.Parameters = append(d.Parameters, macroField{Key: p, Value: html.EscapeString(yamlScalar(parameters[p])), Offset: offset(p)})
+			}
+		case "body":
+			body, children, err := yamlMacroBody(fields[key])
+			if err != nil {
+				return d, fmt.Errorf("%s: %s", yamlScalar(fields["name"]), err)
+			}
+			body.Offset = offset("body")
+			d.Bodies = append(d.Bodies, body)
+			d.Children = children
+		default:
+			d.Attributes = append(d.Attributes, macroField{Key: key, Value: html.EscapeString(yamlScalar(fields[key])), Offset: offset(key)})
+		}
+	}
+	return d, nil
+}
+
+// yamlMacroBody reads the body of a YAML macro: a string, a list of nested
+// macros or a mapping with type, content and macros
+func yamlMacroBody(v interface{}) (macroField, []macroDefinition, error) {
+	body := macroField{Key: MacroContentKeyRichTextBody}
+	var macros interface{}
+	switch b := v.(type) {
+	case string:
+		body.Value = b
+	case []interface{}:
+		macros = b
+	case map[string]interface{}:
+		if t, ok := b["type"]; ok {
+			body.Key = yamlScalar(t)
+		}
+		body.Value = yamlScalar(b["content"])
+		macros = b["macros"]
+	default:
+		return body, nil, fmt.Errorf("body must be a string, a list of macros or a mapping with type, content and macros")
+	}
+	if body.Key == MacroContentKeyPlainTextBody {
+		if macros != nil {
+			return body, nil, fmt.Errorf("a plain-text-body can not contain macros")
+		}
+		body.Value = CDATA(body.Value)
+		return body, nil, nil
+	}
+
+	body.Value = storageValue(body.Value)
+	if macros == nil {
+		return body, nil, nil
+	}
+	list, ok := macros.([]interface{})
+	if !ok {
+		return body, nil, fmt.Errorf("macros must be a list")
+	}
+	var children []macroDefinition
+	for _, m := range list {
+		child, err := yamlMacro(m, func(string) int { return 0 })
+		if err != nil {
+			return body, nil, err
+		}
+		children = append(children, child)
+		body.Value += macroStorage(child)
+	}
+	return body, children, nil
+}
+
+// yamlScalar formats a YAML value as a macro parameter. Lists become comma
+// separated, the way Confluence stores multiple values.
+func yamlScalar(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = yamlScalar(item)
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// yamlKeyOffset returns the source offset of the first line of a block
+// defining key, for validation messages
+func yamlKeyOffset(source []byte, n ast.Node, key string) int {
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		text := string(line.Value(source))
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.HasPrefix(trimmed, key+":") || strings.HasPrefix(trimmed, `"`+key+`":`) {
+			return line.Start + len(text) - len(trimmed)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package renderer
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	// Notebook renders markdown exported from Jupyter or R Markdown: code
+	// blocks without a language are treated as cell output and data URI
+	// images are extracted and attached
+	Notebook = false
+	// NotebookOutputLines wraps cell outputs longer than this many lines in
+	// an expand macro (0 disables)
+	NotebookOutputLines = 20
+
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+)
+
+// stripANSI removes terminal color and cursor escape sequences
+func stripANSI(b []byte) []byte {
+	return ansiEscape.ReplaceAll(b, nil)
+}
+
+// renderNotebookOutput writes a cell output as a plain code macro, inside an
+// expand macro when it is long
+func renderNotebookOutput(w util.BufWriter, body []byte) {
+	body = stripANSI(body)
+	lines := bytes.Count(body, []byte("\n"))
+	expand := NotebookOutputLines > 0 && lines > NotebookOutputLines
+
+	if expand {
+		writeMacroStart(w, "expand")
+		writeParameter(w, "title", "Output ("+strconv.Itoa(lines)+" lines)")
+		_, _ = w.WriteString(`<ac:rich-text-body>`)
+	}
+	writeMacroStart(w, "code")
+	writeParameter(w, "language", "none")
+	writePlainTextBody(w, body)
+	_, _ = w.Write(macroEnd)
+	if expand {
+		_, _ = w.WriteString(`</ac:rich-text-body>`)
+		_, _ = w.Write(macroEnd)
+	}
+}
+
+// isDataURI reports whether an image destination embeds the image itself
+func isDataURI(destination []byte) bool {
+	return bytes.HasPrefix(destination, []byte("data:"))
+}
+
+// dataURIFile decodes a base64 data URI image into a temporary file named
+// after its content and returns its path
+func dataURIFile(destination []byte) (string, error) {
+	header, data, ok := strings.Cut(string(destination), ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return "", fmt.Errorf("unsupported data URI, only base64 encoded images can be attached")
+	}
+	mediaType := strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+
+	content, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 image data: %s", err)
+	}
+
+	extension := ".png"
+	if extensions, _ := mime.ExtensionsByType(mediaType); len(extensions) > 0 {
+		extension = extensions[0]
+	}
+	if mediaType == "image/jpeg" {
+		extension = ".jpg"
+	}
+
+	dir, err := newAttachmentDir("notebook")
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(content)
+	f := filepath.Join(dir, "image-"+hex.EncodeToString(sum[:])[:12]+extension)
+	if err := os.WriteFile(f, content, 0644); err != nil {
+		return "", err
+	}
+	return f, nil
+}
@@ -0,0 +1,373 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/yaml"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	// OpenAPIMacro is the name of an installed Open API viewer macro. When set,
+	// specs are handed to it instead of being rendered to tables.
+	OpenAPIMacro = ""
+
+	openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+)
+
+func isOpenAPICodeBlock(language string) bool {
+	return language == "openapi" || language == "swagger"
+}
+
+// renderOpenAPI renders an OpenAPI or Swagger spec given inline or as a path
+// relative to the markdown file
+func renderOpenAPI(w util.BufWriter, filePath string, body []byte) error {
+	spec := body
+	if location := strings.TrimSpace(string(body)); !strings.Contains(location, "\n") {
+		if !filepath.IsAbs(location) {
+			location = filepath.Join(filepath.Dir(filePath), location)
+		}
+		var err error
+		spec, err = os.ReadFile(location)
+		if err != nil {
+			return fmt.Errorf("unable to read OpenAPI spec: %s", err)
+		}
+	}
+
+	if OpenAPIMacro != "" {
+		writeMacroStart(w, OpenAPIMacro)
+		writePlainTextBody(w, spec)
+		_, _ = w.Write(macroEnd)
+		return nil
+	}
+
+	doc, err := parseOpenAPI(spec)
+	if err != nil {
+		return err
+	}
+	_, _ = w.WriteString(openAPIStorage(doc))
+	return nil
+}
+
+func parseOpenAPI(spec []byte) (map[string]interface{}, error) {
+	var v interface{}
+	var err error
+	if trimmed := strings.TrimSpace(string(spec)); strings.HasPrefix(trimmed, "{") {
+		err = json.Unmarshal(spec, &v)
+	} else {
+		v, err = yaml.Unmarshal(spec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse OpenAPI spec: %s", err)
+	}
+	doc, ok := v.(map[string]interface{})
+	if !ok || (doc["openapi"] == nil && doc["swagger"] == nil) {
+		return nil, fmt.Errorf("not an OpenAPI or Swagger spec")
+	}
+	return doc, nil
+}
+
+// OpenAPITitle returns the info.title of a spec
+func OpenAPITitle(spec []byte) (string, error) {
+	doc, err := parseOpenAPI(spec)
+	if err != nil {
+		return "", err
+	}
+	return str(object(doc, "info"), "title"), nil
+}
+
+type openAPIOperation struct {
+	Method    string
+	Path      string
+	Operation map[string]interface{}
+	// Parameters holds path level and operation parameters
+	Parameters []interface{}
+}
+
+// openAPIStorage renders the spec info, operations grouped by tag and the
+// schemas in storage format
+func openAPIStorage(doc map[string]interface{}) string {
+	var b strings.Builder
+	info := object(doc, "info")
+	b.WriteString(`<p><strong>` + esc(str(info, "title")) + `</strong>`)
+	if version := str(info, "version"); version != "" {
+		b.WriteString(` version ` + esc(version))
+	}
+	b.WriteString(`</p>`)
+	writeParagraphs(&b, str(info, "description"))
+
+	var servers []string
+	for _, s := range array(doc, "servers") {
+		server, _ := s.(map[string]interface{})
+		line := `<code>` + esc(str(server, "url")) + `</code>`
+		if d := str(server, "description"); d != "" {
+			line += ` ` + esc(d)
+		}
+		servers = append(servers, line)
+	}
+	if host := str(doc, "host"); host != "" {
+		servers = append(servers, `<code>`+esc(host+str(doc, "basePath"))+`</code>`)
+	}
+	if len(servers) > 0 {
+		b.WriteString(`<h2>Servers</h2><ul><li>` + strings.Join(servers, `</li><li>`) + `</li></ul>`)
+	}
+
+	groups, order := groupOperations(doc)
+	for _, tag := range order {
+		b.WriteString(`<h2>` + esc(tag) + `</h2>`)
+		for _, op := range groups[tag] {
+			writeOperation(&b, op)
+		}
+	}
+
+	schemas := object(object(doc, "components"), "schemas")
+	if len(schemas) == 0 {
+		schemas = object(doc, "definitions")
+	}
+	if len(schemas) > 0 {
+		b.WriteString(`<h2>Schemas</h2>`)
+		for _, name := range sortedKeys(schemas) {
+			writeExpand(&b, name, codeMacro("json", prettyJSON(schemas[name])))
+		}
+	}
+	return b.String()
+}
+
+// groupOperations groups operations by their first tag, in the order of the
+// spec tags followed by untagged operations
+func groupOperations(doc map[string]interface{}) (map[string][]openAPIOperation, []string) {
+	groups := map[string][]openAPIOperation{}
+	var order []string
+	for _, t := range array(doc, "tags") {
+		if name := str(t.(map[string]interface{}), "name"); name != "" {
+			order = append(order, name)
+			groups[name] = nil
+		}
+	}
+
+	paths := object(doc, "paths")
+	for _, path := range sortedKeys(paths) {
+		item, _ := paths[path].(map[string]interface{})
+		for _, method := range openAPIMethods {
+			operation, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			tag := "Operations"
+			if tags := array(operation, "tags"); len(tags) > 0 {
+				tag = fmt.Sprint(tags[0])
+			}
+			if _, ok := groups[tag]; !ok {
+				order = append(order, tag)
+			}
+			groups[tag] = append(groups[tag], openAPIOperation{
+				Method:     strings.ToUpper(method),
+				Path:       path,
+				Operation:  operation,
+				Parameters: append(array(item, "parameters"), array(operation, "parameters")...),
+			})
+		}
+	}
+
+	var used []string
+	for _, tag := range order {
+		if len(groups[tag]) > 0 {
+			used = append(used, tag)
+		}
+	}
+	return groups, used
+}
+
+func writeOperation(b *strings.Builder, op openAPIOperation) {
+	b.WriteString(`<h3><code>` + op.Method + ` ` + esc(op.Path) + `</code></h3>`)
+	summary := str(op.Operation, "summary")
+	if deprecated, _ := op.Operation["deprecated"].(bool); deprecated {
+		summary = strings.TrimSpace(summary + " (deprecated)")
+	}
+	if summary != "" {
+		b.WriteString(`<p>` + esc(summary) + `</p>`)
+	}
+	writeParagraphs(b, str(op.Operation, "description"))
+
+	var bodySchemas []interface{}
+	if len(op.Parameters) > 0 {
+		var rows [][]string
+		for _, p := range op.Parameters {
+			param, _ := p.(map[string]interface{})
+			schema := object(param, "schema")
+			if str(param, "in") == "body" {
+				bodySchemas = append(bodySchemas, schema)
+			}
+			if len(schema) == 0 {
+				schema = param
+			}
+			required, _ := param["required"].(bool)
+			rows = append(rows, []string{
+				`<code>` + esc(str(param, "name")) + `</code>`,
+				esc(str(param, "in")),
+				esc(schemaType(schema)),
+				yesNo(required),
+				esc(str(param, "description")),
+			})
+		}
+		b.WriteString(`<h4>Parameters</h4>`)
+		writeTable(b, []string{"Name", "In", "Type", "Required", "Description"}, rows)
+	}
+
+	if requestBody := object(op.Operation, "requestBody"); len(requestBody) > 0 {
+		var rows [][]string
+		content := object(requestBody, "content")
+		for _, mediaType := range sortedKeys(content) {
+			schema := object(object(content, mediaType), "schema")
+			bodySchemas = append(bodySchemas, schema)
+			rows = append(rows, []string{`<code>` + esc(mediaType) + `</code>`, esc(schemaType(schema))})
+		}
+		b.WriteString(`<h4>Request body</h4>`)
+		writeParagraphs(b, str(requestBody, "description"))
+		writeTable(b, []string{"Content type", "Schema"}, rows)
+	}
+
+	if responses := object(op.Operation, "responses"); len(responses) > 0 {
+		var rows [][]string
+		for _, status := range sortedKeys(responses) {
+			response, _ := responses[status].(map[string]interface{})
+			var types []string
+			if schema := object(response, "schema"); len(schema) > 0 {
+				types = append(types, schemaType(schema))
+			}
+			content := object(response, "content")
+			for _, mediaType := range sortedKeys(content) {
+				types = append(types, mediaType+": "+schemaType(object(object(content, mediaType), "schema")))
+			}
+			rows = append(rows, []string{esc(status), esc(str(response, "description")), esc(strings.Join(types, ", "))})
+		}
+		b.WriteString(`<h4>Responses</h4>`)
+		writeTable(b, []string{"Status", "Description", "Schema"}, rows)
+	}
+
+	for _, schema := range bodySchemas {
+		if s, ok := schema.(map[string]interface{}); ok && len(s) > 0 && s["$ref"] == nil {
+			writeExpand(b, "Request schema", codeMacro("json", prettyJSON(s)))
+		}
+	}
+}
+
+// schemaType describes a schema in one line, e.g. "array of Pet"
+func schemaType(schema map[string]interface{}) string {
+	if ref := str(schema, "$ref"); ref != "" {
+		return ref[strings.LastIndex(ref, "/")+1:]
+	}
+	for _, combiner := range []string{"oneOf", "anyOf", "allOf"} {
+		if options := array(schema, combiner); len(options) > 0 {
+			var names []string
+			for _, o := range options {
+				option, _ := o.(map[string]interface{})
+				names = append(names, schemaType(option))
+			}
+			separator := " | "
+			if combiner == "allOf" {
+				separator = " & "
+			}
+			return strings.Join(names, separator)
+		}
+	}
+	t := str(schema, "type")
+	if t == "array" {
+		return "array of " + schemaType(object(schema, "items"))
+	}
+	if format := str(schema, "format"); format != "" {
+		t += " (" + format + ")"
+	}
+	if t == "" {
+		return "object"
+	}
+	return t
+}
+
+func writeTable(b *strings.Builder, header []string, rows [][]string) {
+	b.WriteString(`<table><tbody><tr>`)
+	for _, h := range header {
+		b.WriteString(`<th>` + h + `</th>`)
+	}
+	b.WriteString(`</tr>`)
+	for _, row := range rows {
+		b.WriteString(`<tr>`)
+		for _, cell := range row {
+			b.WriteString(`<td>` + cell + `</td>`)
+		}
+		b.WriteString(`</tr>`)
+	}
+	b.WriteString(`</tbody></table>`)
+}
+
+func writeExpand(b *strings.Builder, title, body string) {
+	b.WriteString(`<ac:structured-macro ac:name="expand" ac:schema-version="1">`)
+	b.WriteString(`<ac:parameter ac:name="title">` + esc(title) + `</ac:parameter>`)
+	b.WriteString(`<ac:rich-text-body>` + body + `</ac:rich-text-body></ac:structured-macro>`)
+}
+
+func codeMacro(language, body string) string {
+	return `<ac:structured-macro ac:name="code" ac:schema-version="1">` +
+		`<ac:parameter ac:name="language">` + language + `</ac:parameter>` +
+		`<ac:plain-text-body>` + CDATA(body) + `</ac:plain-text-body>` +
+		`</ac:structured-macro>`
+}
+
+func writeParagraphs(b *strings.Builder, text string) {
+	for _, p := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			b.WriteString(`<p>` + esc(p) + `</p>`)
+		}
+	}
+}
+
+func prettyJSON(v interface{}) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func esc(s string) string {
+	return html.EscapeString(s)
+}
+
+func object(m map[string]interface{}, key string) map[string]interface{} {
+	v, _ := m[key].(map[string]interface{})
+	return v
+}
+
+func array(m map[string]interface{}, key string) []interface{} {
+	v, _ := m[key].([]interface{})
+	return v
+}
+
+func str(m map[string]interface{}, key string) string {
+	if v, ok := m[key]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,123 @@
+package renderer
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// PageLink is the Confluence page a link points to
+type PageLink struct {
+	Title    string
+	SpaceKey string
+	// Anchor is the heading the link points to, if any
+	Anchor string
+}
+
+// ResolvePageLink, when set, looks up the page a link destination of the
+// markdown file at filePath points to. Links it resolves are rendered as page
+// links, which follow renames of the page. ok is false for links to anything
+// else. Links that can not be resolved are reported to diagnostics.
+var ResolvePageLink func(filePath, destination string, diagnostics *Diagnostics) (link PageLink, ok bool)
+
+// ConfluencePageLinkHTMLRender renders links and autolinks to Confluence
+// pages as page links and other links as HTML
+type ConfluencePageLinkHTMLRender struct {
+	filePath    string
+	diagnostics *Diagnostics
+}
+
+// NewConfluencePageLinkHTMLRender returns a new
+// ConfluencePageLinkHTMLRender for the markdown file at filePath.
+func NewConfluencePageLinkHTMLRender(filePath string, diagnostics *Diagnostics) renderer.NodeRenderer {
+	return &ConfluencePageLinkHTMLRender{filePath: filePath, diagnostics: diagnostics}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *ConfluencePageLinkHTMLRender) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindLink, r.renderLink)
+	reg.Register(ast.KindAutoLink, r.renderAutoLink)
+}
+
+func (r *ConfluencePageLinkHTMLRender) renderLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.Link)
+	// lookups are cached, resolving again on exit is cheap
+	if link, ok := ResolvePageLink(r.filePath, string(n.Destination), r.diagnostics); ok {
+		if entering {
+			writePageLinkStart(w, link)
+			_, _ = w.WriteString(`<ac:link-body>`)
+		} else {
+			_, _ = w.WriteString(`</ac:link-body></ac:link>`)
+		}
+		return ast.WalkContinue, nil
+	}
+
+	// like the goldmark HTML renderer
+	if !entering {
+		_, _ = w.WriteString("</a>")
+		return ast.WalkContinue, nil
+	}
+	_, _ = w.WriteString(`<a href="`)
+	if !html.IsDangerousURL(n.Destination) {
+		_, _ = w.Write(util.EscapeHTML(util.URLEscape(n.Destination, true)))
+	}
+	_ = w.WriteByte('"')
+	if n.Title != nil {
+		_, _ = w.WriteString(` title="`)
+		html.DefaultWriter.Write(w, n.Title)
+		_ = w.WriteByte('"')
+	}
+	if n.Attributes() != nil {
+		html.RenderAttributes(w, n, html.LinkAttributeFilter)
+	}
+	_ = w.WriteByte('>')
+	return ast.WalkContinue, nil
+}
+
+func (r *ConfluencePageLinkHTMLRender) renderAutoLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.AutoLink)
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	url := n.URL(source)
+	label := n.Label(source)
+	if link, ok := ResolvePageLink(r.filePath, string(url), r.diagnostics); ok && n.AutoLinkType == ast.AutoLinkURL {
+		// without a body the link shows the current page title
+		writePageLinkStart(w, link)
+		_, _ = w.WriteString(`</ac:link>`)
+		return ast.WalkContinue, nil
+	}
+
+	// like the goldmark HTML renderer
+	_, _ = w.WriteString(`<a href="`)
+	if n.AutoLinkType == ast.AutoLinkEmail && !bytes.HasPrefix(bytes.ToLower(url), []byte("mailto:")) {
+		_, _ = w.WriteString("mailto:")
+	}
+	_, _ = w.Write(util.EscapeHTML(util.URLEscape(url, false)))
+	if n.Attributes() != nil {
+		_ = w.WriteByte('"')
+		html.RenderAttributes(w, n, html.LinkAttributeFilter)
+		_ = w.WriteByte('>')
+	} else {
+		_, _ = w.WriteString(`">`)
+	}
+	_, _ = w.Write(util.EscapeHTML(label))
+	_, _ = w.WriteString(`</a>`)
+	return ast.WalkContinue, nil
+}
+
+// writePageLinkStart writes an ac:link up to the page it links to
+func writePageLinkStart(w util.BufWriter, link PageLink) {
+	_, _ = w.WriteString(`<ac:link`)
+	if link.Anchor != "" {
+		writeStrings(w, ` ac:anchor="`, string(util.EscapeHTML([]byte(link.Anchor))), `"`)
+	}
+	writeStrings(w, `><ri:page ri:content-title="`, string(util.EscapeHTML([]byte(link.Title))), `"`)
+	if link.SpaceKey != "" {
+		writeStrings(w, ` ri:space-key="`, string(util.EscapeHTML([]byte(link.SpaceKey))), `"`)
+	}
+	_, _ = w.WriteString(`/>`)
+}
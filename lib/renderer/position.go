@@ -0,0 +1,153 @@
+package renderer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// Position is a location in a markdown source file
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	if p.Line == 0 {
+		return p.File
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// PositionError is a rendering error annotated with the source position of
+// the node that caused it
+type PositionError struct {
+	Position Position
+	Err      error
+}
+
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Position, e.Err)
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// newPositionError annotates err with the position of node n, unless it
+// already carries a position
+func newPositionError(filePath string, source []byte, n ast.Node, err error) error {
+	var positionError *PositionError
+	if errors.As(err, &positionError) {
+		return err
+	}
+	return &PositionError{
+		Position: nodePosition(filePath, source, n),
+		Err:      err,
+	}
+}
+
+// lineIndexes are the line start offsets of the sources passed to
+// IndexLines, by their first byte
+var lineIndexes = struct {
+	sync.Mutex
+	starts map[*byte][]int
+}{starts: map[*byte][]int{}}
+
+// IndexLines indexes the lines of source until release is called, so that
+// the positions of warnings and errors in a large file are found without
+// counting its lines from the start for each of them. source must not be
+// changed in between.
+func IndexLines(source []byte) (release func()) {
+	if len(source) == 0 {
+		return func() {}
+	}
+	starts := []int{0}
+	for i, c := range source {
+		if c == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	key := &source[0]
+	lineIndexes.Lock()
+	lineIndexes.starts[key] = starts
+	lineIndexes.Unlock()
+	return func() {
+		lineIndexes.Lock()
+		delete(lineIndexes.starts, key)
+		lineIndexes.Unlock()
+	}
+}
+
+// offsetPosition converts a byte offset in source to a 1-based line and column
+func offsetPosition(filePath string, source []byte, offset int) Position {
+	if offset > len(source) {
+		offset = len(source)
+	}
+	var starts []int
+	if len(source) > 0 {
+		lineIndexes.Lock()
+		starts = lineIndexes.starts[&source[0]]
+		lineIndexes.Unlock()
+	}
+	if starts == nil {
+		before := source[:offset]
+		return Position{
+			File:   filePath,
+			Line:   bytes.Count(before, []byte("\n")) + 1,
+			Column: offset - bytes.LastIndexByte(before, '\n'),
+		}
+	}
+	line := sort.Search(len(starts), func(i int) bool { return starts[i] > offset })
+	return Position{
+		File:   filePath,
+		Line:   line,
+		Column: offset - starts[line-1] + 1,
+	}
+}
+
+// nodePosition returns the position of the first source segment belonging to
+// n, looking at its children and then its ancestors for nodes such as
+// images that carry no segment of their own
+func nodePosition(filePath string, source []byte, n ast.Node) Position {
+	if offset, ok := nodeOffset(n); ok {
+		return offsetPosition(filePath, source, offset)
+	}
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Type() == ast.TypeBlock && p.Lines().Len() > 0 {
+			return offsetPosition(filePath, source, p.Lines().At(0).Start)
+		}
+	}
+	return Position{File: filePath}
+}
+
+func nodeOffset(n ast.Node) (int, bool) {
+	switch v := n.(type) {
+	case *ast.FencedCodeBlock:
+		if v.Info != nil {
+			return v.Info.Segment.Start, true
+		}
+	case *ast.Text:
+		return v.Segment.Start, true
+	case *WikiLink:
+		return v.Offset, true
+	case *ast.RawHTML:
+		if v.Segments.Len() > 0 {
+			return v.Segments.At(0).Start, true
+		}
+	}
+	if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+		return n.Lines().At(0).Start, true
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if offset, ok := nodeOffset(c); ok {
+			return offset, true
+		}
+	}
+	return 0, false
+}
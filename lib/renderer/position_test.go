@@ -0,0 +1,29 @@
+package renderer
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOffsetPositionIndexed(t *testing.T) {
+	source := []byte("first\nsecond line\n\nfourth")
+	var want []Position
+	for offset := 0; offset <= len(source); offset++ {
+		want = append(want, offsetPosition("a.md", source, offset))
+	}
+	defer IndexLines(source)()
+	for offset := 0; offset <= len(source); offset++ {
+		if got := offsetPosition("a.md", source, offset); got != want[offset] {
+			t.Errorf("offset %d: got %s, want %s", offset, got, want[offset])
+		}
+	}
+}
+
+func BenchmarkOffsetPosition(b *testing.B) {
+	source := bytes.Repeat([]byte("a line of a large markdown file\n"), 1<<17)
+	defer IndexLines(source)()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		offsetPosition("bench.md", source, (i*7919)%len(source))
+	}
+}
@@ -0,0 +1,166 @@
+package renderer
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Block quote styles
+const (
+	// QuoteBlockquote renders block quotes as blockquote elements
+	QuoteBlockquote = "blockquote"
+	// QuotePanel renders block quotes as grey panels, which look the same
+	// in every Confluence theme
+	QuotePanel = "panel"
+)
+
+// QuoteStyle is the style of block quotes
+var QuoteStyle = QuoteBlockquote
+
+// quoteAttributionDashes start the attribution line of a block quote
+var quoteAttributionDashes = []string{"—", "―", "--", "–"}
+
+// KindQuoteAttribution is the NodeKind of QuoteAttribution nodes
+var KindQuoteAttribution = ast.NewNodeKind("QuoteAttribution")
+
+// QuoteAttribution is the source a block quote ends with, > — Author. Its
+// children are the inline nodes after the dash.
+type QuoteAttribution struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node.Kind
+func (n *QuoteAttribution) Kind() ast.NodeKind {
+	return KindQuoteAttribution
+}
+
+// Dump implements ast.Node.Dump
+func (n *QuoteAttribution) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+type quoteTransformer struct{}
+
+// NewQuoteTransformer returns an AST transformer turning the last line of a
+// block quote into a QuoteAttribution when it starts with a dash, like
+// > — Author, on a line of its own or right below the quote. It runs after
+// the callout transformer, so callouts keep their last line.
+func NewQuoteTransformer() parser.ASTTransformer {
+	return &quoteTransformer{}
+}
+
+func (t *quoteTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var quotes []*ast.Blockquote
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if q, ok := n.(*ast.Blockquote); ok && entering {
+			quotes = append(quotes, q)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, q := range quotes {
+		p, ok := q.LastChild().(*ast.Paragraph)
+		if !ok || p.Lines().Len() == 0 {
+			continue
+		}
+		last := p.Lines().At(p.Lines().Len() - 1)
+		if p == q.FirstChild() && p.Lines().Len() == 1 {
+			// a quote of nothing but a dash line
+			continue
+		}
+
+		// the inline nodes of the last line
+		var nodes []ast.Node
+		for c := p.FirstChild(); c != nil; c = c.NextSibling() {
+			if offset, ok := nodeOffset(c); ok && offset >= last.Start {
+				nodes = append(nodes, c)
+			}
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+		first, ok := nodes[0].(*ast.Text)
+		if !ok {
+			continue
+		}
+		value := string(first.Segment.Value(source))
+		for _, dash := range quoteAttributionDashes {
+			if !strings.HasPrefix(value, dash) || strings.TrimSpace(value[len(dash):]) == "" && len(nodes) == 1 {
+				continue
+			}
+			segment := first.Segment.WithStart(first.Segment.Start + len(dash))
+			first.Segment = segment.TrimLeftSpace(source)
+			attribution := &QuoteAttribution{}
+			for _, c := range nodes {
+				attribution.AppendChild(attribution, c)
+			}
+			if t, ok := p.LastChild().(*ast.Text); ok {
+				t.SetSoftLineBreak(false)
+			}
+			if p.HasChildren() {
+				q.InsertAfter(q, p, attribution)
+			} else {
+				q.ReplaceChild(q, p, attribution)
+			}
+			break
+		}
+	}
+}
+
+// ConfluenceQuoteHTMLRender renders block quotes in the QuoteStyle and
+// their attribution right aligned
+type ConfluenceQuoteHTMLRender struct{}
+
+// NewConfluenceQuoteHTMLRender returns a new ConfluenceQuoteHTMLRender.
+func NewConfluenceQuoteHTMLRender() renderer.NodeRenderer {
+	return &ConfluenceQuoteHTMLRender{}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *ConfluenceQuoteHTMLRender) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindBlockquote, r.renderBlockquote)
+	reg.Register(KindQuoteAttribution, r.renderAttribution)
+}
+
+func (r *ConfluenceQuoteHTMLRender) renderBlockquote(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if QuoteStyle != QuotePanel {
+		// like the goldmark HTML renderer
+		if entering && node.Attributes() != nil {
+			_, _ = w.WriteString("<blockquote")
+			html.RenderAttributes(w, node, html.BlockquoteAttributeFilter)
+			_, _ = w.WriteString(">\n")
+		} else if entering {
+			_, _ = w.WriteString("<blockquote>\n")
+		} else {
+			_, _ = w.WriteString("</blockquote>\n")
+		}
+		return ast.WalkContinue, nil
+	}
+
+	if !entering {
+		_, _ = w.WriteString("</ac:rich-text-body></ac:structured-macro>\n")
+		return ast.WalkContinue, nil
+	}
+	writeMacroStart(w, "panel")
+	writeParameter(w, "borderStyle", "solid")
+	writeParameter(w, "borderColor", "#DFE1E6")
+	writeParameter(w, "bgColor", "#F4F5F7")
+	_, _ = w.WriteString("<ac:rich-text-body>\n")
+	return ast.WalkContinue, nil
+}
+
+func (r *ConfluenceQuoteHTMLRender) renderAttribution(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(`<p style="text-align: right;"><em>— `)
+	} else {
+		_, _ = w.WriteString("</em></p>\n")
+	}
+	return ast.WalkContinue, nil
+}
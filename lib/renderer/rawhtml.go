@@ -0,0 +1,63 @@
+package renderer
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// rawHTMLTransformer warns about the raw HTML left in the document, which is
+// omitted from the page
+type rawHTMLTransformer struct {
+	filePath    string
+	diagnostics *Diagnostics
+}
+
+// NewRawHTMLTransformer returns an AST transformer warning about raw HTML
+// that no other transformer converted. It runs after them and changes
+// nothing. HTML comments are not warned about, they are not shown either way,
+// and neither are closing tags, after their opening tag was.
+func NewRawHTMLTransformer(filePath string, diagnostics *Diagnostics) parser.ASTTransformer {
+	return &rawHTMLTransformer{filePath: filePath, diagnostics: diagnostics}
+}
+
+func (t *rawHTMLTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var raw string
+		switch v := n.(type) {
+		case *ast.HTMLBlock:
+			if v.HTMLBlockType == ast.HTMLBlockType2 {
+				return ast.WalkSkipChildren, nil
+			}
+			raw = htmlBlockText(source, v)
+		case *ast.RawHTML:
+			for i := 0; i < v.Segments.Len(); i++ {
+				segment := v.Segments.At(i)
+				raw += string(segment.Value(source))
+			}
+			if strings.HasPrefix(raw, "<!--") || strings.HasPrefix(raw, "</") {
+				return ast.WalkSkipChildren, nil
+			}
+		default:
+			return ast.WalkContinue, nil
+		}
+		t.diagnostics.warn(WarningHTML, t.filePath, source, n, "raw HTML %s is left out", rawHTMLTag(raw))
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// rawHTMLTag returns the first line of raw HTML, shortened to name it in a
+// warning
+func rawHTMLTag(raw string) string {
+	tag, _, _ := strings.Cut(strings.TrimSpace(raw), "\n")
+	if len(tag) > 40 {
+		tag = strings.ToValidUTF8(tag[:40], "") + "..."
+	}
+	return tag
+}
@@ -0,0 +1,149 @@
+// Package renderertest runs golden file tests of renderers: markdown
+// fixtures are rendered to storage format and compared with the
+// .storage.xml files next to them. It is used by the tests of
+// markdown2confluence and can be used by the tests of extensions.
+//
+//	func TestGolden(t *testing.T) {
+//		renderertest.Run(t, "testdata/*.md", func(filePath string, source []byte) (string, error) {
+//			return renderertest.Render(filePath, source, myExtension)
+//		})
+//	}
+//
+// Run go test -update to write the golden files of new fixtures, or rewrite
+// them after a deliberate change of the output, and review their diff.
+package renderertest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	e "github.com/justmiles/go-markdown2confluence/lib/extension"
+	r "github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the tests")
+
+// GoldenSuffix replaces the .md suffix of a fixture in the name of its
+// golden file
+const GoldenSuffix = ".storage.xml"
+
+// RenderFunc renders the markdown source of the fixture at filePath to
+// storage format
+type RenderFunc func(filePath string, source []byte) (string, error)
+
+// Run renders the fixtures matching pattern, like testdata/*.md, in a
+// subtest each, and compares them with their golden file. With -update the
+// golden files are written instead.
+func Run(t *testing.T, pattern string, render RenderFunc) {
+	t.Helper()
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no fixtures match %s", pattern)
+	}
+	for _, file := range files {
+		file := file
+		t.Run(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)), func(t *testing.T) {
+			source, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := render(file, source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			Compare(t, strings.TrimSuffix(file, filepath.Ext(file))+GoldenSuffix, got)
+		})
+	}
+}
+
+// Compare compares rendered storage format with the golden file, or writes
+// it to the golden file with -update
+func Compare(t *testing.T, golden, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%s, run go test -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("rendered storage format differs from %s:\n%s", golden, got)
+	}
+}
+
+// Render renders markdown like markdown2confluence does, with GitHub
+// flavored markdown, definition lists, heading ids and the Confluence
+// extension, followed by extenders. Renderer settings are taken from the
+// package variables of the renderer package. Attachments are ignored.
+func Render(filePath string, source []byte, extenders ...goldmark.Extender) (string, error) {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.DefinitionList),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(html.WithXHTML()),
+		goldmark.WithExtensions(e.NewConfluenceExtension(filePath)),
+		goldmark.WithExtensions(extenders...),
+	)
+	var buf bytes.Buffer
+	defer r.IndexLines(source)()
+	ctx := parser.NewContext(parser.WithIDs(r.NewSlugger()))
+	if err := md.Convert(source, &buf, parser.WithContext(ctx)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// RegisteredKinds returns the kinds of nodes a node renderer renders
+func RegisteredKinds(nr renderer.NodeRenderer) []ast.NodeKind {
+	var reg kindRegisterer
+	nr.RegisterFuncs(&reg)
+	return reg
+}
+
+type kindRegisterer []ast.NodeKind
+
+func (k *kindRegisterer) Register(kind ast.NodeKind, _ renderer.NodeRendererFunc) {
+	*k = append(*k, kind)
+}
+
+// RecordKinds returns an extender adding the kinds of the nodes of every
+// document it parses to kinds, after all other AST transformers ran. Tests
+// can use it to check that their fixtures cover every node of a renderer.
+func RecordKinds(kinds map[ast.NodeKind]bool) goldmark.Extender {
+	return &kindRecorder{kinds: kinds}
+}
+
+type kindRecorder struct {
+	kinds map[ast.NodeKind]bool
+}
+
+func (k *kindRecorder) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(k, 1<<20)))
+}
+
+func (k *kindRecorder) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			k.kinds[n.Kind()] = true
+		}
+		return ast.WalkContinue, nil
+	})
+}
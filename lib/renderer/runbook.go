@@ -0,0 +1,88 @@
+package renderer
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// DefaultDestructiveCommands matches shell commands that delete data or
+// take systems down
+const DefaultDestructiveCommands = `(?i)\brm\s+-\w*[rf]|\b(drop|truncate)\s+(table|database|schema)\b|\bkubectl\s+(delete|drain)\b|\bterraform\s+destroy\b|\bhelm\s+(uninstall|delete)\b|\bdd\s+if=|\bmkfs\b|\bgit\s+push\s.*(--force|-f)\b|\b(shutdown|reboot|halt)\b`
+
+var (
+	// Runbook renders shell code blocks with a noformat block of the bare
+	// commands to copy, and a warning panel before destructive commands
+	Runbook = false
+	// DestructiveCommands matches the command lines Runbook warns about
+	DestructiveCommands = regexp.MustCompile(DefaultDestructiveCommands)
+
+	runbookLanguages = map[string]bool{"sh": true, "bash": true, "shell": true, "zsh": true, "console": true, "shell-session": true}
+	// shellPrompt matches the prompt of a command in a console transcript,
+	// e.g. "$ " or "user@host:~/src$ "
+	shellPrompt = regexp.MustCompile(`^\s*[\w.@:~/-]*\$\s`)
+)
+
+func isRunbookCodeBlock(language string) bool {
+	return Runbook && runbookLanguages[strings.ToLower(language)]
+}
+
+// runbookCommands returns the command lines of a shell block. Console
+// transcripts keep only the prompted lines, without their prompt and output.
+func runbookCommands(language string, body []byte) string {
+	lines := strings.Split(strings.TrimRight(string(body), "\n"), "\n")
+	transcript := language == "console" || language == "shell-session"
+	if !transcript {
+		for _, line := range lines {
+			if shellPrompt.MatchString(line) {
+				transcript = true
+				break
+			}
+		}
+	}
+	if !transcript {
+		return strings.Join(lines, "\n")
+	}
+
+	var commands []string
+	continued := false
+	for _, line := range lines {
+		if prompt := shellPrompt.FindString(line); prompt != "" {
+			line = line[len(prompt):]
+		} else if !continued {
+			continue
+		}
+		commands = append(commands, line)
+		continued = strings.HasSuffix(strings.TrimSpace(line), `\`)
+	}
+	return strings.Join(commands, "\n")
+}
+
+// writeDestructiveWarning writes a warning panel quoting the destructive
+// commands of a block, if any
+func writeDestructiveWarning(w util.BufWriter, commands string) {
+	var destructive []string
+	for _, line := range strings.Split(commands, "\n") {
+		if DestructiveCommands.MatchString(line) {
+			destructive = append(destructive, `<code>`+html.EscapeString(strings.TrimSpace(line))+`</code>`)
+		}
+	}
+	if len(destructive) == 0 {
+		return
+	}
+	_, _ = w.WriteString(`<ac:structured-macro ac:name="warning" ac:schema-version="1">`)
+	_, _ = w.WriteString(`<ac:parameter ac:name="title">Destructive command</ac:parameter><ac:rich-text-body>`)
+	_, _ = w.WriteString(`<p>Check the target before running ` + strings.Join(destructive, ", ") + `</p>`)
+	_, _ = w.WriteString(`</ac:rich-text-body></ac:structured-macro>`)
+}
+
+// writeCopyBlock writes the commands of a block as a noformat macro to copy
+// from
+func writeCopyBlock(w util.BufWriter, commands string) {
+	_, _ = w.WriteString(`<ac:structured-macro ac:name="noformat" ac:schema-version="1">`)
+	_, _ = w.WriteString(`<ac:parameter ac:name="title">Copy command</ac:parameter>`)
+	writeStrings(w, `<ac:plain-text-body>`, CDATA(commands), `</ac:plain-text-body>`)
+	_, _ = w.WriteString(`</ac:structured-macro>`)
+}
@@ -0,0 +1,131 @@
+package renderer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaxTitleLength is the longest page title Confluence accepts, in UTF-16
+// code units as Confluence counts them
+const MaxTitleLength = 255
+
+var (
+	// TransliterateSlugs spells heading IDs in ASCII, e.g. "Über Größe" as
+	// uber-grosse instead of über-größe. Scripts without a Latin spelling,
+	// like Chinese, are kept.
+	TransliterateSlugs = false
+
+	// compositions is the inverse of decompositions
+	compositions = map[[2]rune]rune{}
+
+	// asciiLetters are the ASCII spellings of letters that do not decompose
+	// to an ASCII base letter
+	asciiLetters = map[rune]string{
+		'ß': "ss", 'Æ': "AE", 'æ': "ae", 'Ø': "O", 'ø': "o", 'Œ': "OE", 'œ': "oe",
+		'Đ': "D", 'đ': "d", 'Ð': "D", 'ð': "d", 'Þ': "TH", 'þ': "th", 'Ł': "L", 'ł': "l",
+		'ı': "i", 'Ħ': "H", 'ħ': "h", 'Ŧ': "T", 'ŧ': "t", 'ĸ': "q", 'Ŋ': "NG", 'ŋ': "ng",
+	}
+)
+
+func init() {
+	for composed, parts := range decompositions {
+		compositions[parts] = composed
+	}
+}
+
+// NormalizeText composes letters followed by a combining mark into one
+// precomposed letter, like Unicode normalization form C. File names on macOS
+// are decomposed, so "Café" read from a directory would not match "Café"
+// typed in a link.
+func NormalizeText(s string) string {
+	if s == "" {
+		return s
+	}
+	runes := []rune(s)
+	out := runes[:1]
+	for _, r := range runes[1:] {
+		last := len(out) - 1
+		if composed, ok := compositions[[2]rune{out[last], r}]; ok {
+			out[last] = composed
+			continue
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// Transliterate spells accented Latin letters and full-width forms in ASCII.
+// Other characters are kept.
+func Transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range NormalizeText(s) {
+		b.WriteString(transliterateRune(r))
+	}
+	return b.String()
+}
+
+func transliterateRune(r rune) string {
+	if r < 0x80 {
+		return string(r)
+	}
+	if spelling, ok := asciiLetters[r]; ok {
+		return spelling
+	}
+	// full-width ASCII, e.g. "ＡＢＣ１２３"
+	if r >= 0xFF01 && r <= 0xFF5E {
+		return string(r - 0xFEE0)
+	}
+	if r == 0x3000 {
+		return " "
+	}
+	// strip the marks of a Latin letter, possibly several like ǘ. Kana keep
+	// their voicing marks, が is not か.
+	for r < 0x3000 {
+		parts, ok := decompositions[r]
+		if !ok {
+			break
+		}
+		r = parts[0]
+	}
+	if unicode.Is(unicode.Mn, r) {
+		return ""
+	}
+	return string(r)
+}
+
+// TitleLength is the length of a title as Confluence counts it: characters
+// outside the Basic Multilingual Plane, such as emoji and rare CJK
+// ideographs, count twice
+func TitleLength(title string) int {
+	n := 0
+	for _, r := range title {
+		n += titleRuneLength(r)
+	}
+	return n
+}
+
+func titleRuneLength(r rune) int {
+	if r > 0xFFFF {
+		return 2
+	}
+	return 1
+}
+
+// TruncateTitle shortens a title to max as counted by TitleLength, ending in
+// an ellipsis. Characters are never split.
+func TruncateTitle(title string, max int) string {
+	if TitleLength(title) <= max {
+		return title
+	}
+	const ellipsis = "…"
+	n := 0
+	var b strings.Builder
+	for _, r := range title {
+		if n+titleRuneLength(r)+TitleLength(ellipsis) > max {
+			break
+		}
+		n += titleRuneLength(r)
+		b.WriteRune(r)
+	}
+	return strings.TrimRightFunc(b.String(), unicode.IsSpace) + ellipsis
+}
@@ -0,0 +1,62 @@
+package renderer
+
+// decompositions maps precomposed letters to their base letter and combining
+// mark, the canonical decompositions of the Unicode Character Database for
+// Latin-1, Latin Extended-A and kana
+var decompositions = map[rune][2]rune{
+	'À': {'A', '\u0300'}, 'Á': {'A', '\u0301'}, 'Â': {'A', '\u0302'}, 'Ã': {'A', '\u0303'},
+	'Ä': {'A', '\u0308'}, 'Å': {'A', '\u030a'}, 'Ç': {'C', '\u0327'}, 'È': {'E', '\u0300'},
+	'É': {'E', '\u0301'}, 'Ê': {'E', '\u0302'}, 'Ë': {'E', '\u0308'}, 'Ì': {'I', '\u0300'},
+	'Í': {'I', '\u0301'}, 'Î': {'I', '\u0302'}, 'Ï': {'I', '\u0308'}, 'Ñ': {'N', '\u0303'},
+	'Ò': {'O', '\u0300'}, 'Ó': {'O', '\u0301'}, 'Ô': {'O', '\u0302'}, 'Õ': {'O', '\u0303'},
+	'Ö': {'O', '\u0308'}, 'Ù': {'U', '\u0300'}, 'Ú': {'U', '\u0301'}, 'Û': {'U', '\u0302'},
+	'Ü': {'U', '\u0308'}, 'Ý': {'Y', '\u0301'}, 'à': {'a', '\u0300'}, 'á': {'a', '\u0301'},
+	'â': {'a', '\u0302'}, 'ã': {'a', '\u0303'}, 'ä': {'a', '\u0308'}, 'å': {'a', '\u030a'},
+	'ç': {'c', '\u0327'}, 'è': {'e', '\u0300'}, 'é': {'e', '\u0301'}, 'ê': {'e', '\u0302'},
+	'ë': {'e', '\u0308'}, 'ì': {'i', '\u0300'}, 'í': {'i', '\u0301'}, 'î': {'i', '\u0302'},
+	'ï': {'i', '\u0308'}, 'ñ': {'n', '\u0303'}, 'ò': {'o', '\u0300'}, 'ó': {'o', '\u0301'},
+	'ô': {'o', '\u0302'}, 'õ': {'o', '\u0303'}, 'ö': {'o', '\u0308'}, 'ù': {'u', '\u0300'},
+	'ú': {'u', '\u0301'}, 'û': {'u', '\u0302'}, 'ü': {'u', '\u0308'}, 'ý': {'y', '\u0301'},
+	'ÿ': {'y', '\u0308'}, 'Ā': {'A', '\u0304'}, 'ā': {'a', '\u0304'}, 'Ă': {'A', '\u0306'},
+	'ă': {'a', '\u0306'}, 'Ą': {'A', '\u0328'}, 'ą': {'a', '\u0328'}, 'Ć': {'C', '\u0301'},
+	'ć': {'c', '\u0301'}, 'Ĉ': {'C', '\u0302'}, 'ĉ': {'c', '\u0302'}, 'Ċ': {'C', '\u0307'},
+	'ċ': {'c', '\u0307'}, 'Č': {'C', '\u030c'}, 'č': {'c', '\u030c'}, 'Ď': {'D', '\u030c'},
+	'ď': {'d', '\u030c'}, 'Ē': {'E', '\u0304'}, 'ē': {'e', '\u0304'}, 'Ĕ': {'E', '\u0306'},
+	'ĕ': {'e', '\u0306'}, 'Ė': {'E', '\u0307'}, 'ė': {'e', '\u0307'}, 'Ę': {'E', '\u0328'},
+	'ę': {'e', '\u0328'}, 'Ě': {'E', '\u030c'}, 'ě': {'e', '\u030c'}, 'Ĝ': {'G', '\u0302'},
+	'ĝ': {'g', '\u0302'}, 'Ğ': {'G', '\u0306'}, 'ğ': {'g', '\u0306'}, 'Ġ': {'G', '\u0307'},
+	'ġ': {'g', '\u0307'}, 'Ģ': {'G', '\u0327'}, 'ģ': {'g', '\u0327'}, 'Ĥ': {'H', '\u0302'},
+	'ĥ': {'h', '\u0302'}, 'Ĩ': {'I', '\u0303'}, 'ĩ': {'i', '\u0303'}, 'Ī': {'I', '\u0304'},
+	'ī': {'i', '\u0304'}, 'Ĭ': {'I', '\u0306'}, 'ĭ': {'i', '\u0306'}, 'Į': {'I', '\u0328'},
+	'į': {'i', '\u0328'}, 'İ': {'I', '\u0307'}, 'Ĵ': {'J', '\u0302'}, 'ĵ': {'j', '\u0302'},
+	'Ķ': {'K', '\u0327'}, 'ķ': {'k', '\u0327'}, 'Ĺ': {'L', '\u0301'}, 'ĺ': {'l', '\u0301'},
+	'Ļ': {'L', '\u0327'}, 'ļ': {'l', '\u0327'}, 'Ľ': {'L', '\u030c'}, 'ľ': {'l', '\u030c'},
+	'Ń': {'N', '\u0301'}, 'ń': {'n', '\u0301'}, 'Ņ': {'N', '\u0327'}, 'ņ': {'n', '\u0327'},
+	'Ň': {'N', '\u030c'}, 'ň': {'n', '\u030c'}, 'Ō': {'O', '\u0304'}, 'ō': {'o', '\u0304'},
+	'Ŏ': {'O', '\u0306'}, 'ŏ': {'o', '\u0306'}, 'Ő': {'O', '\u030b'}, 'ő': {'o', '\u030b'},
+	'Ŕ': {'R', '\u0301'}, 'ŕ': {'r', '\u0301'}, 'Ŗ': {'R', '\u0327'}, 'ŗ': {'r', '\u0327'},
+	'Ř': {'R', '\u030c'}, 'ř': {'r', '\u030c'}, 'Ś': {'S', '\u0301'}, 'ś': {'s', '\u0301'},
+	'Ŝ': {'S', '\u0302'}, 'ŝ': {'s', '\u0302'}, 'Ş': {'S', '\u0327'}, 'ş': {'s', '\u0327'},
+	'Š': {'S', '\u030c'}, 'š': {'s', '\u030c'}, 'Ţ': {'T', '\u0327'}, 'ţ': {'t', '\u0327'},
+	'Ť': {'T', '\u030c'}, 'ť': {'t', '\u030c'}, 'Ũ': {'U', '\u0303'}, 'ũ': {'u', '\u0303'},
+	'Ū': {'U', '\u0304'}, 'ū': {'u', '\u0304'}, 'Ŭ': {'U', '\u0306'}, 'ŭ': {'u', '\u0306'},
+	'Ů': {'U', '\u030a'}, 'ů': {'u', '\u030a'}, 'Ű': {'U', '\u030b'}, 'ű': {'u', '\u030b'},
+	'Ų': {'U', '\u0328'}, 'ų': {'u', '\u0328'}, 'Ŵ': {'W', '\u0302'}, 'ŵ': {'w', '\u0302'},
+	'Ŷ': {'Y', '\u0302'}, 'ŷ': {'y', '\u0302'}, 'Ÿ': {'Y', '\u0308'}, 'Ź': {'Z', '\u0301'},
+	'ź': {'z', '\u0301'}, 'Ż': {'Z', '\u0307'}, 'ż': {'z', '\u0307'}, 'Ž': {'Z', '\u030c'},
+	'ž': {'z', '\u030c'}, 'が': {'か', '\u3099'}, 'ぎ': {'き', '\u3099'}, 'ぐ': {'く', '\u3099'},
+	'げ': {'け', '\u3099'}, 'ご': {'こ', '\u3099'}, 'ざ': {'さ', '\u3099'}, 'じ': {'し', '\u3099'},
+	'ず': {'す', '\u3099'}, 'ぜ': {'せ', '\u3099'}, 'ぞ': {'そ', '\u3099'}, 'だ': {'た', '\u3099'},
+	'ぢ': {'ち', '\u3099'}, 'づ': {'つ', '\u3099'}, 'で': {'て', '\u3099'}, 'ど': {'と', '\u3099'},
+	'ば': {'は', '\u3099'}, 'ぱ': {'は', '\u309a'}, 'び': {'ひ', '\u3099'}, 'ぴ': {'ひ', '\u309a'},
+	'ぶ': {'ふ', '\u3099'}, 'ぷ': {'ふ', '\u309a'}, 'べ': {'へ', '\u3099'}, 'ぺ': {'へ', '\u309a'},
+	'ぼ': {'ほ', '\u3099'}, 'ぽ': {'ほ', '\u309a'}, 'ゔ': {'う', '\u3099'}, 'ゞ': {'ゝ', '\u3099'},
+	'ガ': {'カ', '\u3099'}, 'ギ': {'キ', '\u3099'}, 'グ': {'ク', '\u3099'}, 'ゲ': {'ケ', '\u3099'},
+	'ゴ': {'コ', '\u3099'}, 'ザ': {'サ', '\u3099'}, 'ジ': {'シ', '\u3099'}, 'ズ': {'ス', '\u3099'},
+	'ゼ': {'セ', '\u3099'}, 'ゾ': {'ソ', '\u3099'}, 'ダ': {'タ', '\u3099'}, 'ヂ': {'チ', '\u3099'},
+	'ヅ': {'ツ', '\u3099'}, 'デ': {'テ', '\u3099'}, 'ド': {'ト', '\u3099'}, 'バ': {'ハ', '\u3099'},
+	'パ': {'ハ', '\u309a'}, 'ビ': {'ヒ', '\u3099'}, 'ピ': {'ヒ', '\u309a'}, 'ブ': {'フ', '\u3099'},
+	'プ': {'フ', '\u309a'}, 'ベ': {'ヘ', '\u3099'}, 'ペ': {'ヘ', '\u309a'}, 'ボ': {'ホ', '\u3099'},
+	'ポ': {'ホ', '\u309a'}, 'ヴ': {'ウ', '\u3099'}, 'ヷ': {'ワ', '\u3099'}, 'ヸ': {'ヰ', '\u3099'},
+	'ヹ': {'ヱ', '\u3099'}, 'ヺ': {'ヲ', '\u3099'}, 'ヾ': {'ヽ', '\u3099'},
+}
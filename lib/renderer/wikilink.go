@@ -0,0 +1,247 @@
+package renderer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	// Obsidian enables [[wikilinks]], ![[embeds]] and > [!note] callouts
+	Obsidian = false
+	// MDX enables Docusaurus MDX tolerance, mapping :::note containers to callouts
+	MDX = false
+	// WikiLinkTitles maps lower cased note names and front matter aliases to
+	// the title of the page the note is published as
+	WikiLinkTitles = map[string]string{}
+	// VaultFiles maps the base name of every non markdown file in the vault
+	// to its path, as embeds do not need to be relative to the note
+	VaultFiles = map[string]string{}
+)
+
+// imageExtensions are embedded as images, other files with the view-file macro
+var imageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true,
+	".svg": true, ".webp": true, ".bmp": true,
+}
+
+// KindWikiLink is the NodeKind of WikiLink nodes
+var KindWikiLink = ast.NewNodeKind("WikiLink")
+
+// WikiLink is an Obsidian [[Target#Heading|Label]] link or ![[Target|size]] embed
+type WikiLink struct {
+	ast.BaseInline
+	Target  string
+	Heading string
+	Label   string
+	Embed   bool
+	// Offset is the position of the link in the source
+	Offset int
+}
+
+// Kind implements ast.Node.Kind
+func (n *WikiLink) Kind() ast.NodeKind {
+	return KindWikiLink
+}
+
+// Dump implements ast.Node.Dump
+func (n *WikiLink) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Target":  n.Target,
+		"Heading": n.Heading,
+		"Label":   n.Label,
+		"Embed":   fmt.Sprint(n.Embed),
+	}, nil)
+}
+
+type wikiLinkParser struct{}
+
+// NewWikiLinkParser returns an inline parser for Obsidian wikilinks and embeds
+func NewWikiLinkParser() parser.InlineParser {
+	return &wikiLinkParser{}
+}
+
+func (p *wikiLinkParser) Trigger() []byte {
+	return []byte{'!', '['}
+}
+
+func (p *wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	embed := false
+	if len(line) > 0 && line[0] == '!' {
+		embed = true
+		line = line[1:]
+	}
+	if len(line) < 4 || line[0] != '[' || line[1] != '[' {
+		return nil
+	}
+	end := strings.Index(string(line[2:]), "]]")
+	if end < 1 {
+		return nil
+	}
+	inner := string(line[2 : 2+end])
+	if strings.ContainsAny(inner, "[]") {
+		return nil
+	}
+
+	n := &WikiLink{Embed: embed, Offset: segment.Start}
+	if i := strings.Index(inner, "|"); i >= 0 {
+		n.Label = strings.TrimSpace(inner[i+1:])
+		inner = inner[:i]
+	}
+	if i := strings.Index(inner, "#"); i >= 0 {
+		n.Heading = strings.TrimSpace(inner[i+1:])
+		inner = inner[:i]
+	}
+	n.Target = strings.TrimSpace(inner)
+
+	consumed := 2 + end + 2
+	if embed {
+		consumed++
+	}
+	block.Advance(consumed)
+	return n
+}
+
+// ConfluenceWikiLinkHTMLRender renders WikiLink nodes as Confluence page
+// links, attachments and include macros
+type ConfluenceWikiLinkHTMLRender struct {
+	Attachments []string
+	filePath    string
+}
+
+// NewConfluenceWikiLinkHTMLRender returns a new ConfluenceWikiLinkHTMLRender.
+func NewConfluenceWikiLinkHTMLRender(filePath string) *ConfluenceWikiLinkHTMLRender {
+	return &ConfluenceWikiLinkHTMLRender{filePath: filePath}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *ConfluenceWikiLinkHTMLRender) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindWikiLink, r.renderWikiLink)
+}
+
+func (r *ConfluenceWikiLinkHTMLRender) renderWikiLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*WikiLink)
+
+	if n.Embed {
+		if f, ok := r.vaultFile(n.Target); ok {
+			if err := r.renderEmbeddedFile(w, f, n.Label); err != nil {
+				return ast.WalkStop, newPositionError(r.filePath, source, n, err)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		// embedded notes are included from their published page
+		_, _ = w.WriteString(`<ac:structured-macro ac:name="include" ac:schema-version="1"><ac:parameter ac:name=""><ac:link><ri:page ri:content-title="`)
+		_, _ = w.Write(util.EscapeHTML([]byte(wikiLinkTitle(n.Target))))
+		_, _ = w.WriteString(`"/></ac:link></ac:parameter></ac:structured-macro>`)
+		return ast.WalkSkipChildren, nil
+	}
+
+	_, _ = w.WriteString(`<ac:link`)
+	if n.Heading != "" {
+		_, _ = w.WriteString(` ac:anchor="`)
+		_, _ = w.Write(util.EscapeHTML([]byte(n.Heading)))
+		_ = w.WriteByte('"')
+	}
+	_ = w.WriteByte('>')
+	if n.Target != "" {
+		_, _ = w.WriteString(`<ri:page ri:content-title="`)
+		_, _ = w.Write(util.EscapeHTML([]byte(wikiLinkTitle(n.Target))))
+		_, _ = w.WriteString(`"/>`)
+	}
+	label := n.Label
+	if label == "" && n.Target == "" {
+		label = n.Heading
+	}
+	if label != "" {
+		_, _ = w.WriteString(`<ac:link-body>`)
+		_, _ = w.Write(util.EscapeHTML([]byte(label)))
+		_, _ = w.WriteString(`</ac:link-body>`)
+	}
+	_, _ = w.WriteString(`</ac:link>`)
+	return ast.WalkSkipChildren, nil
+}
+
+// renderEmbeddedFile attaches an embedded file and shows it as an image or
+// with the view-file macro. size is an Obsidian width or widthxheight.
+func (r *ConfluenceWikiLinkHTMLRender) renderEmbeddedFile(w util.BufWriter, f, size string) error {
+	if isDrawioFile(f) {
+		attachments, err := renderDrawio(w, f)
+		if err != nil {
+			return err
+		}
+		r.Attachments = append(r.Attachments, attachments...)
+		return nil
+	}
+
+	if !imageExtensions[strings.ToLower(filepath.Ext(f))] {
+		r.Attachments = append(r.Attachments, f)
+		_, _ = w.WriteString(`<ac:structured-macro ac:name="view-file" ac:schema-version="1"><ac:parameter ac:name="name"><ri:attachment ri:filename="`)
+		_, _ = w.WriteString(AttachmentName(f))
+		_, _ = w.WriteString(`"/></ac:parameter></ac:structured-macro>`)
+		return nil
+	}
+
+	if isSharedAsset(f) {
+		writeSharedAssetImage(w, f)
+		return nil
+	}
+
+	r.Attachments = append(r.Attachments, f)
+	_, _ = w.WriteString(`<ac:image`)
+	width, height, _ := strings.Cut(size, "x")
+	if isDigits(width) {
+		_, _ = w.WriteString(` ac:width="` + width + `"`)
+	}
+	if isDigits(height) {
+		_, _ = w.WriteString(` ac:height="` + height + `"`)
+	}
+	_, _ = w.WriteString(`><ri:attachment ri:filename="`)
+	_, _ = w.WriteString(AttachmentName(f))
+	_, _ = w.WriteString(`"/></ac:image>`)
+	return nil
+}
+
+// vaultFile resolves an embed target relative to the note or by its name
+// anywhere in the vault
+func (r *ConfluenceWikiLinkHTMLRender) vaultFile(target string) (string, bool) {
+	if strings.HasSuffix(strings.ToLower(target), ".md") {
+		return "", false
+	}
+	if f, err := localFile(r.filePath, []byte(target)); err == nil {
+		return f, true
+	}
+	f, ok := VaultFiles[filepath.Base(target)]
+	return f, ok
+}
+
+// wikiLinkTitle returns the page title for a note name or alias, falling back
+// to the name itself for pages that are not part of the vault
+func wikiLinkTitle(target string) string {
+	name := strings.TrimSuffix(filepath.Base(target), ".md")
+	if title, ok := WikiLinkTitles[strings.ToLower(name)]; ok {
+		return title
+	}
+	return name
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
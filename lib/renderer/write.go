@@ -0,0 +1,77 @@
+package renderer
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Fragments of storage format written for many nodes, kept as byte slices
+// so writing them does not convert a string each time
+var (
+	plainTextBodyStart = []byte(`<ac:plain-text-body><![CDATA[`)
+	plainTextBodyEnd   = []byte(`]]></ac:plain-text-body>`)
+	macroEnd           = []byte(`</ac:structured-macro>`)
+	cdataEnd           = []byte(`]]>`)
+	// cdataSplit ends a CDATA section between ]] and >, which continue in a
+	// new section
+	cdataSplit = []byte(`]]]]><![CDATA[>`)
+)
+
+// builders are reused for storage format that is needed as a string, e.g.
+// macros nested in the body of another macro
+var builders = sync.Pool{New: func() interface{} { return new(strings.Builder) }}
+
+// buildString returns what write writes, using a pooled builder
+func buildString(write func(w *strings.Builder)) string {
+	b := builders.Get().(*strings.Builder)
+	defer builders.Put(b)
+	b.Reset()
+	write(b)
+	return b.String()
+}
+
+// writeStrings writes each of parts to w, instead of concatenating them first
+func writeStrings(w io.StringWriter, parts ...string) {
+	for _, s := range parts {
+		_, _ = w.WriteString(s)
+	}
+}
+
+// writeMacroStart opens a structured macro
+func writeMacroStart(w io.StringWriter, name string) {
+	writeStrings(w, `<ac:structured-macro ac:name="`, name, `" ac:schema-version="1">`)
+}
+
+// writeParameter writes a macro parameter, value is written as is
+func writeParameter(w io.StringWriter, name, value string) {
+	writeStrings(w, `<ac:parameter ac:name="`, name, `">`, value, `</ac:parameter>`)
+}
+
+// writePlainTextBody writes body as the plain text body of a macro
+func writePlainTextBody(w io.Writer, body []byte) {
+	_, _ = w.Write(plainTextBodyStart)
+	writeCDATAContent(w, body)
+	_, _ = w.Write(plainTextBodyEnd)
+}
+
+// writeCDATAContent writes data into an open CDATA section, splitting the
+// section at every ]]> in data, which would end it early
+func writeCDATAContent(w io.Writer, data []byte) {
+	for {
+		i := bytes.Index(data, cdataEnd)
+		if i < 0 {
+			_, _ = w.Write(data)
+			return
+		}
+		_, _ = w.Write(data[:i])
+		_, _ = w.Write(cdataSplit)
+		data = data[i+len(cdataEnd):]
+	}
+}
+
+// CDATA returns s as a CDATA section, split at every ]]> in s
+func CDATA(s string) string {
+	return "<![CDATA[" + strings.ReplaceAll(s, string(cdataEnd), string(cdataSplit)) + "]]>"
+}
@@ -0,0 +1,45 @@
+package lib
+
+import (
+	"runtime"
+	"sync"
+)
+
+// renderedFile is the result of rendering a file ahead of its upload
+type renderedFile struct {
+	body   string
+	images []string
+	err    error
+}
+
+// renderAll renders files concurrently, at most m.RenderParallel at a time,
+// before anything is uploaded. Render then returns the stored result, so
+// validating, previewing and comparing files does not wait for uploads.
+// Rendering only reads the shared settings, each file is rendered by one
+// goroutine.
+func (m *Markdown2Confluence) renderAll(files []MarkdownFile) {
+	workers := m.RenderParallel
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	queue := make(chan *MarkdownFile)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range queue {
+				body, images, err := f.Render(m)
+				f.rendered = &renderedFile{body: body, images: images, err: err}
+			}
+		}()
+	}
+	for i := range files {
+		if m.Interrupted() {
+			break
+		}
+		queue <- &files[i]
+	}
+	close(queue)
+	wg.Wait()
+}
@@ -0,0 +1,187 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// RepoConfigFiles are looked up in the working directory and its parents
+var RepoConfigFiles = []string{".markdown2confluence.yml", ".markdown2confluence.yaml", ".markdown2confluence.json"}
+
+// RepoConfig holds settings a repository declares for everyone publishing it
+type RepoConfig struct {
+	// MinVersion is the oldest markdown2confluence release allowed to
+	// publish the repository, so all contributors produce the same output
+	MinVersion string `json:"min-version"`
+	// Warnings is the warning level of warning classes: ignore, warn or
+	// error, which fails the file with --strict
+	Warnings map[string]string `json:"warnings"`
+	// Profiles are named sets of flag values, such as the endpoint, space,
+	// parent, labels and renderer options of a destination. Profile is the
+	// one used without --profile.
+	Profiles map[string]map[string]interface{} `json:"profiles"`
+	Profile  string                            `json:"profile"`
+
+	path string
+}
+
+// FindRepoConfig loads the closest repo config file above dir. It returns
+// nil without an error when there is none.
+func FindRepoConfig(dir string) (*RepoConfig, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		for _, name := range RepoConfigFiles {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			var config RepoConfig
+			if err := decodeConfigFile(path, &config); err != nil {
+				return nil, fmt.Errorf("Unable to read repo config %s: %s", path, err)
+			}
+			config.path = path
+			return &config, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// CheckVersion fails when version is older than the minimum version of the
+// repo config. Development builds (0.0.0) are not checked.
+func (c *RepoConfig) CheckVersion(version string) error {
+	if c == nil || c.MinVersion == "" || version == "0.0.0" {
+		return nil
+	}
+	if CompareVersions(version, c.MinVersion) < 0 {
+		return fmt.Errorf("markdown2confluence %s is older than version %s required by %s, run 'markdown2confluence self-update'", version, c.MinVersion, c.path)
+	}
+	return nil
+}
+
+// WarningPolicy returns the warning levels of the repo config by warning
+// class, for renderer.WarningPolicy
+func (c *RepoConfig) WarningPolicy() (map[renderer.WarningClass]renderer.WarningLevel, error) {
+	policy := map[renderer.WarningClass]renderer.WarningLevel{}
+	if c == nil {
+		return policy, nil
+	}
+	for name, value := range c.Warnings {
+		class, err := renderer.ParseWarningClass(name)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid warnings in %s: %s", c.path, err)
+		}
+		level, err := renderer.ParseWarningLevel(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid warnings in %s: %s", c.path, err)
+		}
+		policy[class] = level
+	}
+	return policy, nil
+}
+
+// ProfileFlags returns the flag values of the profile name, or of the
+// default profile of the repo config when name is empty, by flag name. Lists
+// are joined with commas and ${VAR} references are expanded, so credentials
+// can stay in the environment. There are none without a profile.
+func (c *RepoConfig) ProfileFlags(name string) (map[string]string, error) {
+	if name == "" && c != nil {
+		name = c.Profile
+	}
+	if name == "" {
+		return nil, nil
+	}
+	if c == nil {
+		return nil, fmt.Errorf("no profile %s, there is no %s", name, strings.Join(RepoConfigFiles, ", "))
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		var names []string
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		available := "none"
+		if len(names) > 0 {
+			available = strings.Join(names, ", ")
+		}
+		return nil, fmt.Errorf("no profile %s in %s, it has %s", name, c.path, available)
+	}
+	flags := map[string]string{}
+	for flag, value := range profile {
+		var values []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			values = v
+		case map[string]interface{}:
+			return nil, fmt.Errorf("profile %s in %s: %s must be a value or a list", name, c.path, flag)
+		default:
+			values = []interface{}{v}
+		}
+		s := make([]string, len(values))
+		for i, v := range values {
+			switch v := v.(type) {
+			case nil:
+			case float64:
+				s[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				s[i] = os.ExpandEnv(fmt.Sprint(v))
+			}
+		}
+		flags[flag] = strings.Join(s, ",")
+	}
+	return flags, nil
+}
+
+// CompareVersions compares two semantic versions, with or without a leading
+// v, and returns -1, 0 or 1. A pre-release sorts before its release.
+func CompareVersions(a, b string) int {
+	a, preA := splitPreRelease(strings.TrimPrefix(a, "v"))
+	b, preB := splitPreRelease(strings.TrimPrefix(b, "v"))
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			y, _ = strconv.Atoi(partsB[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	case preA < preB:
+		return -1
+	}
+	return 1
+}
+
+func splitPreRelease(version string) (string, string) {
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		return version[:i], version[i+1:]
+	}
+	return version, ""
+}
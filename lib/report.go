@@ -0,0 +1,141 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// Actions recorded for a page in a Report
+const (
+	ActionCreated   = "created"
+	ActionUpdated   = "updated"
+	ActionFailed    = "failed"
+	ActionValidated = "validated"
+	// ActionUnchanged is recorded when --content-hash found the page up to date
+	ActionUnchanged = "unchanged"
+	// ActionSkipped is recorded for files an interrupted run did not get to
+	ActionSkipped = "skipped"
+	// ActionConflict is recorded when --on-conflict skip left a page someone
+	// else edited during the run as it was
+	ActionConflict = "conflict"
+	// ActionEdited is recorded when --manual-edits skip left a page edited in
+	// Confluence since it was last published as it was
+	ActionEdited = "edited"
+)
+
+// PageResult is the outcome of publishing a single markdown file
+type PageResult struct {
+	Path   string `json:"path"`
+	Title  string `json:"title"`
+	PageID string `json:"pageId,omitempty"`
+	URL    string `json:"url,omitempty"`
+	// WebURL is the full URL of the page, URL its short link
+	WebURL string `json:"webUrl,omitempty"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+	// Line is the source line an error was reported for, if known
+	Line int `json:"line,omitempty"`
+	// Endpoint is the name of the endpoint the page was published to when
+	// publishing to several endpoints
+	Endpoint string `json:"endpoint,omitempty"`
+	// Section is the heading id of a page split off the file at Path
+	Section string `json:"section,omitempty"`
+	// SkippedAttachments are files over the attachment size limit that were
+	// not uploaded
+	SkippedAttachments []string `json:"skippedAttachments,omitempty"`
+	// Encoding is the encoding of a source converted to UTF-8
+	Encoding string `json:"encoding,omitempty"`
+	// PreviousVersion, CreatedPages, CreatedRedirects, CreatedAttachments and
+	// PreviousAttachmentVersions tell the undo command what the run changed
+	PreviousVersion            int            `json:"previousVersion,omitempty"`
+	CreatedPages               []string       `json:"createdPages,omitempty"`
+	CreatedRedirects           []string       `json:"createdRedirects,omitempty"`
+	CreatedAttachments         []string       `json:"createdAttachments,omitempty"`
+	PreviousAttachmentVersions map[string]int `json:"previousAttachmentVersions,omitempty"`
+}
+
+// Report collects the results of a run. It is safe for concurrent use.
+type Report struct {
+	Pages []PageResult `json:"pages"`
+	// CreatedPages are the pages the run created for itself rather than for
+	// a file, such as the index, assets and audit pages and their parents
+	CreatedPages []string `json:"createdPages,omitempty"`
+	mu           sync.Mutex
+}
+
+// Add records the result for a page
+func (r *Report) Add(p PageResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Pages = append(r.Pages, p)
+}
+
+// AddCreatedPages records pages the run created for itself. It does nothing
+// on a nil Report, for commands that keep none.
+func (r *Report) AddCreatedPages(ids ...string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.CreatedPages = append(r.CreatedPages, ids...)
+}
+
+// Count returns the number of pages recorded with action
+func (r *Report) Count(action string) int {
+	var n int
+	for _, p := range r.Pages {
+		if p.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+func newPageResult(f *MarkdownFile, url string, err error) PageResult {
+	p := PageResult{
+		Path:                       f.Path,
+		Section:                    f.Section,
+		Title:                      f.Title,
+		PageID:                     f.PageID,
+		URL:                        url,
+		WebURL:                     f.WebURL,
+		Action:                     f.Action,
+		SkippedAttachments:         f.SkippedAttachments,
+		Encoding:                   sourceEncoding(f.Path),
+		PreviousVersion:            f.PreviousVersion,
+		CreatedPages:               f.CreatedPages,
+		CreatedRedirects:           f.CreatedRedirects,
+		CreatedAttachments:         f.CreatedAttachments,
+		PreviousAttachmentVersions: f.PreviousAttachmentVersions,
+	}
+	if err != nil {
+		p.Action = ActionFailed
+		p.Error = err.Error()
+		var positionError *renderer.PositionError
+		if errors.As(err, &positionError) {
+			p.Line = positionError.Position.Line
+		}
+	}
+	return p
+}
+
+// WriteReport writes a report as JSON to file
+func WriteReport(file string, r *Report) error {
+	if r == nil {
+		return nil
+	}
+	dat, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, append(dat, '\n'), 0644); err != nil {
+		return fmt.Errorf("Unable to write report %s: %s", file, err)
+	}
+	return nil
+}
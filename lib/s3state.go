@@ -0,0 +1,203 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3State is a StateStore in an object of S3 or of a store with an S3
+// compatible API, signed with AWS signature version 4
+type s3State struct {
+	location string
+	// url is the object's URL, path style for custom endpoints
+	url                  *url.URL
+	region               string
+	accessKey, secretKey string
+	sessionToken         string
+}
+
+// openS3State opens s3://bucket/key with the credentials and region of the
+// AWS_* environment variables. AWS_ENDPOINT_URL selects an S3 compatible
+// store such as MinIO.
+func openS3State(location *url.URL) (StateStore, error) {
+	region := firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL")
+	if endpoint == "" {
+		endpoint = "https://" + location.Host + ".s3." + region + ".amazonaws.com"
+	} else {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/" + location.Host
+	}
+	return newS3State(location, endpoint, region)
+}
+
+// openGCSState opens gs://bucket/key through the S3 compatible API of Google
+// Cloud Storage, with an HMAC key in AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY
+func openGCSState(location *url.URL) (StateStore, error) {
+	return newS3State(location, "https://storage.googleapis.com/"+location.Host, "auto")
+}
+
+func newS3State(location *url.URL, endpoint, region string) (StateStore, error) {
+	key := strings.TrimPrefix(location.Path, "/")
+	if location.Host == "" || key == "" {
+		return nil, fmt.Errorf("Unable to open state %s: expected %s://bucket/key", location, location.Scheme)
+	}
+	s := &s3State{
+		location:     location.String(),
+		region:       region,
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("Unable to open state %s: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", s.location)
+	}
+	u, err := url.Parse(endpoint + "/" + awsEscape(key))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open state %s: %s", s.location, err)
+	}
+	s.url = u
+	return s, nil
+}
+
+func (s *s3State) Load() ([]byte, error) {
+	res, err := s.do(http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	dat, err := io.ReadAll(res.Body)
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case res.StatusCode >= 300:
+		return nil, fmt.Errorf("%s %s", res.Status, dat)
+	}
+	return dat, err
+}
+
+func (s *s3State) Save(dat []byte) error {
+	res, err := s.do(http.MethodPut, dat)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		response, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s %s", res.Status, response)
+	}
+	return nil
+}
+
+func (s *s3State) String() string {
+	return s.location
+}
+
+// do sends a signed request for the object
+func (s *s3State) do(method string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.url.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	s.sign(req, body, time.Now().UTC())
+	return http.DefaultClient.Do(req)
+}
+
+// sign adds the AWS signature version 4 headers to req
+func (s *s3State) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	date := now.Format("20060102")
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := []string{"host"}
+	values := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers = append(headers, lower)
+			values[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+	sort.Strings(headers)
+	var canonicalHeaders strings.Builder
+	for _, h := range headers {
+		canonicalHeaders.WriteString(h + ":" + values[h] + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsEscape(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{date, s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.accessKey, scope, signedHeaders, signature))
+}
+
+// awsEscape percent-encodes a path but for slashes and unreserved
+// characters
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', strings.IndexByte("-._~/", c) >= 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(dat []byte) string {
+	sum := sha256.Sum256(dat)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// firstEnv returns the first of the environment variables that is set
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
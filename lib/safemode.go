@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// guardUpdate is the operation checked before updating an existing page,
+// which moves it when its parent changed
+const guardUpdate = "update"
+
+// SafeMode restricts deleting, moving and updating existing pages to the
+// part of Confluence a run manages, so a misconfigured run can not damage
+// pages elsewhere
+type SafeMode struct {
+	// Spaces are the keys of the spaces pages may be changed in
+	Spaces []string
+	// RootID is the id of the page below which pages may be changed
+	RootID string
+}
+
+// Enabled tells whether any restriction is configured
+func (s SafeMode) Enabled() bool {
+	return len(s.Spaces) > 0 || s.RootID != ""
+}
+
+// guard is the confluence.Guard of safe mode. Pages have to be in one of
+// the spaces and below the root page. The root page itself may only be
+// updated and have attachments moved to or from it, and pages may not be
+// moved next to it.
+func (m *Markdown2Confluence) guard(operation, position string, ids []string) error {
+	for i, id := range ids {
+		var page *confluence.Page
+		var err error
+		if operation == confluence.GuardPurge {
+			page, err = m.client.GetTrashedPage(id)
+		} else {
+			page, err = m.client.GetPage(id, "space", "ancestors")
+		}
+		if err != nil {
+			return fmt.Errorf("Safe mode: refusing to %s page %s, unable to look it up: %s", operation, id, err)
+		}
+
+		reason := m.SafeMode.outside(page)
+		isTarget := operation == confluence.GuardMove && i > 0
+		if reason == "" && page.ID == m.SafeMode.RootID &&
+			!(operation == guardUpdate || operation == confluence.GuardMoveAttachment || isTarget && position == confluence.MoveAppend) {
+			reason = "it is the root page"
+		}
+		if reason != "" && isTarget {
+			return fmt.Errorf("Safe mode: refusing to move page %s %s %q (%s): %s", ids[0], position, page.Title, page.ID, reason)
+		}
+		if reason != "" {
+			return fmt.Errorf("Safe mode: refusing to %s %q (%s): %s", operation, page.Title, page.ID, reason)
+		}
+	}
+	return nil
+}
+
+// outside explains why page is outside the managed spaces and tree, empty
+// when it is inside
+func (s SafeMode) outside(page *confluence.Page) string {
+	if len(s.Spaces) > 0 {
+		allowed := false
+		for _, key := range s.Spaces {
+			allowed = allowed || strings.EqualFold(key, page.Space.Key)
+		}
+		if !allowed {
+			return fmt.Sprintf("space %s is not one of %s", page.Space.Key, strings.Join(s.Spaces, ", "))
+		}
+	}
+	if s.RootID == "" || page.ID == s.RootID {
+		return ""
+	}
+	for _, a := range page.Ancestors {
+		if a.ID == s.RootID {
+			return ""
+		}
+	}
+	return fmt.Sprintf("it is not below page %s", s.RootID)
+}
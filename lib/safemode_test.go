@@ -0,0 +1,55 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestUpsertStoragePageSafeMode updates an existing index page in and
+// outside of the managed space
+func TestUpsertStoragePageSafeMode(t *testing.T) {
+	tests := []struct {
+		space   string
+		updated bool
+	}{
+		{space: "DOCS", updated: true},
+		{space: "OTHER", updated: false},
+	}
+	for _, test := range tests {
+		t.Run(test.space, func(t *testing.T) {
+			updated := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				page := `{"id":"42","type":"page","title":"Index","space":{"key":"` + test.space + `"},` +
+					`"version":{"number":3},"body":{"storage":{"value":"<p>old</p>"}}}`
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/rest/api/content":
+					w.Write([]byte(`{"results":[` + page + `]}`))
+				case r.Method == http.MethodGet && r.URL.Path == "/rest/api/content/42":
+					w.Write([]byte(page))
+				case r.Method == http.MethodPut && r.URL.Path == "/rest/api/content/42":
+					updated = true
+					w.Write([]byte(strings.Replace(page, `"number":3`, `"number":4`, 1)))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			m := &Markdown2Confluence{Endpoint: server.URL, Space: "DOCS", SafeMode: SafeMode{Spaces: []string{"DOCS"}}}
+			m.CreateClient()
+			_, err := m.upsertStoragePage("Index", "", "<p>new</p>")
+			if updated != test.updated {
+				t.Errorf("updated = %t, want %t", updated, test.updated)
+			}
+			if test.updated && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if !test.updated && (err == nil || !strings.Contains(err.Error(), "Safe mode")) {
+				t.Errorf("expected a safe mode error, got %v", err)
+			}
+		})
+	}
+}
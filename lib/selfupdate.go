@@ -0,0 +1,193 @@
+package lib
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ReleasesRepository is the GitHub repository self-update installs releases from
+var ReleasesRepository = "justmiles/go-markdown2confluence"
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// SelfUpdate replaces the running binary with the GitHub release tagged tag,
+// or the latest release when tag is empty. With checkOnly the available
+// release is only reported.
+func SelfUpdate(current, tag string, checkOnly bool) error {
+	release, err := fetchRelease(tag)
+	if err != nil {
+		return err
+	}
+	if tag == "" && CompareVersions(current, release.TagName) >= 0 {
+		fmt.Printf("markdown2confluence %s is up to date\n", current)
+		return nil
+	}
+	if checkOnly {
+		fmt.Printf("markdown2confluence %s is available, running %s\n", release.TagName, current)
+		return nil
+	}
+
+	archiveURL, checksumsURL := release.assetURLs()
+	if archiveURL == "" {
+		return fmt.Errorf("release %s has no archive for %s/%s", release.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	archive, err := download(archiveURL)
+	if err != nil {
+		return err
+	}
+	if checksumsURL != "" {
+		if err := verifyChecksum(archive, filepath.Base(archiveURL), checksumsURL); err != nil {
+			return err
+		}
+	}
+	binary, err := extractBinary(archive)
+	if err != nil {
+		return fmt.Errorf("Unable to extract %s: %s", filepath.Base(archiveURL), err)
+	}
+	if err := replaceExecutable(binary); err != nil {
+		return fmt.Errorf("Unable to replace markdown2confluence: %s", err)
+	}
+	fmt.Printf("Updated markdown2confluence %s to %s\n", current, release.TagName)
+	return nil
+}
+
+func fetchRelease(tag string) (*githubRelease, error) {
+	url := "https://api.github.com/repos/" + ReleasesRepository + "/releases/latest"
+	if tag != "" {
+		url = "https://api.github.com/repos/" + ReleasesRepository + "/releases/tags/" + tag
+	}
+	body, err := download(url)
+	if err != nil {
+		return nil, err
+	}
+	var release githubRelease
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("Unable to read release %s: %s", url, err)
+	}
+	return &release, nil
+}
+
+// assetURLs returns the archive for this platform, named by goreleaser
+// like go-markdown2confluence_1.2.0_linux_x86_64.tar.gz, and the checksums
+func (r *githubRelease) assetURLs() (archive, checksums string) {
+	arch := runtime.GOARCH
+	switch arch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	}
+	suffix := "_" + runtime.GOOS + "_" + arch + ".tar.gz"
+	for _, a := range r.Assets {
+		switch {
+		case strings.HasSuffix(a.Name, suffix):
+			archive = a.BrowserDownloadURL
+		case a.Name == "checksums.txt":
+			checksums = a.BrowserDownloadURL
+		}
+	}
+	return archive, checksums
+}
+
+func download(url string) ([]byte, error) {
+	res, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to download %s: %s", url, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to download %s: %s", url, res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+func verifyChecksum(archive []byte, name, checksumsURL string) error {
+	checksums, err := download(checksumsURL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(archive)
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == name {
+			if fields[0] != hex.EncodeToString(sum[:]) {
+				return fmt.Errorf("checksum mismatch for %s", name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("no checksum for %s", name)
+}
+
+func extractBinary(archive []byte) ([]byte, error) {
+	name := "markdown2confluence"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in archive", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Base(header.Name) == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// replaceExecutable writes binary next to the running executable and moves
+// it in place. The old binary is moved aside first as Windows can not
+// overwrite a running executable.
+func replaceExecutable(binary []byte) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return err
+	}
+
+	tmp := executable + ".new"
+	if err := os.WriteFile(tmp, binary, 0755); err != nil {
+		return err
+	}
+	old := executable + ".old"
+	os.Remove(old)
+	if err := os.Rename(executable, old); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, executable); err != nil {
+		os.Rename(old, executable)
+		return err
+	}
+	os.Remove(old)
+	return nil
+}
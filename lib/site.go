@@ -0,0 +1,281 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/justmiles/go-confluence"
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// sectionIndexFiles hold the content of their directory's section page, in
+// order of precedence: Hugo branch bundles, MkDocs and README files
+var sectionIndexFiles = []string{"_index.md", "index.md", "README.md"}
+
+// siteNode is a page of a static site content tree. Sections without an
+// index file have no Path and are published as placeholder pages.
+type siteNode struct {
+	Title    string
+	Weight   int
+	Path     string
+	Children []*siteNode
+}
+
+// PublishSite mirrors a Hugo or MkDocs site to Confluence. Front matter
+// titles and weights, drafts and section index files are honoured, so the
+// site keeps its structure and order.
+func (m *Markdown2Confluence) PublishSite(dir string) []error {
+	m.CreateClient()
+	defer renderer.UseAttachmentDir()()
+	m.Report = &Report{}
+
+	contentDir := siteContentDir(dir)
+	root, err := m.buildSiteNode(contentDir)
+	if err != nil {
+		return []error{fmt.Errorf("Unable to read site %s: %s", contentDir, err)}
+	}
+	if root == nil {
+		return []error{fmt.Errorf("no markdown files found in %s", contentDir)}
+	}
+
+	if !m.ValidateOnly && !m.SkipPreflight {
+		if err := m.Preflight(); err != nil {
+			return []error{err}
+		}
+	}
+
+	var ancestorID string
+	if !m.ValidateOnly {
+		ancestorID, err = m.resolveParentID()
+		if err != nil {
+			return []error{err}
+		}
+	}
+
+	var errors []error
+	if root.Path != "" {
+		id, err := m.publishSiteNode(root, ancestorID)
+		if err != nil {
+			return []error{err}
+		}
+		ancestorID = id
+	}
+	errors = append(errors, m.publishSiteChildren(root, ancestorID)...)
+
+	if m.NotifyWebhook != "" && !m.ValidateOnly {
+		if err := m.Notify(); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	return errors
+}
+
+// siteContentDir returns the markdown directory of a site root: docs/ for
+// MkDocs, content/ for Hugo and dir itself otherwise
+func siteContentDir(dir string) string {
+	if _, err := os.Stat(filepath.Join(dir, "mkdocs.yml")); err == nil {
+		if docs := filepath.Join(dir, "docs"); isDir(docs) {
+			return docs
+		}
+	}
+	if content := filepath.Join(dir, "content"); isDir(content) {
+		return content
+	}
+	return dir
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// resolveParentID resolves --parent-id or --parent to the page content is published under
+func (m *Markdown2Confluence) resolveParentID() (string, error) {
+	if m.ParentId != "" {
+		return m.ParentId, nil
+	}
+	if m.Parent == "" {
+		return "", nil
+	}
+	if id, _ := strconv.Atoi(m.Parent); id != 0 {
+		return m.Parent, nil
+	}
+	f := MarkdownFile{Parents: deleteEmpty(strings.Split(m.Parent, "/"))}
+	id, err := f.FindOrCreateAncestors(m)
+	m.Report.AddCreatedPages(f.CreatedPages...)
+	return id, err
+}
+
+// buildSiteNode reads a content directory, returning nil when it holds no
+// publishable markdown
+func (m *Markdown2Confluence) buildSiteNode(dir string) (*siteNode, error) {
+	node := &siteNode{Title: filepath.Base(dir)}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	indexRank := len(sectionIndexFiles)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if strings.HasPrefix(entry.Name(), ".") || m.IsExcluded(path) {
+			continue
+		}
+
+		if entry.IsDir() {
+			child, err := m.buildSiteNode(path)
+			if err != nil {
+				return nil, err
+			}
+			if child != nil {
+				node.Children = append(node.Children, child)
+			}
+			continue
+		}
+
+		if !isMarkdownFile(entry.Name()) {
+			continue
+		}
+		page, publish, err := m.readSitePage(path)
+		if err != nil {
+			return nil, err
+		}
+		if !publish {
+			continue
+		}
+
+		if rank := indexOf(sectionIndexFiles, strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))+".md"); rank >= 0 {
+			if rank < indexRank {
+				indexRank = rank
+				node.Path = page.Path
+				node.Weight = page.Weight
+				if page.Title != strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())) {
+					node.Title = page.Title
+				}
+			}
+			continue
+		}
+		node.Children = append(node.Children, page)
+	}
+
+	if node.Path == "" && len(node.Children) == 0 {
+		return nil, nil
+	}
+	sortSiteNodes(node.Children)
+	return node, nil
+}
+
+// readSitePage reads the front matter of a markdown file. Drafts and
+// headless pages are not published.
+func (m *Markdown2Confluence) readSitePage(path string) (*siteNode, bool, error) {
+	dat, err := readMarkdown(path)
+	if err != nil {
+		return nil, false, err
+	}
+	fm, _ := ParseFrontMatter(dat)
+	if fm.Bool("draft") || fm.Bool("headless") {
+		if m.Debug {
+			fmt.Printf("skipping %s: draft\n", path)
+		}
+		return nil, false, nil
+	}
+
+	title := fm.Get("title")
+	if title == "" {
+		title = getDocumentTitle(path)
+	}
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return &siteNode{Title: title, Weight: fm.Int("weight"), Path: path}, true, nil
+}
+
+// sortSiteNodes orders pages like Hugo: by weight, pages without a weight
+// last, then by title
+func sortSiteNodes(nodes []*siteNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		wi, wj := nodes[i].Weight, nodes[j].Weight
+		if wi != wj {
+			if wi == 0 || wj == 0 {
+				return wj == 0
+			}
+			return wi < wj
+		}
+		return nodes[i].Title < nodes[j].Title
+	})
+}
+
+// publishSiteChildren publishes the children of node below ancestorID in
+// order and moves them into that order when the pages already existed
+func (m *Markdown2Confluence) publishSiteChildren(node *siteNode, ancestorID string) []error {
+	var errors []error
+	var previousID string
+	for _, child := range node.Children {
+		if m.Interrupted() {
+			return append(errors, fmt.Errorf("%w: %s was not published", ErrInterrupted, child.Title))
+		}
+		id, err := m.publishSiteNode(child, ancestorID)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+
+		if previousID != "" && id != "" {
+			if err := m.client.MovePage(id, confluence.MoveAfter, previousID); err != nil && m.Debug {
+				fmt.Printf("unable to order page %s: %s\n", child.Title, err)
+			}
+		}
+		previousID = id
+
+		if len(child.Children) > 0 {
+			errors = append(errors, m.publishSiteChildren(child, id)...)
+		}
+	}
+	return errors
+}
+
+// publishSiteNode publishes a page or section placeholder and returns its id
+func (m *Markdown2Confluence) publishSiteNode(node *siteNode, ancestorID string) (string, error) {
+	if node.Path == "" {
+		if m.ValidateOnly {
+			return "", nil
+		}
+		f := MarkdownFile{Title: node.Title}
+		id, err := f.FindOrCreateAncestor(m, m.client, ancestorID, node.Title)
+		if err != nil {
+			return "", err
+		}
+		return id, nil
+	}
+
+	f := MarkdownFile{
+		Path:     node.Path,
+		Title:    node.Title,
+		Ancestor: ancestorID,
+	}
+	url, err := f.Upload(m)
+	m.Report.Add(newPageResult(&f, url, err))
+	if err != nil {
+		return "", fmt.Errorf("Unable to upload markdown file %s: \n\t%s", f.Path, err)
+	}
+	if m.ValidateOnly {
+		fmt.Printf("%s: valid\n", f.Path)
+	} else {
+		fmt.Printf("%s: %s\n", f.Path, url)
+	}
+	return f.PageID, nil
+}
+
+func indexOf(s []string, v string) int {
+	for i, e := range s {
+		if e == v {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,303 @@
+package lib
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// snapshotManifest is the name of the index of a snapshot archive
+const snapshotManifest = "snapshot.json"
+
+// Snapshot is the index of a snapshot archive. Page bodies are stored as
+// pages/<id>.xml, attachments as attachments/<page id>/<filename>.
+type Snapshot struct {
+	Endpoint string         `json:"endpoint"`
+	Space    string         `json:"space"`
+	RootID   string         `json:"rootId"`
+	Created  string         `json:"created"`
+	Pages    []SnapshotPage `json:"pages"`
+}
+
+// SnapshotPage is a page of a Snapshot, parents before their children
+type SnapshotPage struct {
+	ID          string                       `json:"id"`
+	Title       string                       `json:"title"`
+	ParentID    string                       `json:"parentId,omitempty"`
+	Version     int                          `json:"version"`
+	Labels      []confluence.Label           `json:"labels,omitempty"`
+	Properties  []confluence.ContentProperty `json:"properties,omitempty"`
+	Attachments []SnapshotAttachment         `json:"attachments,omitempty"`
+}
+
+// SnapshotAttachment is an attachment of a SnapshotPage
+type SnapshotAttachment struct {
+	ID        string `json:"id"`
+	Filename  string `json:"filename"`
+	MediaType string `json:"mediaType,omitempty"`
+}
+
+// TakeSnapshot saves the page rootID and all its descendants with their
+// bodies, labels, properties and attachments to a zip archive
+func (m *Markdown2Confluence) TakeSnapshot(ctx context.Context, rootID, file string) error {
+	m.CreateClient()
+	root, err := m.client.GetPage(rootID, "space", "version", "ancestors", "body.storage")
+	if err != nil {
+		return fmt.Errorf("Unable to fetch page %s: %s", rootID, err)
+	}
+	pages := []confluence.Page{*root}
+	err = m.client.ForEachDescendant(ctx, rootID, func(page confluence.Page) error {
+		pages = append(pages, page)
+		return nil
+	}, "version", "ancestors", "body.storage")
+	if err != nil {
+		return fmt.Errorf("Unable to list the pages below %s: %s", rootID, err)
+	}
+
+	out, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("Unable to create snapshot %s: %s", file, err)
+	}
+	defer out.Close()
+	archive := zip.NewWriter(out)
+
+	snapshot := Snapshot{
+		Endpoint: m.Endpoint,
+		Space:    root.Space.Key,
+		RootID:   rootID,
+		Created:  time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, page := range pages {
+		p, err := m.snapshotPage(archive, page)
+		if err != nil {
+			return err
+		}
+		snapshot.Pages = append(snapshot.Pages, p)
+		fmt.Printf("saved %s (%s), %d attachments\n", page.Title, page.ID, len(p.Attachments))
+	}
+
+	w, err := createSnapshotFile(archive, snapshotManifest)
+	if err == nil {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(snapshot)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to write snapshot %s: %s", file, err)
+	}
+	return out.Close()
+}
+
+// snapshotPage writes the body and attachments of a page to the archive
+func (m *Markdown2Confluence) snapshotPage(archive *zip.Writer, page confluence.Page) (SnapshotPage, error) {
+	p := SnapshotPage{ID: page.ID, Title: page.Title, Version: page.Version.Number}
+	if n := len(page.Ancestors); n > 0 {
+		p.ParentID = page.Ancestors[n-1].ID
+	}
+
+	w, err := createSnapshotFile(archive, "pages/"+page.ID+".xml")
+	if err == nil {
+		_, err = io.WriteString(w, page.Body.Storage.Value)
+	}
+	if err != nil {
+		return p, fmt.Errorf("Unable to write page %s to the snapshot: %s", page.ID, err)
+	}
+
+	if p.Labels, err = m.client.GetLabels(page.ID); err != nil {
+		return p, fmt.Errorf("Unable to fetch the labels of page %s: %s", page.ID, err)
+	}
+	if p.Properties, err = m.client.GetContentProperties(page.ID); err != nil {
+		return p, fmt.Errorf("Unable to fetch the properties of page %s: %s", page.ID, err)
+	}
+
+	// GetAttachmentsFiltered fails on pages without attachments
+	attachments, _ := m.client.GetAttachmentsFiltered(page.ID, nil)
+	for _, a := range attachments {
+		attachment, data, err := m.client.DownloadAttachment(a.ID)
+		if err != nil {
+			return p, fmt.Errorf("Unable to download attachment %s of page %s: %s", a.Title, page.ID, err)
+		}
+		// uploads keep the name, so the restored body still references it
+		filename := attachment.Title
+		w, err := createSnapshotFile(archive, "attachments/"+page.ID+"/"+filename)
+		if err == nil {
+			_, err = w.Write(data)
+		}
+		if err != nil {
+			return p, fmt.Errorf("Unable to write attachment %s to the snapshot: %s", filename, err)
+		}
+		p.Attachments = append(p.Attachments, SnapshotAttachment{ID: a.ID, Filename: filename, MediaType: attachment.Metadata.MediaType})
+	}
+	return p, nil
+}
+
+// RestoreSnapshot publishes the pages of a snapshot archive again. Pages that
+// still exist, by id or else by title, are updated when their title or body
+// differ, the others are recreated below their restored parent. The root
+// page goes below parentID, or where it was when parentID is empty. Labels,
+// properties and attachments are added back, nothing is deleted.
+func (m *Markdown2Confluence) RestoreSnapshot(file, parentID string) []error {
+	m.CreateClient()
+	archive, err := zip.OpenReader(file)
+	if err != nil {
+		return []error{fmt.Errorf("Unable to open snapshot %s: %s", file, err)}
+	}
+	defer archive.Close()
+
+	var snapshot Snapshot
+	dat, err := readSnapshotFile(&archive.Reader, snapshotManifest)
+	if err == nil {
+		err = json.Unmarshal(dat, &snapshot)
+	}
+	if err != nil {
+		return []error{fmt.Errorf("Unable to read snapshot %s: %s", file, err)}
+	}
+	space := snapshot.Space
+	if m.Space != "" {
+		space = m.Space
+	}
+
+	dir, err := os.MkdirTemp("", "m2c-restore")
+	if err != nil {
+		return []error{err}
+	}
+	defer os.RemoveAll(dir)
+
+	// restored maps the ids in the snapshot to the ids of the restored pages
+	restored := map[string]string{}
+	var errors []error
+	for _, p := range snapshot.Pages {
+		parent := restored[p.ParentID]
+		if p.ID == snapshot.RootID {
+			parent = p.ParentID
+			if parentID != "" {
+				parent = parentID
+			}
+		} else if parent == "" {
+			errors = append(errors, fmt.Errorf("Unable to restore %s (%s): its parent was not restored", p.Title, p.ID))
+			continue
+		}
+
+		id, err := m.restoreSnapshotPage(&archive.Reader, dir, space, parent, p)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("Unable to restore %s (%s): %s", p.Title, p.ID, err))
+			continue
+		}
+		restored[p.ID] = id
+	}
+	return errors
+}
+
+// restoreSnapshotPage restores a page below parentID and returns its id
+func (m *Markdown2Confluence) restoreSnapshotPage(archive *zip.Reader, dir, space, parentID string, p SnapshotPage) (string, error) {
+	body, err := readSnapshotFile(archive, "pages/"+p.ID+".xml")
+	if err != nil {
+		return "", err
+	}
+
+	page := confluence.Page{Title: p.Title, Space: confluence.PageSpace{Key: space}}
+	page.Body.Storage.Value = string(body)
+	if parentID != "" {
+		page.Ancestors = []confluence.PageAncestor{{ID: parentID}}
+	}
+
+	existing, err := m.client.GetPage(p.ID, "version", "body.storage")
+	if err != nil {
+		existing, err = m.client.GetPageByTitle(space, p.Title, "version", "body.storage")
+	}
+	switch {
+	case err == nil && existing.Title == p.Title && existing.Body.Storage.Value == page.Body.Storage.Value:
+		fmt.Printf("unchanged %s (%s)\n", p.Title, existing.ID)
+		page.ID = existing.ID
+	case err == nil:
+		if m.SafeMode.Enabled() {
+			if err := m.guard(guardUpdate, "", []string{existing.ID}); err != nil {
+				return "", err
+			}
+		}
+		page.ID = existing.ID
+		page.Version = confluence.PageVersion{Number: existing.Version.Number + 1, Message: fmt.Sprintf("Restored version %d from a snapshot", p.Version)}
+		if _, err := m.client.UpdatePage(&page); err != nil {
+			return "", err
+		}
+		fmt.Printf("restored %s (%s)\n", p.Title, page.ID)
+	case errors.Is(err, confluence.ErrPageNotFound):
+		created, err := m.client.CreatePage(&page)
+		if err != nil {
+			return "", err
+		}
+		page.ID = created.ID
+		fmt.Printf("recreated %s (%s) as %s\n", p.Title, p.ID, page.ID)
+	default:
+		return "", err
+	}
+
+	for _, prefix := range []confluence.LabelPrefix{confluence.GlobalPrefix, confluence.LocalPrefix} {
+		var names []string
+		for _, l := range p.Labels {
+			if confluence.LabelPrefix(l.Prefix) == prefix {
+				names = append(names, l.Name)
+			}
+		}
+		if len(names) > 0 {
+			if err := m.client.AddLabels(page.ID, names, prefix); err != nil {
+				return page.ID, fmt.Errorf("unable to add labels: %s", err)
+			}
+		}
+	}
+	for _, property := range p.Properties {
+		if err := m.client.SetContentProperty(page.ID, property.Key, property.Value); err != nil {
+			return page.ID, fmt.Errorf("unable to set property %s: %s", property.Key, err)
+		}
+	}
+
+	var files []string
+	for _, a := range p.Attachments {
+		data, err := readSnapshotFile(archive, "attachments/"+p.ID+"/"+a.Filename)
+		if err != nil {
+			return page.ID, err
+		}
+		f := filepath.Join(dir, p.ID, filepath.FromSlash(path.Base(a.Filename)))
+		if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+			return page.ID, err
+		}
+		if err := os.WriteFile(f, data, 0644); err != nil {
+			return page.ID, err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return page.ID, nil
+	}
+	if _, errs := m.client.AddUpdateAttachments(page.ID, files); len(errs) > 0 {
+		return page.ID, fmt.Errorf("unable to upload attachments: %s", errs[0])
+	}
+	return page.ID, nil
+}
+
+// createSnapshotFile adds a compressed file to a snapshot archive
+func createSnapshotFile(archive *zip.Writer, name string) (io.Writer, error) {
+	return archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+}
+
+// readSnapshotFile returns the content of a file in a snapshot archive
+func readSnapshotFile(archive *zip.Reader, name string) ([]byte, error) {
+	f, err := archive.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot has no %s", name)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
@@ -0,0 +1,234 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// DefaultMaxBodySize is the storage format size above which Confluence
+// rejects page bodies or becomes unusably slow editing them
+const DefaultMaxBodySize = 5 << 20
+
+// childrenMacro lists the pages split off a file on the page left in its place
+const childrenMacro = "\n\n```CONFLUENCE-MACRO\nname: children\n```\n"
+
+// checkBodySize fails a rendered body over m.MaxBodySize, suggesting how to
+// publish the file anyway
+func (m *Markdown2Confluence) checkBodySize(f *MarkdownFile, body string) error {
+	size := int64(len(body))
+	if m.MaxBodySize <= 0 || size <= m.MaxBodySize {
+		return nil
+	}
+	if m.SplitLargePages {
+		return fmt.Errorf("rendered body is %s, over the %s --max-body-size, and has no sections to split it at", formatByteSize(size), formatByteSize(m.MaxBodySize))
+	}
+	return fmt.Errorf("rendered body is %s, over the %s --max-body-size, split %s into several files or publish its top-level sections as child pages with --split-large-pages", formatByteSize(size), formatByteSize(m.MaxBodySize), f.Path)
+}
+
+// splitLargePages splits the files rendering over m.MaxBodySize with
+// --split-large-pages
+func (m *Markdown2Confluence) splitLargePages(files []MarkdownFile) {
+	if !m.SplitLargePages || m.MaxBodySize <= 0 {
+		return
+	}
+	for i := range files {
+		m.splitLargePage(&files[i])
+	}
+}
+
+// splitLargePage publishes the top-level sections of a file rendering over
+// m.MaxBodySize as child pages, titled after the page and their heading. The
+// page keeps the text before the first section and lists its children.
+// Sections still too large are split at their own headings.
+func (m *Markdown2Confluence) splitLargePage(f *MarkdownFile) {
+	if len(f.Combined) > 0 {
+		return
+	}
+	if f.rendered == nil {
+		body, images, err := f.Render(m)
+		f.rendered = &renderedFile{body: body, images: images, err: err}
+	}
+	body := f.rendered.body
+	if f.rendered.err != nil || int64(len(body)) <= m.MaxBodySize {
+		return
+	}
+	var err error
+	source := f.Content
+	if source == nil {
+		if source, err = readMarkdown(f.Path); err != nil {
+			return
+		}
+	}
+	_, markdown := ParseFrontMatter(source)
+	sections := splitSections(markdown, 0)
+	if len(sections) < 2 {
+		return
+	}
+	if m.Debug {
+		fmt.Printf("splitting %s into %d pages, its body is %s\n", f.Path, len(sections), formatByteSize(int64(len(body))))
+	}
+
+	// the front matter stays on the page, sections keep their line numbers.
+	// Front matter is blanked with as many lines, so the rest of markdown is
+	// the end of source.
+	introEnd := len(source) - len(markdown) + sections[0].start
+	intro := append(source[:introEnd:introEnd], childrenMacro...)
+	parts := make([]MarkdownFile, len(sections))
+	for i, s := range sections {
+		parts[i] = MarkdownFile{
+			Path:    f.Path,
+			Title:   f.Title + " - " + s.title,
+			Section: strings.TrimPrefix(f.Section+"/"+s.id, "/"),
+			Parents: append(append([]string{}, f.Parents...), f.Title),
+			Locale:  f.Locale,
+			Space:   f.Space,
+			Content: append(bytes.Repeat([]byte("\n"), bytes.Count(markdown[:s.bodyStart], []byte("\n"))), markdown[s.bodyStart:s.end]...),
+		}
+		m.splitLargePage(&parts[i])
+	}
+	f.Content = intro
+	f.Parts = parts
+	f.rendered = nil
+}
+
+// section is a top-level section of markdown, from its heading at start to
+// end. Its body starts after the heading.
+type section struct {
+	title, id             string
+	start, bodyStart, end int
+}
+
+// splitSections returns the sections of the headings of level in markdown,
+// or with level 0 of the shallowest heading level used more than once, nil
+// when there are none
+func splitSections(markdown []byte, level int) []section {
+	doc := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader(markdown))
+	var headings []*ast.Heading
+	count := map[int]int{}
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		if h, ok := n.(*ast.Heading); ok && h.Lines().Len() > 0 {
+			headings = append(headings, h)
+			count[h.Level]++
+		}
+	}
+	if level == 0 {
+		for l := 6; l > 0; l-- {
+			if count[l] > 1 {
+				level = l
+			}
+		}
+	}
+	if count[level] == 0 {
+		return nil
+	}
+
+	var sections []section
+	ids := renderer.NewSlugger()
+	for _, h := range headings {
+		if h.Level != level {
+			continue
+		}
+		lines := h.Lines()
+		start := bytes.LastIndexByte(markdown[:lines.At(0).Start], '\n') + 1
+		// ATX heading lines end before the line break, setext ones after it
+		bodyStart := lineEnd(markdown, lines.At(lines.Len()-1).Stop-1)
+		if !bytes.HasPrefix(bytes.TrimLeft(markdown[start:], " "), []byte("#")) {
+			// the underline of a setext heading
+			bodyStart = lineEnd(markdown, bodyStart)
+		}
+		if len(sections) > 0 {
+			sections[len(sections)-1].end = start
+		}
+		title := strings.TrimSpace(string(h.Text(markdown)))
+		sections = append(sections, section{
+			title:     title,
+			id:        string(ids.Generate([]byte(title), ast.KindHeading)),
+			start:     start,
+			bodyStart: bodyStart,
+			end:       len(markdown),
+		})
+	}
+	return sections
+}
+
+// lineEnd returns the offset after the line break ending the line at offset
+func lineEnd(markdown []byte, offset int) int {
+	if offset > len(markdown) {
+		return len(markdown)
+	}
+	if i := bytes.IndexByte(markdown[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(markdown)
+}
+
+// splitByH1 replaces the files with H1 headings by a page per H1 with
+// --split-h1, titled after the heading and with the text up to the next H1.
+// Text before the first H1 stays on its page, the front matter of the file
+// applies to all of them.
+func (m *Markdown2Confluence) splitByH1(files []MarkdownFile) ([]MarkdownFile, error) {
+	var pages []MarkdownFile
+	for _, f := range files {
+		source := f.Content
+		if source == nil {
+			var err error
+			if source, err = readMarkdown(f.Path); err != nil {
+				return nil, fmt.Errorf("Could not open file %s:\n\t%s", f.Path, err)
+			}
+		}
+		_, markdown := ParseFrontMatter(source)
+		sections := splitSections(markdown, 1)
+		if len(sections) == 0 {
+			pages = append(pages, f)
+			continue
+		}
+
+		// offsets in markdown are offsets in source after the front matter
+		offset := len(source) - len(markdown)
+		frontMatter := offset + len(markdown) - len(bytes.TrimLeft(markdown, "\n"))
+		titles := map[string]string{}
+		for i, s := range sections {
+			page := f
+			page.Title = m.normalizeTitle(s.title)
+			page.Section = s.id
+			if other, ok := titles[page.Title]; ok {
+				return nil, fmt.Errorf("%s has the H1 headings #%s and #%s both titled %q, page titles must be unique", f.Path, other, s.id, page.Title)
+			}
+			titles[page.Title] = s.id
+			keep := [][2]int{{0, frontMatter}, {offset + s.bodyStart, offset + s.end}}
+			if i == 0 {
+				keep = append(keep, [2]int{frontMatter, offset + s.start})
+			}
+			page.Content = keepLines(source, keep...)
+			pages = append(pages, page)
+		}
+	}
+	return pages, nil
+}
+
+// keepLines returns source with the lines outside the ranges of line start
+// offsets blanked, so positions in it stay the same
+func keepLines(source []byte, ranges ...[2]int) []byte {
+	out := make([]byte, 0, len(source))
+	for i, c := range source {
+		kept := false
+		for _, r := range ranges {
+			if i >= r[0] && i < r[1] {
+				kept = true
+				break
+			}
+		}
+		if kept || c == '\n' {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// StateStore keeps the sync state between runs, such as the --url-map.
+// Runners that start from scratch every time keep it in a remote store.
+type StateStore interface {
+	// Load returns the saved state, nil when nothing was saved yet
+	Load() ([]byte, error)
+	// Save replaces the saved state
+	Save(dat []byte) error
+	// String names the location of the state in messages
+	String() string
+}
+
+// stateStores opens the StateStores of locations with a scheme, by scheme
+var stateStores = map[string]func(location *url.URL) (StateStore, error){
+	"s3": openS3State,
+	"gs": openGCSState,
+}
+
+// RegisterStateStore adds a StateStore for locations like scheme://...
+func RegisterStateStore(scheme string, open func(location *url.URL) (StateStore, error)) {
+	stateStores[scheme] = open
+}
+
+// OpenStateStore returns the StateStore of location: an s3:// or gs://
+// object, a location of a registered scheme or else a local file
+func OpenStateStore(location string) (StateStore, error) {
+	if i := strings.Index(location, "://"); i > 0 {
+		open, ok := stateStores[location[:i]]
+		if !ok {
+			return nil, fmt.Errorf("Unable to open state %s: unknown scheme %s", location, location[:i])
+		}
+		u, err := url.Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to open state %s: %s", location, err)
+		}
+		return open(u)
+	}
+	return fileState(location), nil
+}
+
+// fileState is a StateStore in a local file
+type fileState string
+
+func (f fileState) Load() ([]byte, error) {
+	dat, err := ioutil.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return dat, err
+}
+
+func (f fileState) Save(dat []byte) error {
+	return ioutil.WriteFile(string(f), dat, 0644)
+}
+
+func (f fileState) String() string {
+	return string(f)
+}
@@ -0,0 +1,136 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// storageRoot wraps a rendered body so it can be parsed as a single XML
+// document with the Confluence namespaces declared
+const (
+	storageRootStart = `<storage xmlns:ac="http://atlassian.com/content" xmlns:ri="http://atlassian.com/resource/identifier">`
+	storageRootEnd   = `</storage>`
+
+	acNamespace = "http://atlassian.com/content"
+	riNamespace = "http://atlassian.com/resource/identifier"
+)
+
+// StorageFormatError describes a problem in a rendered storage format body
+type StorageFormatError struct {
+	Line    int
+	Message string
+}
+
+func (e StorageFormatError) Error() string {
+	return fmt.Sprintf("storage format line %d: %s", e.Line, e.Message)
+}
+
+// requiredAttributes lists attributes that Confluence requires on its elements
+var requiredAttributes = map[xml.Name][]xml.Name{
+	{Space: acNamespace, Local: "structured-macro"}: {{Space: acNamespace, Local: "name"}},
+	{Space: acNamespace, Local: "parameter"}:        {{Space: acNamespace, Local: "name"}},
+	{Space: riNamespace, Local: "attachment"}:       {{Space: riNamespace, Local: "filename"}},
+}
+
+// plainTextElements may only contain character data (CDATA)
+var plainTextElements = map[xml.Name]struct{}{
+	{Space: acNamespace, Local: "plain-text-body"}:      {},
+	{Space: acNamespace, Local: "plain-text-link-body"}: {},
+}
+
+// ValidateStorageFormat checks that a rendered body is well formed XML and
+// that Confluence specific elements are complete, so that problems are
+// reported locally instead of as opaque API errors.
+func ValidateStorageFormat(body string) []error {
+	var errors []error
+	doc := storageRootStart + body + storageRootEnd
+	decoder := xml.NewDecoder(strings.NewReader(doc))
+	decoder.Entity = xml.HTMLEntity
+	decoder.AutoClose = nil
+
+	var stack []xml.Name
+	line := func() int {
+		offset := int(decoder.InputOffset()) - len(storageRootStart)
+		if offset < 0 {
+			offset = 0
+		}
+		if offset > len(body) {
+			offset = len(body)
+		}
+		return bytes.Count([]byte(body[:offset]), []byte("\n")) + 1
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errors = append(errors, StorageFormatError{line(), err.Error()})
+			return errors
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(stack) > 0 {
+				if _, ok := plainTextElements[stack[len(stack)-1]]; ok {
+					errors = append(errors, StorageFormatError{line(), fmt.Sprintf("element <%s> is not allowed inside <ac:%s>, use CDATA", t.Name.Local, stack[len(stack)-1].Local)})
+				}
+			}
+			for _, required := range requiredAttributes[t.Name] {
+				if !hasAttribute(t, required) {
+					errors = append(errors, StorageFormatError{line(), fmt.Sprintf("<%s> is missing the %s attribute", qualifiedName(t.Name), qualifiedName(required))})
+				}
+			}
+			if t.Name.Space != "" && t.Name.Space != acNamespace && t.Name.Space != riNamespace {
+				errors = append(errors, StorageFormatError{line(), fmt.Sprintf("unknown namespace prefix in element <%s:%s>", t.Name.Space, t.Name.Local)})
+			}
+			stack = append(stack, t.Name)
+		case xml.EndElement:
+			// an unescaped ]]> ending a CDATA section early is reported by
+			// the decoder, as it is not allowed outside of CDATA either
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return errors
+}
+
+// removeInvalidXMLChars leaves out the control characters XML does not
+// allow, even as character references, so that sources containing them
+// still publish
+func removeInvalidXMLChars(body string) string {
+	valid := func(r rune) bool {
+		return r == '\t' || r == '\n' || r == '\r' || r >= 0x20 && r != 0xFFFE && r != 0xFFFF
+	}
+	if strings.IndexFunc(body, func(r rune) bool { return !valid(r) }) < 0 {
+		return body
+	}
+	return strings.Map(func(r rune) rune {
+		if !valid(r) {
+			return -1
+		}
+		return r
+	}, body)
+}
+
+func hasAttribute(e xml.StartElement, name xml.Name) bool {
+	for _, a := range e.Attr {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func qualifiedName(n xml.Name) string {
+	switch n.Space {
+	case acNamespace:
+		return "ac:" + n.Local
+	case riNamespace:
+		return "ri:" + n.Local
+	}
+	return n.Local
+}
@@ -0,0 +1,244 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// TemplateData is available to title, header and footer templates
+type TemplateData struct {
+	// Title is the page title the file would get without a title template
+	Title       string
+	Path        string
+	FrontMatter map[string]string
+	Env         map[string]string
+	// Date is when the run started
+	Date      time.Time
+	GitBranch string
+	GitCommit string
+	// Published is the date of Date, empty with --deterministic
+	Published string
+	// RepoURL is the web URL of the git origin remote, SourceURL the file
+	// there and SourcePath its path in the repository
+	RepoURL    string
+	SourceURL  string
+	SourcePath string
+	// Owners are the CODEOWNERS of the file
+	Owners []string
+	// Words and ReadingTime, in minutes, count the rendered page text
+	Words       int
+	ReadingTime int
+}
+
+var (
+	runStarted = time.Now()
+
+	gitInfoMu sync.Mutex
+	gitInfo   = map[string][2]string{}
+	gitRepos  = map[string][2]string{}
+)
+
+// templateFuncs are a subset of the sprig functions, with sprig's argument
+// order so templates work with either
+var templateFuncs = template.FuncMap{
+	"now": time.Now,
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"dateModify": func(duration string, t time.Time) time.Time {
+		d, err := time.ParseDuration(duration)
+		if err != nil {
+			return t
+		}
+		return t.Add(d)
+	},
+	"env":        os.Getenv,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"title":      strings.Title,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"join": func(separator string, list []string) string {
+		return strings.Join(list, separator)
+	},
+	// trunc counts characters, not bytes, so CJK text is never cut mid-character
+	"trunc": func(n int, s string) string {
+		if r := []rune(s); n >= 0 && len(r) > n {
+			return string(r[:n])
+		}
+		return s
+	},
+	"split": func(separator, s string) []string { return strings.Split(s, separator) },
+	"quote": func(s string) string { return fmt.Sprintf("%q", s) },
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// templateData collects the variables for the templates of a file
+func (f *MarkdownFile) templateData(fm FrontMatter) TemplateData {
+	data := TemplateData{
+		Title:       f.Title,
+		Path:        f.Path,
+		FrontMatter: map[string]string{},
+		Env:         map[string]string{},
+		Date:        runStarted,
+	}
+	for key := range fm {
+		data.FrontMatter[key] = fm.Get(key)
+	}
+	for _, e := range os.Environ() {
+		if name, value, ok := strings.Cut(e, "="); ok {
+			data.Env[name] = value
+		}
+	}
+	data.GitBranch, data.GitCommit = gitHead(filepath.Dir(f.Path))
+	if !renderer.Deterministic {
+		data.Published = runStarted.Format("2006-01-02")
+	}
+	data.RepoURL, data.SourceURL = sourceURL(f.Path, data.GitBranch, data.GitCommit)
+	if data.SourceURL != "" {
+		_, data.SourcePath, _ = repoFile(f.Path, data.GitBranch, data.GitCommit)
+	}
+	data.Owners = fileOwners(f.Path)
+	return data
+}
+
+// git runs a git command in dir, returning its trimmed output or empty on
+// failure
+func git(dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// gitHead returns the branch and commit checked out in dir, empty outside
+// of a git work tree
+func gitHead(dir string) (branch, commit string) {
+	gitInfoMu.Lock()
+	defer gitInfoMu.Unlock()
+	if info, ok := gitInfo[dir]; ok {
+		return info[0], info[1]
+	}
+	branch, commit = git(dir, "rev-parse", "--abbrev-ref", "HEAD"), git(dir, "rev-parse", "HEAD")
+	gitInfo[dir] = [2]string{branch, commit}
+	return branch, commit
+}
+
+// gitRepo returns the root of the git work tree containing dir and the URL
+// of its origin remote, empty outside of a git work tree
+func gitRepo(dir string) (root, remote string) {
+	gitInfoMu.Lock()
+	defer gitInfoMu.Unlock()
+	if info, ok := gitRepos[dir]; ok {
+		return info[0], info[1]
+	}
+	root = git(dir, "rev-parse", "--show-toplevel")
+	if root != "" {
+		remote = git(dir, "remote", "get-url", "origin")
+	}
+	gitRepos[dir] = [2]string{root, remote}
+	return root, remote
+}
+
+// executeTemplate renders a title, header or footer template
+func executeTemplate(name, text string, data TemplateData) (string, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %s", name, err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("unable to execute %s template: %s", name, err)
+	}
+	return b.String(), nil
+}
+
+// renderFragment renders a header or footer template file as markdown
+func (m *Markdown2Confluence) renderFragment(name, path string, f *MarkdownFile, data TemplateData) (string, []string, error) {
+	if path == "" {
+		return "", nil, nil
+	}
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("Could not open %s template %s:\n\t%s", name, path, err)
+	}
+	markdown, err := executeTemplate(name, string(text), data)
+	if err != nil {
+		return "", nil, err
+	}
+	// links and images resolve relative to the page's file
+	return renderContent(f.Path, markdown, m.WithHardWraps)
+}
+
+// applyTemplates sets the title from --title-template and wraps body in the
+// --managed-notice, the --banner and the rendered --header and --footer
+func (m *Markdown2Confluence) applyTemplates(f *MarkdownFile, fm FrontMatter, body string, images []string) (string, []string, error) {
+	banner := m.Banner && !strings.EqualFold(fm.Get("banner"), "false")
+	if m.TitleTemplate == "" && m.HeaderTemplate == "" && m.FooterTemplate == "" && !banner && !m.ManagedNotice {
+		return body, images, nil
+	}
+	data := f.templateData(fm)
+	data.Words = countWords(body)
+	data.ReadingTime = readingTime(data.Words)
+
+	if m.TitleTemplate != "" {
+		title, err := executeTemplate("title", m.TitleTemplate, data)
+		if err != nil {
+			return "", nil, err
+		}
+		if title = strings.TrimSpace(title); title == "" {
+			return "", nil, fmt.Errorf("the title template rendered an empty title for %s", f.Path)
+		}
+		title = m.normalizeTitle(title)
+		f.Title = title
+		data.Title = title
+	}
+
+	header, headerImages, err := m.renderFragment("header", m.HeaderTemplate, f, data)
+	if err != nil {
+		return "", nil, err
+	}
+	footer, footerImages, err := m.renderFragment("footer", m.FooterTemplate, f, data)
+	if err != nil {
+		return "", nil, err
+	}
+	images = append(append(headerImages, images...), footerImages...)
+	if banner {
+		rendered, bannerImages, err := m.renderBanner(f, data)
+		if err != nil {
+			return "", nil, err
+		}
+		header = rendered + header
+		images = append(bannerImages, images...)
+	}
+	if m.ManagedNotice {
+		notice, _, err := m.renderInfoPanel(f, "managed notice", ManagedNotice, data)
+		if err != nil {
+			return "", nil, err
+		}
+		header = notice + header
+	}
+	return header + body + footer, images, nil
+}
@@ -0,0 +1,33 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/justmiles/go-markdown2confluence/lib/renderer"
+)
+
+// normalizeTitle composes decomposed letters, spells the title in ASCII with
+// TransliterateTitles and shortens titles Confluence would reject
+func (m *Markdown2Confluence) normalizeTitle(title string) string {
+	title = renderer.NormalizeText(strings.TrimSpace(title))
+	if m.TransliterateTitles {
+		title = renderer.Transliterate(title)
+	}
+	if renderer.TitleLength(title) > renderer.MaxTitleLength {
+		short := renderer.TruncateTitle(title, renderer.MaxTitleLength)
+		fmt.Printf("Warning: title %q is longer than %d characters, shortened to %q\n", title, renderer.MaxTitleLength, short)
+		title = short
+	}
+	return title
+}
+
+// normalizeTitles normalizes the titles of files and their parent pages
+func (m *Markdown2Confluence) normalizeTitles(files []MarkdownFile) {
+	for i := range files {
+		files[i].Title = m.normalizeTitle(files[i].Title)
+		for j, parent := range files[i].Parents {
+			files[i].Parents[j] = m.normalizeTitle(parent)
+		}
+	}
+}
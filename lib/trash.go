@@ -0,0 +1,50 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// PurgeTrash permanently deletes trashed pages of m.Space. When parentID is
+// set only pages that were below that page are purged, otherwise the whole
+// trash is, after confirming it with --interactive. With dryRun the pages are
+// only listed.
+func (m *Markdown2Confluence) PurgeTrash(ctx context.Context, parentID string, dryRun bool) []error {
+	m.CreateClient()
+	pages, err := m.client.GetTrashedPages(ctx, m.Space)
+	if err != nil {
+		return []error{fmt.Errorf("Unable to list trashed pages in %s: %s", m.Space, err)}
+	}
+	if parentID == "" && m.Interactive && !dryRun && len(pages) > 0 &&
+		!m.confirm(fmt.Sprintf("Purge all %d trashed pages of space %s?", len(pages), m.Space)) {
+		return []error{ErrNotConfirmed}
+	}
+
+	var errors []error
+	for _, page := range pages {
+		if parentID != "" && !hasAncestor(page, parentID) {
+			continue
+		}
+		if dryRun {
+			fmt.Printf("would purge %s (%s)\n", page.Title, page.ID)
+			continue
+		}
+		if err := m.client.PurgePage(page.ID); err != nil {
+			errors = append(errors, fmt.Errorf("Unable to purge %s (%s): %s", page.Title, page.ID, err))
+			continue
+		}
+		fmt.Printf("purged %s (%s)\n", page.Title, page.ID)
+	}
+	return errors
+}
+
+func hasAncestor(page confluence.Page, id string) bool {
+	for _, a := range page.Ancestors {
+		if a.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,306 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/justmiles/go-confluence"
+)
+
+// UndoPlan is what rolling back a run does: the pages to restore to their
+// version before the run, and the pages and attachments it created
+type UndoPlan struct {
+	// Restore maps page ids to the version to restore
+	Restore map[string]int
+	// Pages are the ids of created pages, in the order they were created
+	Pages []string
+	// Redirects are the ids of created redirect stubs, which hold the old
+	// titles of renamed pages until they are deleted
+	Redirects []string
+	// Attachments are the created attachments
+	Attachments []UndoAttachment
+	// RestoreAttachments are the attachments the run uploaded new versions
+	// of, with the version to restore
+	RestoreAttachments []UndoAttachment
+}
+
+// UndoAttachment is an attachment created or updated by a run
+type UndoAttachment struct {
+	ID     string
+	PageID string
+	// Version is the version to restore an updated attachment to
+	Version int
+}
+
+func newUndoPlan() *UndoPlan {
+	return &UndoPlan{Restore: map[string]int{}}
+}
+
+// LoadUndoPlan reads the plan from the --report file or the --audit-log of a
+// run
+func LoadUndoPlan(file string) (*UndoPlan, error) {
+	dat, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s: %s", file, err)
+	}
+
+	var report struct {
+		Pages        *[]PageResult `json:"pages"`
+		CreatedPages []string      `json:"createdPages"`
+	}
+	if err := json.Unmarshal(dat, &report); err == nil && report.Pages != nil {
+		return undoReport(*report.Pages, report.CreatedPages), nil
+	}
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(bytes.NewReader(dat))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("Unable to read %s: neither a report nor an audit log: %s", file, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to read %s: %s", file, err)
+	}
+	return undoAuditLog(entries), nil
+}
+
+// undoReport plans the undo of the pages of a report and of the pages the
+// run created for itself. A failed page with an id was created or updated
+// before the error.
+func undoReport(pages []PageResult, created []string) *UndoPlan {
+	plan := newUndoPlan()
+	for _, p := range pages {
+		plan.Pages = append(plan.Pages, p.CreatedPages...)
+		plan.Redirects = append(plan.Redirects, p.CreatedRedirects...)
+		for _, id := range p.CreatedAttachments {
+			plan.Attachments = append(plan.Attachments, UndoAttachment{ID: id, PageID: p.PageID})
+		}
+		for _, id := range sortedKeys(p.PreviousAttachmentVersions) {
+			plan.RestoreAttachments = append(plan.RestoreAttachments, UndoAttachment{ID: id, PageID: p.PageID, Version: p.PreviousAttachmentVersions[id]})
+		}
+		switch {
+		case p.PageID == "":
+		case p.Action == ActionUpdated || p.Action == ActionFailed && p.PreviousVersion > 0:
+			plan.Restore[p.PageID] = p.PreviousVersion
+		case p.Action == ActionCreated || p.Action == ActionFailed:
+			plan.Pages = append(plan.Pages, p.PageID)
+		}
+	}
+	plan.Pages = append(plan.Pages, created...)
+	return plan
+}
+
+// undoAuditLog plans the undo of the successful calls of an audit log. Pages
+// and attachments updated several times are restored to the version before
+// the first update. Created pages labelled RedirectLabel are redirect stubs.
+func undoAuditLog(entries []AuditEntry) *UndoPlan {
+	plan := newUndoPlan()
+	restoreAttachment := map[string]int{}
+	redirects := map[string]bool{}
+	for _, e := range entries {
+		if e.Status < 200 || e.Status >= 300 || e.PageID == "" {
+			continue
+		}
+		switch e.Operation {
+		case "create page":
+			plan.Pages = append(plan.Pages, e.PageID)
+		case "update page":
+			if v, ok := plan.Restore[e.PageID]; e.Method == http.MethodPut && e.Version > 1 && (!ok || e.Version-1 < v) {
+				plan.Restore[e.PageID] = e.Version - 1
+			}
+		case "add label":
+			for _, l := range e.Labels {
+				redirects[e.PageID] = redirects[e.PageID] || l == RedirectLabel
+			}
+		case "upload attachment":
+			if e.AttachmentID == "" {
+				continue
+			}
+			i, ok := restoreAttachment[e.AttachmentID]
+			switch {
+			case e.Version == 1:
+				plan.Attachments = append(plan.Attachments, UndoAttachment{ID: e.AttachmentID, PageID: e.PageID})
+			case e.Version > 1 && !ok:
+				restoreAttachment[e.AttachmentID] = len(plan.RestoreAttachments)
+				plan.RestoreAttachments = append(plan.RestoreAttachments, UndoAttachment{ID: e.AttachmentID, PageID: e.PageID, Version: e.Version - 1})
+			case e.Version > 1 && e.Version-1 < plan.RestoreAttachments[i].Version:
+				plan.RestoreAttachments[i].Version = e.Version - 1
+			}
+		}
+	}
+
+	pages := plan.Pages[:0]
+	for _, id := range plan.Pages {
+		if redirects[id] {
+			plan.Redirects = append(plan.Redirects, id)
+		} else {
+			pages = append(pages, id)
+		}
+	}
+	plan.Pages = pages
+	return plan
+}
+
+// Undo rolls back a run: created attachments are deleted, updated
+// attachments and pages get a new version with the content they had before
+// the run and created pages are moved to the trash, newest first. Redirect
+// stubs go to the trash first, so renamed pages get their old titles back.
+// With dryRun the changes are only listed.
+func (m *Markdown2Confluence) Undo(plan *UndoPlan, dryRun bool) []error {
+	m.CreateClient()
+	created := map[string]bool{}
+	for _, id := range plan.Pages {
+		created[id] = true
+	}
+	for _, id := range plan.Redirects {
+		created[id] = true
+	}
+
+	var errors []error
+	for _, a := range plan.Attachments {
+		id, pageID := a.ID, a.PageID
+		// deleting the page takes its attachments along
+		if created[pageID] {
+			continue
+		}
+		if dryRun {
+			fmt.Printf("would delete attachment %s of page %s\n", id, pageID)
+			continue
+		}
+		if err := m.client.DeleteAttachment(pageID, id); err != nil {
+			errors = append(errors, fmt.Errorf("Unable to delete attachment %s of page %s: %s", id, pageID, err))
+			continue
+		}
+		fmt.Printf("deleted attachment %s of page %s\n", id, pageID)
+	}
+
+	for _, a := range plan.RestoreAttachments {
+		if created[a.PageID] {
+			continue
+		}
+		if err := m.restoreAttachment(a, dryRun); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	errors = append(errors, m.deletePages(plan.Redirects, dryRun)...)
+
+	for _, id := range sortedKeys(plan.Restore) {
+		if created[id] {
+			continue
+		}
+		if err := m.restorePage(id, plan.Restore[id], dryRun); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return append(errors, m.deletePages(plan.Pages, dryRun)...)
+}
+
+// deletePages moves created pages to the trash, newest first
+func (m *Markdown2Confluence) deletePages(ids []string, dryRun bool) []error {
+	var errors []error
+	for i := len(ids) - 1; i >= 0; i-- {
+		id := ids[i]
+		if dryRun {
+			fmt.Printf("would delete page %s\n", id)
+			continue
+		}
+		if err := m.client.DeleteContent(confluence.Content{ID: id}); err != nil {
+			errors = append(errors, fmt.Errorf("Unable to delete page %s: %s", id, err))
+			continue
+		}
+		fmt.Printf("deleted page %s\n", id)
+	}
+	return errors
+}
+
+// restorePage publishes the title and body of an earlier version of a page
+// as its next version
+func (m *Markdown2Confluence) restorePage(id string, version int, dryRun bool) error {
+	current, err := m.client.GetPage(id, "version", "space")
+	if err != nil {
+		return fmt.Errorf("Unable to fetch page %s: %s", id, err)
+	}
+	if current.Version.Number == version {
+		return nil
+	}
+	if dryRun {
+		fmt.Printf("would restore %s (%s) to version %d\n", current.Title, id, version)
+		return nil
+	}
+	previous, err := m.client.GetPageVersion(id, version)
+	if err != nil {
+		return fmt.Errorf("Unable to fetch version %d of page %s: %s", version, id, err)
+	}
+
+	if m.SafeMode.Enabled() {
+		if err := m.guard(guardUpdate, "", []string{id}); err != nil {
+			return err
+		}
+	}
+	page := confluence.Page{ID: id, Title: previous.Title, Space: current.Space}
+	page.Body.Storage.Value = previous.Body.Storage.Value
+	page.Version.Number = current.Version.Number + 1
+	page.Version.Message = fmt.Sprintf("Restored version %d", version)
+	if _, err := m.client.UpdatePage(&page); err != nil {
+		return fmt.Errorf("Unable to restore page %s to version %d: %s", id, version, err)
+	}
+	fmt.Printf("restored %s (%s) to version %d\n", previous.Title, id, version)
+	return nil
+}
+
+// restoreAttachment uploads the data of an earlier version of an attachment
+// as its next version, as Confluence does not delete current versions
+func (m *Markdown2Confluence) restoreAttachment(a UndoAttachment, dryRun bool) error {
+	current, err := m.client.GetAttachmentByID(a.ID)
+	if err != nil {
+		return fmt.Errorf("Unable to fetch attachment %s of page %s: %s", a.ID, a.PageID, err)
+	}
+	if current.Version.Number == a.Version {
+		return nil
+	}
+	if dryRun {
+		fmt.Printf("would restore attachment %s (%s) of page %s to version %d\n", current.Title, a.ID, a.PageID, a.Version)
+		return nil
+	}
+	previous, data, err := m.client.DownloadAttachmentVersion(a.ID, a.Version)
+	if err != nil {
+		return fmt.Errorf("Unable to download version %d of attachment %s: %s", a.Version, a.ID, err)
+	}
+
+	if m.SafeMode.Enabled() {
+		if err := m.guard(guardUpdate, "", []string{a.PageID}); err != nil {
+			return err
+		}
+	}
+	dir, err := os.MkdirTemp("", "m2c-undo")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	f := filepath.Join(dir, filepath.Base(current.Title))
+	if err := os.WriteFile(f, data, 0644); err != nil {
+		return err
+	}
+	if _, err := m.client.UpdateAttachment(a.PageID, a.ID, f, true); err != nil {
+		return fmt.Errorf("Unable to restore attachment %s of page %s to version %d: %s", a.ID, a.PageID, a.Version, err)
+	}
+	fmt.Printf("restored attachment %s (%s) of page %s to version %d\n", previous.Title, a.ID, a.PageID, a.Version)
+	return nil
+}
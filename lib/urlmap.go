@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+)
+
+// PageLink is where a source file was published, an entry of the --url-map
+// file
+type PageLink struct {
+	PageID  string `json:"pageId"`
+	Title   string `json:"title"`
+	TinyURL string `json:"tinyUrl,omitempty"`
+	WebURL  string `json:"webUrl,omitempty"`
+	// Endpoint is the name of the endpoint when publishing to several
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// WriteURLMap writes the pages of a report to a JSON file mapping source
+// paths to PageLinks, in a local file or another StateStore location.
+// Entries of earlier runs are kept, so runs publishing only some files, e.g.
+// with --modified-since, complete the map.
+func WriteURLMap(location string, r *Report) error {
+	if r == nil {
+		return nil
+	}
+	store, err := OpenStateStore(location)
+	if err != nil {
+		return err
+	}
+	links, err := loadURLMap(store)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range r.Pages {
+		if p.PageID == "" || p.Path == StdinPath {
+			continue
+		}
+		key := pageKey(p.Path, p.Section)
+		if p.Endpoint != "" {
+			key = p.Endpoint + ":" + key
+		}
+		links[key] = PageLink{
+			PageID:   p.PageID,
+			Title:    p.Title,
+			TinyURL:  p.URL,
+			WebURL:   p.WebURL,
+			Endpoint: p.Endpoint,
+		}
+	}
+
+	dat, err := json.MarshalIndent(links, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := store.Save(append(dat, '\n')); err != nil {
+		return fmt.Errorf("Unable to write URL map %s: %s", store, err)
+	}
+	return nil
+}
+
+// pageKey is the key of the page of a source file in the URL map, or of a
+// section split off it
+func pageKey(path, section string) string {
+	key := filepath.ToSlash(path)
+	if section != "" {
+		key += "#" + section
+	}
+	return key
+}
+
+// LoadURLMap reads a URL map written by WriteURLMap, empty if it does not
+// exist
+func LoadURLMap(location string) (map[string]PageLink, error) {
+	store, err := OpenStateStore(location)
+	if err != nil {
+		return nil, err
+	}
+	return loadURLMap(store)
+}
+
+func loadURLMap(store StateStore) (map[string]PageLink, error) {
+	links := map[string]PageLink{}
+	dat, err := store.Load()
+	if err == nil && dat != nil {
+		err = json.Unmarshal(dat, &links)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read URL map %s: %s", store, err)
+	}
+	return links, nil
+}
+
+// PrintURLs prints the source path and page URL of each published file, one
+// tab separated pair per line, for scripts
+func PrintURLs(r *Report) {
+	if r == nil {
+		return
+	}
+	for _, p := range r.Pages {
+		url := p.WebURL
+		if url == "" {
+			url = p.URL
+		}
+		if url == "" || p.Action == ActionFailed {
+			continue
+		}
+		fmt.Printf("%s\t%s\n", p.Path, url)
+	}
+}
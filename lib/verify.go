@@ -0,0 +1,139 @@
+package lib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ignoredVerifyAttributes are added or rewritten by Confluence on save and
+// are not considered a difference
+var ignoredVerifyAttributes = map[string]struct{}{
+	"macro-id":       {},
+	"local-id":       {},
+	"schema-version": {},
+}
+
+// VerifyPage fetches a published page back from Confluence and compares its
+// body with what was sent
+func (f *MarkdownFile) VerifyPage(m *Markdown2Confluence, sent string) error {
+	page, err := m.client.GetPage(f.PageID, "body.storage")
+	if err != nil {
+		return fmt.Errorf("Error fetching page %s for verification: %s", f.Title, err)
+	}
+	return VerifyStorage(sent, page.Body.Storage.Value)
+}
+
+// VerifyStorage compares two storage format bodies after normalization and
+// describes where Confluence changed the markup
+func VerifyStorage(sent, received string) error {
+	want, err := normalizeStorage(sent)
+	if err != nil {
+		return fmt.Errorf("unable to normalize sent body: %s", err)
+	}
+	got, err := normalizeStorage(received)
+	if err != nil {
+		return fmt.Errorf("unable to normalize received body: %s", err)
+	}
+
+	var problems []string
+	wantMacros, gotMacros := countMacros(want), countMacros(got)
+	for _, name := range sortedKeys(wantMacros) {
+		if gotMacros[name] < wantMacros[name] {
+			problems = append(problems, fmt.Sprintf("%d of %d %q macros were stripped", wantMacros[name]-gotMacros[name], wantMacros[name], name))
+		}
+	}
+
+	for i := 0; i < len(want) || i < len(got); i++ {
+		var w, g string
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(got) {
+			g = got[i]
+		}
+		if w != g {
+			problems = append(problems, fmt.Sprintf("first difference at token %d: sent %q, received %q", i, w, g))
+			break
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("verification failed, Confluence changed the page markup:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+	return nil
+}
+
+// normalizeStorage reduces a body to a list of comparable tokens: elements
+// with their Confluence attributes and whitespace collapsed text
+func normalizeStorage(body string) ([]string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(storageRootStart + body + storageRootEnd))
+	decoder.Entity = xml.HTMLEntity
+	decoder.Strict = false
+
+	var tokens []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return tokens, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "storage" {
+				continue
+			}
+			var attrs []string
+			for _, a := range t.Attr {
+				if a.Name.Space != acNamespace && a.Name.Space != riNamespace {
+					continue
+				}
+				if _, ok := ignoredVerifyAttributes[a.Name.Local]; ok {
+					continue
+				}
+				attrs = append(attrs, qualifiedName(a.Name)+`="`+a.Value+`"`)
+			}
+			sort.Strings(attrs)
+			tokens = append(tokens, "<"+qualifiedName(t.Name)+" "+strings.Join(attrs, " ")+">")
+		case xml.EndElement:
+			if t.Name.Local == "storage" {
+				continue
+			}
+			tokens = append(tokens, "</"+qualifiedName(t.Name)+">")
+		case xml.CharData:
+			text := strings.Join(strings.Fields(string(t)), " ")
+			if text != "" {
+				tokens = append(tokens, text)
+			}
+		}
+	}
+}
+
+func countMacros(tokens []string) map[string]int {
+	counts := make(map[string]int)
+	for _, t := range tokens {
+		if !strings.HasPrefix(t, "<ac:structured-macro ") {
+			continue
+		}
+		for _, a := range strings.Fields(strings.TrimSuffix(t, ">")) {
+			if strings.HasPrefix(a, "ac:name=") {
+				counts[strings.Trim(strings.TrimPrefix(a, "ac:name="), `"`)]++
+			}
+		}
+	}
+	return counts
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
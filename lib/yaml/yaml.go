@@ -0,0 +1,520 @@
+// Package yaml decodes the subset of YAML used by configuration and API
+// description files: block and flow mappings and sequences, plain, quoted
+// and block scalars. Anchors, aliases, tags and multiple documents are not
+// supported.
+package yaml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal decodes a YAML document into map[string]interface{},
+// []interface{}, string, bool, int, float64 or nil values
+func Unmarshal(data []byte) (interface{}, error) {
+	p := &parser{}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		p.lines = append(p.lines, strings.TrimRight(line, " \t"))
+	}
+	// skip a leading document marker
+	if i := p.next(); i >= 0 && strings.TrimSpace(p.lines[i]) == "---" {
+		p.pos = i + 1
+	}
+	v, err := p.parseNode(0)
+	if err != nil {
+		return nil, err
+	}
+	if i := p.next(); i >= 0 && strings.TrimSpace(p.lines[i]) != "..." {
+		return nil, p.errorf(i, "unexpected content %q", strings.TrimSpace(p.lines[i]))
+	}
+	return v, nil
+}
+
+type parser struct {
+	lines []string
+	pos   int
+}
+
+func (p *parser) errorf(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("yaml: line %d: %s", line+1, fmt.Sprintf(format, args...))
+}
+
+// next returns the index of the next line with content, -1 at the end
+func (p *parser) next() int {
+	for i := p.pos; i < len(p.lines); i++ {
+		content := strings.TrimSpace(p.lines[i])
+		if content != "" && !strings.HasPrefix(content, "#") {
+			return i
+		}
+	}
+	return -1
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// parseNode parses the block starting at the next line if it is indented at
+// least minIndent, returning nil otherwise
+func (p *parser) parseNode(minIndent int) (interface{}, error) {
+	i := p.next()
+	if i < 0 {
+		return nil, nil
+	}
+	line := p.lines[i]
+	indent := indentation(line)
+	if indent < minIndent {
+		return nil, nil
+	}
+	content := strings.TrimSpace(line)
+	switch {
+	case content == "-" || strings.HasPrefix(content, "- "):
+		return p.parseSequence(indent)
+	case mappingKey(content) >= 0:
+		return p.parseMapping(indent)
+	}
+	p.pos = i
+	return p.parseValue(indent - 1)
+}
+
+// mappingKey returns the index of the colon separating a mapping key from its
+// value, or -1 if content is not a mapping entry
+func mappingKey(content string) int {
+	if content == "" || strings.ContainsRune("[{|>", rune(content[0])) {
+		return -1
+	}
+	if content[0] == '"' || content[0] == '\'' {
+		end := closingQuote(content, 0)
+		if end < 0 || end+1 >= len(content) || content[end+1] != ':' {
+			return -1
+		}
+		if end+2 == len(content) || content[end+2] == ' ' {
+			return end + 1
+		}
+		return -1
+	}
+	for i := 0; i < len(content); i++ {
+		if content[i] == ':' && (i+1 == len(content) || content[i+1] == ' ') {
+			return i
+		}
+		if content[i] == '#' && i > 0 && content[i-1] == ' ' {
+			return -1
+		}
+	}
+	return -1
+}
+
+func (p *parser) parseMapping(indent int) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	for {
+		i := p.next()
+		if i < 0 || indentation(p.lines[i]) != indent {
+			return m, nil
+		}
+		content := strings.TrimSpace(p.lines[i])
+		colon := mappingKey(content)
+		if colon < 0 {
+			return nil, p.errorf(i, "expected a mapping key, got %q", content)
+		}
+		key := unquote(strings.TrimSpace(content[:colon]))
+		rest := strings.TrimSpace(content[colon+1:])
+		// replace the key by spaces so the value parses in place
+		p.lines[i] = strings.Repeat(" ", indent+colon+1) + " " + rest
+		p.pos = i
+
+		if rest == "" || strings.HasPrefix(rest, "#") {
+			p.pos = i + 1
+			value, err := p.parseNode(indent + 1)
+			if err != nil {
+				return nil, err
+			}
+			// sequences may be indented like their key
+			if value == nil {
+				if j := p.next(); j >= 0 && indentation(p.lines[j]) == indent && strings.HasPrefix(strings.TrimSpace(p.lines[j]), "-") {
+					value, err = p.parseSequence(indent)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+			m[key] = value
+			continue
+		}
+
+		value, err := p.parseValue(indent)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+}
+
+func (p *parser) parseSequence(indent int) ([]interface{}, error) {
+	s := []interface{}{}
+	for {
+		i := p.next()
+		if i < 0 || indentation(p.lines[i]) != indent {
+			return s, nil
+		}
+		content := strings.TrimSpace(p.lines[i])
+		if content != "-" && !strings.HasPrefix(content, "- ") {
+			return s, nil
+		}
+
+		if content == "-" {
+			p.pos = i + 1
+			value, err := p.parseNode(indent + 1)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, value)
+			continue
+		}
+
+		// replace the dash by a space so the item parses as an indented block
+		item := strings.TrimLeft(content[1:], " ")
+		itemIndent := indent + len(content) - len(item)
+		p.lines[i] = strings.Repeat(" ", itemIndent) + item
+		p.pos = i
+		var value interface{}
+		var err error
+		if item == "-" || strings.HasPrefix(item, "- ") || mappingKey(item) >= 0 {
+			value, err = p.parseNode(itemIndent)
+		} else {
+			value, err = p.parseValue(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, value)
+	}
+}
+
+// parseValue parses the inline value on the current line and any
+// continuation lines indented more than parentIndent
+func (p *parser) parseValue(parentIndent int) (interface{}, error) {
+	i := p.pos
+	content := strings.TrimSpace(p.lines[i])
+	p.pos = i + 1
+
+	switch {
+	case strings.HasPrefix(content, "|") || strings.HasPrefix(content, ">"):
+		return p.parseBlockScalar(content, parentIndent), nil
+	case strings.HasPrefix(content, "[") || strings.HasPrefix(content, "{"):
+		text := stripComment(content)
+		for !balanced(text) && p.pos < len(p.lines) {
+			text += " " + stripComment(strings.TrimSpace(p.lines[p.pos]))
+			p.pos++
+		}
+		f := &flowParser{s: text}
+		v, err := f.parse()
+		if err != nil {
+			return nil, p.errorf(i, "%s", err)
+		}
+		return v, nil
+	case strings.HasPrefix(content, `"`) || strings.HasPrefix(content, `'`):
+		text := content
+		for closingQuote(text, 0) < 0 && p.pos < len(p.lines) {
+			text += " " + strings.TrimSpace(p.lines[p.pos])
+			p.pos++
+		}
+		end := closingQuote(text, 0)
+		if end < 0 {
+			return nil, p.errorf(i, "unterminated quoted string")
+		}
+		return unquote(text[:end+1]), nil
+	}
+
+	text := stripComment(content)
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		next := strings.TrimSpace(line)
+		if next == "" || indentation(line) <= parentIndent || strings.HasPrefix(next, "#") {
+			break
+		}
+		text += " " + stripComment(next)
+		p.pos++
+	}
+	return resolve(text), nil
+}
+
+// parseBlockScalar reads a literal (|) or folded (>) block scalar
+func (p *parser) parseBlockScalar(header string, parentIndent int) string {
+	header = stripComment(header)
+	folded := header[0] == '>'
+	chomp := ""
+	if strings.Contains(header, "-") {
+		chomp = "-"
+	} else if strings.Contains(header, "+") {
+		chomp = "+"
+	}
+
+	var lines []string
+	indent := -1
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		if indent < 0 {
+			indent = indentation(line)
+		}
+		if indentation(line) < indent || indentation(line) <= parentIndent {
+			break
+		}
+		lines = append(lines, line[indent:])
+		p.pos++
+	}
+
+	// trailing blank lines belong to the chomping, not the content
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	// give blank lines that do not belong to this scalar back to the parser
+	p.pos -= trailing
+
+	var text string
+	if folded {
+		var b strings.Builder
+		for j, line := range lines {
+			switch {
+			case j == 0:
+			case line == "" || lines[j-1] == "":
+				b.WriteString("\n")
+			case strings.HasPrefix(line, " ") || strings.HasPrefix(lines[j-1], " "):
+				b.WriteString("\n")
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(line)
+		}
+		text = b.String()
+	} else {
+		text = strings.Join(lines, "\n")
+	}
+
+	switch chomp {
+	case "-":
+		return text
+	case "+":
+		return text + "\n" + strings.Repeat("\n", trailing)
+	}
+	if text == "" {
+		return ""
+	}
+	return text + "\n"
+}
+
+// stripComment removes a trailing comment outside of quotes
+func stripComment(s string) string {
+	quote := byte(0)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return strings.TrimSpace(s[:i])
+		}
+	}
+	return s
+}
+
+func balanced(s string) bool {
+	depth := 0
+	quote := byte(0)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// closingQuote returns the index of the quote closing the string starting at
+// start, or -1
+func closingQuote(s string, start int) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote:
+			if quote == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// unquote returns the value of a quoted scalar, or s itself
+func unquote(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	switch {
+	case s[0] == '"' && s[len(s)-1] == '"':
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+		return s[1 : len(s)-1]
+	case s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
+
+// resolve converts a plain scalar to its typed value
+func resolve(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && strings.ContainsAny(s, ".eE") {
+		return f
+	}
+	return s
+}
+
+// flowParser parses flow collections such as [a, "b"] and {a: 1}
+type flowParser struct {
+	s   string
+	pos int
+}
+
+func (f *flowParser) skipSpace() {
+	for f.pos < len(f.s) && (f.s[f.pos] == ' ' || f.s[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+func (f *flowParser) parse() (interface{}, error) {
+	f.skipSpace()
+	if f.pos >= len(f.s) {
+		return nil, fmt.Errorf("unexpected end of flow collection")
+	}
+	switch f.s[f.pos] {
+	case '[':
+		f.pos++
+		s := []interface{}{}
+		for {
+			f.skipSpace()
+			if f.pos < len(f.s) && f.s[f.pos] == ']' {
+				f.pos++
+				return s, nil
+			}
+			v, err := f.parse()
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+			if err := f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		f.pos++
+		m := map[string]interface{}{}
+		for {
+			f.skipSpace()
+			if f.pos < len(f.s) && f.s[f.pos] == '}' {
+				f.pos++
+				return m, nil
+			}
+			key, err := f.scalar(true)
+			if err != nil {
+				return nil, err
+			}
+			f.skipSpace()
+			var value interface{}
+			if f.pos < len(f.s) && f.s[f.pos] == ':' {
+				f.pos++
+				value, err = f.parse()
+				if err != nil {
+					return nil, err
+				}
+			}
+			m[fmt.Sprint(key)] = value
+			if err := f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f.scalar(false)
+}
+
+// separator consumes a comma, leaving the closing bracket in place
+func (f *flowParser) separator(closing byte) error {
+	f.skipSpace()
+	if f.pos >= len(f.s) {
+		return fmt.Errorf("unterminated flow collection")
+	}
+	switch f.s[f.pos] {
+	case ',':
+		f.pos++
+		return nil
+	case closing:
+		return nil
+	}
+	return fmt.Errorf("unexpected %q in flow collection", f.s[f.pos])
+}
+
+func (f *flowParser) scalar(key bool) (interface{}, error) {
+	f.skipSpace()
+	if f.pos < len(f.s) && (f.s[f.pos] == '"' || f.s[f.pos] == '\'') {
+		end := closingQuote(f.s, f.pos)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated quoted string")
+		}
+		v := unquote(f.s[f.pos : end+1])
+		f.pos = end + 1
+		return v, nil
+	}
+	start := f.pos
+	for f.pos < len(f.s) {
+		c := f.s[f.pos]
+		if c == ',' || c == ']' || c == '}' || (key && c == ':') {
+			break
+		}
+		if c == ':' && f.pos+1 < len(f.s) && f.s[f.pos+1] == ' ' {
+			break
+		}
+		f.pos++
+	}
+	text := strings.TrimSpace(f.s[start:f.pos])
+	if key {
+		return text, nil
+	}
+	return resolve(text), nil
+}
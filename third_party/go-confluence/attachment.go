@@ -0,0 +1,813 @@
+package confluence
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-querystring/query"
+	"github.com/naminomare/gogutil/fileio"
+)
+
+// https://docs.atlassian.com/atlassian-confluence/REST/6.5.2/#content/{id}/child/attachment
+
+const (
+	AttachmentNotFoundError = "attachment not found"
+)
+
+// Attachments ..
+type Attachments struct {
+	Results []Attachment `json:"results"`
+	Size    int          `json:"size"`
+}
+
+// Attachment ...
+type Attachment struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+	Title    string `json:"title"`
+	Metadata struct {
+		Comment   string `json:"comment"`
+		MediaType string `json:"mediaType"`
+	} `json:"metadata"`
+	Version struct {
+		Number int `json:"number"`
+	} `json:"version"`
+	Extensions struct {
+		MediaType string  `json:"mediaType"`
+		FileSize  float64 `json:"fileSize"`
+	} `json:"extensions"`
+	Container struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	} `json:"container"`
+}
+
+// AttachmentResults Results
+type AttachmentResults struct {
+	Results []AttachmentFetchResult `json:"results"`
+	Start   float64                 `json:"start"`
+	Limit   float64                 `json:"limit"`
+	Size    float64                 `json:"size"`
+	Links   map[string]string       `json:"_links"`
+}
+
+// AttachmentFetchResult ...
+type AttachmentFetchResult struct {
+	ID         string               `json:"id"`
+	Type       string               `json:"type"`
+	Status     string               `json:"status"`
+	Title      string               `json:"title"`
+	MetaData   AttachmentMetaData   `json:"metadata"`
+	Extensions AttachmentExtensions `json:"extensions"`
+	Expandable AttachmentExpandable `json:"_expandable"`
+	Links      AttachmentLinks      `json:"_links"`
+}
+
+// AttachmentMetaData ...
+type AttachmentMetaData struct {
+	MediaType  string                 `json:"mediaType"`
+	Labels     AttachmentLabels       `json:"labels"`
+	Expandable map[string]interface{} `json:"_expandable"`
+}
+
+// AttachmentLabels ...
+type AttachmentLabels struct {
+	Results []interface{}     `json:"results"`
+	Start   float64           `json:"start"`
+	Limit   float64           `json:"limit"`
+	Size    float64           `json:"size"`
+	Links   map[string]string `json:"_links"`
+}
+
+// AttachmentExtensions Extensions
+type AttachmentExtensions struct {
+	MediaType string  `json:"mediaType"`
+	FileSize  float64 `json:"fileSize"`
+	Comment   string  `json:"comment"`
+}
+
+// AttachmentExpandable expandable
+type AttachmentExpandable struct {
+	Container    string `json:"container"`
+	Operations   string `json:"operations"`
+	Children     string `json:"children"`
+	Restrictions string `json:"restrictions"`
+	History      string `json:"history"`
+	// Ancestors string `json:"ancestors"`
+	// Body string `json:"body"`
+	// Version string `json:"version"`
+	Descendants string `json:"descendants"`
+	Space       string `json:"space"`
+}
+
+// AttachmentLinks links
+type AttachmentLinks struct {
+	Self      string `json:"self"`
+	Webui     string `json:"webui"`
+	Download  string `json:"download"`
+	Thumbnail string `json:"thumbnail"`
+}
+
+type UpdateAttachmentNameRequest struct {
+	Title string `json:"title"`
+	ID    string `json:"id"`
+	Version Version `json:"version"`
+}
+type Version struct {
+	Number    int  `json:"number"`
+	MajorEdit bool `json:"majorEdit"`
+}
+
+// UnmarshalJSON Custom Unmarshaller
+func (a *AttachmentLinks) UnmarshalJSON(data []byte) error {
+	type Alias AttachmentLinks
+	aux := &struct {
+		*Alias
+	}{
+		Alias: (*Alias)(a),
+	}
+	err := json.Unmarshal(data, &aux)
+	if err != nil {
+		return err
+	}
+
+	a.Thumbnail = strings.Replace(a.Download, "attachments", "thumbnails", 1)
+
+	// Dirty hack nees to convert image macro to use ! in storage mode
+	a.Thumbnail = stripQueryParam(a.Thumbnail, "modificationDate")
+	a.Thumbnail = stripQueryParam(a.Thumbnail, "cacheVersion")
+	a.Thumbnail = stripQueryParam(a.Thumbnail, "api")
+	a.Thumbnail = stripQueryParam(a.Thumbnail, "version")
+
+	return nil
+}
+
+func stripQueryParam(inURL string, stripKey string) string {
+	u, err := url.Parse(inURL)
+	if err != nil {
+		return inURL
+	}
+	q := u.Query()
+	q.Del(stripKey)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (client *Client) newAttachmentEndpoint(contentID string) string {
+	return "/rest/api/content/" + contentID + "/child/attachment"
+}
+
+func (client *Client) attachmentEndpoint(contentID, attachmentID string) string {
+	return client.newAttachmentEndpoint(contentID) + "/" + attachmentID
+}
+
+func (client *Client) attachmentDataEndpoint(contentID, attachmentID string) string {
+	return client.attachmentEndpoint(contentID, attachmentID) + "/data"
+}
+
+// DeleteAttachment ..
+func (client *Client) DeleteAttachment(contentID string, attachmentID string) error {
+	endpoint := client.attachmentEndpoint(contentID, attachmentID)
+
+	_, err := client.request("DELETE", endpoint, "", nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAttachment ...
+func (client *Client) GetAttachment(contentID, attachmentID string) (*Attachment, error) {
+	endpoint := client.attachmentEndpoint(contentID, attachmentID)
+
+	res, err := client.request("GET", endpoint, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments Attachments
+	err = json.Unmarshal(res, &attachments)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments.Results) < 1 {
+		return nil, fmt.Errorf("empty list")
+	}
+
+	return &attachments.Results[0], nil
+}
+
+// GetAttachments ...
+func (client *Client) GetAttachments(contentID string) (*[]Attachment, error) {
+	attachments, err := client.GetAttachmentsFiltered(contentID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) < 1 {
+		return nil, fmt.Errorf("empty list")
+	}
+	return &attachments, nil
+}
+
+// GetAttachmentByFilename ...
+func (client *Client) GetAttachmentByFilename(contentID, filename string) (*Attachment, error) {
+	attachments, err := client.GetAttachmentsFiltered(contentID, &GetAttachmentsQueryParameters{
+		Filename: filename,
+		Limit:    1,
+		Max:      1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) < 1 {
+		return nil, fmt.Errorf("attachment not found")
+	}
+
+	return &attachments[0], nil
+}
+
+// GetAttachmentsQueryParameters query parameters for GetAttachmentsFiltered
+type GetAttachmentsQueryParameters struct {
+	Filename     string   `url:"filename,omitempty"`
+	MediaType    string   `url:"mediaType,omitempty"`
+	Expand       []string `url:"-"`
+	ExpandString string   `url:"expand,omitempty"`
+	Start        int      `url:"start,omitempty"`
+	// Limit is the page size requested from the API
+	Limit int `url:"limit,omitempty"`
+	// Max stops paging once this many attachments are collected (0 for all)
+	Max int `url:"-"`
+	// FilenamePrefix keeps attachments whose title starts with the prefix.
+	// The API has no prefix filter, so it is applied client side.
+	FilenamePrefix string `url:"-"`
+	// MediaTypePrefix keeps attachments whose media type starts with the
+	// prefix, e.g. "image/". It is applied client side.
+	MediaTypePrefix string `url:"-"`
+}
+
+// GetAttachmentsFiltered lists the attachments of a piece of content,
+// following pagination until all matching attachments are collected.
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-id-child-attachment-get
+func (client *Client) GetAttachmentsFiltered(contentID string, qp *GetAttachmentsQueryParameters) ([]Attachment, error) {
+	var params GetAttachmentsQueryParameters
+	if qp != nil {
+		params = *qp
+	}
+	if params.Limit == 0 {
+		params.Limit = 50
+	}
+	params.ExpandString = strings.Join(params.Expand, ",")
+
+	var results []Attachment
+	for {
+		v, _ := query.Values(params)
+		res, err := client.request("GET", client.newAttachmentEndpoint(contentID), v.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var attachments Attachments
+		err = json.Unmarshal(res, &attachments)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range attachments.Results {
+			if params.FilenamePrefix != "" && !strings.HasPrefix(a.Title, params.FilenamePrefix) {
+				continue
+			}
+			if params.MediaTypePrefix != "" && !strings.HasPrefix(a.Metadata.MediaType, params.MediaTypePrefix) {
+				continue
+			}
+			results = append(results, a)
+			if params.Max > 0 && len(results) >= params.Max {
+				return results, nil
+			}
+		}
+
+		if len(attachments.Results) < params.Limit {
+			return results, nil
+		}
+		params.Start += len(attachments.Results)
+	}
+}
+
+func (client *Client) UpdateAttachmentName(contentID, attachmentID string, path string) (*Attachment, error) {
+	return client.updateAttachmentName(contentID, attachmentID, path, 1)
+}
+
+// updateAttachmentName renames an attachment, versionNumber must be the
+// attachment's next version
+func (client *Client) updateAttachmentName(contentID, attachmentID string, path string, versionNumber int) (*Attachment, error) {
+	version := Version{
+		Number:    versionNumber,
+		MajorEdit: false,
+	}
+	request := UpdateAttachmentNameRequest{
+		ID:    attachmentID,
+		Title: path,
+		Version: version,
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := client.attachmentEndpoint(contentID, attachmentID)
+	res, err := client.request("PUT", endpoint, "", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	var attachment Attachment
+	err = json.Unmarshal(res, &attachment)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// UpdateAttachment ...
+func (client *Client) UpdateAttachment(contentID, attachmentID, path string, minorEdit bool) (*Attachment, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+
+	md5HashString, err := GetFileMD5Hash(path)
+	if err != nil {
+		return nil, err
+	}
+
+	part, err := writer.CreateFormFile("file", fi.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.Copy(part, file)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.WriteField("minorEdit", strconv.FormatBool(minorEdit))
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.WriteField("comment", md5HashString)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := client.attachmentDataEndpoint(contentID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int64(body.Len())
+	preRequest := func(req *http.Request) {
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.ContentLength = size
+	}
+
+	res, err := client.request("POST", endpoint, "", client.throttle(body), preRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachment Attachment
+	err = json.Unmarshal(res, &attachment)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// AddAttachment ...
+func (client *Client) AddAttachment(contentID, path string) (*Attachment, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	md5HashString, err := GetFileMD5Hash(path)
+	if err != nil {
+		return nil, err
+	}
+
+	part, err := writer.CreateFormFile("file", fi.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.Copy(part, file)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.WriteField("comment", md5HashString)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	endpoint := client.newAttachmentEndpoint(contentID)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(body.Len())
+	preRequest := func(req *http.Request) {
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.ContentLength = size
+	}
+
+	res, err := client.request("POST", endpoint, "", client.throttle(body), preRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments Attachments
+	err = json.Unmarshal(res, &attachments)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments.Results) < 1 {
+		return nil, fmt.Errorf("empty list")
+	}
+
+	return &attachments.Results[0], nil
+}
+
+// AddUpdateAttachments uploads files to a page under their own filename,
+// the md5 hash of their content is kept in the comment. Files already
+// attached with the same content are skipped, files that changed are
+// uploaded as a new version of the attachment so its history is kept and
+// earlier versions of the page still show theirs, anything else is added as
+// a new attachment. Attachments are never renamed. It returns the
+// attachments it uploaded.
+func (client *Client) AddUpdateAttachments(contentID string, files []string) ([]*Attachment, []error) {
+	var results []*Attachment
+	var errors []error
+
+	attachmentsByName, _ := client.getPageAttachmentsByName(contentID)
+	uploaded := make(map[string]string)
+
+	for _, f := range files {
+		filename := path.Base(f)
+		if previous, ok := uploaded[filename]; ok {
+			if previous != f {
+				errors = append(errors, fmt.Errorf("attachments %s and %s have the same name %s", previous, f, filename))
+			}
+			continue
+		}
+		uploaded[filename] = f
+
+		md5HashString, err := GetFileMD5Hash(f)
+		attachment := attachmentsByName[filename]
+		switch {
+		case err != nil:
+		case attachment == nil:
+			attachment, err = client.AddAttachment(contentID, f)
+		case attachment.Metadata.Comment == md5HashString:
+			fmt.Println(fmt.Sprintf("attachment %s already exists, skipping,md5=%s", filename, md5HashString))
+			continue
+		default:
+			fmt.Println(fmt.Sprintf("attachment %s changed, uploading new version", filename))
+			attachment, err = client.UpdateAttachment(contentID, attachment.ID, f, true)
+		}
+		if err == nil {
+			results = append(results, attachment)
+		} else {
+			errors = append(errors, err)
+		}
+	}
+	return results, errors
+}
+
+// getPageAttachmentsByName indexes the attachments of a page by title
+func (client *Client) getPageAttachmentsByName(pageID string) (map[string]*Attachment, error) {
+	attachments, err := client.GetAttachments(pageID)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]*Attachment)
+	for i, a := range *attachments {
+		m[a.Title] = &(*attachments)[i]
+	}
+	return m, nil
+}
+
+func (client *Client) GetPageAttachmentsAndToMap(pageID string) (map[string]*Attachment, error) {
+	attachments, err := client.GetAttachments(pageID)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]*Attachment)
+	for i, a := range *attachments {
+		m[a.Metadata.Comment] = &(*attachments)[i]
+		//fmt.Println(fmt.Sprintf("page:%s,exist attachment %s,md5=%s", pageID, a.Title, a.Metadata.Comment))
+	}
+	return m, nil
+}
+
+// FetchAttachmentMetaData ...
+func (client *Client) FetchAttachmentMetaData(contentID string) (*AttachmentResults, error) {
+	endpoint := client.newAttachmentEndpoint(contentID)
+
+	res, err := client.request(
+		http.MethodGet,
+		endpoint,
+		"",
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments AttachmentResults
+	err = json.Unmarshal(res, &attachments)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments.Results) < 1 {
+		return nil, fmt.Errorf("empty list")
+	}
+
+	return &attachments, err
+}
+
+// DownloadAttachmentsFromPage ...
+func (client *Client) DownloadAttachmentsFromPage(pageID, directory string) error {
+	res, err := client.FetchAttachmentMetaData(pageID)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(directory, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range res.Results {
+		downloadURL := client.Endpoint + v.Links.Download
+		path, err := fileio.GetNonExistFileName(filepath.Join(directory, v.Title), 1000)
+		if err != nil {
+			return err
+		}
+		err = client.DownloadFromURL(downloadURL, path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DownloadFromURL ...
+func (client *Client) DownloadFromURL(url, outputFilepath string) error {
+	resp, err := client.request(
+		http.MethodGet,
+		url,
+		"",
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	fh, err := os.Create(outputFilepath)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	_, err = fh.Write(resp)
+
+	return err
+}
+
+
+func GetFileMD5Hash(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+
+// MoveAttachment moves an attachment to another piece of content. When the
+// instance does not support changing the attachment container (older Server
+// versions) the attachment is copied to the new content and deleted from the
+// old one. Other errors are returned as they are.
+func (client *Client) MoveAttachment(fromContentID, attachmentID, toContentID string) (*Attachment, error) {
+	attachment, err := client.getAttachmentContent(attachmentID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	type container struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}
+	request := struct {
+		ID        string    `json:"id"`
+		Type      string    `json:"type"`
+		Title     string    `json:"title"`
+		Version   Version   `json:"version"`
+		Container container `json:"container"`
+	}{
+		ID:        attachmentID,
+		Type:      "attachment",
+		Title:     attachment.Title,
+		Version:   Version{Number: attachment.Version.Number + 1},
+		Container: container{ID: toContentID, Type: "page"},
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.request("PUT", client.attachmentEndpoint(fromContentID, attachmentID), "", bytes.NewReader(body))
+	if err == nil {
+		var moved Attachment
+		if err := json.Unmarshal(res, &moved); err != nil {
+			return nil, err
+		}
+		return &moved, nil
+	}
+	if !containerChangeUnsupported(res) {
+		return nil, err
+	}
+
+	moved, err := client.CopyAttachment(fromContentID, attachmentID, toContentID)
+	if err != nil {
+		return nil, err
+	}
+	return moved, client.DeleteAttachment(fromContentID, attachmentID)
+}
+
+// containerChangeUnsupported tells whether a failed attachment update was
+// rejected for changing the container: older Server versions answer 501, or
+// 400 naming the container
+func containerChangeUnsupported(body []byte) bool {
+	var res APIResponse
+	if json.Unmarshal(body, &res) != nil {
+		return false
+	}
+	return res.StatusCode == http.StatusNotImplemented ||
+		res.StatusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(res.Message), "container")
+}
+
+// CopyAttachment downloads an attachment and uploads it to another piece of content
+func (client *Client) CopyAttachment(fromContentID, attachmentID, toContentID string) (*Attachment, error) {
+	attachment, err := client.getAttachmentContent(attachmentID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.download(attachment.Links.Download)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "attachment")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	// AddAttachment keeps the filename, so pages referencing it still do
+	f := filepath.Join(dir, attachment.Title)
+	if err := os.WriteFile(f, data, 0644); err != nil {
+		return nil, err
+	}
+	return client.AddAttachment(toContentID, f)
+}
+
+// DownloadAttachment returns an attachment with its data
+func (client *Client) DownloadAttachment(attachmentID string) (*Attachment, []byte, error) {
+	attachment, err := client.getAttachmentContent(attachmentID, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := client.download(attachment.Links.Download)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &attachment.Attachment, data, nil
+}
+
+// GetAttachmentByID returns the current version of an attachment
+func (client *Client) GetAttachmentByID(attachmentID string) (*Attachment, error) {
+	attachment, err := client.getAttachmentContent(attachmentID, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment.Attachment, nil
+}
+
+// DownloadAttachmentVersion returns an earlier version of an attachment with
+// its data
+func (client *Client) DownloadAttachmentVersion(attachmentID string, version int) (*Attachment, []byte, error) {
+	attachment, err := client.getAttachmentContent(attachmentID, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := client.download(attachment.Links.Download)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &attachment.Attachment, data, nil
+}
+
+// attachmentContent is an attachment as returned by the content endpoint
+type attachmentContent struct {
+	Attachment
+	Links AttachmentLinks `json:"_links"`
+}
+
+// getAttachmentContent fetches the current version of an attachment, or the
+// given earlier one
+func (client *Client) getAttachmentContent(attachmentID string, version int) (*attachmentContent, error) {
+	query := "expand=version,container"
+	if version > 0 {
+		query = "status=historical&version=" + strconv.Itoa(version) + "&" + query
+	}
+	res, err := client.request("GET", "/rest/api/content/"+attachmentID, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var attachment attachmentContent
+	if err := json.Unmarshal(res, &attachment); err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// download fetches binary data relative to the endpoint, such as an attachment download link
+func (client *Client) download(downloadPath string) ([]byte, error) {
+	req, err := http.NewRequest("GET", client.Endpoint+downloadPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if client.Cookie != "" {
+		req.Header.Set("Cookie", fmt.Sprintf("JSESSIONID=%v", client.Cookie))
+	} else if client.AccessToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", client.AccessToken))
+	} else {
+		req.SetBasicAuth(client.Username, client.Password)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download of %s failed: %s", downloadPath, res.Status)
+	}
+	return io.ReadAll(client.throttle(res.Body))
+}
@@ -0,0 +1,75 @@
+package confluence
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is used when Client.CacheTTL is not set
+const DefaultCacheTTL = 5 * time.Minute
+
+// ttlCache is a small in-memory cache for lookups that rarely change during a
+// run, such as users and groups
+type ttlCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+func (c *ttlCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *ttlCache) set(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]cacheEntry)
+	}
+	c.entries[key] = cacheEntry{value: value, expires: time.Now().Add(ttl)}
+}
+
+func (c *ttlCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+// cached returns the cached value for key or calls fetch and caches its result
+func (client *Client) cached(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	if client.CacheTTL < 0 {
+		return fetch()
+	}
+	if v, ok := client.cache.get(key); ok {
+		return v, nil
+	}
+	v, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	ttl := client.CacheTTL
+	if ttl == 0 {
+		ttl = DefaultCacheTTL
+	}
+	client.cache.set(key, v, ttl)
+	return v, nil
+}
+
+// ClearCache drops all cached user and group lookups
+func (client *Client) ClearCache() {
+	client.cache.clear()
+}
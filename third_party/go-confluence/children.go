@@ -0,0 +1,122 @@
+package confluence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrStopIteration can be returned by a ForEachChild or ForEachDescendant
+// callback to stop the traversal without an error
+var ErrStopIteration = errors.New("stop iteration")
+
+// childPageLimit is the page size used when listing children
+const childPageLimit = 50
+
+// PageFunc is called for every page visited by a traversal
+type PageFunc func(page Page) error
+
+// ForEachChild calls fn for every direct child page of id, fetching further
+// result pages as needed
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-id-child-type-get
+func (client *Client) ForEachChild(ctx context.Context, id string, fn PageFunc, expand ...string) error {
+	err := client.forEachChild(ctx, id, fn, expand)
+	if errors.Is(err, ErrStopIteration) {
+		return nil
+	}
+	return err
+}
+
+func (client *Client) forEachChild(ctx context.Context, id string, fn PageFunc, expand []string) error {
+	if len(expand) == 0 {
+		expand = []string{"version"}
+	}
+	start := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		v := url.Values{}
+		v.Set("start", strconv.Itoa(start))
+		v.Set("limit", strconv.Itoa(childPageLimit))
+		v.Set("expand", strings.Join(expand, ","))
+		body, err := client.request("GET", "/rest/api/content/"+id+"/child/page", v.Encode(), nil)
+		if err != nil {
+			return err
+		}
+
+		var response struct {
+			Results []Page `json:"results"`
+			Size    int    `json:"size"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return err
+		}
+
+		for _, page := range response.Results {
+			if err := fn(page); err != nil {
+				return err
+			}
+		}
+
+		if len(response.Results) < childPageLimit {
+			return nil
+		}
+		start += len(response.Results)
+	}
+}
+
+// ForEachDescendant calls fn for every page below id, depth first with
+// parents visited before their children
+func (client *Client) ForEachDescendant(ctx context.Context, id string, fn PageFunc, expand ...string) error {
+	err := client.forEachDescendant(ctx, id, fn, expand)
+	if errors.Is(err, ErrStopIteration) {
+		return nil
+	}
+	return err
+}
+
+func (client *Client) forEachDescendant(ctx context.Context, id string, fn PageFunc, expand []string) error {
+	var children []Page
+	err := client.forEachChild(ctx, id, func(page Page) error {
+		children = append(children, page)
+		return nil
+	}, expand)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		if err := fn(child); err != nil {
+			return err
+		}
+		if err := client.forEachDescendant(ctx, child.ID, fn, expand); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetChildren returns the direct child pages of id
+func (client *Client) GetChildren(ctx context.Context, id string, expand ...string) ([]Page, error) {
+	var pages []Page
+	err := client.ForEachChild(ctx, id, func(page Page) error {
+		pages = append(pages, page)
+		return nil
+	}, expand...)
+	return pages, err
+}
+
+// GetDescendants returns all pages below id
+func (client *Client) GetDescendants(ctx context.Context, id string, expand ...string) ([]Page, error) {
+	var pages []Page
+	err := client.ForEachDescendant(ctx, id, func(page Page) error {
+		pages = append(pages, page)
+		return nil
+	}, expand...)
+	return pages, err
+}
@@ -0,0 +1,249 @@
+package confluence
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-querystring/query"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrVersionConflict is returned by updates of content that got another
+// version since it was read
+var ErrVersionConflict = errors.New("version conflict")
+
+func (client *Client) labelEndpoint(contentID string) string {
+	return "/rest/api/content/" + contentID + "/label"
+}
+
+// GetContent Returns all content in a Confluence instance.
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-get
+func (client *Client) GetContent(qp *GetContentQueryParameters) ([]Content, error) {
+
+	qp.ExpandString = strings.Join(qp.Expand, ",")
+	v, _ := query.Values(qp)
+	queryParams := v.Encode()
+
+	body, err := client.request("GET", "/rest/api/content", queryParams, nil)
+	if err != nil {
+		return nil, err
+	}
+	var contentResponse ContentResponse
+	err = json.Unmarshal(body, &contentResponse)
+	if err != nil {
+		log.Error("Unable to unmarshal ContentResponse. Received: '", string(body), "'")
+	}
+	return contentResponse.Results, err
+}
+
+// GetContentQueryParameters query parameters for GetContent
+type GetContentQueryParameters struct {
+	QueryParameters
+	Expand       []string `url:"-"`
+	ExpandString string   `url:"expand,omitempty"`
+	Limit        int      `url:"limit,omitempty"`
+	Orderby      string   `url:"orderby,omitempty"`
+	PostingDay   string   `url:"postingDay,omitempty"`
+	Spacekey     string   `url:"spaceKey,omitempty"`
+	Start        int      `url:"start,omitempty"`
+	Title        string   `url:"title,omitempty"`
+	Trigger      string   `url:"trigger,omitempty"`
+	Type         string   `url:"type,omitempty"`
+}
+
+// CreateContent creates a new piece of content or publishes an existing draft.
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-post
+func (client *Client) CreateContent(bp *CreateContentBodyParameters, qp *QueryParameters) (Content, error) {
+	var res Content
+	var queryParams string
+	if qp != nil {
+		v, _ := query.Values(qp)
+		queryParams = v.Encode()
+	}
+
+	contentBytes, err := json.Marshal(bp)
+	if err != nil {
+		log.Error("Unable to marshal body. Received: '", err, "'")
+	}
+
+	body, err := client.request("POST", "/rest/api/content", queryParams, bytes.NewReader(contentBytes))
+	if err != nil {
+		return res, err
+	}
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		log.Error(body)
+		log.Error(err)
+		log.Error("Unable to unmarshal CreateContentResponse. Received: '", string(body), "'")
+	}
+	return res, err
+}
+
+// UpdateContent updates a piece of content. Use this method to update the title or body of a piece of content, change the status, change the parent page, and more.
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-id-put
+func (client *Client) UpdateContent(content *Content, qp *QueryParameters) (Content, error) {
+	var queryParams string
+	if qp != nil {
+		v, _ := query.Values(qp)
+		queryParams = v.Encode()
+	}
+
+	contentBytes, err := json.Marshal(content)
+	if err != nil {
+		log.Error("Unable to marshal body. Received: '", err, "'")
+	}
+
+	body, err := client.request("PUT", "/rest/api/content/"+content.ID, queryParams, bytes.NewReader(contentBytes))
+	if err != nil {
+		var res APIResponse
+		if json.Unmarshal(body, &res) == nil && res.StatusCode == http.StatusConflict {
+			err = fmt.Errorf("%w: %s", ErrVersionConflict, err)
+		}
+		return *content, err
+	}
+	err = json.Unmarshal(body, &content)
+	if err != nil {
+		log.Error(body)
+		log.Error(err)
+		log.Error("Unable to unmarshal UpdateContent response. Received: '", string(body), "'")
+	}
+	return *content, err
+}
+
+// LabelPrefix ...
+type LabelPrefix string
+
+const (
+	// GlobalPrefix ...
+	GlobalPrefix LabelPrefix = "global"
+	// LocalPrefix ...
+	LocalPrefix LabelPrefix = "local"
+)
+
+// AddLabels ...
+func (client *Client) AddLabels(contentID string, labels []string, prefix LabelPrefix) error {
+	type Label struct {
+		Prefix string `json:"prefix"`
+		Name   string `json:"name"`
+	}
+	var labelsContent []Label
+	for _, l := range labels {
+		labelsContent = append(labelsContent, Label{string(prefix), l})
+	}
+
+	labelsContentBytes, err := json.Marshal(labelsContent)
+	if err != nil {
+		return err
+	}
+	labelEndpoint := client.labelEndpoint(contentID)
+	_, err = client.request("POST", labelEndpoint, "", bytes.NewReader(labelsContentBytes))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Label is a label of a piece of content
+type Label struct {
+	Prefix string `json:"prefix"`
+	Name   string `json:"name"`
+	ID     string `json:"id,omitempty"`
+}
+
+// GetLabels returns the labels of a piece of content
+func (client *Client) GetLabels(contentID string) ([]Label, error) {
+	var labels []Label
+	start := 0
+	for {
+		body, err := client.request("GET", client.labelEndpoint(contentID), fmt.Sprintf("start=%d&limit=200", start), nil)
+		if err != nil {
+			return nil, err
+		}
+		var res struct {
+			Results []Label `json:"results"`
+			Size    int     `json:"size"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil {
+			return nil, err
+		}
+		labels = append(labels, res.Results...)
+		if len(res.Results) < 200 {
+			return labels, nil
+		}
+		start += len(res.Results)
+	}
+}
+
+// RemoveLabel removes a label from a piece of content
+func (client *Client) RemoveLabel(contentID, name string) error {
+	_, err := client.request("DELETE", client.labelEndpoint(contentID), "name="+url.QueryEscape(name), nil)
+	return err
+}
+
+// CreateContentBodyParameters query parameters for CreateContent
+type CreateContentBodyParameters struct {
+	Content
+}
+
+// DeleteContent oves a piece of content to the space’s trash or purges it from the trash, depending on the content’s type and status:
+//   - If the content’s type is `page` or `blogpost` and its status is `current`, it will be trashed.
+//   - If the content’s type is `page` or `blogpost` and its status is `trashed`, the content will be purged from the trash and deleted permanently. Note, you must also set the `status` query parameter to `trashed` in your request.
+//   - If the content’s type is `comment` or `attachment`, it will be deleted permanently without being trashed.
+//
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-id-delete
+func (client *Client) DeleteContent(content Content) error {
+	_, err := client.request("DELETE", "/rest/api/content/"+content.ID, "", nil)
+	return err
+}
+
+// ContentResponse represents the data returned from the Confluence API
+type ContentResponse struct {
+	Results []Content `json:"results"`
+}
+
+// Content represents the data returned from the Confluence API
+type Content struct {
+	ID        string `json:"id,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Title     string `json:"title,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Ancestors []struct {
+		ID string `json:"id,omitempty"`
+	} `json:"ancestors,omitempty"`
+	Space struct {
+		Key string `json:"key,omitempty"`
+	} `json:"space,omitempty"`
+	Version struct {
+		Number  int    `json:"number,omitempty"`
+		Message string `json:"message,omitempty"`
+	} `json:"version,omitempty"`
+	Body struct {
+		Storage struct {
+			Value           string        `json:"value,omitempty"`
+			Representation  string        `json:"representation,omitempty"`
+			EmbeddedContent []interface{} `json:"embeddedContent,omitempty"`
+			Expandable      struct {
+				Content string `json:"content,omitempty"`
+			} `json:"_expandable,omitempty"`
+		} `json:"storage,omitempty"`
+	} `json:"body,omitempty"`
+	Links struct {
+		Self   string `json:"self,omitempty"`
+		Tinyui string `json:"tinyui,omitempty"`
+		Editui string `json:"editui,omitempty"`
+		Webui  string `json:"webui,omitempty"`
+	} `json:"_links,omitempty"`
+	// Metadata is only returned when expanded, e.g. metadata.properties.<key>
+	Metadata *ContentMetadata `json:"metadata,omitempty"`
+}
+
+// ContentMetadata holds the expanded content properties by key
+type ContentMetadata struct {
+	Properties map[string]ContentProperty `json:"properties,omitempty"`
+}
@@ -0,0 +1,143 @@
+package confluence
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// CopyPageOptions controls what CopyPage and CopyPageHierarchy copy
+type CopyPageOptions struct {
+	CopyAttachments bool
+	CopyPermissions bool
+	CopyProperties  bool
+	CopyLabels      bool
+	// TitlePrefix is prepended to the title of every copied page, which is
+	// required when copying within the same space
+	TitlePrefix string
+}
+
+// CopyPage copies a single page below destinationParentID (Cloud only)
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-id-copy-post
+func (client *Client) CopyPage(id, destinationParentID, title string, opts CopyPageOptions) (*Page, error) {
+	if client.isServer() {
+		return nil, ErrNotSupported
+	}
+	type destination struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	request := struct {
+		CopyAttachments bool        `json:"copyAttachments"`
+		CopyPermissions bool        `json:"copyPermissions"`
+		CopyProperties  bool        `json:"copyProperties"`
+		CopyLabels      bool        `json:"copyLabels"`
+		Destination     destination `json:"destination"`
+		PageTitle       string      `json:"pageTitle,omitempty"`
+	}{
+		CopyAttachments: opts.CopyAttachments,
+		CopyPermissions: opts.CopyPermissions,
+		CopyProperties:  opts.CopyProperties,
+		CopyLabels:      opts.CopyLabels,
+		Destination:     destination{Type: "parent_page", Value: destinationParentID},
+		PageTitle:       title,
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	body, err := client.request("POST", "/rest/api/content/"+id+"/copy", "", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	var page Page
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// CopyPageHierarchy starts an asynchronous copy of a page and all its
+// descendants below destinationParentID (Cloud only) and returns the task id
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-id-pagehierarchy-copy-post
+func (client *Client) CopyPageHierarchy(id, destinationParentID string, opts CopyPageOptions) (string, error) {
+	if client.isServer() {
+		return "", ErrNotSupported
+	}
+	type titleOptions struct {
+		Prefix string `json:"prefix,omitempty"`
+	}
+	request := struct {
+		CopyAttachments   bool         `json:"copyAttachments"`
+		CopyPermissions   bool         `json:"copyPermissions"`
+		CopyProperties    bool         `json:"copyProperties"`
+		CopyLabels        bool         `json:"copyLabels"`
+		DestinationPageID string       `json:"destinationPageId"`
+		TitleOptions      titleOptions `json:"titleOptions"`
+	}{
+		CopyAttachments:   opts.CopyAttachments,
+		CopyPermissions:   opts.CopyPermissions,
+		CopyProperties:    opts.CopyProperties,
+		CopyLabels:        opts.CopyLabels,
+		DestinationPageID: destinationParentID,
+		TitleOptions:      titleOptions{Prefix: opts.TitlePrefix},
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	body, err := client.request("POST", "/rest/api/content/"+id+"/pagehierarchy/copy", "", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	var task struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &task); err != nil {
+		return "", err
+	}
+	return task.ID, nil
+}
+
+// CopyPageTree copies a page and its descendants below destinationParentID
+// by creating each page again. It works on Server and Data Center where the
+// copy endpoints are not available, and returns the id of the copied root.
+func (client *Client) CopyPageTree(ctx context.Context, id, destinationParentID string, opts CopyPageOptions) (string, error) {
+	source, err := client.GetPage(id, "space", "body.storage")
+	if err != nil {
+		return "", err
+	}
+
+	copied, err := client.CreatePage(&Page{
+		Title:     opts.TitlePrefix + source.Title,
+		Space:     source.Space,
+		Ancestors: []PageAncestor{{ID: destinationParentID}},
+		Body:      source.Body,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if opts.CopyAttachments {
+		attachments, err := client.GetAttachmentsFiltered(id, nil)
+		if err != nil {
+			return "", err
+		}
+		for _, a := range attachments {
+			if _, err := client.CopyAttachment(id, a.ID, copied.ID); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	children, err := client.GetChildren(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	for _, child := range children {
+		if _, err := client.CopyPageTree(ctx, child.ID, copied.ID, opts); err != nil {
+			return "", err
+		}
+	}
+	return copied.ID, nil
+}
@@ -0,0 +1,124 @@
+package confluence
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNotSupported is returned for API calls the deployment does not offer,
+// e.g. account id lookups on Server or page hierarchy copies on Data Center
+var ErrNotSupported = errors.New("not supported by this Confluence deployment")
+
+// Deployment types
+const (
+	DeploymentCloud  = "cloud"
+	DeploymentServer = "server"
+)
+
+// ServerInfo describes the Confluence instance behind an endpoint
+type ServerInfo struct {
+	Name        string `xml:"name"`
+	TypeID      string `xml:"typeId"`
+	Version     string `xml:"version"`
+	BuildNumber string `xml:"buildNumber"`
+	// Deployment is DeploymentCloud or DeploymentServer, the latter also
+	// covering Data Center
+	Deployment string `xml:"-"`
+}
+
+// ServerInfo returns the product, version and deployment type of the
+// instance from its application links manifest. The result is cached.
+func (client *Client) ServerInfo() (*ServerInfo, error) {
+	v, err := client.cached("serverinfo", func() (interface{}, error) {
+		req, err := http.NewRequest("GET", client.Endpoint+"/rest/applinks/1.0/manifest", nil)
+		if err != nil {
+			return nil, err
+		}
+		client.authenticate(req)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(res.Body)
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unable to read the manifest of %s: %s", client.Endpoint, res.Status)
+		}
+
+		var info ServerInfo
+		if err := xml.Unmarshal(body, &info); err != nil {
+			return nil, fmt.Errorf("unable to read the manifest of %s: %s", client.Endpoint, err)
+		}
+		info.Deployment = DeploymentServer
+		// Cloud reports a fixed 1000.x version
+		if client.cloudHost() || strings.HasPrefix(info.Version, "1000.") {
+			info.Deployment = DeploymentCloud
+		}
+		return &info, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*ServerInfo), nil
+}
+
+// IsCloud reports whether the endpoint is Confluence Cloud. Atlassian hosted
+// endpoints are recognised without a request, others by their manifest.
+func (client *Client) IsCloud() bool {
+	if client.cloudHost() {
+		return true
+	}
+	info, err := client.ServerInfo()
+	return err == nil && info.Deployment == DeploymentCloud
+}
+
+// isServer reports whether the endpoint was detected as Server or Data
+// Center. Unlike !IsCloud it is false when detection fails.
+func (client *Client) isServer() bool {
+	if client.cloudHost() {
+		return false
+	}
+	info, err := client.ServerInfo()
+	return err == nil && info.Deployment == DeploymentServer
+}
+
+func (client *Client) cloudHost() bool {
+	return isCloudHost(client.Endpoint)
+}
+
+func isCloudHost(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return strings.HasSuffix(host, ".atlassian.net") || strings.HasSuffix(host, ".jira.com")
+}
+
+// NormalizeEndpoint trims trailing slashes and adds the /wiki context path
+// Cloud serves Confluence under, so https://example.atlassian.net works as
+// an endpoint. Server and Data Center context paths are left alone.
+func NormalizeEndpoint(endpoint string) string {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if u, err := url.Parse(endpoint); err == nil && isCloudHost(endpoint) && u.Path == "" {
+		endpoint += "/wiki"
+	}
+	return endpoint
+}
+
+// FindUser looks a user up by the identifier the deployment uses: an email
+// address or account id on Cloud, a username on Server and Data Center
+func (client *Client) FindUser(identifier string) (*User, error) {
+	if client.isServer() {
+		return client.GetUserByUsername(identifier)
+	}
+	if strings.Contains(identifier, "@") {
+		return client.GetUserByEmail(identifier)
+	}
+	return client.GetUser(identifier)
+}
@@ -0,0 +1,11 @@
+module github.com/justmiles/go-confluence
+
+go 1.19
+
+require (
+	github.com/google/go-querystring v1.1.0
+	github.com/naminomare/gogutil v0.0.0-20220326064723-17315315cf0e
+	github.com/sirupsen/logrus v1.9.0
+)
+
+require golang.org/x/sys v0.1.0 // indirect
@@ -0,0 +1,86 @@
+package confluence
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Operations passed to a Guard
+const (
+	GuardDelete           = "delete"
+	GuardPurge            = "purge"
+	GuardDeleteAttachment = "delete attachment"
+	GuardMove             = "move"
+	GuardMoveAttachment   = "move attachment"
+)
+
+// Guard is called before every request deleting or moving content, with the
+// operation and the ids of the pages it affects. For GuardMove these are the
+// page and the target page, position is MoveBefore, MoveAfter or
+// MoveAppend. For GuardMoveAttachment they are the page the attachment is on
+// and the page it is moved to. An error aborts the request.
+type Guard func(operation, position string, contentIDs []string) error
+
+// guardedPath matches the content endpoints of destructive requests
+var guardedPath = regexp.MustCompile(`^/rest/api/content/([^/]+)(?:/(child/attachment|move)/(.+))?$`)
+
+// guardedOperation returns the Guard operation, position and page ids of a
+// request with body, an empty operation for requests that do not delete or
+// move content
+func guardedOperation(method, apiEndpoint, queryParams string, body []byte) (operation, position string, contentIDs []string) {
+	match := guardedPath.FindStringSubmatch(apiEndpoint)
+	if match == nil {
+		return "", "", nil
+	}
+	id, sub, rest := match[1], match[2], match[3]
+	switch {
+	case method == "DELETE" && sub == "":
+		if q, err := url.ParseQuery(queryParams); err == nil && q.Get("status") == "trashed" {
+			return GuardPurge, "", []string{id}
+		}
+		return GuardDelete, "", []string{id}
+	case method == "DELETE" && sub == "child/attachment":
+		return GuardDeleteAttachment, "", []string{id}
+	case method == "PUT" && sub == "move":
+		position, target, _ := strings.Cut(rest, "/")
+		return GuardMove, position, []string{id, target}
+	case method == "PUT" && sub == "child/attachment" && !strings.Contains(rest, "/"):
+		// an attachment update with another container moves it
+		var attachment struct {
+			Container struct {
+				ID string `json:"id"`
+			} `json:"container"`
+		}
+		if json.Unmarshal(body, &attachment) == nil && attachment.Container.ID != "" && attachment.Container.ID != id {
+			return GuardMoveAttachment, "", []string{id, attachment.Container.ID}
+		}
+	}
+	return "", "", nil
+}
+
+// guard runs the client's Guard for destructive requests. It reads the
+// payload of attachment updates, which move attachments when they change
+// their container, and returns a payload to send in place of the one read.
+func (client *Client) guard(method, apiEndpoint, queryParams string, payload io.Reader) (io.Reader, error) {
+	if client.Guard == nil {
+		return payload, nil
+	}
+	var body []byte
+	if method == "PUT" && payload != nil && strings.Contains(apiEndpoint, "/child/attachment/") {
+		var err error
+		if body, err = ioutil.ReadAll(payload); err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(body)
+	}
+	operation, position, ids := guardedOperation(method, apiEndpoint, queryParams, body)
+	if operation == "" {
+		return payload, nil
+	}
+	return payload, client.Guard(operation, position, ids)
+}
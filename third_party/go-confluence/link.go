@@ -0,0 +1,100 @@
+package confluence
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrNotPageLink is returned by ResolveLink for links that do not point to
+// a page of the client's Confluence
+var ErrNotPageLink = errors.New("not a link to a Confluence page")
+
+var (
+	tinyLinkPath    = regexp.MustCompile(`^/x/([A-Za-z0-9_-]+)/?$`)
+	spacesPagePath  = regexp.MustCompile(`^/spaces/[^/]+/pages/(\d+)(?:/.*)?$`)
+	displayPagePath = regexp.MustCompile(`^/display/([^/]+)/([^/]+)/?$`)
+)
+
+// ResolveLink returns the page, with its space, a link to Confluence points
+// to: a tiny link such as /x/AbCd or a page URL such as
+// /spaces/KEY/pages/123/Title, /pages/viewpage.action?pageId=123 or
+// /display/KEY/Title. Links are absolute URLs of the client's endpoint or
+// paths on it. Results are cached.
+func (client *Client) ResolveLink(link string) (*Page, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, ErrNotPageLink
+	}
+	endpoint, err := url.Parse(client.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case u.IsAbs() && !strings.EqualFold(u.Host, endpoint.Host):
+		return nil, ErrNotPageLink
+	case !u.IsAbs() && (u.Host != "" || !strings.HasPrefix(u.Path, "/")):
+		return nil, ErrNotPageLink
+	}
+	path := u.Path
+	if base := strings.TrimSuffix(endpoint.Path, "/"); base != "" && strings.HasPrefix(path, base+"/") {
+		path = strings.TrimPrefix(path, base)
+	}
+
+	var id string
+	if match := tinyLinkPath.FindStringSubmatch(path); match != nil {
+		pageID, err := tinyLinkID(match[1])
+		if err != nil {
+			return nil, ErrNotPageLink
+		}
+		id = strconv.FormatUint(pageID, 10)
+	} else if match := spacesPagePath.FindStringSubmatch(path); match != nil {
+		id = match[1]
+	} else if path == "/pages/viewpage.action" && u.Query().Get("pageId") != "" {
+		id = u.Query().Get("pageId")
+	} else if match := displayPagePath.FindStringSubmatch(path); match != nil {
+		return client.LookupPage(match[1], strings.ReplaceAll(match[2], "+", " "))
+	} else {
+		return nil, ErrNotPageLink
+	}
+
+	v, err := client.cached("page:"+id, func() (interface{}, error) {
+		return client.GetPage(id, "space")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Page), nil
+}
+
+// LookupPage returns the page with title in space, with its space, or
+// ErrPageNotFound. Results are cached.
+func (client *Client) LookupPage(space, title string) (*Page, error) {
+	v, err := client.cached("page:title:"+space+":"+title, func() (interface{}, error) {
+		return client.GetPageByTitle(space, title, "space")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Page), nil
+}
+
+// tinyLinkID decodes the page id of a tiny link code: the page id as little
+// endian bytes, base64 encoded with - and _ for / and + and without trailing
+// zero bytes
+func tinyLinkID(code string) (uint64, error) {
+	code = strings.NewReplacer("-", "/", "_", "+").Replace(code)
+	if len(code) > 11 {
+		return 0, errors.New("tiny link code too long")
+	}
+	code += strings.Repeat("A", 11-len(code)) + "="
+	b, err := base64.StdEncoding.DecodeString(code)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
@@ -0,0 +1,193 @@
+package confluence
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrPageNotFound is returned when a page lookup has no result
+var ErrPageNotFound = errors.New("page not found")
+
+// DefaultPageExpand is used by GetPage and GetPageByTitle when no expand is given
+var DefaultPageExpand = []string{"space", "version", "ancestors", "body.storage"}
+
+// Page is a typed Confluence page
+type Page struct {
+	ID        string         `json:"id,omitempty"`
+	Type      string         `json:"type,omitempty"`
+	Status    string         `json:"status,omitempty"`
+	Title     string         `json:"title,omitempty"`
+	Space     PageSpace      `json:"space,omitempty"`
+	Ancestors []PageAncestor `json:"ancestors,omitempty"`
+	Body      PageBody       `json:"body,omitempty"`
+	Version   PageVersion    `json:"version,omitempty"`
+	Links     PageLinks      `json:"_links,omitempty"`
+}
+
+// PageSpace identifies the space of a page
+type PageSpace struct {
+	Key string `json:"key,omitempty"`
+}
+
+// PageAncestor is a parent page. Only ID is used when creating or updating.
+type PageAncestor struct {
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+// PageBody holds the page content
+type PageBody struct {
+	Storage PageStorage `json:"storage,omitempty"`
+}
+
+// PageStorage is the storage format representation of a page body
+type PageStorage struct {
+	Value          string `json:"value"`
+	Representation string `json:"representation,omitempty"`
+}
+
+// PageVersion is the page version. Updates must send the next version number.
+type PageVersion struct {
+	Number    int    `json:"number,omitempty"`
+	Message   string `json:"message,omitempty"`
+	MinorEdit bool   `json:"minorEdit,omitempty"`
+}
+
+// PageLinks are the links returned for a page
+type PageLinks struct {
+	Self   string `json:"self,omitempty"`
+	Tinyui string `json:"tinyui,omitempty"`
+	Editui string `json:"editui,omitempty"`
+	Webui  string `json:"webui,omitempty"`
+	Base   string `json:"base,omitempty"`
+}
+
+func expandQuery(expand []string) string {
+	if len(expand) == 0 {
+		expand = DefaultPageExpand
+	}
+	v := url.Values{}
+	v.Set("expand", strings.Join(expand, ","))
+	return v.Encode()
+}
+
+// GetPage returns a page by id, expanding the given properties
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-id-get
+func (client *Client) GetPage(id string, expand ...string) (*Page, error) {
+	body, err := client.request("GET", "/rest/api/content/"+id, expandQuery(expand), nil)
+	if err != nil {
+		return nil, err
+	}
+	var page Page
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, err
+	}
+	if page.ID == "" {
+		return nil, ErrPageNotFound
+	}
+	return &page, nil
+}
+
+// GetPageVersion returns an earlier version of a page, with its title and
+// body
+func (client *Client) GetPageVersion(id string, version int) (*Page, error) {
+	v := url.Values{}
+	v.Set("status", "historical")
+	v.Set("version", strconv.Itoa(version))
+	body, err := client.request("GET", "/rest/api/content/"+id, v.Encode()+"&"+expandQuery([]string{"version", "body.storage"}), nil)
+	if err != nil {
+		return nil, err
+	}
+	var page Page
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, err
+	}
+	if page.ID == "" {
+		return nil, ErrPageNotFound
+	}
+	return &page, nil
+}
+
+// GetPageByTitle returns the page with title in space, or ErrPageNotFound
+func (client *Client) GetPageByTitle(space, title string, expand ...string) (*Page, error) {
+	v := url.Values{}
+	v.Set("spaceKey", space)
+	v.Set("title", title)
+	v.Set("type", "page")
+	v.Set("limit", "1")
+	body, err := client.request("GET", "/rest/api/content", v.Encode()+"&"+expandQuery(expand), nil)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Results []Page `json:"results"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Results) == 0 {
+		return nil, ErrPageNotFound
+	}
+	return &response.Results[0], nil
+}
+
+// CreatePage creates a page. The body representation defaults to storage.
+func (client *Client) CreatePage(page *Page) (*Page, error) {
+	page.Type = "page"
+	if page.Body.Storage.Representation == "" {
+		page.Body.Storage.Representation = "storage"
+	}
+	return client.sendPage("POST", "/rest/api/content", page)
+}
+
+// UpdatePage updates a page. page.Version.Number must be the next version,
+// i.e. the current version plus one.
+func (client *Client) UpdatePage(page *Page) (*Page, error) {
+	if page.ID == "" {
+		return nil, fmt.Errorf("page id is required for updates")
+	}
+	if page.Version.Number < 2 {
+		return nil, fmt.Errorf("page %s update needs the next version number, got %d", page.ID, page.Version.Number)
+	}
+	page.Type = "page"
+	if page.Body.Storage.Representation == "" {
+		page.Body.Storage.Representation = "storage"
+	}
+	return client.sendPage("PUT", "/rest/api/content/"+page.ID, page)
+}
+
+func (client *Client) sendPage(method, endpoint string, page *Page) (*Page, error) {
+	payload, err := json.Marshal(page)
+	if err != nil {
+		return nil, err
+	}
+	body, err := client.request(method, endpoint, "", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	var res Page
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Move positions for MovePage
+const (
+	MoveBefore = "before"
+	MoveAfter  = "after"
+	MoveAppend = "append"
+)
+
+// MovePage moves a page before or after a sibling, or appends it as the last
+// child of targetID (Cloud only)
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-pageId-move-position-targetId-put
+func (client *Client) MovePage(id, position, targetID string) error {
+	_, err := client.request("PUT", "/rest/api/content/"+id+"/move/"+position+"/"+targetID, "", nil)
+	return err
+}
@@ -0,0 +1,148 @@
+package confluence
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrPropertyNotFound is returned when a content property does not exist
+var ErrPropertyNotFound = errors.New("content property not found")
+
+// ContentProperty is a JSON value stored on a page under a key
+type ContentProperty struct {
+	ID      string          `json:"id,omitempty"`
+	Key     string          `json:"key"`
+	Value   json.RawMessage `json:"value"`
+	Version struct {
+		Number int `json:"number"`
+	} `json:"version,omitempty"`
+}
+
+// GetContentProperty returns the property key of a page, or ErrPropertyNotFound
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-id-property-key-get
+func (client *Client) GetContentProperty(contentID, key string) (*ContentProperty, error) {
+	body, err := client.request("GET", "/rest/api/content/"+contentID+"/property/"+url.PathEscape(key), "", nil)
+	if err != nil {
+		var res APIResponse
+		if json.Unmarshal(body, &res) == nil && res.StatusCode == http.StatusNotFound {
+			return nil, ErrPropertyNotFound
+		}
+		return nil, err
+	}
+	var property ContentProperty
+	if err := json.Unmarshal(body, &property); err != nil {
+		return nil, err
+	}
+	return &property, nil
+}
+
+// GetContentProperties returns the properties of a page
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-id-property-get
+func (client *Client) GetContentProperties(contentID string) ([]ContentProperty, error) {
+	body, err := client.request("GET", "/rest/api/content/"+contentID+"/property", "limit=200", nil)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Results []ContentProperty `json:"results"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+// SetContentProperty creates the property key of a page or updates it to the
+// next version
+func (client *Client) SetContentProperty(contentID, key string, value interface{}) error {
+	v, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	property := ContentProperty{Key: key, Value: v}
+
+	existing, err := client.GetContentProperty(contentID, key)
+	method, endpoint := "PUT", "/rest/api/content/"+contentID+"/property/"+url.PathEscape(key)
+	switch {
+	case errors.Is(err, ErrPropertyNotFound):
+		method, endpoint = "POST", "/rest/api/content/"+contentID+"/property"
+		property.Version.Number = 1
+	case err != nil:
+		return err
+	default:
+		property.Version.Number = existing.Version.Number + 1
+	}
+
+	payload, err := json.Marshal(property)
+	if err != nil {
+		return err
+	}
+	_, err = client.request(method, endpoint, "", bytes.NewReader(payload))
+	return err
+}
+
+// ErrPropertyConflict is returned when a content property was created or
+// changed by someone else in the meantime
+var ErrPropertyConflict = errors.New("content property was changed concurrently")
+
+// CreateContentProperty creates the property key of a page, or returns
+// ErrPropertyConflict when it exists
+func (client *Client) CreateContentProperty(contentID, key string, value interface{}) error {
+	v, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	property := ContentProperty{Key: key, Value: v}
+	property.Version.Number = 1
+	payload, err := json.Marshal(property)
+	if err != nil {
+		return err
+	}
+	body, err := client.request("POST", "/rest/api/content/"+contentID+"/property", "", bytes.NewReader(payload))
+	if err != nil && propertyConflict(body) {
+		return ErrPropertyConflict
+	}
+	return err
+}
+
+// UpdateContentProperty sets the property key of a page to version, or
+// returns ErrPropertyConflict when its current version is not version-1
+func (client *Client) UpdateContentProperty(contentID, key string, value interface{}, version int) error {
+	v, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	property := ContentProperty{Key: key, Value: v}
+	property.Version.Number = version
+	payload, err := json.Marshal(property)
+	if err != nil {
+		return err
+	}
+	body, err := client.request("PUT", "/rest/api/content/"+contentID+"/property/"+url.PathEscape(key), "", bytes.NewReader(payload))
+	if err != nil && propertyConflict(body) {
+		return ErrPropertyConflict
+	}
+	return err
+}
+
+// DeleteContentProperty deletes the property key of a page
+func (client *Client) DeleteContentProperty(contentID, key string) error {
+	_, err := client.request("DELETE", "/rest/api/content/"+contentID+"/property/"+url.PathEscape(key), "", nil)
+	return err
+}
+
+// propertyConflict tells whether a failed property write collided with
+// another one: Confluence answers 409 for stale versions and 400 for keys
+// that already exist
+func propertyConflict(body []byte) bool {
+	var res APIResponse
+	if json.Unmarshal(body, &res) != nil {
+		return false
+	}
+	return res.StatusCode == http.StatusConflict ||
+		res.StatusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(res.Message), "already exists")
+}
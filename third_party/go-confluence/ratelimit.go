@@ -0,0 +1,70 @@
+package confluence
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket shared by all transfers of a client, so the
+// limit holds across concurrent uploads
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	return &rateLimiter{rate: float64(bytesPerSecond), tokens: float64(bytesPerSecond), last: time.Now()}
+}
+
+// wait takes n tokens, sleeping until the bucket has refilled enough. The
+// bucket holds at most a second worth of tokens.
+func (l *rateLimiter) wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	time.Sleep(delay)
+}
+
+// throttledReader reads from r no faster than its limiter allows
+type throttledReader struct {
+	r       io.Reader
+	limiter *rateLimiter
+	chunk   int
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > t.chunk {
+		p = p[:t.chunk]
+	}
+	n, err := t.r.Read(p)
+	t.limiter.wait(n)
+	return n, err
+}
+
+// throttle limits reading r to client.MaxTransferRate
+func (client *Client) throttle(r io.Reader) io.Reader {
+	if client.MaxTransferRate <= 0 {
+		return r
+	}
+	client.limiterOnce.Do(func() {
+		client.limiter = newRateLimiter(client.MaxTransferRate)
+	})
+	chunk := 32 * 1024
+	if int64(chunk) > client.MaxTransferRate {
+		chunk = int(client.MaxTransferRate)
+	}
+	return &throttledReader{r: r, limiter: client.limiter, chunk: chunk}
+}
@@ -0,0 +1,44 @@
+package confluence
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// restrictionSubject is a user or group a restriction applies to
+type restrictionSubject struct {
+	Type      string `json:"type"`
+	AccountID string `json:"accountId,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+type restriction struct {
+	Operation    string `json:"operation"`
+	Restrictions struct {
+		User  []restrictionSubject `json:"user"`
+		Group []restrictionSubject `json:"group"`
+	} `json:"restrictions"`
+}
+
+// SetEditRestrictions replaces the edit restrictions of a page, so that only
+// users and members of groups can edit it. Read restrictions are left alone.
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-id-restriction-put
+func (client *Client) SetEditRestrictions(contentID string, users []User, groups []string) error {
+	r := restriction{Operation: "update"}
+	r.Restrictions.User = []restrictionSubject{}
+	r.Restrictions.Group = []restrictionSubject{}
+	for _, u := range users {
+		r.Restrictions.User = append(r.Restrictions.User, restrictionSubject{Type: "known", AccountID: u.AccountID, Username: u.Username})
+	}
+	for _, g := range groups {
+		r.Restrictions.Group = append(r.Restrictions.Group, restrictionSubject{Type: "group", Name: g})
+	}
+
+	payload, err := json.Marshal([]restriction{r})
+	if err != nil {
+		return err
+	}
+	_, err = client.request("PUT", "/rest/api/content/"+contentID+"/restriction", "", bytes.NewReader(payload))
+	return err
+}
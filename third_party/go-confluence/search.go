@@ -0,0 +1,162 @@
+package confluence
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-querystring/query"
+	log "github.com/sirupsen/logrus"
+)
+
+// Search searches for content using the Confluence Query Language (CQL)
+// https://developer.atlassian.com/cloud/confluence/rest/#api-search-get
+//
+// Example:
+//
+//	searchResults, err := client.Search(&confluence.SearchQueryParameters{
+//	  CQL:   "space = PE",
+//	  Limit: 1,
+//	})
+//
+//	if err != nil {
+//	  errorAndExit(err)
+//	}
+//
+//	for _, searchResult := range searchResults {
+//	  fmt.Println(searchResult.Title)
+//	}
+func (client *Client) Search(qp *SearchQueryParameters) ([]SearchResult, error) {
+	var queryParams string
+	if qp != nil {
+		v, _ := query.Values(qp)
+		queryParams = v.Encode()
+	}
+	var searchResponse SearchResponse
+
+	body, err := client.request("GET", "/rest/api/search", queryParams, nil)
+	if err != nil {
+		return searchResponse.Results, err
+	}
+	err = json.Unmarshal(body, &searchResponse)
+	if err != nil {
+		log.Error("Unable to unmarshal SearchResponse. Received: '", string(body), "'")
+	}
+
+	if searchResponse.Message != "" {
+		err = errors.New(searchResponse.Message)
+	}
+	return searchResponse.Results, err
+
+}
+
+// SearchQueryParameters query parameters for Search
+type SearchQueryParameters struct {
+	CQL                   string `url:"cql"`
+	CQLContext            string `url:"cqlcontext,omitempty"`
+	IncludeArchivedSpaces bool   `url:"includeArchivedSpaces,omitempty"`
+	Limit                 int    `url:"limit,omitempty"`
+	Start                 int    `url:"start,omitempty"`
+}
+
+// SearchResponse represents the data returned from the Confluence API
+type SearchResponse struct {
+	APIResponse
+	Results        []SearchResult `json:"results,omitempty"`
+	Start          int            `json:"start,omitempty"`
+	Limit          int            `json:"limit,omitempty"`
+	Size           int            `json:"size,omitempty"`
+	TotalSize      int            `json:"totalSize,omitempty"`
+	CqlQuery       string         `json:"cqlQuery,omitempty"`
+	SearchDuration int            `json:"searchDuration,omitempty"`
+	Links          struct {
+		Base    string `json:"base,omitempty"`
+		Context string `json:"context,omitempty"`
+	} `json:"_links,omitempty"`
+}
+
+// SearchResult results from Search
+type SearchResult struct {
+	Space struct {
+		Key      string `json:"key,omitempty"`
+		Name     string `json:"name,omitempty"`
+		Type     string `json:"type,omitempty"`
+		Metadata struct {
+		} `json:"metadata,omitempty"`
+		Status     string `json:"status,omitempty"`
+		Expandable struct {
+			Operations  string `json:"operations,omitempty"`
+			Permissions string `json:"permissions,omitempty"`
+			Description string `json:"description,omitempty"`
+		} `json:"_expandable,omitempty"`
+		Links struct {
+			Self string `json:"self,omitempty"`
+		} `json:"_links,omitempty"`
+	} `json:"space,omitempty"`
+	Title                 string `json:"title,omitempty"`
+	Excerpt               string `json:"excerpt,omitempty"`
+	URL                   string `json:"url,omitempty"`
+	ResultGlobalContainer struct {
+		Title      string `json:"title"`
+		DisplayURL string `json:"displayUrl"`
+	} `json:"resultGlobalContainer"`
+	Breadcrumbs          []interface{} `json:"breadcrumbs,omitempty"`
+	EntityType           string        `json:"entityType,omitempty"`
+	IconCSSClass         string        `json:"iconCssClass,omitempty"`
+	LastModified         time.Time     `json:"lastModified,omitempty"`
+	FriendlyLastModified string        `json:"friendlyLastModified,omitempty"`
+	Score                float64       `json:"score,omitempty"`
+	Content              `json:"content,omitempty"`
+}
+
+// SearchContent returns all content matching a CQL query, following the
+// result pages, with the given properties expanded
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-search-get
+func (client *Client) SearchContent(cql string, expand ...string) ([]Content, error) {
+	const limit = 100
+	v := url.Values{}
+	v.Set("cql", cql)
+	v.Set("limit", strconv.Itoa(limit))
+	if len(expand) > 0 {
+		v.Set("expand", strings.Join(expand, ","))
+	}
+	endpoint, queryParams := "/rest/api/content/search", v.Encode()
+
+	var results []Content
+	for start := 0; ; {
+		body, err := client.request("GET", endpoint, queryParams, nil)
+		if err != nil {
+			return nil, err
+		}
+		var response struct {
+			Results []Content `json:"results"`
+			Size    int       `json:"size"`
+			Links   struct {
+				Next string `json:"next"`
+			} `json:"_links"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, err
+		}
+		results = append(results, response.Results...)
+
+		switch {
+		case response.Links.Next != "":
+			// Cloud paginates with a cursor in the next link
+			next, err := url.Parse(response.Links.Next)
+			if err != nil {
+				return nil, err
+			}
+			endpoint, queryParams = next.Path, next.RawQuery
+		case response.Size == limit:
+			start += limit
+			v.Set("start", strconv.Itoa(start))
+			queryParams = v.Encode()
+		default:
+			return results, nil
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package confluence
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// Space is a Confluence space
+type Space struct {
+	ID         int              `json:"id,omitempty"`
+	Key        string           `json:"key,omitempty"`
+	Name       string           `json:"name,omitempty"`
+	Type       string           `json:"type,omitempty"`
+	Status     string           `json:"status,omitempty"`
+	Operations []SpaceOperation `json:"operations,omitempty"`
+	Homepage   *Page            `json:"homepage,omitempty"`
+}
+
+// SpaceOperation is an operation the current user may perform in a space,
+// e.g. {Operation: "create", TargetType: "page"}
+type SpaceOperation struct {
+	Operation  string `json:"operation"`
+	TargetType string `json:"targetType"`
+}
+
+// GetSpace returns a space, expanding the given properties
+// https://developer.atlassian.com/cloud/confluence/rest/#api-space-spaceKey-get
+func (client *Client) GetSpace(key string, expand ...string) (*Space, error) {
+	var queryParams string
+	if len(expand) > 0 {
+		v := url.Values{}
+		v.Set("expand", strings.Join(expand, ","))
+		queryParams = v.Encode()
+	}
+	body, err := client.request("GET", "/rest/api/space/"+url.PathEscape(key), queryParams, nil)
+	if err != nil {
+		return nil, err
+	}
+	var space Space
+	if err := json.Unmarshal(body, &space); err != nil {
+		return nil, err
+	}
+	return &space, nil
+}
+
+// CanPerform reports whether the space operations allow operation on targetType
+func (s *Space) CanPerform(operation, targetType string) bool {
+	for _, o := range s.Operations {
+		if o.Operation == operation && o.TargetType == targetType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package confluence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LongTask is an asynchronous Confluence operation such as a page hierarchy
+// copy or a space deletion
+type LongTask struct {
+	ID                 string            `json:"id"`
+	Name               LongTaskMessage   `json:"name,omitempty"`
+	ElapsedTime        int64             `json:"elapsedTime,omitempty"`
+	PercentageComplete int               `json:"percentageComplete,omitempty"`
+	Successful         bool              `json:"successful"`
+	Finished           bool              `json:"finished"`
+	Messages           []LongTaskMessage `json:"messages,omitempty"`
+	Status             string            `json:"status,omitempty"`
+}
+
+// LongTaskMessage is a translated message reported by a long running task
+type LongTaskMessage struct {
+	Key         string        `json:"key,omitempty"`
+	Translation string        `json:"translation,omitempty"`
+	Args        []interface{} `json:"args,omitempty"`
+}
+
+func (t *LongTask) messages() string {
+	var messages []string
+	for _, m := range t.Messages {
+		if m.Translation != "" {
+			messages = append(messages, m.Translation)
+		} else if m.Key != "" {
+			messages = append(messages, m.Key)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// WaitOptions controls how WaitForTask polls a task
+type WaitOptions struct {
+	// Interval is the first delay between polls, 1s when zero
+	Interval time.Duration
+	// MaxInterval caps the backoff, 30s when zero
+	MaxInterval time.Duration
+	// Progress is called with the task after every poll
+	Progress func(task *LongTask)
+}
+
+// GetTask returns the state of a long running task
+// https://developer.atlassian.com/cloud/confluence/rest/#api-longtask-id-get
+func (client *Client) GetTask(id string) (*LongTask, error) {
+	body, err := client.request("GET", "/rest/api/longtask/"+id, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	var task LongTask
+	if err := json.Unmarshal(body, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// WaitForTask polls a long running task with exponential backoff until it
+// finishes or ctx is done. An unsuccessful task is returned with an error.
+func (client *Client) WaitForTask(ctx context.Context, id string, opts WaitOptions) (*LongTask, error) {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	maxInterval := opts.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = 30 * time.Second
+	}
+
+	for {
+		task, err := client.GetTask(id)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Progress != nil {
+			opts.Progress(task)
+		}
+		if task.Finished {
+			if !task.Successful {
+				return task, fmt.Errorf("task %s failed: %s", id, task.messages())
+			}
+			return task, nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return task, ctx.Err()
+		case <-timer.C:
+		}
+		interval *= 2
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package confluence
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// GetTrashedPages returns the trashed pages of a space, with their ancestors
+// expanded where Confluence still knows them
+func (client *Client) GetTrashedPages(ctx context.Context, space string) ([]Page, error) {
+	var pages []Page
+	start := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		v := url.Values{}
+		v.Set("spaceKey", space)
+		v.Set("type", "page")
+		v.Set("status", "trashed")
+		v.Set("expand", "ancestors,version")
+		v.Set("start", strconv.Itoa(start))
+		v.Set("limit", strconv.Itoa(childPageLimit))
+		body, err := client.request("GET", "/rest/api/content", v.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Results []Page `json:"results"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, err
+		}
+		pages = append(pages, response.Results...)
+
+		if len(response.Results) < childPageLimit {
+			return pages, nil
+		}
+		start += len(response.Results)
+	}
+}
+
+// GetTrashedPage returns a trashed page by id, with its space and ancestors
+func (client *Client) GetTrashedPage(id string) (*Page, error) {
+	v := url.Values{}
+	v.Set("status", "trashed")
+	v.Set("expand", "space,ancestors")
+	body, err := client.request("GET", "/rest/api/content/"+id, v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var page Page
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, err
+	}
+	if page.ID == "" {
+		return nil, ErrPageNotFound
+	}
+	return &page, nil
+}
+
+// PurgePage permanently deletes a trashed page
+// https://developer.atlassian.com/cloud/confluence/rest/#api-content-id-delete
+func (client *Client) PurgePage(id string) error {
+	_, err := client.request("DELETE", "/rest/api/content/"+id, "status=trashed", nil)
+	return err
+}
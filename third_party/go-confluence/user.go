@@ -0,0 +1,166 @@
+package confluence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// ErrUserNotFound is returned when a user lookup has no result
+var ErrUserNotFound = errors.New("user not found")
+
+// groupMemberLimit is the page size used when listing group members
+const groupMemberLimit = 200
+
+// User is a Confluence user. Cloud identifies users by AccountID, Server and
+// Data Center by Username and UserKey, see FindUser.
+type User struct {
+	Type        string `json:"type,omitempty"`
+	AccountID   string `json:"accountId,omitempty"`
+	AccountType string `json:"accountType,omitempty"`
+	Username    string `json:"username,omitempty"`
+	UserKey     string `json:"userKey,omitempty"`
+	Email       string `json:"email,omitempty"`
+	PublicName  string `json:"publicName,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// GetUser returns a user by Cloud account id, results are cached
+// https://developer.atlassian.com/cloud/confluence/rest/#api-user-get
+func (client *Client) GetUser(accountID string) (*User, error) {
+	if client.isServer() {
+		return nil, ErrNotSupported
+	}
+	return client.getUser("accountId", accountID)
+}
+
+// GetUserByUsername returns a Server or Data Center user by username, results are cached
+func (client *Client) GetUserByUsername(username string) (*User, error) {
+	// Cloud removed usernames for privacy reasons
+	if client.cloudHost() {
+		return nil, ErrNotSupported
+	}
+	return client.getUser("username", username)
+}
+
+func (client *Client) getUser(param, value string) (*User, error) {
+	v, err := client.cached("user:"+param+":"+value, func() (interface{}, error) {
+		q := url.Values{}
+		q.Set(param, value)
+		body, err := client.request("GET", "/rest/api/user", q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		var user User
+		if err := json.Unmarshal(body, &user); err != nil {
+			return nil, err
+		}
+		if user.AccountID == "" && user.Username == "" && user.UserKey == "" {
+			return nil, ErrUserNotFound
+		}
+		return &user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*User), nil
+}
+
+// CurrentUser returns the user the client authenticates as, the result is
+// cached
+// https://developer.atlassian.com/cloud/confluence/rest/#api-user-current-get
+func (client *Client) CurrentUser() (*User, error) {
+	v, err := client.cached("user:current", func() (interface{}, error) {
+		body, err := client.request("GET", "/rest/api/user/current", "", nil)
+		if err != nil {
+			return nil, err
+		}
+		var user User
+		if err := json.Unmarshal(body, &user); err != nil {
+			return nil, err
+		}
+		return &user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*User), nil
+}
+
+// GetUserByEmail searches for a user by email address, results are cached.
+// Cloud only returns users whose email is visible to the caller.
+// https://developer.atlassian.com/cloud/confluence/rest/#api-search-user-get
+func (client *Client) GetUserByEmail(email string) (*User, error) {
+	v, err := client.cached("user:email:"+email, func() (interface{}, error) {
+		q := url.Values{}
+		q.Set("cql", fmt.Sprintf("type=user and user.email=%q", email))
+		q.Set("limit", "1")
+		body, err := client.request("GET", "/rest/api/search/user", q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		var response struct {
+			Results []struct {
+				User User `json:"user"`
+			} `json:"results"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, err
+		}
+		if len(response.Results) == 0 {
+			return nil, ErrUserNotFound
+		}
+		user := response.Results[0].User
+		if user.Email == "" {
+			user.Email = email
+		}
+		return &user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*User), nil
+}
+
+// GetGroupMembers returns all members of a group, fetching further result
+// pages as needed. Results are cached.
+// https://developer.atlassian.com/cloud/confluence/rest/#api-group-groupName-member-get
+func (client *Client) GetGroupMembers(ctx context.Context, group string) ([]User, error) {
+	v, err := client.cached("group:"+group, func() (interface{}, error) {
+		var users []User
+		start := 0
+		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			q := url.Values{}
+			q.Set("start", strconv.Itoa(start))
+			q.Set("limit", strconv.Itoa(groupMemberLimit))
+			body, err := client.request("GET", "/rest/api/group/"+url.PathEscape(group)+"/member", q.Encode(), nil)
+			if err != nil {
+				return nil, err
+			}
+
+			var response struct {
+				Results []User `json:"results"`
+			}
+			if err := json.Unmarshal(body, &response); err != nil {
+				return nil, err
+			}
+			users = append(users, response.Results...)
+
+			if len(response.Results) < groupMemberLimit {
+				return users, nil
+			}
+			start += len(response.Results)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]User), nil
+}